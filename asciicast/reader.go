@@ -0,0 +1,170 @@
+package asciicast
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Event is an event of a recording.
+type Event struct {
+	Time time.Duration // Since the start of the recording
+	Type string        // EventOutput, EventInput, EventResize, or another type
+	Data string
+}
+
+// Size returns the size of the terminal a resize event records.
+func (e Event) Size() (cols int, rows int, err error) {
+	if _, err := fmt.Sscanf(e.Data, "%dx%d", &cols, &rows); err != nil {
+		return 0, 0, fmt.Errorf("asciicast: invalid size %q", e.Data)
+	}
+	return cols, rows, nil
+}
+
+// Reader reads the events of a cast file.
+type Reader struct {
+	Header  Header
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewReader reads the header of a cast file from r and returns a Reader for
+// its events.
+func NewReader(r io.Reader) (*Reader, error) {
+	cr := &Reader{scanner: bufio.NewScanner(r)}
+	cr.scanner.Buffer(nil, 1<<24)
+
+	if !cr.scanner.Scan() {
+		if err := cr.scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("asciicast: missing header")
+	}
+	cr.line++
+
+	if err := json.Unmarshal(cr.scanner.Bytes(), &cr.Header); err != nil {
+		return nil, fmt.Errorf("asciicast: header: %v", err)
+	}
+	if cr.Header.Version != 2 {
+		return nil, fmt.Errorf("asciicast: unsupported version %d", cr.Header.Version)
+	}
+	return cr, nil
+}
+
+// Next returns the next event of the recording, or io.EOF after the last.
+// Blank lines are skipped.
+func (r *Reader) Next() (Event, error) {
+	for r.scanner.Scan() {
+		r.line++
+		if len(r.scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var fields []interface{}
+		if err := json.Unmarshal(r.scanner.Bytes(), &fields); err != nil {
+			return Event{}, fmt.Errorf("asciicast: line %d: %v", r.line, err)
+		}
+
+		if len(fields) == 3 {
+			seconds, timed := fields[0].(float64)
+			eventType, typed := fields[1].(string)
+			data, ok := fields[2].(string)
+			if timed && typed && ok {
+				return Event{Time: time.Duration(seconds * float64(time.Second)), Type: eventType, Data: data}, nil
+			}
+		}
+		return Event{}, fmt.Errorf("asciicast: line %d: invalid event", r.line)
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return Event{}, err
+	}
+	return Event{}, io.EOF
+}
+
+// PlayOption configures optional behavior of Play.
+type PlayOption func(*player)
+
+type player struct {
+	speed  float64
+	idle   time.Duration
+	resize func(cols int, rows int) error
+	sleep  func(time.Duration)
+}
+
+// WithSpeed plays the recording speed times as fast as it was recorded.
+func WithSpeed(speed float64) PlayOption {
+	return func(p *player) {
+		p.speed = speed
+	}
+}
+
+// WithIdleLimit shortens pauses between events to at most limit, before
+// the speed is applied.
+func WithIdleLimit(limit time.Duration) PlayOption {
+	return func(p *player) {
+		p.idle = limit
+	}
+}
+
+// WithResizeHandler calls resize with the size of the terminal each resize
+// event records.
+func WithResizeHandler(resize func(cols int, rows int) error) PlayOption {
+	return func(p *player) {
+		p.resize = resize
+	}
+}
+
+// WithSleep overrides the function Play waits between events with.
+func WithSleep(sleep func(time.Duration)) PlayOption {
+	return func(p *player) {
+		p.sleep = sleep
+	}
+}
+
+// Play writes the output of the recording r reads to w, waiting before each
+// event for the time that passed before it was recorded. Input events are
+// skipped, as are resizes unless WithResizeHandler is given.
+func Play(r *Reader, w io.Writer, opts ...PlayOption) error {
+	p := player{speed: 1, sleep: time.Sleep}
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	var last time.Duration
+	for {
+		event, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		pause := event.Time - last
+		if p.idle > 0 && pause > p.idle {
+			pause = p.idle
+		}
+		if pause > 0 {
+			p.sleep(time.Duration(float64(pause) / p.speed))
+		}
+		last = event.Time
+
+		switch event.Type {
+		case EventOutput:
+			_, err = io.WriteString(w, event.Data)
+		case EventResize:
+			if p.resize != nil {
+				var cols, rows int
+				if cols, rows, err = event.Size(); err == nil {
+					err = p.resize(cols, rows)
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
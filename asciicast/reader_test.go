@@ -0,0 +1,65 @@
+package asciicast
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReader(t *testing.T) {
+	cast := `{"version":2,"width":80,"height":24,"timestamp":1500000000,"title":"build"}
+[1.5,"o","\u001b[1mhello\r\n"]
+
+[1.50025,"i","q"]
+[3.5,"r","100x30"]
+[9.5,"o","bye"]
+`
+	r, err := NewReader(strings.NewReader(cast))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Header.Width != 80 || r.Header.Height != 24 || r.Header.Title != "build" {
+		t.Errorf("header is %+v", r.Header)
+	}
+
+	var output bytes.Buffer
+	var pauses []time.Duration
+	var sizes []string
+	err = Play(r, &output,
+		WithSpeed(2),
+		WithIdleLimit(4*time.Second),
+		WithSleep(func(d time.Duration) {
+			pauses = append(pauses, d)
+		}),
+		WithResizeHandler(func(cols int, rows int) error {
+			sizes = append(sizes, fmt.Sprintf("%dx%d", cols, rows))
+			return nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := output.String(); actual != "\x1b[1mhello\r\nbye" {
+		t.Errorf("played %q", actual)
+	}
+	expected := []time.Duration{750 * time.Millisecond, 125 * time.Microsecond, 999875 * time.Microsecond, 2 * time.Second}
+	if fmt.Sprint(pauses) != fmt.Sprint(expected) {
+		t.Errorf("paused for %v, expected %v", pauses, expected)
+	}
+	if fmt.Sprint(sizes) != "[100x30]" {
+		t.Errorf("resized to %v", sizes)
+	}
+
+	for _, invalid := range []string{"", `{"version":1}`, `{"version":2}` + "\n[1,\"o\"]"} {
+		r, err := NewReader(strings.NewReader(invalid))
+		if err == nil {
+			_, err = r.Next()
+		}
+		if err == nil || err == io.EOF {
+			t.Errorf("reading %q succeeded", invalid)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Package asciicast records terminal sessions as asciinema cast files
+// (version 2), which standard players such as asciinema-player replay, and
+// plays them back; see
+// https://docs.asciinema.org/manual/asciicast/v2/.
+package asciicast
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Header is the first line of a cast file, describing the recording.
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// The event types of a cast file.
+const (
+	EventOutput = "o"
+	EventInput  = "i"
+	EventResize = "r"
+)
+
+// Writer records a session to a cast file. Writes to it record the output of
+// the session, so it can be combined with the session's output using
+// io.MultiWriter; Input and Resize record the rest.
+//
+// A Writer is safe for concurrent use.
+type Writer struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	start   time.Time
+	now     func() time.Time
+	partial map[string][]byte // Incomplete UTF-8 sequences ending writes, by event type
+}
+
+// WriterOption configures optional behavior of a Writer.
+type WriterOption func(*Writer, *Header)
+
+// WithTitle sets the title of the recording.
+func WithTitle(title string) WriterOption {
+	return func(_ *Writer, h *Header) {
+		h.Title = title
+	}
+}
+
+// WithEnv records environment variables, conventionally SHELL and TERM.
+func WithEnv(env map[string]string) WriterOption {
+	return func(_ *Writer, h *Header) {
+		h.Env = env
+	}
+}
+
+// WithClock overrides the source of the current time, which timestamps the
+// recording and its events.
+func WithClock(now func() time.Time) WriterOption {
+	return func(w *Writer, _ *Header) {
+		w.now = now
+	}
+}
+
+// NewWriter writes the header of a recording of a cols by rows terminal to w
+// and returns a Writer recording events to it, timed from now.
+func NewWriter(w io.Writer, cols int, rows int, opts ...WriterOption) (*Writer, error) {
+	cw := &Writer{
+		encoder: json.NewEncoder(w),
+		now:     time.Now,
+		partial: make(map[string][]byte),
+	}
+	cw.encoder.SetEscapeHTML(false)
+
+	header := Header{Version: 2, Width: cols, Height: rows}
+	for _, opt := range opts {
+		opt(cw, &header)
+	}
+
+	cw.start = cw.now()
+	header.Timestamp = cw.start.Unix()
+	if err := cw.encoder.Encode(header); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+// Write records p as output of the session.
+func (w *Writer) Write(p []byte) (int, error) {
+	if err := w.event(EventOutput, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Input records p as input to the session.
+func (w *Writer) Input(p []byte) error {
+	return w.event(EventInput, p)
+}
+
+// Resize records the terminal being resized to cols by rows.
+func (w *Writer) Resize(cols int, rows int) error {
+	return w.event(EventResize, []byte(fmt.Sprintf("%dx%d", cols, rows)))
+}
+
+// event records data as an event of a type. Event data must be whole UTF-8
+// characters, so an incomplete sequence ending data is held until the rest
+// arrives.
+func (w *Writer) event(eventType string, data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data = append(w.partial[eventType], data...)
+	complete := len(data) - incompleteSuffix(data)
+	w.partial[eventType] = append([]byte(nil), data[complete:]...)
+	if complete == 0 {
+		return nil
+	}
+
+	elapsed := w.now().Sub(w.start).Seconds()
+	elapsed = math.Round(elapsed*1e6) / 1e6
+	return w.encoder.Encode([]interface{}{elapsed, eventType, string(data[:complete])})
+}
+
+// incompleteSuffix returns the length of an incomplete UTF-8 sequence ending
+// data, or 0 if there is none.
+func incompleteSuffix(data []byte) int {
+	for n := 1; n < utf8.UTFMax && n <= len(data); n++ {
+		c := data[len(data)-n]
+		if utf8.RuneStart(c) {
+			if !utf8.FullRune(data[len(data)-n:]) {
+				return n
+			}
+			return 0
+		}
+	}
+	return 0
+}
@@ -0,0 +1,39 @@
+package asciicast
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestWriter(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	clock := func() time.Time {
+		return now
+	}
+
+	var cast bytes.Buffer
+	w, err := NewWriter(&cast, 80, 24, WithTitle("build"), WithEnv(map[string]string{"TERM": "xterm-256color"}), WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(1500 * time.Millisecond)
+	w.Write([]byte("\x1b[1mhello <world>\r\n"))
+	now = now.Add(250 * time.Microsecond)
+	w.Write([]byte("\xe4\xb8"))
+	w.Input([]byte("q"))
+	w.Write([]byte("\xad"))
+	now = now.Add(2 * time.Second)
+	w.Resize(100, 30)
+
+	expected := `{"version":2,"width":80,"height":24,"timestamp":1500000000,"title":"build","env":{"TERM":"xterm-256color"}}
+[1.5,"o","\u001b[1mhello <world>\r\n"]
+[1.50025,"i","q"]
+[1.50025,"o","中"]
+[3.50025,"r","100x30"]
+`
+	if actual := cast.String(); actual != expected {
+		t.Errorf("cast is %q, expected %q", actual, expected)
+	}
+}
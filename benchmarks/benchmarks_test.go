@@ -0,0 +1,155 @@
+package benchmarks
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/go-ansiterm"
+	"github.com/Azure/go-ansiterm/vscreen"
+)
+
+// stream is a stream of terminal output in testdata, with the size of the
+// terminal it was captured on or generated for.
+type stream struct {
+	name string
+	cols int
+	rows int
+}
+
+var corpus = []stream{
+	{"vim_session", 80, 24},
+	{"top_session", 80, 24},
+	{"htop_frame", 80, 12},
+	{"docker_build", 80, 24},
+	{"synthetic_diff", 80, 24},
+}
+
+// readSize is the size of the reads the streams are written in, as a
+// terminal would receive them.
+const readSize = 4096
+
+// benchmarkCorpus runs a sub-benchmark on each stream of the corpus, calling
+// write with the stream's data in reads of readSize bytes, starting with a
+// new terminal created by newTerminal each time the stream is written.
+func benchmarkCorpus(b *testing.B, newTerminal func(b *testing.B, s stream) func([]byte) error) {
+	for _, s := range corpus {
+		s := s
+		data, err := ioutil.ReadFile(filepath.Join("testdata", s.name+".in"))
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(s.name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				write := newTerminal(b, s)
+				for p := data; len(p) > 0; {
+					n := readSize
+					if n > len(p) {
+						n = len(p)
+					}
+					if err := write(p[:n]); err != nil {
+						b.Fatal(err)
+					}
+					p = p[n:]
+				}
+			}
+		})
+	}
+}
+
+// parse returns a function parsing its input with parser.
+func parse(parser *ansiterm.AnsiParser) func([]byte) error {
+	return func(p []byte) error {
+		_, err := parser.Parse(p)
+		return err
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	benchmarkCorpus(b, func(b *testing.B, s stream) func([]byte) error {
+		return parse(ansiterm.CreateParser("Ground", nopHandler{}, ansiterm.WithUTF8()))
+	})
+}
+
+func BenchmarkScreen(b *testing.B) {
+	benchmarkCorpus(b, func(b *testing.B, s stream) func([]byte) error {
+		screen := vscreen.New(s.cols, s.rows)
+		return func(p []byte) error {
+			_, err := screen.Write(p)
+			return err
+		}
+	})
+}
+
+func BenchmarkPassthrough(b *testing.B) {
+	benchmarkCorpus(b, func(b *testing.B, s stream) func([]byte) error {
+		handler := ansiterm.NewPassthroughHandler(ioutil.Discard)
+		return parse(ansiterm.CreateParser("Ground", handler, ansiterm.WithUTF8()))
+	})
+}
+
+// nopHandler is an AnsiEventHandler that ignores every event, to measure the
+// parser alone.
+type nopHandler struct{}
+
+func (nopHandler) Print(b byte) error              { return nil }
+func (nopHandler) Execute(b byte) error            { return nil }
+func (nopHandler) CUU(int) error                   { return nil }
+func (nopHandler) CUD(int) error                   { return nil }
+func (nopHandler) CUF(int) error                   { return nil }
+func (nopHandler) CUB(int) error                   { return nil }
+func (nopHandler) CNL(int) error                   { return nil }
+func (nopHandler) CPL(int) error                   { return nil }
+func (nopHandler) CHA(int) error                   { return nil }
+func (nopHandler) CUP(int, int) error              { return nil }
+func (nopHandler) HVP(int, int) error              { return nil }
+func (nopHandler) VPA(int) error                   { return nil }
+func (nopHandler) CHT(int) error                   { return nil }
+func (nopHandler) CBT(int) error                   { return nil }
+func (nopHandler) HTS() error                      { return nil }
+func (nopHandler) TBC(int) error                   { return nil }
+func (nopHandler) DECTCEM(bool) error              { return nil }
+func (nopHandler) DECSCUSR(int) error              { return nil }
+func (nopHandler) SynchronizedUpdate(bool) error   { return nil }
+func (nopHandler) IRM(bool) error                  { return nil }
+func (nopHandler) DECOM(bool) error                { return nil }
+func (nopHandler) DECAWM(bool) error               { return nil }
+func (nopHandler) ED(int) error                    { return nil }
+func (nopHandler) EL(int) error                    { return nil }
+func (nopHandler) IL(int) error                    { return nil }
+func (nopHandler) DL(int) error                    { return nil }
+func (nopHandler) SGR([]int) error                 { return nil }
+func (nopHandler) REP(int) error                   { return nil }
+func (nopHandler) SU(int) error                    { return nil }
+func (nopHandler) SD(int) error                    { return nil }
+func (nopHandler) DA([]string) error               { return nil }
+func (nopHandler) DSR(int) error                   { return nil }
+func (nopHandler) DECCKM(bool) error               { return nil }
+func (nopHandler) DECKPAM(bool) error              { return nil }
+func (nopHandler) MouseMode(int, bool) error       { return nil }
+func (nopHandler) BracketedPaste(bool) error       { return nil }
+func (nopHandler) Win32InputMode(bool) error       { return nil }
+func (nopHandler) FocusReporting(bool) error       { return nil }
+func (nopHandler) AlternateScreen(int, bool) error { return nil }
+func (nopHandler) XTMODKEYS([]int) error           { return nil }
+func (nopHandler) KittyKeyboard(byte, []int) error { return nil }
+func (nopHandler) DECSTBM(int, int) error          { return nil }
+func (nopHandler) DECLRMM(bool) error              { return nil }
+func (nopHandler) DECSLRM(int, int) error          { return nil }
+func (nopHandler) DECRQSS(string) error            { return nil }
+func (nopHandler) XTGETTCAP([]string) error        { return nil }
+func (nopHandler) XTWINOPS([]int) error            { return nil }
+func (nopHandler) RI() error                       { return nil }
+func (nopHandler) RIS() error                      { return nil }
+func (nopHandler) DECSTR() error                   { return nil }
+func (nopHandler) XTPUSHSGR([]int) error           { return nil }
+func (nopHandler) XTPOPSGR() error                 { return nil }
+func (nopHandler) DECSC() error                    { return nil }
+func (nopHandler) DECRC() error                    { return nil }
+func (nopHandler) DECALN() error                   { return nil }
+func (nopHandler) SCS(int, byte) error             { return nil }
+func (nopHandler) OSC(int, string) error           { return nil }
+func (nopHandler) Flush() error                    { return nil }
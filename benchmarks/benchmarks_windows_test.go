@@ -0,0 +1,34 @@
+// +build windows
+
+package benchmarks
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/Azure/go-ansiterm"
+	"github.com/Azure/go-ansiterm/winterm"
+)
+
+func BenchmarkWinterm(b *testing.B) {
+	// A buffer that is never made active renders without disturbing the
+	// console the benchmarks run in
+	buffer, err := winterm.CreateConsoleScreenBuffer()
+	if err != nil {
+		b.Skipf("creating a console screen buffer: %v", err)
+	}
+	defer syscall.CloseHandle(syscall.Handle(buffer))
+
+	benchmarkCorpus(b, func(b *testing.B, s stream) func([]byte) error {
+		err := winterm.SetConsoleScreenBufferSize(buffer, winterm.COORD{X: winterm.SHORT(s.cols), Y: winterm.SHORT(s.rows)})
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		handler, err := winterm.NewWinEventHandler(syscall.Handle(buffer), winterm.WithoutResponses())
+		if err != nil {
+			b.Fatal(err)
+		}
+		return parse(ansiterm.CreateParser("Ground", handler, ansiterm.WithUTF8()))
+	})
+}
@@ -0,0 +1,27 @@
+// Package benchmarks measures the parser and event handlers on terminal
+// output, so that performance regressions in the state machine or the Flush
+// path show up in
+//
+//	go test -bench . ./benchmarks
+//
+// and can be profiled with the test flags -cpuprofile and -memprofile. Each
+// benchmark runs on every stream of the corpus in testdata, written in 4 KB
+// reads as a terminal receives them:
+//
+//	vim_session.in     vim paging and searching through a highlighted file
+//	top_session.in     top refreshing its display every half second
+//	htop_frame.in      a frame of htop, on 80x12 cells
+//	docker_build.in    docker build redrawing its progress in place
+//	synthetic_diff.in  git diff --color=always of this module's own sources
+//
+// synthetic_diff.in is generated rather than captured from a session: it
+// stands in for a large colored file being cat, so its numbers measure
+// dense SGR runs, not any real workload.
+//
+// BenchmarkParse parses with a handler that does nothing, measuring the
+// parser alone; BenchmarkScreen renders onto a vscreen.Screen, and
+// BenchmarkPassthrough writes the output back out as ANSI, through the
+// buffering and Flush of the PassthroughHandler. On Windows, BenchmarkWinterm
+// renders with the winterm handler onto a console screen buffer that is never
+// displayed.
+package benchmarks
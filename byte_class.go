@@ -0,0 +1,46 @@
+package ansiterm
+
+// byteClass is a set of the byte ranges of constants.go, those the parser's
+// state diagram distinguishes.
+type byteClass uint16
+
+const (
+	classExecute byteClass = 1 << iota
+	classPrintable
+	classIntermediate
+	classCsiParam
+	classCsiCollectable
+	classAlphabetic
+	classToGround
+	classEscapeToGround
+	classEscapeIntermediateToGround
+)
+
+// byteClasses holds the classes of each byte, so that classifying one is a
+// lookup rather than a scan of the ranges.
+var byteClasses = classifyBytes()
+
+func classifyBytes() [256]byteClass {
+	var classes [256]byteClass
+	for class, bytes := range map[byteClass][]byte{
+		classExecute:                    Executors,
+		classPrintable:                  Printables,
+		classIntermediate:               Intermeds,
+		classCsiParam:                   CsiParams,
+		classCsiCollectable:             CsiCollectables,
+		classAlphabetic:                 Alphabetics,
+		classToGround:                   ToGroundBytes,
+		classEscapeToGround:             EscapeToGroundBytes,
+		classEscapeIntermediateToGround: EscapeIntermediateToGroundBytes,
+	} {
+		for _, b := range bytes {
+			classes[b] |= class
+		}
+	}
+	return classes
+}
+
+// is reports whether b is in class.
+func is(b byte, class byteClass) bool {
+	return byteClasses[b]&class != 0
+}
@@ -0,0 +1,33 @@
+package ansiterm
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestByteClasses(t *testing.T) {
+	tests := []struct {
+		name  string
+		class byteClass
+		bytes []byte
+	}{
+		{"Executors", classExecute, Executors},
+		{"Printables", classPrintable, Printables},
+		{"Intermeds", classIntermediate, Intermeds},
+		{"CsiParams", classCsiParam, CsiParams},
+		{"CsiCollectables", classCsiCollectable, CsiCollectables},
+		{"Alphabetics", classAlphabetic, Alphabetics},
+		{"ToGroundBytes", classToGround, ToGroundBytes},
+		{"EscapeToGroundBytes", classEscapeToGround, EscapeToGroundBytes},
+		{"EscapeIntermediateToGroundBytes", classEscapeIntermediateToGround, EscapeIntermediateToGroundBytes},
+	}
+
+	for _, test := range tests {
+		for i := 0; i < 256; i++ {
+			b := byte(i)
+			if in := bytes.IndexByte(test.bytes, b) >= 0; is(b, test.class) != in {
+				t.Errorf("%s: byte %#x is classified %v, expected %v", test.name, b, !in, in)
+			}
+		}
+	}
+}
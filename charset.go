@@ -0,0 +1,55 @@
+package ansiterm
+
+// decSpecialGraphics maps 0x5F-0x7E to the DEC Special Graphics (line drawing) set.
+// See http://vt100.net/docs/vt220-rm/table2-4.html.
+var decSpecialGraphics = [...]rune{
+	0x00A0, // _ blank
+	0x25C6, // ` diamond
+	0x2592, // a checkerboard
+	0x2409, // b HT
+	0x240C, // c FF
+	0x240D, // d CR
+	0x240A, // e LF
+	0x00B0, // f degree
+	0x00B1, // g plus/minus
+	0x2424, // h NL
+	0x240B, // i VT
+	0x2518, // j lower right corner
+	0x2510, // k upper right corner
+	0x250C, // l upper left corner
+	0x2514, // m lower left corner
+	0x253C, // n crossing lines
+	0x23BA, // o scan line 1
+	0x23BB, // p scan line 3
+	0x2500, // q horizontal line
+	0x23BC, // r scan line 7
+	0x23BD, // s scan line 9
+	0x251C, // t left tee
+	0x2524, // u right tee
+	0x2534, // v bottom tee
+	0x252C, // w top tee
+	0x2502, // x vertical bar
+	0x2264, // y less than or equal
+	0x2265, // z greater than or equal
+	0x03C0, // { pi
+	0x2260, // | not equal
+	0x00A3, // } pound sterling
+	0x00B7, // ~ centered dot
+}
+
+// TranslateCharset maps r, printed while charset is designated and invoked,
+// to the Unicode rune it represents. Unrecognized charsets leave r unchanged.
+func TranslateCharset(charset byte, r rune) rune {
+	switch charset {
+	case ANSI_CHARSET_DEC_SPECIAL:
+		if 0x5F <= r && r <= 0x7E {
+			return decSpecialGraphics[r-0x5F]
+		}
+	case ANSI_CHARSET_UK:
+		if r == '#' {
+			return 0x00A3
+		}
+	}
+
+	return r
+}
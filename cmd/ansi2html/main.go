@@ -0,0 +1,149 @@
+// Command ansi2html converts captured terminal output, such as a colored
+// build log, to a standalone HTML page. The output is parsed and rendered on
+// a virtual screen (see vscreen), whose HTML export forms the page, so
+// colors, attributes, hyperlinks and redrawn lines appear as a terminal
+// showed them.
+//
+// Usage:
+//
+//	ansi2html [-cols n] [-rows n] [-theme dark|light] [-title text] [-onlcr=false] [-o file] [file ...]
+//
+// With no files, or for the file -, it reads standard input; several files
+// are written to the screen one after another. Line feeds are translated to
+// CR LF, as a terminal driver does, so output captured without one displays
+// as it would have; -onlcr=false writes them unchanged.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/Azure/go-ansiterm/vscreen"
+)
+
+var (
+	cols   = flag.Int("cols", 80, "width of the terminal the output was written to")
+	rows   = flag.Int("rows", 0, "height of the terminal; by default, tall enough that no line scrolls off it")
+	theme  = flag.String("theme", "dark", "page colors: dark or light")
+	title  = flag.String("title", "", "title of the page; by default, the name of the first file")
+	output = flag.String("o", "", "file to write the page to, rather than standard output")
+	onlcr  = flag.Bool("onlcr", true, "translate LF to CR LF, as a terminal driver does")
+)
+
+// themes are the default colors of the page, as background and foreground.
+var themes = map[string][2]string{
+	"dark":  {"#1e1e1e", "#d4d4d4"},
+	"light": {"#ffffff", "#1e1e1e"},
+}
+
+const page = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { margin: 0; background: %[2]s; }
+pre.vscreen { margin: 0; padding: 1em; background: %[2]s; color: %[3]s; font-family: ui-monospace, Menlo, Consolas, monospace; line-height: 1.2; }
+pre.vscreen a { color: inherit; }
+</style>
+</head>
+<body>
+%[4]s
+</body>
+</html>
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: ansi2html [flags] [file ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	colors, ok := themes[*theme]
+	if !ok {
+		fatal(fmt.Errorf("unknown theme %q", *theme))
+	}
+
+	files := flag.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	if *title == "" {
+		*title = files[0]
+		if *title == "-" {
+			*title = "ansi2html"
+		}
+	}
+
+	var input bytes.Buffer
+	for _, name := range files {
+		if err := read(&input, name); err != nil {
+			fatal(err)
+		}
+	}
+
+	data := input.Bytes()
+	if *onlcr {
+		data = bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
+	}
+
+	height := *rows
+	if height < 1 {
+		height = lines(data, *cols)
+	}
+
+	screen := vscreen.New(*cols, height)
+	screen.Write(data)
+
+	out := io.WriteCloser(os.Stdout)
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fatal(err)
+		}
+		out = file
+	}
+
+	if _, err := fmt.Fprintf(out, page, html.EscapeString(*title), colors[0], colors[1], screen.HTML()); err != nil {
+		fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		fatal(err)
+	}
+}
+
+// read appends the file name, or standard input for -, to input.
+func read(input *bytes.Buffer, name string) error {
+	if name == "-" {
+		_, err := input.ReadFrom(os.Stdin)
+		return err
+	}
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	input.Write(data)
+	return nil
+}
+
+// lines returns a number of rows no output can scroll off: one per line feed,
+// plus one for each time the bytes between them could wrap at width.
+func lines(output []byte, width int) int {
+	n := 1
+	for _, line := range bytes.Split(output, []byte("\n")) {
+		n += 1 + len(line)/width
+	}
+	return n
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "ansi2html: %v\n", err)
+	os.Exit(1)
+}
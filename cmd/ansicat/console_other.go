@@ -0,0 +1,17 @@
+// +build !windows
+
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/Azure/go-ansiterm"
+)
+
+// newHandler returns the handler writing to standard output. Outside Windows
+// the terminal interprets ANSI itself, so output is always passed through
+// and there are no diagnostics to log.
+func newHandler(passthrough bool, log io.Writer) (ansiterm.AnsiEventHandler, func() error, error) {
+	return ansiterm.NewPassthroughHandler(os.Stdout), func() error { return nil }, nil
+}
@@ -0,0 +1,36 @@
+// +build windows
+
+package main
+
+import (
+	"io"
+	"os"
+	"syscall"
+
+	"github.com/Azure/go-ansiterm"
+	"github.com/Azure/go-ansiterm/winterm"
+)
+
+// newHandler returns the handler rendering to standard output, and a function
+// restoring the console when done. Output redirected away from the console
+// is passed through.
+func newHandler(passthrough bool, log io.Writer) (ansiterm.AnsiEventHandler, func() error, error) {
+	if passthrough {
+		return ansiterm.NewPassthroughHandler(os.Stdout), func() error { return nil }, nil
+	}
+
+	opts := []winterm.HandlerOption{winterm.WithoutResponses()}
+	if log != nil {
+		opts = append(opts, winterm.WithLogOutput(log))
+	}
+
+	handler, err := winterm.NewWinEventHandler(syscall.Handle(os.Stdout.Fd()), opts...)
+	if winterm.IsNotConsole(err) {
+		return ansiterm.NewPassthroughHandler(os.Stdout), func() error { return nil }, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return handler, handler.Close, nil
+}
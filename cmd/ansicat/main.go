@@ -0,0 +1,136 @@
+// Command ansicat writes files of ANSI output, such as captured colored logs,
+// to the terminal through the parser. On Windows it renders them with the
+// console API (see winterm), so they display on consoles without virtual
+// terminal support; elsewhere, or with -passthrough, the parsed sequences
+// are written back out for the terminal to interpret. It doubles as a
+// manual test of the handlers.
+//
+// Usage:
+//
+//	ansicat [-passthrough] [-rate bytes] [-log file] [file ...]
+//
+// With no files, or for the file -, it reads standard input. Queries in the
+// files, such as DA or DSR, are not answered.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Azure/go-ansiterm"
+)
+
+var (
+	passthrough = flag.Bool("passthrough", false, "write the parsed sequences back out rather than using the console API")
+	rate        = flag.Int("rate", 0, "write at most this many bytes a second, to watch output as it was produced; 0 is unlimited")
+	logFile     = flag.String("log", "", "write the console handler's diagnostics to this file (Windows)")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: ansicat [flags] [file ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var log io.Writer
+	if *logFile != "" {
+		file, err := os.Create(*logFile)
+		if err != nil {
+			fatal(err)
+		}
+		defer file.Close()
+		log = file
+	}
+
+	handler, closeHandler, err := newHandler(*passthrough, log)
+	if err != nil {
+		fatal(err)
+	}
+
+	parser := ansiterm.CreateParser("Ground", handler, ansiterm.WithUTF8(), ansiterm.WithoutQueries())
+	var out io.Writer = writerFunc(parser.Parse)
+	if *rate > 0 {
+		out = &throttledWriter{w: out, rate: *rate}
+	}
+
+	files := flag.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	status := 0
+	for _, name := range files {
+		if err := cat(out, name); err != nil {
+			fmt.Fprintf(os.Stderr, "ansicat: %v\n", err)
+			status = 1
+		}
+	}
+
+	if err := closeHandler(); err != nil {
+		fatal(err)
+	}
+	os.Exit(status)
+}
+
+// cat writes the file name, or standard input for -, to out.
+func cat(out io.Writer, name string) error {
+	if name == "-" {
+		_, err := io.Copy(out, os.Stdin)
+		return err
+	}
+
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(out, file)
+	return err
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "ansicat: %v\n", err)
+	os.Exit(1)
+}
+
+// writerFunc adapts a function, such as AnsiParser.Parse, to io.Writer.
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+// throttledWriter writes to w at no more than rate bytes a second, in pieces
+// small enough for the output to appear smoothly.
+type throttledWriter struct {
+	w    io.Writer
+	rate int
+}
+
+func (t *throttledWriter) Write(p []byte) (int, error) {
+	chunk := t.rate / 50
+	if chunk < 1 {
+		chunk = 1
+	}
+
+	written := 0
+	for written < len(p) {
+		n := len(p) - written
+		if n > chunk {
+			n = chunk
+		}
+
+		if _, err := t.w.Write(p[written : written+n]); err != nil {
+			return written, err
+		}
+		written += n
+		time.Sleep(time.Duration(n) * time.Second / time.Duration(t.rate))
+	}
+
+	return written, nil
+}
@@ -0,0 +1,69 @@
+// Command ansiplay plays an asciinema cast file, such as one ansirec
+// recorded, on the terminal with its original timing. On Windows the output
+// is rendered by the console handler (see winterm.NewAnsiWriter), so it
+// displays on consoles without VT support; elsewhere, or with -passthrough,
+// it is written out unchanged for the terminal to interpret.
+//
+// Usage:
+//
+//	ansiplay [-speed n] [-idle duration] [-passthrough] file.cast
+//
+// For the file -, it reads standard input.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Azure/go-ansiterm/asciicast"
+	"github.com/Azure/go-ansiterm/winterm"
+)
+
+var (
+	speed       = flag.Float64("speed", 1, "play this many times as fast as recorded")
+	idle        = flag.Duration("idle", 0, "shorten pauses to at most this long; 0 keeps them")
+	passthrough = flag.Bool("passthrough", false, "write the output unchanged rather than using the console API")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: ansiplay [flags] file.cast\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 || *speed <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	in := os.Stdin
+	if name := flag.Arg(0); name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			fatal(err)
+		}
+		defer file.Close()
+		in = file
+	}
+
+	cast, err := asciicast.NewReader(in)
+	if err != nil {
+		fatal(err)
+	}
+
+	var out io.Writer = os.Stdout
+	if !*passthrough {
+		out = winterm.NewAnsiWriter(winterm.STD_OUTPUT_HANDLE)
+	}
+
+	if err := asciicast.Play(cast, out, asciicast.WithSpeed(*speed), asciicast.WithIdleLimit(*idle)); err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "ansiplay: %v\n", err)
+	os.Exit(1)
+}
@@ -0,0 +1,115 @@
+// Command ansirec records a terminal session as an asciinema cast file, which
+// ansiplay or any asciinema player plays back.
+//
+// Usage:
+//
+//	ansirec [-cols n] [-rows n] [-title text] [-input] [-trace file] out.cast [command [arg ...]]
+//
+// Given a command, it runs it attached to a pseudo console (see
+// winterm.PseudoConsole, available from Windows 10 1809), showing its output
+// on the console while recording it and passing the console's input to it,
+// until it exits. -input records that input too, and -trace writes an input
+// trace of the console input read (see winterm.NewReplayReader).
+//
+// Without a command, it records standard input, such as the output of a
+// program piped to it, copying it to standard output. Programs often write
+// plain text when not writing to a terminal, so this suits those that can be
+// told to use color regardless.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Azure/go-ansiterm/asciicast"
+)
+
+var (
+	cols        = flag.Int("cols", 0, "width of the terminal; by default, that of the console, or 80")
+	rows        = flag.Int("rows", 0, "height of the terminal; by default, that of the console, or 24")
+	title       = flag.String("title", "", "title of the recording")
+	recordInput = flag.Bool("input", false, "record the input passed to the command")
+	traceFile   = flag.String("trace", "", "write an input trace of the console input to this file (Windows)")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: ansirec [flags] out.cast [command [arg ...]]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	width, height, ok := consoleSize()
+	if !ok {
+		width, height = 80, 24
+	}
+	if *cols > 0 {
+		width = *cols
+	}
+	if *rows > 0 {
+		height = *rows
+	}
+
+	file, err := os.Create(flag.Arg(0))
+	if err != nil {
+		fatal(err)
+	}
+
+	env := make(map[string]string)
+	for _, name := range []string{"SHELL", "TERM"} {
+		if value, ok := os.LookupEnv(name); ok {
+			env[name] = value
+		}
+	}
+
+	cast, err := asciicast.NewWriter(file, width, height, asciicast.WithTitle(*title), asciicast.WithEnv(env))
+	if err != nil {
+		fatal(err)
+	}
+
+	if command := flag.Args()[1:]; len(command) > 0 {
+		var trace io.Writer
+		if *traceFile != "" {
+			traceOut, err := os.Create(*traceFile)
+			if err != nil {
+				fatal(err)
+			}
+			defer traceOut.Close()
+			trace = traceOut
+		}
+
+		err = record(cast, width, height, command, trace)
+	} else {
+		_, err = io.Copy(io.MultiWriter(os.Stdout, cast), os.Stdin)
+	}
+	if err != nil {
+		fatal(err)
+	}
+
+	if err := file.Close(); err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "ansirec: %v\n", err)
+	os.Exit(1)
+}
+
+// inputWriter records what is written to it as input to the session.
+type inputWriter struct {
+	cast *asciicast.Writer
+}
+
+func (w inputWriter) Write(p []byte) (int, error) {
+	if err := w.cast.Input(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
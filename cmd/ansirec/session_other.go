@@ -0,0 +1,21 @@
+// +build !windows
+
+package main
+
+import (
+	"errors"
+	"io"
+
+	"github.com/Azure/go-ansiterm/asciicast"
+)
+
+// consoleSize reports no console; outside Windows the size is given by flags.
+func consoleSize() (cols int, rows int, ok bool) {
+	return 0, 0, false
+}
+
+// record reports that commands cannot be run: their output is recorded
+// through a pseudo console, which only Windows provides here.
+func record(cast *asciicast.Writer, cols int, rows int, command []string, trace io.Writer) error {
+	return errors.New("running a command needs a Windows pseudo console; pipe its output to ansirec instead")
+}
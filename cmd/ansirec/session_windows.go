@@ -0,0 +1,73 @@
+// +build windows
+
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/Azure/go-ansiterm/asciicast"
+	"github.com/Azure/go-ansiterm/winterm"
+)
+
+// consoleSize returns the size of the console window standard output shows.
+func consoleSize() (cols int, rows int, ok bool) {
+	info, err := winterm.GetConsoleScreenBufferInfo(os.Stdout.Fd())
+	if err != nil {
+		return 0, 0, false
+	}
+	return int(info.Window.Right-info.Window.Left) + 1, int(info.Window.Bottom-info.Window.Top) + 1, true
+}
+
+// record runs command attached to a pseudo console of cols by rows, showing
+// its output on the console and recording it to cast, until it exits.
+func record(cast *asciicast.Writer, cols int, rows int, command []string, trace io.Writer) error {
+	pc, err := winterm.NewPseudoConsole(cols, rows)
+	if err != nil {
+		return err
+	}
+
+	args := make([]string, len(command))
+	for i, arg := range command {
+		args[i] = syscall.EscapeArg(arg)
+	}
+
+	process, err := pc.Start(strings.Join(args, " "))
+	if err != nil {
+		pc.Close()
+		return err
+	}
+
+	// The output is rendered by the console handler, so sessions display
+	// on consoles without VT support too
+	copied := make(chan struct{})
+	go func() {
+		io.Copy(io.MultiWriter(winterm.NewAnsiWriter(winterm.STD_OUTPUT_HANDLE), cast), pc)
+		close(copied)
+	}()
+
+	var opts []winterm.ReaderOption
+	if trace != nil {
+		opts = append(opts, winterm.WithInputTrace(trace))
+	}
+	if input, err := winterm.NewAnsiReaderFromFile(os.Stdin, opts...); err == nil {
+		defer input.Close()
+
+		var to io.Writer = pc
+		if *recordInput {
+			to = io.MultiWriter(pc, inputWriter{cast})
+		}
+		go io.Copy(to, input)
+	}
+
+	_, err = process.Wait()
+
+	// Closing the pseudo console flushes its last output, then ends the copy
+	if closeErr := pc.Close(); err == nil {
+		err = closeErr
+	}
+	<-copied
+	return err
+}
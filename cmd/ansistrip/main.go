@@ -0,0 +1,89 @@
+// Command ansistrip removes escape sequences from files of terminal output,
+// such as CI logs, writing their plain text to standard output. It is built
+// on the parser's StripHandler, and with -window on its LineHandler.
+//
+// Usage:
+//
+//	ansistrip [-collapse] [-window lines] [file ...]
+//
+// With no files, or for the file -, it reads standard input.
+//
+// By default each state of a line redrawn after a carriage return, as
+// progress indicators do, is kept as a line of its own. With -collapse only
+// the last state is kept. With -window, redraws moving the cursor up over
+// the last lines, as multi-line progress displays do, are collapsed too, at
+// the cost of holding back that many lines until they are final.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Azure/go-ansiterm"
+)
+
+var (
+	collapse = flag.Bool("collapse", false, "keep only the last state of lines redrawn after a carriage return")
+	window   = flag.Int("window", 0, "collapse redraws of up to this many lines above the cursor; implies -collapse")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: ansistrip [flags] [file ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	out := bufio.NewWriter(os.Stdout)
+
+	files := flag.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	status := 0
+	for _, name := range files {
+		if err := strip(out, name); err != nil {
+			fmt.Fprintf(os.Stderr, "ansistrip: %v\n", err)
+			status = 1
+		}
+	}
+
+	if err := out.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "ansistrip: %v\n", err)
+		status = 1
+	}
+	os.Exit(status)
+}
+
+// strip writes the text of the file name, or standard input for -, to out.
+func strip(out io.Writer, name string) error {
+	in := os.Stdin
+	if name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+
+	var w io.WriteCloser
+	switch {
+	case *window > 0:
+		w = ansiterm.LineWriter(out, *window)
+	case *collapse:
+		w = ansiterm.StripWriter(out)
+	default:
+		w = ansiterm.StripWriter(out, ansiterm.WithRedrawnLines())
+	}
+
+	if _, err := io.Copy(w, in); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
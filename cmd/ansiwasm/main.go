@@ -0,0 +1,143 @@
+// +build js,wasm
+
+// Command ansiwasm makes the parser and virtual screen (see vscreen)
+// available to JavaScript when compiled to WebAssembly, so that browser-based
+// viewers of terminal output render it with the same emulation as programs
+// using the Go packages. Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o ansiterm.wasm ./cmd/ansiwasm
+//
+// and run it with the wasm_exec.js support file of the same Go release, in
+// $(go env GOROOT)/lib/wasm (misc/wasm before Go 1.24). It defines the global ansiterm object, whose
+// newScreen(cols, rows, options) creates a blank screen:
+//
+//	const screen = ansiterm.newScreen(80, 24, {onTitle: t => document.title = t});
+//	screen.write(output); // a Uint8Array or a string
+//	view.innerHTML = screen.html();
+//
+// Screens have the methods write(data), resize(cols, rows), size() and
+// cursor(), which return [cols, rows] and [x, y], text(), html(), svg() and
+// json(), which return the screen in these forms, and close(), which releases
+// the screen. The options are callbacks: onBell(), onTitle(title) and
+// onResponse(data), which receives replies to queries as strings; without
+// it, queries are not answered.
+package main
+
+import (
+	"syscall/js"
+
+	"github.com/Azure/go-ansiterm/vscreen"
+)
+
+func main() {
+	js.Global().Set("ansiterm", map[string]interface{}{
+		"newScreen": js.FuncOf(newScreen),
+	})
+
+	// The functions are called while main runs
+	select {}
+}
+
+// newScreen implements ansiterm.newScreen(cols, rows, options).
+func newScreen(this js.Value, args []js.Value) interface{} {
+	var opts []vscreen.ScreenOption
+	if len(args) > 2 && args[2].Type() == js.TypeObject {
+		options := args[2]
+		if f := options.Get("onBell"); f.Type() == js.TypeFunction {
+			opts = append(opts, vscreen.WithBellCallback(func() { f.Invoke() }))
+		}
+		if f := options.Get("onTitle"); f.Type() == js.TypeFunction {
+			opts = append(opts, vscreen.WithTitleCallback(func(title string) { f.Invoke(title) }))
+		}
+		if f := options.Get("onResponse"); f.Type() == js.TypeFunction {
+			opts = append(opts, vscreen.WithResponseWriter(responseWriter(f)))
+		}
+	}
+
+	s := vscreen.New(intArg(args, 0, 80), intArg(args, 1, 24), opts...)
+
+	var funcs []js.Func
+	method := func(f func(args []js.Value) interface{}) js.Func {
+		fn := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			return f(args)
+		})
+		funcs = append(funcs, fn)
+		return fn
+	}
+
+	screen := map[string]interface{}{
+		"write": method(func(args []js.Value) interface{} {
+			if len(args) > 0 {
+				s.Write(bytesArg(args[0]))
+			}
+			return nil
+		}),
+		"resize": method(func(args []js.Value) interface{} {
+			cols, rows := s.Size()
+			s.Resize(intArg(args, 0, cols), intArg(args, 1, rows))
+			return nil
+		}),
+		"size": method(func(args []js.Value) interface{} {
+			cols, rows := s.Size()
+			return []interface{}{cols, rows}
+		}),
+		"cursor": method(func(args []js.Value) interface{} {
+			x, y := s.Cursor()
+			return []interface{}{x, y}
+		}),
+		"text": method(func(args []js.Value) interface{} {
+			return s.String()
+		}),
+		"html": method(func(args []js.Value) interface{} {
+			return s.HTML()
+		}),
+		"svg": method(func(args []js.Value) interface{} {
+			return s.SVG()
+		}),
+		"json": method(func(args []js.Value) interface{} {
+			data, err := s.MarshalJSON()
+			if err != nil {
+				return js.Global().Get("Error").New(err.Error())
+			}
+			return string(data)
+		}),
+	}
+	screen["close"] = method(func(args []js.Value) interface{} {
+		for _, fn := range funcs {
+			fn.Release()
+		}
+		funcs = nil
+		return nil
+	})
+	return screen
+}
+
+// responseWriter passes the replies written to it to a JavaScript function.
+type responseWriter js.Value
+
+func (w responseWriter) Write(p []byte) (int, error) {
+	js.Value(w).Invoke(string(p))
+	return len(p), nil
+}
+
+// bytesArg returns the bytes of a Uint8Array or the UTF-8 encoding of a
+// string, and nil for other values.
+func bytesArg(v js.Value) []byte {
+	switch {
+	case v.Type() == js.TypeString:
+		return []byte(v.String())
+	case v.InstanceOf(js.Global().Get("Uint8Array")):
+		b := make([]byte, v.Length())
+		js.CopyBytesToGo(b, v)
+		return b
+	}
+	return nil
+}
+
+// intArg returns args[i] as an int, or dflt if it is missing or not a number.
+func intArg(args []js.Value, i int, dflt int) int {
+	if i >= len(args) || args[i].Type() != js.TypeNumber {
+		return dflt
+	}
+	return args[i].Int()
+}
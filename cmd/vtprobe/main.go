@@ -0,0 +1,502 @@
+// Command vtprobe audits the escape sequences in a program's output, to find
+// why it renders wrong through the Windows console handler (winterm), as
+// when a program in a container is attached to from a Windows console.
+//
+// Usage:
+//
+//	vtprobe [-level vt100|vt220|vt520|xterm] [-flagged] [file ...]
+//
+// It splits the files, or standard input, into sequences with
+// ansiterm.Tokenizer, and lists how often each was used, most used first,
+// with the terminal that introduced it and how the console handler treats
+// it:
+//
+//	ok       rendered
+//	partial  approximated, as the note says
+//	ignored  parsed, but without effect
+//	dropped  not recognized by the parser
+//
+// Sequences that are not rendered, or come from a later terminal than
+// -level, are flagged with a '!'. -level defaults to vt220, the terminal
+// the handler identifies as to device attribute requests. Basic ECMA-48
+// functions, such as the eight colors, count as vt100. With -flagged, only
+// flagged sequences are listed.
+//
+// To capture a program's output, pipe it to vtprobe, or record a session
+// with ansirec and pass the output of ansiplay -passthrough.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Azure/go-ansiterm"
+)
+
+var (
+	levelName = flag.String("level", "vt220", "flag sequences from terminals later than this: vt100, vt220, vt520, or xterm")
+	flagged   = flag.Bool("flagged", false, "list only flagged sequences")
+)
+
+type level int
+
+const (
+	vt100 level = iota
+	vt220
+	vt520
+	xterm
+)
+
+var levelNames = [...]string{"vt100", "vt220", "vt520", "xterm"}
+
+type status int
+
+const (
+	supported status = iota
+	partial
+	ignored
+	dropped
+)
+
+var statusNames = [...]string{"ok", "partial", "ignored", "dropped"}
+
+// support describes how the console handler treats a sequence.
+type support struct {
+	level  level
+	status status
+	note   string
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: vtprobe [flags] [file ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	limit := level(-1)
+	for i, name := range levelNames {
+		if name == *levelName {
+			limit = level(i)
+		}
+	}
+	if limit < 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	a := audit{counts: make(map[string]int)}
+	if flag.NArg() == 0 {
+		if err := a.read("standard input", os.Stdin); err != nil {
+			fatal(err)
+		}
+	}
+	for _, name := range flag.Args() {
+		file, err := os.Open(name)
+		if err != nil {
+			fatal(err)
+		}
+		err = a.read(name, file)
+		file.Close()
+		if err != nil {
+			fatal(err)
+		}
+	}
+
+	a.print(os.Stdout, limit)
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "vtprobe: %v\n", err)
+	os.Exit(1)
+}
+
+// audit counts the sequences read.
+type audit struct {
+	counts    map[string]int
+	text      int
+	truncated []string
+}
+
+// read counts the sequences of the stream r.
+func (a *audit) read(name string, r io.Reader) error {
+	tokenizer := ansiterm.NewTokenizer(r)
+	for {
+		token, err := tokenizer.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err == io.ErrUnexpectedEOF {
+			a.truncated = append(a.truncated, name)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if token.Kind == ansiterm.TokenText {
+			a.text += len(token.Raw)
+		}
+		for _, name := range names(token) {
+			a.counts[name]++
+		}
+	}
+}
+
+// print lists the sequences counted, flagging those that are not rendered
+// or come from a terminal later than limit.
+func (a *audit) print(w io.Writer, limit level) {
+	sequences := make([]string, 0, len(a.counts))
+	for name := range a.counts {
+		sequences = append(sequences, name)
+	}
+	sort.Slice(sequences, func(i, j int) bool {
+		if a.counts[sequences[i]] != a.counts[sequences[j]] {
+			return a.counts[sequences[i]] > a.counts[sequences[j]]
+		}
+		return sequences[i] < sequences[j]
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, " \tCOUNT\tSEQUENCE\tLEVEL\tWINTERM\tNOTE\n")
+
+	count := 0
+	for _, name := range sequences {
+		s := lookup(name)
+		mark := " "
+		if s.status != supported || s.level > limit {
+			mark = "!"
+			count++
+		} else if *flagged {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\n", mark, a.counts[name], name, levelNames[s.level], statusNames[s.status], s.note)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\n%d kinds of sequence, %d flagged; %d bytes of text\n", len(sequences), count, a.text)
+	for _, name := range a.truncated {
+		fmt.Fprintf(w, "%s ends within a sequence\n", name)
+	}
+}
+
+var controlNames = map[byte]string{
+	0x00: "NUL", 0x07: "BEL", 0x08: "BS", 0x09: "HT", 0x0A: "LF", 0x0B: "VT",
+	0x0C: "FF", 0x0D: "CR", 0x0E: "SO", 0x0F: "SI", 0x18: "CAN", 0x1A: "SUB",
+	0x7F: "DEL",
+}
+
+// names returns the names a token is counted under. The attributes of SGR,
+// the modes of SM and RM, and the operations of a few other sequences are
+// counted separately, as their support differs.
+func names(token ansiterm.Token) []string {
+	switch token.Kind {
+	case ansiterm.TokenControl:
+		if name, ok := controlNames[token.Final]; ok {
+			return []string{name}
+		}
+		return []string{fmt.Sprintf("C0 0x%02X", token.Final)}
+
+	case ansiterm.TokenEscape:
+		return []string{"ESC " + spaced(token.Intermediates+string(token.Final))}
+
+	case ansiterm.TokenCSI:
+		private := ""
+		if token.Private != 0 {
+			private = string(token.Private) + " "
+		}
+		final := spaced(token.Intermediates + string(token.Final))
+
+		switch {
+		case token.Intermediates != "":
+		case token.Final == 'm' && token.Private == 0:
+			return sgrNames(token.Params)
+		case token.Final == 'h' || token.Final == 'l':
+			var modes []string
+			for _, mode := range strings.Split(token.Params, ";") {
+				modes = append(modes, "CSI "+private+mode+" "+final)
+			}
+			return modes
+		case token.Final == 'n' || (token.Final == 't' && token.Private == 0):
+			op := strings.SplitN(token.Params, ";", 2)[0]
+			if op == "" {
+				op = "0"
+			}
+			return []string{"CSI " + private + op + " " + final}
+		}
+		return []string{"CSI " + private + final}
+
+	case ansiterm.TokenOSC:
+		command := strings.SplitN(token.Data, ";", 2)[0]
+		if len(command) > 8 {
+			command = command[:8] + "..."
+		}
+		return []string{"OSC " + command}
+
+	case ansiterm.TokenDCS:
+		header := token.Intermediates + string(token.Final)
+		if token.Private != 0 {
+			header = string(token.Private) + header
+		}
+		name := "DCS " + spaced(header)
+		if token.Intermediates == "$" && token.Final == 'q' {
+			name += " " + token.Data
+		}
+		return []string{name}
+
+	case ansiterm.TokenString:
+		return []string{map[byte]string{'X': "SOS", '^': "PM", '_': "APC"}[token.Final]}
+	}
+
+	return nil
+}
+
+// sgrNames returns a name for each attribute an SGR sets, taking extended
+// colors with their color space.
+func sgrNames(params string) []string {
+	fields := strings.Split(params, ";")
+
+	var attributes []string
+	for i := 0; i < len(fields); i++ {
+		attribute := fields[i]
+		if attribute == "" {
+			attribute = "0"
+		}
+
+		switch {
+		case strings.Contains(attribute, ":"):
+			colon := strings.Split(attribute, ":")
+			attribute = colon[0] + ":" + colon[1]
+		case (attribute == "38" || attribute == "48" || attribute == "58") && i+1 < len(fields):
+			switch fields[i+1] {
+			case "5":
+				attribute += ";5"
+				i += 2
+			case "2":
+				attribute += ";2"
+				i += 4
+			}
+		}
+
+		attributes = append(attributes, "SGR "+attribute)
+	}
+	return attributes
+}
+
+// spaced separates the characters of s with spaces, naming the space SP.
+func spaced(s string) string {
+	chars := make([]string, len(s))
+	for i := 0; i < len(s); i++ {
+		chars[i] = string(s[i])
+		if s[i] == ' ' {
+			chars[i] = "SP"
+		}
+	}
+	return strings.Join(chars, " ")
+}
+
+const styleNote = "shown only with winterm.WithStyleFallback"
+
+// sequences describes how the console handler treats the sequences it
+// knows, by name.
+var sequences = map[string]support{
+	"NUL": {vt100, supported, ""},
+	"BEL": {vt100, supported, ""},
+	"BS":  {vt100, supported, ""},
+	"HT":  {vt100, supported, ""},
+	"LF":  {vt100, supported, ""},
+	"VT":  {vt100, supported, ""},
+	"FF":  {vt100, supported, ""},
+	"CR":  {vt100, supported, ""},
+	"SO":  {vt100, supported, ""},
+	"SI":  {vt100, supported, ""},
+	"CAN": {vt100, supported, ""},
+	"SUB": {vt100, supported, ""},
+	"DEL": {vt100, supported, ""},
+
+	"ESC 7":   {vt100, supported, "DECSC"},
+	"ESC 8":   {vt100, supported, "DECRC"},
+	"ESC =":   {vt100, supported, "DECKPAM"},
+	"ESC >":   {vt100, supported, "DECKPNM"},
+	"ESC D":   {vt100, dropped, "IND; LF does the same outside new line mode"},
+	"ESC E":   {vt100, dropped, "NEL; CR LF does the same"},
+	"ESC H":   {vt100, supported, "HTS"},
+	"ESC M":   {vt100, supported, "RI"},
+	"ESC c":   {vt100, supported, "RIS"},
+	"ESC # 8": {vt100, supported, "DECALN"},
+	"ESC N":   {vt220, dropped, "SS2"},
+	"ESC O":   {vt220, dropped, "SS3"},
+	"ESC n":   {vt220, dropped, "LS2"},
+	"ESC o":   {vt220, dropped, "LS3"},
+	"ESC \\":  {vt100, supported, "ST"},
+
+	"CSI A":     {vt100, supported, "CUU"},
+	"CSI B":     {vt100, supported, "CUD"},
+	"CSI C":     {vt100, supported, "CUF"},
+	"CSI D":     {vt100, supported, "CUB"},
+	"CSI E":     {vt520, supported, "CNL"},
+	"CSI F":     {vt520, supported, "CPL"},
+	"CSI G":     {vt520, supported, "CHA"},
+	"CSI H":     {vt100, supported, "CUP"},
+	"CSI I":     {vt520, supported, "CHT"},
+	"CSI J":     {vt100, supported, "ED"},
+	"CSI K":     {vt100, supported, "EL"},
+	"CSI ? J":   {vt220, partial, "DECSED; erases as ED 0 regardless of the parameter"},
+	"CSI ? K":   {vt220, partial, "DECSEL; erases as EL 0 regardless of the parameter"},
+	"CSI L":     {vt100, supported, "IL"},
+	"CSI M":     {vt100, supported, "DL"},
+	"CSI @":     {vt100, dropped, "ICH"},
+	"CSI P":     {vt100, dropped, "DCH"},
+	"CSI X":     {vt220, dropped, "ECH"},
+	"CSI S":     {vt520, supported, "SU"},
+	"CSI T":     {vt520, supported, "SD"},
+	"CSI Z":     {vt520, supported, "CBT"},
+	"CSI `":     {vt520, dropped, "HPA; CHA does the same"},
+	"CSI a":     {vt520, dropped, "HPR"},
+	"CSI b":     {xterm, supported, "REP"},
+	"CSI c":     {vt100, supported, "DA; answers as a VT220"},
+	"CSI > c":   {vt220, supported, "secondary DA"},
+	"CSI d":     {vt520, supported, "VPA"},
+	"CSI e":     {vt520, dropped, "VPR"},
+	"CSI f":     {vt100, supported, "HVP"},
+	"CSI g":     {vt100, supported, "TBC"},
+	"CSI 5 n":   {vt100, supported, "DSR operating status"},
+	"CSI 6 n":   {vt100, supported, "DSR cursor position"},
+	"CSI r":     {vt100, supported, "DECSTBM"},
+	"CSI s":     {vt520, partial, "taken as DECSLRM, without effect unless mode ?69 is set; ESC 7 saves the cursor"},
+	"CSI u":     {xterm, dropped, "SCORC; ESC 8 restores the cursor"},
+	"CSI < u":   {xterm, supported, "kitty keyboard protocol"},
+	"CSI = u":   {xterm, supported, "kitty keyboard protocol"},
+	"CSI > u":   {xterm, supported, "kitty keyboard protocol"},
+	"CSI ? u":   {xterm, supported, "kitty keyboard protocol"},
+	"CSI > m":   {xterm, supported, "XTMODKEYS"},
+	"CSI 8 t":   {xterm, partial, "resizes only with winterm.WithAllowResize"},
+	"CSI 14 t":  {xterm, supported, "window size report"},
+	"CSI 18 t":  {xterm, supported, "window size report"},
+	"CSI 19 t":  {xterm, supported, "screen size report"},
+	"CSI SP q":  {vt520, partial, "DECSCUSR; blinking styles are steady, bars are half-height blocks"},
+	"CSI ! p":   {vt220, supported, "DECSTR"},
+	"CSI # {":   {xterm, partial, "XTPUSHSGR; the console keeps bold, underline, reverse video and colors"},
+	"CSI # p":   {xterm, partial, "XTPUSHSGR; the console keeps bold, underline, reverse video and colors"},
+	"CSI # }":   {xterm, supported, "XTPOPSGR"},
+	"CSI # q":   {xterm, supported, "XTPOPSGR"},
+	"DCS $ q r": {vt520, supported, "DECRQSS"},
+	"DCS $ q s": {vt520, supported, "DECRQSS"},
+	"DCS + q":   {xterm, supported, "XTGETTCAP; colors and terminal name"},
+
+	"OSC 0":  {xterm, supported, "window title"},
+	"OSC 1":  {xterm, ignored, "icon name"},
+	"OSC 2":  {xterm, supported, "window title"},
+	"OSC 4":  {xterm, ignored, "palette"},
+	"OSC 8":  {xterm, ignored, "hyperlinks show as plain text"},
+	"OSC 52": {xterm, ignored, "clipboard"},
+
+	"SGR 0":    {vt100, supported, ""},
+	"SGR 1":    {vt100, supported, "bold is shown as bright"},
+	"SGR 2":    {xterm, partial, styleNote},
+	"SGR 3":    {xterm, partial, styleNote},
+	"SGR 4":    {vt100, supported, ""},
+	"SGR 5":    {vt100, partial, styleNote},
+	"SGR 6":    {vt100, partial, styleNote},
+	"SGR 7":    {vt100, supported, ""},
+	"SGR 8":    {vt220, ignored, "concealed text shows"},
+	"SGR 9":    {xterm, partial, styleNote},
+	"SGR 22":   {vt220, supported, ""},
+	"SGR 23":   {xterm, partial, styleNote},
+	"SGR 24":   {vt220, supported, ""},
+	"SGR 25":   {vt220, partial, styleNote},
+	"SGR 27":   {vt220, supported, ""},
+	"SGR 29":   {xterm, partial, styleNote},
+	"SGR 38;5": {xterm, partial, "256 colors are shown as the closest of the console's 16"},
+	"SGR 48;5": {xterm, partial, "256 colors are shown as the closest of the console's 16"},
+	"SGR 38;2": {xterm, partial, "24-bit colors are shown as the closest of the console's 16"},
+	"SGR 48;2": {xterm, partial, "24-bit colors are shown as the closest of the console's 16"},
+	"SGR 39":   {vt100, supported, ""},
+	"SGR 49":   {vt100, supported, ""},
+	"SGR 58;5": {xterm, ignored, "underline color"},
+	"SGR 58;2": {xterm, ignored, "underline color"},
+	"SGR 59":   {xterm, ignored, "underline color"},
+}
+
+func init() {
+	for color := 30; color <= 47; color++ {
+		if color != 38 && color != 39 {
+			sequences[fmt.Sprintf("SGR %d", color)] = support{vt100, supported, ""}
+		}
+	}
+	for color := 0; color < 8; color++ {
+		sequences[fmt.Sprintf("SGR %d", 90+color)] = support{xterm, supported, "bright color"}
+		sequences[fmt.Sprintf("SGR %d", 100+color)] = support{xterm, supported, "bright color"}
+	}
+}
+
+// modes describes how the console handler treats the modes of SM and RM,
+// by parameter.
+var modes = map[string]support{
+	"4":     {vt100, supported, "IRM"},
+	"20":    {vt100, dropped, "LNM"},
+	"?1":    {vt100, supported, "DECCKM"},
+	"?3":    {vt100, dropped, "DECCOLM"},
+	"?5":    {vt100, dropped, "DECSCNM"},
+	"?6":    {vt100, supported, "DECOM"},
+	"?7":    {vt100, supported, "DECAWM"},
+	"?12":   {xterm, dropped, "cursor blinking"},
+	"?25":   {vt220, ignored, "DECTCEM; the cursor stays visible"},
+	"?47":   {xterm, ignored, "alternate screen; full-screen programs draw over the main one"},
+	"?69":   {vt520, supported, "DECLRMM"},
+	"?1000": {xterm, supported, "mouse reporting"},
+	"?1002": {xterm, supported, "mouse reporting"},
+	"?1003": {xterm, supported, "mouse reporting"},
+	"?1004": {xterm, supported, "focus reporting"},
+	"?1005": {xterm, dropped, "UTF-8 mouse encoding; ?1006 is supported"},
+	"?1006": {xterm, supported, "SGR mouse encoding"},
+	"?1015": {xterm, dropped, "urxvt mouse encoding; ?1006 is supported"},
+	"?1047": {xterm, ignored, "alternate screen; full-screen programs draw over the main one"},
+	"?1048": {xterm, supported, "save cursor"},
+	"?1049": {xterm, ignored, "alternate screen; full-screen programs draw over the main one"},
+	"?2004": {xterm, supported, "bracketed paste"},
+	"?2026": {xterm, supported, "synchronized update"},
+	"?9001": {xterm, supported, "win32-input-mode"},
+}
+
+// lookup returns how the console handler treats the sequence name.
+func lookup(name string) support {
+	if s, ok := sequences[name]; ok {
+		return s
+	}
+
+	fields := strings.Fields(name)
+	switch {
+	case fields[0] == "CSI" && len(fields) >= 3 && (name[len(name)-1] == 'h' || name[len(name)-1] == 'l'):
+		if s, ok := modes[strings.Join(fields[1:len(fields)-1], "")]; ok {
+			return s
+		}
+		return support{xterm, dropped, "unknown mode"}
+	case fields[0] == "ESC" && len(fields) == 3 && strings.Contains("()*+", fields[1]):
+		if strings.Contains("0AB", fields[2]) {
+			if fields[1] == "(" || fields[1] == ")" {
+				return support{vt100, supported, "SCS"}
+			}
+			return support{vt220, supported, "SCS"}
+		}
+		return support{vt220, partial, "SCS; only ASCII, UK, and DEC Special Graphics are translated"}
+	case fields[0] == "C0":
+		return support{vt100, ignored, ""}
+	case fields[0] == "SGR" && strings.Contains(name, ":"):
+		return support{xterm, dropped, "colon form is taken as SGR 0; use semicolons"}
+	case fields[0] == "SGR":
+		return support{xterm, ignored, "unsupported attribute"}
+	case fields[0] == "OSC":
+		return support{xterm, ignored, ""}
+	case fields[0] == "CSI" && (name[len(name)-1] == 'n' || name[len(name)-1] == 't'):
+		return support{xterm, ignored, "unsupported report or operation"}
+	case strings.HasPrefix(name, "DCS $ q"):
+		return support{vt520, ignored, "DECRQSS; answered as an invalid request"}
+	}
+	return support{xterm, dropped, ""}
+}
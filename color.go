@@ -0,0 +1,202 @@
+package ansiterm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Color is a color selected by SGR, in whichever of the color models
+// terminals support it was given in: DefaultColor, one of the 16 ANSI colors
+// (see BasicColor), an index into the 256 color palette (see PaletteColor),
+// or a 24-bit color (see RGBColor). Keeping the model lets handlers that can
+// display a color exactly do so, while those that cannot downsample it with
+// ToPalette or ToBasic.
+type Color uint32
+
+const (
+	colorBasic   = 1 << 24
+	colorPalette = 2 << 24
+	colorRGB     = 3 << 24
+	colorValue   = 1<<24 - 1
+)
+
+// DefaultColor is the terminal's default foreground or background.
+const DefaultColor Color = 0
+
+// BasicColor returns one of the 16 ANSI colors, selected by SGR 30-37 and
+// 40-47 (0-7) or 90-97 and 100-107 (8-15, the bright variants).
+func BasicColor(index int) Color {
+	return Color(colorBasic | index&0xF)
+}
+
+// PaletteColor returns the color at index in the 256 color palette, selected
+// by SGR 38 ; 5 and 48 ; 5. The first 16 entries are the ANSI colors.
+func PaletteColor(index int) Color {
+	return Color(colorPalette | index&0xFF)
+}
+
+// RGBColor returns a 24-bit color, selected by SGR 38 ; 2 and 48 ; 2.
+func RGBColor(r uint8, g uint8, b uint8) Color {
+	return Color(colorRGB | uint32(r)<<16 | uint32(g)<<8 | uint32(b))
+}
+
+// Basic returns the index of the color, if it is one of the 16 ANSI colors.
+func (c Color) Basic() (int, bool) {
+	return int(c & colorValue), c&^colorValue == colorBasic
+}
+
+// Palette returns the palette index of the color, if it is a palette color
+// or one of the ANSI colors, which are the first 16 entries of the palette.
+func (c Color) Palette() (int, bool) {
+	tag := c &^ colorValue
+	return int(c & colorValue), tag == colorBasic || tag == colorPalette
+}
+
+// RGB returns the components of the color, if it is a 24-bit color.
+func (c Color) RGB() (r uint8, g uint8, b uint8, ok bool) {
+	return uint8(c >> 16), uint8(c >> 8), uint8(c), c&^colorValue == colorRGB
+}
+
+// Components returns the components of the color, with palette colors as
+// xterm displays them by default. DefaultColor has none.
+func (c Color) Components() (r uint8, g uint8, b uint8, ok bool) {
+	if r, g, b, ok := c.RGB(); ok {
+		return r, g, b, true
+	}
+	if index, ok := c.Palette(); ok {
+		r, g, b := paletteRGB(index)
+		return r, g, b, true
+	}
+	return 0, 0, 0, false
+}
+
+// ToPalette returns the color in the 256 color palette: a 24-bit color
+// becomes the closest entry of the palette's color cube or gray ramp.
+// Other colors are returned unchanged.
+func (c Color) ToPalette() Color {
+	r, g, b, ok := c.RGB()
+	if !ok {
+		return c
+	}
+	return PaletteColor(nearestColor(r, g, b, 16, 256))
+}
+
+// ToBasic returns the color as one of the 16 ANSI colors, as a console
+// limited to them displays it: palette and 24-bit colors become the closest
+// of them. DefaultColor is returned unchanged.
+func (c Color) ToBasic() Color {
+	if index, ok := c.Palette(); ok && index < 16 {
+		return BasicColor(index)
+	}
+
+	r, g, b, ok := c.Components()
+	if !ok {
+		return c
+	}
+	return BasicColor(nearestColor(r, g, b, 0, 16))
+}
+
+// MarshalText encodes the color as "default", the index of an ANSI color
+// ("0"-"15"), "p" and a palette index ("p0"-"p255"), or "#" and six
+// hexadecimal digits for a 24-bit color.
+func (c Color) MarshalText() ([]byte, error) {
+	if index, ok := c.Basic(); ok {
+		return []byte(strconv.Itoa(index)), nil
+	}
+	if index, ok := c.Palette(); ok {
+		return []byte("p" + strconv.Itoa(index)), nil
+	}
+	if r, g, b, ok := c.RGB(); ok {
+		return []byte(fmt.Sprintf("#%02x%02x%02x", r, g, b)), nil
+	}
+	return []byte("default"), nil
+}
+
+// UnmarshalText decodes a color encoded by MarshalText.
+func (c *Color) UnmarshalText(text []byte) error {
+	s := string(text)
+	switch {
+	case s == "default":
+		*c = DefaultColor
+		return nil
+	case strings.HasPrefix(s, "#") && len(s) == 7:
+		rgb, err := strconv.ParseUint(s[1:], 16, 32)
+		if err == nil {
+			*c = RGBColor(uint8(rgb>>16), uint8(rgb>>8), uint8(rgb))
+			return nil
+		}
+	case strings.HasPrefix(s, "p"):
+		index, err := strconv.Atoi(s[1:])
+		if err == nil && 0 <= index && index < 256 {
+			*c = PaletteColor(index)
+			return nil
+		}
+	default:
+		index, err := strconv.Atoi(s)
+		if err == nil && 0 <= index && index < 16 {
+			*c = BasicColor(index)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid color %q", s)
+}
+
+// ExtendedColor parses the parameters following SGR 38 or 48: 5 ; index or
+// 2 ; r ; g ; b. It returns the number of parameters that form the color, or
+// 0 if they do not, in which case the rest of the SGR cannot be interpreted.
+func ExtendedColor(params []int) (Color, int) {
+	switch {
+	case len(params) >= 2 && params[0] == 5:
+		return PaletteColor(params[1]), 2
+	case len(params) >= 4 && params[0] == 2:
+		return RGBColor(uint8(params[1]), uint8(params[2]), uint8(params[3])), 4
+	}
+
+	return DefaultColor, 0
+}
+
+// xtermColors are the 16 ANSI colors as xterm displays them.
+var xtermColors = [16][3]uint8{
+	{0x00, 0x00, 0x00}, {0xcd, 0x00, 0x00}, {0x00, 0xcd, 0x00}, {0xcd, 0xcd, 0x00},
+	{0x00, 0x00, 0xee}, {0xcd, 0x00, 0xcd}, {0x00, 0xcd, 0xcd}, {0xe5, 0xe5, 0xe5},
+	{0x7f, 0x7f, 0x7f}, {0xff, 0x00, 0x00}, {0x00, 0xff, 0x00}, {0xff, 0xff, 0x00},
+	{0x5c, 0x5c, 0xff}, {0xff, 0x00, 0xff}, {0x00, 0xff, 0xff}, {0xff, 0xff, 0xff},
+}
+
+// paletteRGB returns the components of a color of xterm's 256 color palette:
+// the ANSI colors, a 6x6x6 color cube, then a 24 step gray ramp.
+func paletteRGB(index int) (uint8, uint8, uint8) {
+	switch {
+	case index < 16:
+		c := xtermColors[index]
+		return c[0], c[1], c[2]
+	case index < 232:
+		index -= 16
+		level := func(n int) uint8 {
+			if n == 0 {
+				return 0
+			}
+			return uint8(55 + 40*n)
+		}
+		return level(index / 36), level(index / 6 % 6), level(index % 6)
+	}
+
+	gray := uint8(8 + 10*(index-232))
+	return gray, gray, gray
+}
+
+// nearestColor returns the index of the palette color closest to r, g, b
+// among those from first up to, but not including, last.
+func nearestColor(r uint8, g uint8, b uint8, first int, last int) int {
+	nearest, best := first, -1
+	for index := first; index < last; index++ {
+		pr, pg, pb := paletteRGB(index)
+		dr, dg, db := int(r)-int(pr), int(g)-int(pg), int(b)-int(pb)
+		if distance := dr*dr + dg*dg + db*db; best < 0 || distance < best {
+			nearest, best = index, distance
+		}
+	}
+	return nearest
+}
@@ -0,0 +1,85 @@
+package ansiterm
+
+import (
+	"testing"
+)
+
+func TestColorConversions(t *testing.T) {
+	tests := []struct {
+		color   Color
+		palette Color
+		basic   Color
+	}{
+		{DefaultColor, DefaultColor, DefaultColor},
+		{BasicColor(3), BasicColor(3), BasicColor(3)},
+		{PaletteColor(3), PaletteColor(3), BasicColor(3)},
+		{PaletteColor(196), PaletteColor(196), BasicColor(9)},
+		{RGBColor(0xff, 0x00, 0x00), PaletteColor(196), BasicColor(9)},
+		{RGBColor(0x87, 0xaf, 0xff), PaletteColor(111), BasicColor(12)},
+		{RGBColor(0x80, 0x80, 0x80), PaletteColor(244), BasicColor(8)},
+	}
+
+	for _, test := range tests {
+		if actual := test.color.ToPalette(); actual != test.palette {
+			t.Errorf("%#x.ToPalette() = %#x, expected %#x", test.color, actual, test.palette)
+		}
+		if actual := test.color.ToBasic(); actual != test.basic {
+			t.Errorf("%#x.ToBasic() = %#x, expected %#x", test.color, actual, test.basic)
+		}
+	}
+
+	if r, g, b, ok := BasicColor(4).Components(); !ok || r != 0 || g != 0 || b != 0xee {
+		t.Errorf("BasicColor(4).Components() = %d,%d,%d,%v", r, g, b, ok)
+	}
+	if _, _, _, ok := DefaultColor.Components(); ok {
+		t.Errorf("DefaultColor has components")
+	}
+}
+
+func TestColorText(t *testing.T) {
+	colors := map[Color]string{
+		DefaultColor:            "default",
+		BasicColor(12):          "12",
+		PaletteColor(12):        "p12",
+		RGBColor(0xab, 0, 0xff): "#ab00ff",
+	}
+
+	for color, text := range colors {
+		actual, err := color.MarshalText()
+		if err != nil || string(actual) != text {
+			t.Errorf("%#x.MarshalText() = %q,%v, expected %q", color, actual, err, text)
+		}
+
+		var decoded Color
+		if err := decoded.UnmarshalText([]byte(text)); err != nil || decoded != color {
+			t.Errorf("UnmarshalText(%q) = %#x,%v, expected %#x", text, decoded, err, color)
+		}
+	}
+
+	for _, text := range []string{"", "16", "p256", "#12345", "#gggggg", "red"} {
+		var decoded Color
+		if err := decoded.UnmarshalText([]byte(text)); err == nil {
+			t.Errorf("UnmarshalText(%q) succeeded", text)
+		}
+	}
+}
+
+func TestExtendedColor(t *testing.T) {
+	tests := []struct {
+		params []int
+		color  Color
+		n      int
+	}{
+		{[]int{5, 200, 1}, PaletteColor(200), 2},
+		{[]int{2, 1, 2, 3}, RGBColor(1, 2, 3), 4},
+		{[]int{5}, DefaultColor, 0},
+		{[]int{2, 1, 2}, DefaultColor, 0},
+		{[]int{3, 1}, DefaultColor, 0},
+	}
+
+	for _, test := range tests {
+		if color, n := ExtendedColor(test.params); color != test.color || n != test.n {
+			t.Errorf("ExtendedColor(%v) = %#x,%d, expected %#x,%d", test.params, color, n, test.color, test.n)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Package conformance checks a terminal emulation against scenarios drawn
+// from the vttest and esctest suites, producing a scorecard of the
+// capabilities it supports, to track emulation fidelity over time.
+//
+// The scenarios are restated from the suites rather than running them:
+// each writes output to a fresh 80x24 terminal and checks the resulting
+// cursor position and text, where the suites would ask the terminal with
+// DSR and DECRQCRA.
+package conformance
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Terminal is the emulation under test. vscreen.Screen implements it.
+type Terminal interface {
+	io.Writer
+
+	// Cursor returns the zero-based cursor position.
+	Cursor() (x int, y int)
+
+	// Line returns the text of row y, zero-based, without trailing blanks.
+	Line(y int) string
+}
+
+// The size of the terminals scenarios run on.
+const (
+	Cols = 80
+	Rows = 24
+)
+
+// Scenario is a check of one behavior of a terminal.
+type Scenario struct {
+	Name       string // The vttest screen or esctest test it is drawn from
+	Capability string // The group of behaviors it belongs to, for the scorecard
+	Output     string // Written to a fresh terminal
+	Check      Check
+}
+
+// Check examines a terminal after a scenario's output, returning an error
+// describing how it differs from the expected state.
+type Check func(Terminal) error
+
+// Result is the outcome of a scenario.
+type Result struct {
+	Scenario Scenario
+	Err      error // nil if the scenario passed
+}
+
+// Scorecard is the outcome of a run of scenarios.
+type Scorecard []Result
+
+// Run runs scenarios, or all of Scenarios if none are given, each against a
+// terminal of Cols by Rows cells created by newTerminal.
+func Run(newTerminal func(cols int, rows int) Terminal, scenarios ...Scenario) Scorecard {
+	if len(scenarios) == 0 {
+		scenarios = Scenarios
+	}
+
+	var card Scorecard
+	for _, scenario := range scenarios {
+		term := newTerminal(Cols, Rows)
+		err := runScenario(term, scenario)
+		card = append(card, Result{Scenario: scenario, Err: err})
+	}
+	return card
+}
+
+// runScenario runs a scenario, reporting a panic in the terminal as its
+// failure.
+func runScenario(term Terminal, scenario Scenario) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	if _, err := io.WriteString(term, scenario.Output); err != nil {
+		return err
+	}
+	return scenario.Check(term)
+}
+
+// Passed returns the number of scenarios that passed.
+func (card Scorecard) Passed() int {
+	passed := 0
+	for _, result := range card {
+		if result.Err == nil {
+			passed++
+		}
+	}
+	return passed
+}
+
+// String returns the scorecard as text: the scenarios passed of each
+// capability, in order, then the failures.
+func (card Scorecard) String() string {
+	passed := make(map[string]int)
+	total := make(map[string]int)
+	for _, result := range card {
+		total[result.Scenario.Capability]++
+		if result.Err == nil {
+			passed[result.Scenario.Capability]++
+		}
+	}
+
+	capabilities := make([]string, 0, len(total))
+	for capability := range total {
+		capabilities = append(capabilities, capability)
+	}
+	sort.Strings(capabilities)
+
+	var text strings.Builder
+	for _, capability := range capabilities {
+		fmt.Fprintf(&text, "%-24s %3d/%d\n", capability, passed[capability], total[capability])
+	}
+	fmt.Fprintf(&text, "%-24s %3d/%d\n", "total", card.Passed(), len(card))
+
+	for _, result := range card {
+		if result.Err != nil {
+			fmt.Fprintf(&text, "FAIL %s: %v\n", result.Scenario.Name, result.Err)
+		}
+	}
+	return text.String()
+}
+
+// CursorAt checks that the cursor is at the one-based position x, y, as DSR
+// reports it.
+func CursorAt(x int, y int) Check {
+	return func(term Terminal) error {
+		if actualX, actualY := term.Cursor(); actualX+1 != x || actualY+1 != y {
+			return fmt.Errorf("cursor at %d,%d, expected %d,%d", actualX+1, actualY+1, x, y)
+		}
+		return nil
+	}
+}
+
+// LineIs checks the text of the one-based row y.
+func LineIs(y int, text string) Check {
+	return func(term Terminal) error {
+		if actual := term.Line(y - 1); actual != text {
+			return fmt.Errorf("line %d is %q, expected %q", y, actual, text)
+		}
+		return nil
+	}
+}
+
+// LinesAre checks the text of consecutive rows from the one-based row y.
+func LinesAre(y int, lines ...string) Check {
+	checks := make([]Check, len(lines))
+	for i, line := range lines {
+		checks[i] = LineIs(y+i, line)
+	}
+	return All(checks...)
+}
+
+// All checks each of checks, reporting the first failure.
+func All(checks ...Check) Check {
+	return func(term Terminal) error {
+		for _, check := range checks {
+			if err := check(term); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
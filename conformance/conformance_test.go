@@ -0,0 +1,87 @@
+package conformance
+
+import (
+	"flag"
+	"io/ioutil"
+	"testing"
+
+	"github.com/Azure/go-ansiterm"
+	"github.com/Azure/go-ansiterm/vscreen"
+	"github.com/Azure/go-ansiterm/winterm"
+	"github.com/Azure/go-ansiterm/winterm/wintermtest"
+)
+
+var update = flag.Bool("update", false, "rewrite the recorded scorecard")
+
+// TestVirtualScreen checks the virtual screen's scorecard against the one
+// recorded, so that changes in fidelity, either way, are noticed.
+func TestVirtualScreen(t *testing.T) {
+	card := Run(func(cols int, rows int) Terminal {
+		return vscreen.New(cols, rows)
+	})
+
+	checkScorecard(t, card, "testdata/vscreen.scorecard")
+}
+
+// TestWinterm checks the scorecard of the Windows console handler, rendering
+// to an in-memory console.
+func TestWinterm(t *testing.T) {
+	card := Run(func(cols int, rows int) Terminal {
+		console := wintermtest.NewConsole(cols, rows)
+		handler, err := winterm.NewConsoleEventHandler(console, console.Handle(), nil, winterm.WithoutResponses())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return &wintermTerminal{
+			console: console,
+			parser:  ansiterm.CreateParser("Ground", handler, ansiterm.WithUTF8()),
+		}
+	})
+
+	checkScorecard(t, card, "testdata/winterm.scorecard")
+}
+
+// wintermTerminal is a Terminal rendering with a winterm handler.
+type wintermTerminal struct {
+	console *wintermtest.Console
+	parser  *ansiterm.AnsiParser
+}
+
+func (term *wintermTerminal) Write(p []byte) (int, error) {
+	return term.parser.Parse(p)
+}
+
+func (term *wintermTerminal) Cursor() (x int, y int) {
+	info, err := term.console.GetConsoleScreenBufferInfo(term.console.Handle())
+	if err != nil {
+		panic(err)
+	}
+
+	return int(info.CursorPosition.X - info.Window.Left), int(info.CursorPosition.Y - info.Window.Top)
+}
+
+func (term *wintermTerminal) Line(y int) string {
+	return term.console.Screen(term.console.Handle())[y]
+}
+
+// checkScorecard checks card against the scorecard recorded in the file
+// named recorded, or with -update rewrites it.
+func checkScorecard(t *testing.T, card Scorecard, recorded string) {
+	t.Helper()
+
+	if *update {
+		if err := ioutil.WriteFile(recorded, []byte(card.String()), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	expected, err := ioutil.ReadFile(recorded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := card.String(); actual != string(expected) {
+		t.Errorf("scorecard changed; run with -update if intended:\n%s\nexpected:\n%s", actual, expected)
+	}
+}
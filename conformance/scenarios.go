@@ -0,0 +1,79 @@
+package conformance
+
+import (
+	"strings"
+)
+
+// Scenarios are the scenarios Run runs by default.
+var Scenarios = []Scenario{
+	// Cursor movement
+	{"esctest CUU_DefaultParam", "cursor movement", "\x1b[3;5H\x1b[A", CursorAt(5, 2)},
+	{"esctest CUU_StopsAtTopLine", "cursor movement", "\x1b[3;5H\x1b[99A", CursorAt(5, 1)},
+	{"esctest CUU_StopsAtTopMargin", "cursor movement", "\x1b[5;10r\x1b[7;1H\x1b[99A", CursorAt(1, 5)},
+	{"esctest CUD_StopsAtBottomLine", "cursor movement", "\x1b[3;5H\x1b[99B", CursorAt(5, 24)},
+	{"esctest CUF_StopsAtRightSide", "cursor movement", "\x1b[3;5H\x1b[999C", CursorAt(80, 3)},
+	{"esctest CUB_StopsAtLeftEdge", "cursor movement", "\x1b[3;5H\x1b[99D", CursorAt(1, 3)},
+	{"esctest CNL_DefaultParam", "cursor movement", "\x1b[3;5H\x1b[E", CursorAt(1, 4)},
+	{"esctest CPL_DefaultParam", "cursor movement", "\x1b[3;5H\x1b[F", CursorAt(1, 2)},
+	{"esctest CHA_ZeroParam", "cursor movement", "\x1b[3;5H\x1b[0G", CursorAt(1, 3)},
+	{"esctest CUP_ZeroIsTreatedAsOne", "cursor movement", "\x1b[3;5H\x1b[0;0H", CursorAt(1, 1)},
+	{"esctest CUP_OutOfBoundsParams", "cursor movement", "\x1b[99;999H", CursorAt(80, 24)},
+	{"esctest HVP_DefaultParams", "cursor movement", "\x1b[3;5H\x1b[f", CursorAt(1, 1)},
+	{"esctest VPA_DefaultParams", "cursor movement", "\x1b[3;5H\x1b[d", CursorAt(5, 1)},
+
+	// Origin mode
+	{"esctest DECOM_CUPRelativeToMargins", "origin mode", "\x1b[5;10r\x1b[?6h\x1b[2;3H", CursorAt(3, 6)},
+	{"esctest DECOM_CUPClampedToMargins", "origin mode", "\x1b[5;10r\x1b[?6h\x1b[99;1H", CursorAt(1, 10)},
+	{"esctest DECOM_HomesCursor", "origin mode", "\x1b[5;10r\x1b[20;20H\x1b[?6h", CursorAt(1, 5)},
+
+	// Erasing
+	{"esctest ED_0", "erasing", "abc\r\ndef\r\nghi\x1b[2;2H\x1b[J", LinesAre(1, "abc", "d", "")},
+	{"esctest ED_1", "erasing", "abc\r\ndef\r\nghi\x1b[2;2H\x1b[1J", LinesAre(1, "", "  f", "ghi")},
+	{"esctest ED_2", "erasing", "abc\r\ndef\r\nghi\x1b[2;2H\x1b[2J", All(LinesAre(1, "", "", ""), CursorAt(2, 2))},
+	{"esctest EL_0", "erasing", "abcdef\x1b[1;3H\x1b[K", LineIs(1, "ab")},
+	{"esctest EL_1", "erasing", "abcdef\x1b[1;3H\x1b[1K", LineIs(1, "   def")},
+	{"esctest EL_2", "erasing", "abcdef\x1b[1;3H\x1b[2K", All(LineIs(1, ""), CursorAt(3, 1))},
+	{"esctest ECH_DefaultParam", "erasing", "abc\x1b[1;2H\x1b[X", LineIs(1, "a c")},
+
+	// Editing
+	{"esctest IL_DefaultParam", "editing", "a\r\nb\r\nc\x1b[2;1H\x1b[L", LinesAre(1, "a", "", "b", "c")},
+	{"esctest DL_DefaultParam", "editing", "a\r\nb\r\nc\x1b[2;1H\x1b[M", LinesAre(1, "a", "c", "")},
+	{"esctest ICH_DefaultParam", "editing", "abc\x1b[1;2H\x1b[@", LineIs(1, "a bc")},
+	{"esctest DCH_DefaultParam", "editing", "abc\x1b[1;2H\x1b[P", LineIs(1, "ac")},
+	{"esctest SM_IRM", "editing", "abc\x1b[1;2H\x1b[4hX", LineIs(1, "aXbc")},
+	{"esctest REP_DefaultParam", "editing", "ab\x1b[b", LineIs(1, "abb")},
+
+	// Scrolling
+	{"vttest LF_ScrollsAtBottom", "scrolling", "1\r\n2\x1b[24;1H\n", All(LineIs(1, "2"), CursorAt(1, 24))},
+	{"esctest IND_ScrollsAtBottom", "scrolling", "1\r\n2\x1b[24;1H\x1bD", All(LineIs(1, "2"), CursorAt(1, 24))},
+	{"esctest NEL_Basic", "scrolling", "\x1b[3;5H\x1bE", CursorAt(1, 4)},
+	{"esctest RI_ScrollsAtTop", "scrolling", "a\x1b[1;1H\x1bM", LinesAre(1, "", "a")},
+	{"esctest SU_DefaultParam", "scrolling", "a\r\nb\x1b[S", LinesAre(1, "b", "")},
+	{"esctest SD_DefaultParam", "scrolling", "a\x1b[T", LinesAre(1, "", "a")},
+	{"esctest DECSTBM_ScrollsOnNewline", "scrolling", "1\r\n2\r\n3\r\n4\x1b[2;3r\x1b[3;1H\n", LinesAre(1, "1", "3", "", "4")},
+	{"esctest DECSLRM_SUWithinMargins", "scrolling", "abcd\r\nefgh\x1b[?69h\x1b[2;3s\x1b[1;1H\x1b[S", LinesAre(1, "afgd", "e  h")},
+
+	// Wrapping
+	{"vttest Autowrap", "wrapping", strings.Repeat("x", 80) + "y", All(LinesAre(1, strings.Repeat("x", 80), "y"), CursorAt(2, 2))},
+	{"esctest DECAWM_Reset", "wrapping", "\x1b[?7l" + strings.Repeat("x", 80) + "y", All(LineIs(1, strings.Repeat("x", 79)+"y"), CursorAt(80, 1))},
+	{"esctest DECAWM_CRCancelsWrap", "wrapping", strings.Repeat("x", 80) + "\rz", All(LineIs(1, "z"+strings.Repeat("x", 79)), CursorAt(2, 1))},
+
+	// Tab stops
+	{"vttest DefaultTabStops", "tabs", "\tx", All(LineIs(1, "        x"), CursorAt(10, 1))},
+	{"esctest HTS_Basic", "tabs", "\x1b[3g\x1b[5G\x1bH\r\tx", LineIs(1, "    x")},
+	{"esctest CBT_DefaultParam", "tabs", "\x1b[20G\x1b[Z", CursorAt(17, 1)},
+	{"esctest CHT_ExplicitParam", "tabs", "\x1b[2I", CursorAt(17, 1)},
+
+	// Saving the cursor
+	{"esctest DECSC_DECRC", "cursor save", "\x1b[5;6H\x1b7\x1b[H\x1b8", CursorAt(6, 5)},
+	{"esctest DECRC_WithoutDECSC", "cursor save", "\x1b[5;6H\x1b8", CursorAt(1, 1)},
+
+	// Character sets
+	{"vttest DECSpecialGraphics", "character sets", "\x1b(0lqk\x1b(B", LineIs(1, "┌─┐")},
+	{"vttest G1LockingShift", "character sets", "\x1b)0\x0eq\x0fq", LineIs(1, "─q")},
+
+	// Resets and test patterns
+	{"esctest DECALN", "reset", "\x1b#8", All(LineIs(1, strings.Repeat("E", 80)), LineIs(24, strings.Repeat("E", 80)), CursorAt(1, 1))},
+	{"esctest RIS", "reset", "abc\x1b[5;5H\x1bc", All(LineIs(1, ""), CursorAt(1, 1))},
+	{"esctest DECSTR_ResetsMarginsAndOrigin", "reset", "\x1b[5;10r\x1b[?6h\x1b[!p\x1b[24;1H", CursorAt(1, 24)},
+}
@@ -21,13 +21,13 @@ const (
 	ANSI_SGR_RESET              = 0
 	ANSI_SGR_BOLD               = 1
 	ANSI_SGR_DIM                = 2
-	_ANSI_SGR_ITALIC            = 3
+	ANSI_SGR_ITALIC             = 3
 	ANSI_SGR_UNDERLINE          = 4
-	_ANSI_SGR_BLINKSLOW         = 5
-	_ANSI_SGR_BLINKFAST         = 6
+	ANSI_SGR_BLINKSLOW          = 5
+	ANSI_SGR_BLINKFAST          = 6
 	ANSI_SGR_REVERSE            = 7
 	_ANSI_SGR_INVISIBLE         = 8
-	_ANSI_SGR_LINETHROUGH       = 9
+	ANSI_SGR_LINETHROUGH        = 9
 	_ANSI_SGR_FONT_00           = 10
 	_ANSI_SGR_FONT_01           = 11
 	_ANSI_SGR_FONT_02           = 12
@@ -41,13 +41,13 @@ const (
 	_ANSI_SGR_FONT_10           = 20
 	_ANSI_SGR_DOUBLEUNDERLINE   = 21
 	ANSI_SGR_BOLD_DIM_OFF       = 22
-	_ANSI_SGR_ITALIC_OFF        = 23
+	ANSI_SGR_ITALIC_OFF         = 23
 	ANSI_SGR_UNDERLINE_OFF      = 24
-	_ANSI_SGR_BLINK_OFF         = 25
+	ANSI_SGR_BLINK_OFF          = 25
 	_ANSI_SGR_RESERVED_00       = 26
 	ANSI_SGR_REVERSE_OFF        = 27
 	_ANSI_SGR_INVISIBLE_OFF     = 28
-	_ANSI_SGR_LINETHROUGH_OFF   = 29
+	ANSI_SGR_LINETHROUGH_OFF    = 29
 	ANSI_SGR_FOREGROUND_BLACK   = 30
 	ANSI_SGR_FOREGROUND_RED     = 31
 	ANSI_SGR_FOREGROUND_GREEN   = 32
@@ -70,6 +70,24 @@ const (
 	ANSI_SGR_BACKGROUND_DEFAULT = 49
 	// 50 - 65: Unsupported
 
+	// aixterm bright colors
+	ANSI_SGR_FOREGROUND_BRIGHT_BLACK   = 90
+	ANSI_SGR_FOREGROUND_BRIGHT_RED     = 91
+	ANSI_SGR_FOREGROUND_BRIGHT_GREEN   = 92
+	ANSI_SGR_FOREGROUND_BRIGHT_YELLOW  = 93
+	ANSI_SGR_FOREGROUND_BRIGHT_BLUE    = 94
+	ANSI_SGR_FOREGROUND_BRIGHT_MAGENTA = 95
+	ANSI_SGR_FOREGROUND_BRIGHT_CYAN    = 96
+	ANSI_SGR_FOREGROUND_BRIGHT_WHITE   = 97
+	ANSI_SGR_BACKGROUND_BRIGHT_BLACK   = 100
+	ANSI_SGR_BACKGROUND_BRIGHT_RED     = 101
+	ANSI_SGR_BACKGROUND_BRIGHT_GREEN   = 102
+	ANSI_SGR_BACKGROUND_BRIGHT_YELLOW  = 103
+	ANSI_SGR_BACKGROUND_BRIGHT_BLUE    = 104
+	ANSI_SGR_BACKGROUND_BRIGHT_MAGENTA = 105
+	ANSI_SGR_BACKGROUND_BRIGHT_CYAN    = 106
+	ANSI_SGR_BACKGROUND_BRIGHT_WHITE   = 107
+
 	ANSI_MAX_CMD_LENGTH = 4096
 
 	MAX_INPUT_EVENTS = 128
@@ -77,11 +95,18 @@ const (
 	DEFAULT_HEIGHT   = 24
 
 	ANSI_BEL              = 0x07
+	ANSI_BACKSPACE        = 0x08
+	ANSI_TAB              = 0x09
 	ANSI_LINE_FEED        = 0x0A
+	ANSI_VERTICAL_TAB     = 0x0B
+	ANSI_FORM_FEED        = 0x0C
 	ANSI_CARRIAGE_RETURN  = 0x0D
+	ANSI_SHIFT_OUT        = 0x0E
+	ANSI_SHIFT_IN         = 0x0F
 	ANSI_ESCAPE_PRIMARY   = 0x1B
 	ANSI_ESCAPE_SECONDARY = 0x5B
 	ANSI_OSC_STRING_ENTRY = 0x5D
+	ANSI_DCS_ENTRY        = 0x50
 	ANSI_COMMAND_FIRST    = 0x40
 	ANSI_COMMAND_LAST     = 0x7E
 	DCS_ENTRY             = 0x90
@@ -92,11 +117,18 @@ const (
 	ANSI_CMD_G1           = ')'
 	ANSI_CMD_G2           = '*'
 	ANSI_CMD_G3           = '+'
+	ANSI_CMD_DEC_LINE     = '#'
 	ANSI_CMD_DECPNM       = '>'
 	ANSI_CMD_DECPAM       = '='
 	ANSI_CMD_OSC          = ']'
 	ANSI_CMD_STR_TERM     = '\\'
 
+	// Character sets designated by SCS
+	// See http://vt100.net/docs/vt220-rm/chapter4.html#S4.6.
+	ANSI_CHARSET_ASCII       = 'B'
+	ANSI_CHARSET_UK          = 'A'
+	ANSI_CHARSET_DEC_SPECIAL = '0'
+
 	KEY_CONTROL_PARAM_2 = ";2"
 	KEY_CONTROL_PARAM_3 = ";3"
 	KEY_CONTROL_PARAM_4 = ";4"
@@ -4,4 +4,6 @@ type AnsiContext struct {
 	currentChar byte
 	paramBuffer []byte
 	interBuffer []byte
+	dcsBuffer   []byte
+	oscBuffer   []byte
 }
@@ -34,9 +34,40 @@ type AnsiEventHandler interface {
 	// Horizontal and Vertical Position (depends on PUM)
 	HVP(int, int) error
 
+	// Vertical line Position Absolute
+	VPA(int) error
+
+	// Cursor Horizontal forward Tabulation
+	CHT(int) error
+
+	// Cursor Backward Tabulation
+	CBT(int) error
+
+	// Horizontal Tabulation Set
+	HTS() error
+
+	// Tabulation Clear
+	TBC(int) error
+
 	// Text Cursor Enable Mode
 	DECTCEM(bool) error
 
+	// Set Cursor Style
+	DECSCUSR(int) error
+
+	// Synchronized Update (private mode 2026): while set, output may be
+	// buffered and presented at once when it is reset
+	SynchronizedUpdate(bool) error
+
+	// Insertion Replacement Mode
+	IRM(bool) error
+
+	// Origin Mode
+	DECOM(bool) error
+
+	// Autowrap Mode
+	DECAWM(bool) error
+
 	// Erase in Display
 	ED(int) error
 
@@ -52,6 +83,9 @@ type AnsiEventHandler interface {
 	// Set Graphics Rendition
 	SGR([]int) error
 
+	// REPeat the preceding graphic character
+	REP(int) error
+
 	// Pan Down
 	SU(int) error
 
@@ -61,9 +95,89 @@ type AnsiEventHandler interface {
 	// Device Attributes
 	DA([]string) error
 
-	// Set Top and Bottom Margins
+	// Device Status Report
+	DSR(int) error
+
+	// Cursor Keys Mode (true selects application cursor keys)
+	DECCKM(bool) error
+
+	// Keypad Application Mode (false selects numeric mode, DECKPNM)
+	DECKPAM(bool) error
+
+	// Mouse tracking and reporting (xterm); the first parameter is the
+	// private mode: 1000, 1002, 1003 or 1006
+	MouseMode(int, bool) error
+
+	// Bracketed Paste Mode (xterm)
+	BracketedPaste(bool) error
+
+	// win32-input-mode (ConPTY)
+	Win32InputMode(bool) error
+
+	// Focus In/Out Reporting (xterm)
+	FocusReporting(bool) error
+
+	// Alternate screen buffer (xterm); the first parameter is the private
+	// mode: 47, 1047 or 1049
+	AlternateScreen(int, bool) error
+
+	// Set key modifier options (xterm); the first parameter is the
+	// resource, of which 4 selects the modifyOtherKeys level
+	XTMODKEYS([]int) error
+
+	// Progressive keyboard enhancement (kitty); the first parameter is the
+	// operation: '>' push, '<' pop, '=' set, or '?' query
+	KittyKeyboard(byte, []int) error
+
+	// Set Top and Bottom Margins (0 selects the default margin)
 	DECSTBM(int, int) error
 
+	// Left Right Margin Mode
+	DECLRMM(bool) error
+
+	// Set Left and Right Margins (0 selects the default margin, only honored in DECLRMM)
+	DECSLRM(int, int) error
+
+	// Request Selection or Setting
+	DECRQSS(string) error
+
+	// Request termcap/terminfo strings (xterm), with the capability names decoded from hex
+	XTGETTCAP([]string) error
+
+	// Window manipulation (xterm); the first parameter selects the operation
+	XTWINOPS([]int) error
+
 	// Reverse Index
 	RI() error
+
+	// Reset to Initial State
+	RIS() error
+
+	// Soft Terminal Reset
+	DECSTR() error
+
+	// Push the renditions selected by SGR (xterm); the parameters select
+	// which to restore when popped, all of them if there are none
+	XTPUSHSGR([]int) error
+
+	// Pop the renditions pushed by XTPUSHSGR (xterm)
+	XTPOPSGR() error
+
+	// Save Cursor
+	DECSC() error
+
+	// Restore Cursor
+	DECRC() error
+
+	// Screen Alignment Pattern
+	DECALN() error
+
+	// Select Character Set (designates a character set to G0-G3)
+	SCS(int, byte) error
+
+	// Operating System Command: the command number and its text (Ps ; Pt)
+	OSC(int, string) error
+
+	// Flush updates from previous commands
+	Flush() error
 }
@@ -0,0 +1,329 @@
+package ansiterm
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// LineHandler is an AnsiEventHandler that writes the logical lines of a
+// stream as plain text once they are final, collapsing the redraws of
+// progress bars and spinners into the state they end in. It keeps the last
+// lines of output live, as a terminal keeps them on its screen: carriage
+// returns, cursor movement, and erasure within them rewrite them in place,
+// and a line is written only when it scrolls out of the live window or
+// Close is called. LF, VT and FF start a new line, as in newline mode, since
+// logged output often lacks the CR a terminal driver would add. Control
+// sequences are otherwise dropped.
+type LineHandler struct {
+	out    io.Writer
+	window int
+	lines  [][]string // The live lines, cells holding a character and its combining marks, or "" where a wide character extends
+	x, y   int
+	last   string // The most recently printed cell, for REP
+	utf8   []byte // An incomplete UTF-8 sequence
+}
+
+// NewLineHandler creates a LineHandler writing lines to out, keeping window
+// lines live; if window is less than 1, DEFAULT_HEIGHT are kept.
+func NewLineHandler(out io.Writer, window int) *LineHandler {
+	if window < 1 {
+		window = DEFAULT_HEIGHT
+	}
+	return &LineHandler{out: out, window: window, lines: [][]string{nil}}
+}
+
+// LineWriter returns a writer that parses UTF-8 terminal output and writes
+// its final lines to w, as a LineHandler keeping window lines live does.
+// Close writes the lines still live.
+func LineWriter(w io.Writer, window int) io.WriteCloser {
+	h := NewLineHandler(w, window)
+	return &lineWriter{
+		parser:  CreateParser("Ground", h, WithUTF8()),
+		handler: h,
+	}
+}
+
+type lineWriter struct {
+	parser  *AnsiParser
+	handler *LineHandler
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	return w.parser.Parse(p)
+}
+
+func (w *lineWriter) Close() error {
+	return w.handler.Close()
+}
+
+// Close writes the lines still live, except an empty line the cursor is on,
+// which output ending in a newline leaves.
+func (h *LineHandler) Close() error {
+	lines := h.lines
+	if last := len(lines) - 1; h.y == last && lineString(lines[last]) == "" {
+		lines = lines[:last]
+	}
+
+	for _, line := range lines {
+		if _, err := io.WriteString(h.out, lineString(line)+"\n"); err != nil {
+			return err
+		}
+	}
+
+	h.lines = [][]string{nil}
+	h.x, h.y = 0, 0
+	return nil
+}
+
+func (h *LineHandler) Print(b byte) error {
+	h.utf8 = append(h.utf8, b)
+	if !utf8.FullRune(h.utf8) {
+		return nil
+	}
+
+	r, _ := utf8.DecodeRune(h.utf8)
+	h.utf8 = h.utf8[:0]
+	h.put(r)
+	return nil
+}
+
+func (h *LineHandler) Execute(b byte) error {
+	switch b {
+	case ANSI_CARRIAGE_RETURN:
+		h.x = 0
+	case ANSI_LINE_FEED, ANSI_VERTICAL_TAB, ANSI_FORM_FEED:
+		h.x = 0
+		return h.moveDown(1)
+	case ANSI_BACKSPACE:
+		h.CUB(1)
+	case ANSI_TAB:
+		h.CHT(1)
+	}
+	return nil
+}
+
+// put writes a character at the cursor, overwriting the line.
+func (h *LineHandler) put(r rune) {
+	width := RuneWidth(r)
+	if width == 0 {
+		// Combining marks join the character before the cursor
+		if line := h.lines[h.y]; h.x > 0 && h.x <= len(line) {
+			line[h.x-1] += string(r)
+		}
+		return
+	}
+
+	cell := string(r)
+	h.set(h.x, cell)
+	if width == 2 {
+		h.set(h.x+1, "")
+	}
+	h.x += width
+	h.last = cell
+}
+
+// set sets the cell at x of the cursor's line, erasing any wide character it
+// splits.
+func (h *LineHandler) set(x int, cell string) {
+	line := h.lines[h.y]
+	for len(line) <= x {
+		line = append(line, " ")
+	}
+
+	if line[x] == "" && x > 0 {
+		line[x-1] = " "
+	}
+	if x+1 < len(line) && line[x+1] == "" {
+		line[x+1] = " "
+	}
+
+	line[x] = cell
+	h.lines[h.y] = line
+}
+
+// moveDown moves the cursor down n lines, adding lines after the last and
+// writing those that leave the live window.
+func (h *LineHandler) moveDown(n int) error {
+	h.y += n
+	for len(h.lines) <= h.y {
+		h.lines = append(h.lines, nil)
+	}
+
+	for len(h.lines) > h.window {
+		if _, err := io.WriteString(h.out, lineString(h.lines[0])+"\n"); err != nil {
+			return err
+		}
+		h.lines = h.lines[1:]
+		h.y--
+	}
+	return nil
+}
+
+// moveUp moves the cursor up n lines, within the live window.
+func (h *LineHandler) moveUp(n int) {
+	h.y -= n
+	if h.y < 0 {
+		h.y = 0
+	}
+}
+
+// lineString returns the text of a line, without trailing blanks.
+func lineString(line []string) string {
+	return strings.TrimRight(strings.Join(line, ""), " ")
+}
+
+func (h *LineHandler) CUU(param int) error {
+	h.moveUp(param)
+	return nil
+}
+
+func (h *LineHandler) CUD(param int) error {
+	return h.moveDown(param)
+}
+
+func (h *LineHandler) CUF(param int) error {
+	h.x += param
+	return nil
+}
+
+func (h *LineHandler) CUB(param int) error {
+	h.x -= param
+	if h.x < 0 {
+		h.x = 0
+	}
+	return nil
+}
+
+func (h *LineHandler) CNL(param int) error {
+	h.x = 0
+	return h.moveDown(param)
+}
+
+func (h *LineHandler) CPL(param int) error {
+	h.x = 0
+	h.moveUp(param)
+	return nil
+}
+
+func (h *LineHandler) CHA(param int) error {
+	h.x = param - 1
+	if h.x < 0 {
+		h.x = 0
+	}
+	return nil
+}
+
+// CUP and HVP address the live window as the bottom of the screen.
+func (h *LineHandler) CUP(row int, col int) error {
+	top := len(h.lines) - h.window
+	if top < 0 {
+		top = 0
+	}
+
+	h.CHA(col)
+	if y := top + row - 1; y > h.y {
+		return h.moveDown(y - h.y)
+	}
+	h.moveUp(h.y - (top + row - 1))
+	return nil
+}
+
+func (h *LineHandler) HVP(row int, col int) error {
+	return h.CUP(row, col)
+}
+
+func (h *LineHandler) VPA(param int) error {
+	return h.CUP(param, h.x+1)
+}
+
+func (h *LineHandler) CHT(param int) error {
+	h.x = (h.x/8 + param) * 8
+	return nil
+}
+
+func (h *LineHandler) EL(param int) error {
+	line := h.lines[h.y]
+	switch param {
+	case 0:
+		if h.x < len(line) {
+			h.lines[h.y] = line[:h.x]
+		}
+	case 1:
+		for x := 0; x <= h.x && x < len(line); x++ {
+			line[x] = " "
+		}
+	case 2:
+		h.lines[h.y] = nil
+	}
+	return nil
+}
+
+func (h *LineHandler) ED(param int) error {
+	switch param {
+	case 0:
+		h.EL(0)
+		h.lines = h.lines[:h.y+1]
+	case 1:
+		for y := 0; y < h.y; y++ {
+			h.lines[y] = nil
+		}
+		h.EL(1)
+	}
+
+	// Erasing the whole screen leaves what it showed in the log
+	return nil
+}
+
+func (h *LineHandler) REP(param int) error {
+	for i := 0; i < param && h.last != ""; i++ {
+		r, _ := utf8.DecodeRuneInString(h.last)
+		h.put(r)
+	}
+	return nil
+}
+
+// The rest of the stream does not change the text.
+
+func (h *LineHandler) CBT(int) error                   { return nil }
+func (h *LineHandler) HTS() error                      { return nil }
+func (h *LineHandler) TBC(int) error                   { return nil }
+func (h *LineHandler) DECTCEM(bool) error              { return nil }
+func (h *LineHandler) DECSCUSR(int) error              { return nil }
+func (h *LineHandler) SynchronizedUpdate(bool) error   { return nil }
+func (h *LineHandler) IRM(bool) error                  { return nil }
+func (h *LineHandler) DECOM(bool) error                { return nil }
+func (h *LineHandler) DECAWM(bool) error               { return nil }
+func (h *LineHandler) IL(int) error                    { return nil }
+func (h *LineHandler) DL(int) error                    { return nil }
+func (h *LineHandler) SGR([]int) error                 { return nil }
+func (h *LineHandler) SU(int) error                    { return nil }
+func (h *LineHandler) SD(int) error                    { return nil }
+func (h *LineHandler) DA([]string) error               { return nil }
+func (h *LineHandler) DSR(int) error                   { return nil }
+func (h *LineHandler) DECCKM(bool) error               { return nil }
+func (h *LineHandler) DECKPAM(bool) error              { return nil }
+func (h *LineHandler) MouseMode(int, bool) error       { return nil }
+func (h *LineHandler) BracketedPaste(bool) error       { return nil }
+func (h *LineHandler) Win32InputMode(bool) error       { return nil }
+func (h *LineHandler) FocusReporting(bool) error       { return nil }
+func (h *LineHandler) AlternateScreen(int, bool) error { return nil }
+func (h *LineHandler) XTMODKEYS([]int) error           { return nil }
+func (h *LineHandler) KittyKeyboard(byte, []int) error { return nil }
+func (h *LineHandler) DECSTBM(int, int) error          { return nil }
+func (h *LineHandler) DECLRMM(bool) error              { return nil }
+func (h *LineHandler) DECSLRM(int, int) error          { return nil }
+func (h *LineHandler) DECRQSS(string) error            { return nil }
+func (h *LineHandler) XTGETTCAP([]string) error        { return nil }
+func (h *LineHandler) XTWINOPS([]int) error            { return nil }
+func (h *LineHandler) RI() error                       { return nil }
+func (h *LineHandler) RIS() error                      { return nil }
+func (h *LineHandler) DECSTR() error                   { return nil }
+func (h *LineHandler) XTPUSHSGR([]int) error           { return nil }
+func (h *LineHandler) XTPOPSGR() error                 { return nil }
+func (h *LineHandler) DECSC() error                    { return nil }
+func (h *LineHandler) DECRC() error                    { return nil }
+func (h *LineHandler) DECALN() error                   { return nil }
+func (h *LineHandler) SCS(int, byte) error             { return nil }
+func (h *LineHandler) OSC(int, string) error           { return nil }
+func (h *LineHandler) Flush() error                    { return nil }
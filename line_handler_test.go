@@ -0,0 +1,35 @@
+package ansiterm
+
+import (
+	"bytes"
+	"testing"
+)
+
+func lineHelper(t *testing.T, window int, output string, expected string) {
+	var text bytes.Buffer
+	w := LineWriter(&text, window)
+	w.Write([]byte(output))
+	w.Close()
+
+	if actual := text.String(); actual != expected {
+		t.Errorf("%q: captured %q, expected %q", output, actual, expected)
+	}
+}
+
+func TestLineWriter(t *testing.T) {
+	lineHelper(t, 0, "plain\r\ntext", "plain\ntext\n")
+	lineHelper(t, 0, "\x1b[32mgreen\x1b[0m\n", "green\n")
+	lineHelper(t, 0, "10% [=   ]\r50% [==  ]\r100% [====]\r\n", "100% [====]\n")
+	lineHelper(t, 0, "downloading\rdone\x1b[K\n", "done\n")
+
+	pull := "a: Waiting\r\nb: Waiting\r\n" +
+		"\x1b[2A\x1b[2Ka: Downloading\r\x1b[2B" +
+		"\x1b[1A\x1b[2Kb: Pull complete\r\x1b[1B" +
+		"\x1b[2A\x1b[2Ka: Pull complete\r\x1b[2B"
+	lineHelper(t, 0, pull, "a: Pull complete\nb: Pull complete\n")
+
+	lineHelper(t, 2, "1\n2\n3\x1b[5Ax", "1\n2x\n3\n")
+	lineHelper(t, 0, "one\ntwo\nthree\x1b[2A\x1b[J", "one\n")
+	lineHelper(t, 0, "日本\rx", "x 本\n")
+	lineHelper(t, 0, "ab\x1b[3b\tc", "abbbb   c\n")
+}
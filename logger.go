@@ -0,0 +1,45 @@
+package ansiterm
+
+import (
+	"fmt"
+	"io"
+	"log"
+)
+
+// Logger receives the diagnostics of the parser and event handlers: a trace of
+// the bytes, states and sequences processed through Infof, which is verbose,
+// and sequences that could not be handled through Errorf.
+//
+// The methods are those of leveled loggers such as logrus, whose loggers can
+// be used directly; the logrusadapter package adapts them to log the trace at
+// debug level. NewLogger creates a Logger using only the standard library.
+type Logger interface {
+	Infof(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// DiscardLogger is a Logger that drops all messages without formatting them.
+var DiscardLogger Logger = discardLogger{}
+
+type discardLogger struct{}
+
+func (discardLogger) Infof(format string, args ...interface{})  {}
+func (discardLogger) Errorf(format string, args ...interface{}) {}
+
+// NewLogger returns a Logger writing each message to w as a line prefixed with
+// the time and its level.
+func NewLogger(w io.Writer) Logger {
+	return &stdLogger{log.New(w, "", log.LstdFlags|log.Lmicroseconds)}
+}
+
+type stdLogger struct {
+	l *log.Logger
+}
+
+func (s *stdLogger) Infof(format string, args ...interface{}) {
+	s.l.Output(2, "INFO "+fmt.Sprintf(format, args...))
+}
+
+func (s *stdLogger) Errorf(format string, args ...interface{}) {
+	s.l.Output(2, "ERROR "+fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,36 @@
+package ansiterm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParserLogger(t *testing.T) {
+	var log bytes.Buffer
+	parser := CreateParser("Ground", CreateTestAnsiEventHandler(), WithParserLogger(NewLogger(&log)))
+	parser.Parse([]byte("\x1b[5A\x1b[z"))
+
+	lines := strings.Split(strings.TrimSuffix(log.String(), "\n"), "\n")
+	var infos, errors int
+	for _, line := range lines {
+		switch {
+		case strings.Contains(line, " INFO "):
+			infos++
+		case strings.Contains(line, " ERROR Unsupported CSI command: 'z'"):
+			errors++
+		default:
+			t.Errorf("unexpected log line %q", line)
+		}
+	}
+
+	if !strings.Contains(log.String(), " INFO csiDispatch: A([5])") {
+		t.Errorf("CUU dispatch not logged:\n%s", log.String())
+	}
+	if errors != 1 {
+		t.Errorf("%d unsupported sequence errors logged, expected 1:\n%s", errors, log.String())
+	}
+	if infos == 0 {
+		t.Errorf("no trace logged")
+	}
+}
@@ -0,0 +1,33 @@
+// Package logrusadapter directs the diagnostics of the ansiterm parser and
+// event handlers to logrus, which the ansiterm packages themselves do not
+// depend on so that programs not using logrus need not vendor it.
+//
+// The trace of processed bytes and sequences, which ansiterm logs through
+// Logger.Infof, is logged at debug level, and errors at error level:
+//
+//	parser := ansiterm.CreateParser("Ground", handler,
+//		ansiterm.WithParserLogger(logrusadapter.New(logrus.StandardLogger())))
+package logrusadapter
+
+import (
+	"github.com/Azure/go-ansiterm"
+	"github.com/sirupsen/logrus"
+)
+
+// New returns an ansiterm.Logger logging to l, which may be a *logrus.Logger
+// or a *logrus.Entry carrying fields.
+func New(l logrus.FieldLogger) ansiterm.Logger {
+	return logger{l}
+}
+
+type logger struct {
+	l logrus.FieldLogger
+}
+
+func (a logger) Infof(format string, args ...interface{}) {
+	a.l.Debugf(format, args...)
+}
+
+func (a logger) Errorf(format string, args ...interface{}) {
+	a.l.Errorf(format, args...)
+}
@@ -1,18 +1,13 @@
 package ansiterm
 
 import (
-	"errors"
-	"fmt"
-	"io/ioutil"
 	"os"
-
-	"github.com/Sirupsen/logrus"
 )
 
-var logger *logrus.Logger
-
 type AnsiParser struct {
 	currState          State
+	state              StateId
+	table              *stateTable
 	eventHandler       AnsiEventHandler
 	context            *AnsiContext
 	CsiEntry           State
@@ -24,35 +19,91 @@ type AnsiParser struct {
 	Ground             State
 	OscString          State
 	stateMap           []State
+	utf8               bool
+	noQueries          bool
+	logger             Logger
+	trace              bool // Whether diagnostics are logged, rather than discarded
+	stats              *Stats
+}
+
+// Option configures optional behavior of an AnsiParser.
+type Option func(*AnsiParser)
+
+// WithUTF8 treats bytes 0x80-0xFF in the ground state as printable so UTF-8
+// encoded text reaches the event handler's Print. 8-bit C1 controls overlap
+// UTF-8 continuation bytes and are not recognized in this mode.
+func WithUTF8() Option {
+	return func(ap *AnsiParser) {
+		ap.utf8 = true
+	}
 }
 
-func CreateParser(initialState string, evtHandler AnsiEventHandler) *AnsiParser {
-	logFile := ioutil.Discard
+// WithoutQueries drops requests that would make the event handler respond
+// (DA, DSR, DECRQSS, XTGETTCAP, the XTWINOPS reports and the kitty keyboard query), for consumers that only render
+// output and must never inject responses into their data path.
+func WithoutQueries() Option {
+	return func(ap *AnsiParser) {
+		ap.noQueries = true
+	}
+}
 
-	if isDebugEnv := os.Getenv(LogEnv); isDebugEnv == "1" {
-		logFile, _ = os.Create("ansiParser.log")
+// WithParserLogger directs the parser's diagnostics to logger. By default
+// they are discarded, unless the LogEnv environment variable is "1", in which
+// case they are written to the file ansiParser.log.
+func WithParserLogger(logger Logger) Option {
+	return func(ap *AnsiParser) {
+		ap.logger = logger
 	}
+}
 
-	logger = &logrus.Logger{
-		Out:       logFile,
-		Formatter: new(logrus.TextFormatter),
-		Level:     logrus.InfoLevel,
+// WithParserStats counts the bytes the parser parses, the events it
+// dispatches and the sequences it does not support in stats.
+func WithParserStats(stats *Stats) Option {
+	return func(ap *AnsiParser) {
+		ap.stats = stats
 	}
+}
 
+func CreateParser(initialState string, evtHandler AnsiEventHandler, opts ...Option) *AnsiParser {
 	parser := &AnsiParser{
 		eventHandler: evtHandler,
 		context:      &AnsiContext{},
 	}
 
-	parser.CsiEntry = CsiEntryState{BaseState{name: "CsiEntry", parser: parser}}
-	parser.CsiParam = CsiParamState{BaseState{name: "CsiParam", parser: parser}}
-	parser.DcsEntry = DcsEntryState{BaseState{name: "DcsEntry", parser: parser}}
-	parser.Escape = EscapeState{BaseState{name: "Escape", parser: parser}}
-	parser.EscapeIntermediate = EscapeIntermediateState{BaseState{name: "EscapeIntermediate", parser: parser}}
-	parser.Error = ErrorState{BaseState{name: "Error", parser: parser}}
-	parser.Ground = GroundState{BaseState{name: "Ground", parser: parser}}
-	parser.OscString = OscStringState{BaseState{name: "OscString", parser: parser}}
+	for _, opt := range opts {
+		opt(parser)
+	}
+
+	if parser.logger == nil {
+		parser.logger = DiscardLogger
+		if isDebugEnv := os.Getenv(LogEnv); isDebugEnv == "1" {
+			if logFile, err := os.Create("ansiParser.log"); err == nil {
+				parser.logger = NewLogger(logFile)
+			}
+		}
+	}
+
+	parser.trace = parser.logger != DiscardLogger
+
+	if parser.stats != nil {
+		parser.eventHandler = statsHandler{handler: evtHandler, stats: parser.stats}
+	}
+
+	parser.table = stateTables[0]
+	if parser.utf8 {
+		parser.table = stateTables[1]
+	}
+
+	parser.CsiEntry = CsiEntryState{BaseState{name: "CsiEntry", id: stateCsiEntry, parser: parser}}
+	parser.CsiParam = CsiParamState{BaseState{name: "CsiParam", id: stateCsiParam, parser: parser}}
+	parser.DcsEntry = DcsEntryState{BaseState{name: "DcsEntry", id: stateDcsEntry, parser: parser}}
+	parser.Escape = EscapeState{BaseState{name: "Escape", id: stateEscape, parser: parser}}
+	parser.EscapeIntermediate = EscapeIntermediateState{BaseState{name: "EscapeIntermediate", id: stateEscapeIntermediate, parser: parser}}
+	parser.Error = ErrorState{BaseState{name: "Error", id: stateError, parser: parser}}
+	parser.Ground = GroundState{BaseState{name: "Ground", id: stateGround, parser: parser}}
+	parser.OscString = OscStringState{BaseState{name: "OscString", id: stateOscString, parser: parser}}
 
+	// Indexed by StateId
 	parser.stateMap = []State{
 		parser.CsiEntry,
 		parser.CsiParam,
@@ -64,74 +115,66 @@ func CreateParser(initialState string, evtHandler AnsiEventHandler) *AnsiParser
 		parser.OscString,
 	}
 
-	parser.currState = getState(initialState, parser.stateMap)
-
-	logger.Infof("CreateParser: parser %p", parser)
-	return parser
-}
-
-func getState(name string, states []State) State {
-	for _, el := range states {
-		if el.Name() == name {
-			return el
+	parser.state = stateGround
+	for id, state := range parser.stateMap {
+		if state.Name() == initialState {
+			parser.state = StateId(id)
 		}
 	}
+	parser.currState = parser.stateMap[parser.state]
 
-	return nil
+	parser.logger.Infof("CreateParser: parser %p", parser)
+	return parser
 }
 
 func (ap *AnsiParser) Parse(bytes []byte) (int, error) {
 	for i, b := range bytes {
-		if err := ap.handle(b); err != nil {
-			return i, err
+		ap.context.currentChar = b
+		t := &ap.table[ap.state][b]
+
+		var err error
+		if t.next == ap.state {
+			if t.action != actionNone {
+				err = ap.perform(t.action)
+			}
+		} else {
+			err = ap.changeState(t)
 		}
-	}
 
-	return len(bytes), nil
-}
-
-func (ap *AnsiParser) handle(b byte) error {
-	ap.context.currentChar = b
-	newState, err := ap.currState.Handle(b)
-	if err != nil {
-		return err
-	}
-
-	if newState == nil {
-		logger.Warning("newState is nil")
-		return errors.New(fmt.Sprintf("New state of 'nil' is invalid."))
-	}
-
-	if newState != ap.currState {
-		if err := ap.changeState(newState); err != nil {
-			return err
+		if err != nil {
+			ap.stats.parsed(i)
+			return i, err
 		}
 	}
 
-	return nil
+	ap.stats.parsed(len(bytes))
+	return len(bytes), ap.eventHandler.Flush()
 }
 
-func (ap *AnsiParser) changeState(newState State) error {
-	logger.Infof("ChangeState %s --> %s", ap.currState.Name(), newState.Name())
+// changeState performs a transition to another state: the exit action of
+// the current state, the transition's action, and the entry action of the
+// next state. The state is left unchanged if an action fails.
+func (ap *AnsiParser) changeState(t *transition) error {
+	if ap.trace {
+		ap.logger.Infof("ChangeState %s --> %s", ap.currState.Name(), ap.stateMap[t.next].Name())
+	}
 
-	// Exit old state
-	if err := ap.currState.Exit(); err != nil {
-		logger.Infof("Exit state '%s' failed with : '%v'", ap.currState.Name(), err)
+	if err := ap.exit(ap.state); err != nil {
+		ap.logger.Infof("Exit state '%s' failed with : '%v'", ap.currState.Name(), err)
 		return err
 	}
 
-	// Perform transition action
-	if err := ap.currState.Transition(newState); err != nil {
-		logger.Infof("Transition from '%s' to '%s' failed with: '%v'", ap.currState.Name(), newState.Name, err)
+	if err := ap.perform(t.action); err != nil {
+		ap.logger.Infof("Transition from '%s' to '%s' failed with: '%v'", ap.currState.Name(), ap.stateMap[t.next].Name(), err)
 		return err
 	}
 
-	// Enter new state
-	if err := newState.Enter(); err != nil {
-		logger.Infof("Enter state '%s' failed with: '%v'", newState.Name(), err)
+	if err := ap.enter(t.next); err != nil {
+		ap.logger.Infof("Enter state '%s' failed with: '%v'", ap.stateMap[t.next].Name(), err)
 		return err
 	}
 
-	ap.currState = newState
+	ap.state = t.next
+	ap.currState = ap.stateMap[t.next]
 	return nil
 }
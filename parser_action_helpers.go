@@ -2,6 +2,7 @@ package ansiterm
 
 import (
 	"strconv"
+	"strings"
 )
 
 func parseParams(bytes []byte) ([]string, error) {
@@ -27,18 +28,38 @@ func parseParams(bytes []byte) ([]string, error) {
 		params = append(params, s)
 	}
 
-	logger.Infof("Parsed params: %v with length: %d", params, len(params))
 	return params, nil
 }
 
+// parseDcs splits a device control string into its parameters, intermediates,
+// final character, and the data string that follows.
+func parseDcs(bytes []byte) (params []string, intermeds string, final byte, data string) {
+	i := 0
+	for i < len(bytes) && is(bytes[i], classCsiParam) {
+		i++
+	}
+	params, _ = parseParams(bytes[:i])
+
+	start := i
+	for i < len(bytes) && is(bytes[i], classIntermediate) {
+		i++
+	}
+	intermeds = string(bytes[start:i])
+
+	if i < len(bytes) && is(bytes[i], classAlphabetic) {
+		final = bytes[i]
+		i++
+	}
+
+	return params, intermeds, final, string(bytes[i:])
+}
+
 func parseCmd(context AnsiContext) (string, error) {
 	return string(context.currentChar), nil
 }
 
 func getInt(params []string, dflt int) int {
-	i := getInts(params, 1, dflt)[0]
-	logger.Infof("getInt: %v", i)
-	return i
+	return getInts(params, 1, dflt)[0]
 }
 
 func getInts(params []string, minCount int, dflt int) []int {
@@ -56,22 +77,65 @@ func getInts(params []string, minCount int, dflt int) []int {
 		}
 	}
 
-	logger.Infof("getInts: %v", ints)
-
 	return ints
 }
 
 func (ap *AnsiParser) hDispatch(params []string) error {
-	if len(params) == 1 && params[0] == "?25" {
-		return ap.eventHandler.DECTCEM(true)
-	}
-
-	return nil
+	return ap.modeDispatch(params, true)
 }
 
 func (ap *AnsiParser) lDispatch(params []string) error {
-	if len(params) == 1 && params[0] == "?25" {
-		return ap.eventHandler.DECTCEM(false)
+	return ap.modeDispatch(params, false)
+}
+
+// modeDispatch handles Set Mode (SM) and Reset Mode (RM). A leading '?'
+// selects DEC private modes and applies to every parameter.
+func (ap *AnsiParser) modeDispatch(params []string, set bool) error {
+	private := false
+	if len(params) > 0 && strings.HasPrefix(params[0], "?") {
+		private = true
+		params[0] = params[0][1:]
+	}
+
+	for _, param := range params {
+		var err error
+
+		switch {
+		case private && param == "1":
+			err = ap.eventHandler.DECCKM(set)
+		case private && param == "6":
+			err = ap.eventHandler.DECOM(set)
+		case private && param == "7":
+			err = ap.eventHandler.DECAWM(set)
+		case private && param == "25":
+			err = ap.eventHandler.DECTCEM(set)
+		case private && param == "69":
+			err = ap.eventHandler.DECLRMM(set)
+		case private && param == "1004":
+			err = ap.eventHandler.FocusReporting(set)
+		case private && (param == "47" || param == "1047" || param == "1049"):
+			mode, _ := strconv.Atoi(param)
+			err = ap.eventHandler.AlternateScreen(mode, set)
+		case private && param == "1048" && set:
+			err = ap.eventHandler.DECSC()
+		case private && param == "1048":
+			err = ap.eventHandler.DECRC()
+		case private && (param == "1000" || param == "1002" || param == "1003" || param == "1006"):
+			mode, _ := strconv.Atoi(param)
+			err = ap.eventHandler.MouseMode(mode, set)
+		case private && param == "2004":
+			err = ap.eventHandler.BracketedPaste(set)
+		case private && param == "9001":
+			err = ap.eventHandler.Win32InputMode(set)
+		case private && param == "2026":
+			err = ap.eventHandler.SynchronizedUpdate(set)
+		case !private && param == "4":
+			err = ap.eventHandler.IRM(set)
+		}
+
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -85,3 +149,9 @@ func getEraseParam(params []string) int {
 
 	return param
 }
+
+// isWindowReport reports whether an XTWINOPS operation asks the terminal to
+// report state rather than change it.
+func isWindowReport(op int) bool {
+	return 11 <= op && op <= 21
+}
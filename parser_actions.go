@@ -1,34 +1,73 @@
 package ansiterm
 
 import (
-	"fmt"
+	"strconv"
+	"strings"
 )
 
 func (ap *AnsiParser) collectParam() error {
 	currChar := ap.context.currentChar
-	logger.Infof("collectParam %#x", currChar)
+	if ap.trace {
+		ap.logger.Infof("collectParam %#x", currChar)
+	}
 	ap.context.paramBuffer = append(ap.context.paramBuffer, currChar)
 	return nil
 }
 
 func (ap *AnsiParser) collectInter() error {
 	currChar := ap.context.currentChar
-	logger.Infof("collectInter %#x", currChar)
-	ap.context.paramBuffer = append(ap.context.interBuffer, currChar)
+	if ap.trace {
+		ap.logger.Infof("collectInter %#x", currChar)
+	}
+	ap.context.interBuffer = append(ap.context.interBuffer, currChar)
 	return nil
 }
 
 func (ap *AnsiParser) escDispatch() error {
 	cmd, _ := parseCmd(*ap.context)
 	intermeds := ap.context.interBuffer
-	logger.Infof("escDispatch currentChar: %#x", ap.context.currentChar)
-	logger.Infof("escDispatch: %v(%v)", cmd, intermeds)
+	if ap.trace {
+		ap.logger.Infof("escDispatch currentChar: %#x", ap.context.currentChar)
+		ap.logger.Infof("escDispatch: %v(%v)", cmd, intermeds)
+	}
+
+	if len(intermeds) == 1 {
+		switch intermeds[0] {
+		case ANSI_CMD_G0, ANSI_CMD_G1, ANSI_CMD_G2, ANSI_CMD_G3:
+			return ap.eventHandler.SCS(int(intermeds[0]-ANSI_CMD_G0), ap.context.currentChar)
+		case ANSI_CMD_DEC_LINE:
+			if cmd == "8" {
+				return ap.eventHandler.DECALN()
+			}
+		}
+	}
+
+	if len(intermeds) > 0 {
+		ap.stats.unsupportedSequence()
+		return nil
+	}
 
 	switch cmd {
+	case "7":
+		return ap.eventHandler.DECSC()
+	case "8":
+		return ap.eventHandler.DECRC()
+	case "=":
+		return ap.eventHandler.DECKPAM(true)
+	case ">":
+		return ap.eventHandler.DECKPAM(false)
+	case "H":
+		return ap.eventHandler.HTS()
 	case "M":
 		return ap.eventHandler.RI()
+	case "c":
+		return ap.eventHandler.RIS()
+	case "\\":
+		// ST, ending the string it follows
+		return nil
 	}
 
+	ap.stats.unsupportedSequence()
 	return nil
 }
 
@@ -36,7 +75,13 @@ func (ap *AnsiParser) csiDispatch() error {
 	cmd, _ := parseCmd(*ap.context)
 	params, _ := parseParams(ap.context.paramBuffer)
 
-	logger.Infof("csiDispatch: %v(%v)", cmd, params)
+	if ap.trace {
+		ap.logger.Infof("csiDispatch: %v(%v)", cmd, params)
+	}
+
+	if len(ap.context.interBuffer) > 0 {
+		return ap.csiIntermediateDispatch(cmd, string(ap.context.interBuffer), params)
+	}
 
 	switch cmd {
 	case "A":
@@ -57,6 +102,8 @@ func (ap *AnsiParser) csiDispatch() error {
 		ints := getInts(params, 2, 1)
 		x, y := ints[0], ints[1]
 		return ap.eventHandler.CUP(x, y)
+	case "I":
+		return ap.eventHandler.CHT(getInt(params, 1))
 	case "J":
 		param := getEraseParam(params)
 		return ap.eventHandler.ED(param)
@@ -71,41 +118,168 @@ func (ap *AnsiParser) csiDispatch() error {
 		return ap.eventHandler.SU(getInt(params, 1))
 	case "T":
 		return ap.eventHandler.SD(getInt(params, 1))
+	case "Z":
+		return ap.eventHandler.CBT(getInt(params, 1))
+	case "b":
+		return ap.eventHandler.REP(getInt(params, 1))
 	case "c":
+		if ap.noQueries {
+			return nil
+		}
 		return ap.eventHandler.DA(params)
+	case "d":
+		return ap.eventHandler.VPA(getInt(params, 1))
 	case "f":
 		ints := getInts(params, 2, 1)
 		x, y := ints[0], ints[1]
 		return ap.eventHandler.HVP(x, y)
+	case "g":
+		return ap.eventHandler.TBC(getInt(params, 0))
 	case "h":
 		return ap.hDispatch(params)
 	case "l":
 		return ap.lDispatch(params)
 	case "m":
+		if len(params) > 0 && strings.HasPrefix(params[0], ">") {
+			params[0] = params[0][1:]
+			return ap.eventHandler.XTMODKEYS(getInts(params, 2, 0))
+		}
 		return ap.eventHandler.SGR(getInts(params, 1, 0))
+	case "n":
+		if ap.noQueries {
+			return nil
+		}
+		return ap.eventHandler.DSR(getInt(params, 0))
 	case "r":
-		ints := getInts(params, 2, 1)
+		ints := getInts(params, 2, 0)
 		top, bottom := ints[0], ints[1]
 		return ap.eventHandler.DECSTBM(top, bottom)
+	case "s":
+		// Without parameters, CSI s is SCOSC, saving the cursor as DECSC
+		// does
+		if len(params) == 0 {
+			return ap.eventHandler.DECSC()
+		}
+		ints := getInts(params, 2, 0)
+		left, right := ints[0], ints[1]
+		return ap.eventHandler.DECSLRM(left, right)
+	case "u":
+		// Likewise CSI u is SCORC, restoring it
+		if len(params) == 0 {
+			return ap.eventHandler.DECRC()
+		}
+		if params[0] == "" || !strings.ContainsRune("<=>?", rune(params[0][0])) {
+			ap.stats.unsupportedSequence()
+			ap.logger.Errorf("Unsupported CSI command: '%s', with full context:  %v", cmd, ap.context)
+			return nil
+		}
+		op := params[0][0]
+		if op == '?' && ap.noQueries {
+			return nil
+		}
+		params[0] = params[0][1:]
+		return ap.eventHandler.KittyKeyboard(op, getInts(params, 2, 0))
+	case "t":
+		ints := getInts(params, 3, 0)
+		if ap.noQueries && isWindowReport(ints[0]) {
+			return nil
+		}
+		return ap.eventHandler.XTWINOPS(ints)
 	default:
-		logger.Errorf(fmt.Sprintf("Unsupported CSI command: '%s', with full context:  %v", cmd, ap.context))
+		ap.stats.unsupportedSequence()
+		ap.logger.Errorf("Unsupported CSI command: '%s', with full context:  %v", cmd, ap.context)
 		return nil
 	}
 
 }
 
+// csiIntermediateDispatch handles control sequences whose final character is
+// preceded by intermediate characters.
+func (ap *AnsiParser) csiIntermediateDispatch(cmd string, intermeds string, params []string) error {
+	switch intermeds + cmd {
+	case " q":
+		return ap.eventHandler.DECSCUSR(getInt(params, 0))
+	case "!p":
+		return ap.eventHandler.DECSTR()
+	case "#{", "#p":
+		return ap.eventHandler.XTPUSHSGR(getInts(params, 0, 0))
+	case "#}", "#q":
+		return ap.eventHandler.XTPOPSGR()
+	default:
+		ap.stats.unsupportedSequence()
+		ap.logger.Errorf("Unsupported CSI command: '%s%s', with full context:  %v", intermeds, cmd, ap.context)
+		return nil
+	}
+}
+
+func (ap *AnsiParser) dcsDispatch() error {
+	params, intermeds, final, data := parseDcs(ap.context.dcsBuffer)
+	if ap.trace {
+		ap.logger.Infof("dcsDispatch: %c(%v, %v) %q", final, params, intermeds, data)
+	}
+
+	if intermeds == "$" && final == 'q' {
+		if ap.noQueries {
+			return nil
+		}
+		return ap.eventHandler.DECRQSS(data)
+	}
+
+	if intermeds == "+" && final == 'q' {
+		if ap.noQueries {
+			return nil
+		}
+		return ap.eventHandler.XTGETTCAP(parseTermcapNames(data))
+	}
+
+	ap.stats.unsupportedSequence()
+	return nil
+}
+
+// oscDispatch passes an operating system command, Ps ; Pt, to the handler.
+// Commands whose Ps is not a number are ignored.
+func (ap *AnsiParser) oscDispatch() error {
+	data := string(ap.context.oscBuffer)
+	if ap.trace {
+		ap.logger.Infof("oscDispatch: %q", data)
+	}
+
+	ps, pt := data, ""
+	if i := strings.IndexByte(data, ';'); i >= 0 {
+		ps, pt = data[:i], data[i+1:]
+	}
+
+	command, err := strconv.Atoi(ps)
+	if err != nil {
+		return nil
+	}
+
+	return ap.eventHandler.OSC(command, pt)
+}
+
 func (ap *AnsiParser) print() error {
-	logger.Infof("AnsiParser::print %#x", ap.context.currentChar)
+	if ap.trace {
+		ap.logger.Infof("AnsiParser::print %#x", ap.context.currentChar)
+	}
 	return ap.eventHandler.Print(ap.context.currentChar)
 }
 
+// clear empties the context for a new sequence, keeping the capacity of its
+// buffers.
 func (ap *AnsiParser) clear() error {
-	ap.context = &AnsiContext{}
+	*ap.context = AnsiContext{
+		paramBuffer: ap.context.paramBuffer[:0],
+		interBuffer: ap.context.interBuffer[:0],
+		dcsBuffer:   ap.context.dcsBuffer[:0],
+		oscBuffer:   ap.context.oscBuffer[:0],
+	}
 	return nil
 }
 
 func (ap *AnsiParser) execute() error {
-	logger.Infof("AnsiParser::execute %#x", ap.context.currentChar)
+	if ap.trace {
+		ap.logger.Infof("AnsiParser::execute %#x", ap.context.currentChar)
+	}
 
 	return ap.eventHandler.Execute(ap.context.currentChar)
 
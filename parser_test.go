@@ -10,13 +10,14 @@ func TestStateTransitions(t *testing.T) {
 	stateTransitionHelper(t, "CsiEntry", "CsiParam", CsiCollectables)
 	stateTransitionHelper(t, "Escape", "CsiEntry", []byte{ANSI_ESCAPE_SECONDARY})
 	stateTransitionHelper(t, "Escape", "OscString", []byte{0x5D})
+	stateTransitionHelper(t, "Escape", "DcsEntry", []byte{ANSI_DCS_ENTRY})
 	stateTransitionHelper(t, "Escape", "Ground", EscapeToGroundBytes)
 	stateTransitionHelper(t, "Escape", "EscapeIntermediate", Intermeds)
 	stateTransitionHelper(t, "EscapeIntermediate", "EscapeIntermediate", Intermeds)
 	stateTransitionHelper(t, "EscapeIntermediate", "EscapeIntermediate", Executors)
 	stateTransitionHelper(t, "EscapeIntermediate", "Ground", EscapeIntermediateToGroundBytes)
 	stateTransitionHelper(t, "OscString", "Ground", []byte{ANSI_BEL})
-	stateTransitionHelper(t, "OscString", "Ground", []byte{0x5C})
+	stateTransitionHelper(t, "OscString", "OscString", []byte{0x5C})
 	stateTransitionHelper(t, "Ground", "Ground", Executors)
 }
 
@@ -77,10 +78,43 @@ func TestCursor(t *testing.T) {
 	cursorSingleParamHelper(t, 'E', "CNL")
 	cursorSingleParamHelper(t, 'F', "CPL")
 	cursorSingleParamHelper(t, 'G', "CHA")
+	cursorSingleParamHelper(t, 'd', "VPA")
+	cursorSingleParamHelper(t, 'I', "CHT")
+	cursorSingleParamHelper(t, 'Z', "CBT")
+	funcCallParamHelper(t, []byte{'g'}, "CsiEntry", "Ground", []string{"TBC([0])"})
+	funcCallParamHelper(t, []byte{'3', 'g'}, "CsiEntry", "Ground", []string{"TBC([3])"})
 	cursorTwoParamHelper(t, 'H', "CUP")
 	cursorTwoParamHelper(t, 'f', "HVP")
 	funcCallParamHelper(t, []byte{'?', '2', '5', 'h'}, "CsiEntry", "Ground", []string{"DECTCEM([true])"})
 	funcCallParamHelper(t, []byte{'?', '2', '5', 'l'}, "CsiEntry", "Ground", []string{"DECTCEM([false])"})
+	funcCallParamHelper(t, []byte{' ', 'q'}, "CsiEntry", "Ground", []string{"DECSCUSR([0])"})
+	funcCallParamHelper(t, []byte{'5', ' ', 'q'}, "CsiEntry", "Ground", []string{"DECSCUSR([5])"})
+	funcCallParamHelper(t, []byte{'5', ' ', '!', 'q'}, "CsiEntry", "Ground", []string{})
+	funcCallParamHelper(t, []byte{'!', 'p'}, "CsiEntry", "Ground", []string{"DECSTR([])"})
+}
+
+func TestModes(t *testing.T) {
+	funcCallParamHelper(t, []byte{'4', 'h'}, "CsiEntry", "Ground", []string{"IRM([true])"})
+	funcCallParamHelper(t, []byte{'4', 'l'}, "CsiEntry", "Ground", []string{"IRM([false])"})
+	funcCallParamHelper(t, []byte{'?', '4', 'h'}, "CsiEntry", "Ground", []string{})
+	funcCallParamHelper(t, []byte{'?', '1', 'h'}, "CsiEntry", "Ground", []string{"DECCKM([true])"})
+	funcCallParamHelper(t, []byte{'?', '1', 'l'}, "CsiEntry", "Ground", []string{"DECCKM([false])"})
+	funcCallParamHelper(t, []byte{'?', '6', 'h'}, "CsiEntry", "Ground", []string{"DECOM([true])"})
+	funcCallParamHelper(t, []byte{'?', '6', 'l'}, "CsiEntry", "Ground", []string{"DECOM([false])"})
+	funcCallParamHelper(t, []byte{'?', '7', ';', '6', 'l'}, "CsiEntry", "Ground", []string{"DECAWM([false])", "DECOM([false])"})
+	funcCallParamHelper(t, []byte{'?', '2', '5', ';', '4', 'l'}, "CsiEntry", "Ground", []string{"DECTCEM([false])"})
+	funcCallParamHelper(t, []byte{'4', ';', '2', '5', 'h'}, "CsiEntry", "Ground", []string{"IRM([true])"})
+	funcCallParamHelper(t, []byte{'?', '1', '0', '0', '2', ';', '1', '0', '0', '6', 'h'}, "CsiEntry", "Ground", []string{"MouseMode([1002 true])", "MouseMode([1006 true])"})
+	funcCallParamHelper(t, []byte{'?', '1', '0', '0', '0', 'l'}, "CsiEntry", "Ground", []string{"MouseMode([1000 false])"})
+	funcCallParamHelper(t, []byte{'?', '1', '0', '0', '4', 'h'}, "CsiEntry", "Ground", []string{"FocusReporting([true])"})
+	funcCallParamHelper(t, []byte{'?', '2', '0', '0', '4', 'h'}, "CsiEntry", "Ground", []string{"BracketedPaste([true])"})
+	funcCallParamHelper(t, []byte{'?', '1', '0', '4', '9', 'h'}, "CsiEntry", "Ground", []string{"AlternateScreen([1049 true])"})
+	funcCallParamHelper(t, []byte{'?', '4', '7', ';', '1', '0', '4', '7', 'l'}, "CsiEntry", "Ground", []string{"AlternateScreen([47 false])", "AlternateScreen([1047 false])"})
+	funcCallParamHelper(t, []byte{'?', '1', '0', '4', '8', 'h'}, "CsiEntry", "Ground", []string{"DECSC([])"})
+	funcCallParamHelper(t, []byte{'?', '1', '0', '4', '8', 'l'}, "CsiEntry", "Ground", []string{"DECRC([])"})
+	funcCallParamHelper(t, []byte{'?', '9', '0', '0', '1', 'h'}, "CsiEntry", "Ground", []string{"Win32InputMode([true])"})
+	funcCallParamHelper(t, []byte{'?', '2', '0', '2', '6', 'h'}, "CsiEntry", "Ground", []string{"SynchronizedUpdate([true])"})
+	funcCallParamHelper(t, []byte{'?', '2', '0', '2', '6', 'l'}, "CsiEntry", "Ground", []string{"SynchronizedUpdate([false])"})
 }
 
 func TestErase(t *testing.T) {
@@ -98,6 +132,11 @@ func TestSelectGraphicRendition(t *testing.T) {
 	funcCallParamHelper(t, []byte{'0', ';', '1', ';', '2', 'm'}, "CsiEntry", "Ground", []string{"SGR([0 1 2])"})
 }
 
+func TestRepeat(t *testing.T) {
+	funcCallParamHelper(t, []byte{'b'}, "CsiEntry", "Ground", []string{"REP([1])"})
+	funcCallParamHelper(t, []byte{'8', '0', 'b'}, "CsiEntry", "Ground", []string{"REP([80])"})
+}
+
 func TestScroll(t *testing.T) {
 	scrollHelper(t, 'S', "SU")
 	scrollHelper(t, 'T', "SD")
@@ -138,4 +177,89 @@ func TestC0(t *testing.T) {
 
 func TestEscDispatch(t *testing.T) {
 	funcCallParamHelper(t, []byte{'M'}, "Escape", "Ground", []string{"RI([])"})
+	funcCallParamHelper(t, []byte{'c'}, "Escape", "Ground", []string{"RIS([])"})
+	funcCallParamHelper(t, []byte{'7'}, "Escape", "Ground", []string{"DECSC([])"})
+	funcCallParamHelper(t, []byte{'8'}, "Escape", "Ground", []string{"DECRC([])"})
+	funcCallParamHelper(t, []byte{'H'}, "Escape", "Ground", []string{"HTS([])"})
+	funcCallParamHelper(t, []byte{'='}, "Escape", "Ground", []string{"DECKPAM([true])"})
+	funcCallParamHelper(t, []byte{'>'}, "Escape", "Ground", []string{"DECKPAM([false])"})
+	funcCallParamHelper(t, []byte{'#', '8'}, "Escape", "Ground", []string{"DECALN([])"})
+	funcCallParamHelper(t, []byte{'#', '3'}, "Escape", "Ground", []string{})
+	funcCallParamHelper(t, []byte{'(', '0'}, "Escape", "Ground", []string{"SCS([0 0])"})
+	funcCallParamHelper(t, []byte{')', 'B'}, "Escape", "Ground", []string{"SCS([1 B])"})
+}
+
+func TestUTF8Print(t *testing.T) {
+	evtHandler := CreateTestAnsiEventHandler()
+	parser := CreateParser("Ground", evtHandler, WithUTF8())
+	bytes := []byte("\u00e9\u2500")
+	parser.Parse(bytes)
+	validateState(t, parser.currState, "Ground")
+
+	expectedCalls := []string{}
+	for _, b := range bytes {
+		expectedCalls = append(expectedCalls, fmt.Sprintf("Print([%s])", string(b)))
+	}
+	validateFuncCalls(t, evtHandler.FunctionCalls, expectedCalls)
+}
+
+func TestMargins(t *testing.T) {
+	funcCallParamHelper(t, []byte{'r'}, "CsiEntry", "Ground", []string{"DECSTBM([0 0])"})
+	funcCallParamHelper(t, []byte{'0', ';', '0', 'r'}, "CsiEntry", "Ground", []string{"DECSTBM([0 0])"})
+	funcCallParamHelper(t, []byte{'2', ';', '1', '0', 'r'}, "CsiEntry", "Ground", []string{"DECSTBM([2 10])"})
+	funcCallParamHelper(t, []byte{'s'}, "CsiEntry", "Ground", []string{"DECSC([])"})
+	funcCallParamHelper(t, []byte{'u'}, "CsiEntry", "Ground", []string{"DECRC([])"})
+	funcCallParamHelper(t, []byte{'0', ';', '0', 's'}, "CsiEntry", "Ground", []string{"DECSLRM([0 0])"})
+	funcCallParamHelper(t, []byte{'5', ';', '4', '0', 's'}, "CsiEntry", "Ground", []string{"DECSLRM([5 40])"})
+	funcCallParamHelper(t, []byte{'?', '6', '9', 'h'}, "CsiEntry", "Ground", []string{"DECLRMM([true])"})
+}
+
+func TestWindowOps(t *testing.T) {
+	funcCallParamHelper(t, []byte{'1', '8', 't'}, "CsiEntry", "Ground", []string{"XTWINOPS([18 0 0])"})
+	funcCallParamHelper(t, []byte{'8', ';', '2', '4', ';', '8', '0', 't'}, "CsiEntry", "Ground", []string{"XTWINOPS([8 24 80])"})
+}
+
+func TestSGRStack(t *testing.T) {
+	funcCallParamHelper(t, []byte{'#', '{'}, "CsiEntry", "Ground", []string{"XTPUSHSGR([])"})
+	funcCallParamHelper(t, []byte{'1', '0', ';', '1', '1', '#', 'p'}, "CsiEntry", "Ground", []string{"XTPUSHSGR([10 11])"})
+	funcCallParamHelper(t, []byte{'#', '}'}, "CsiEntry", "Ground", []string{"XTPOPSGR([])"})
+	funcCallParamHelper(t, []byte{'#', 'q'}, "CsiEntry", "Ground", []string{"XTPOPSGR([])"})
+}
+
+func TestKeyboardProtocols(t *testing.T) {
+	funcCallParamHelper(t, []byte{'>', '4', ';', '2', 'm'}, "CsiEntry", "Ground", []string{"XTMODKEYS([4 2])"})
+	funcCallParamHelper(t, []byte{'>', '4', 'm'}, "CsiEntry", "Ground", []string{"XTMODKEYS([4 0])"})
+	funcCallParamHelper(t, []byte{'>', '1', 'u'}, "CsiEntry", "Ground", []string{"KittyKeyboard([> 1 0])"})
+	funcCallParamHelper(t, []byte{'<', 'u'}, "CsiEntry", "Ground", []string{"KittyKeyboard([< 0 0])"})
+	funcCallParamHelper(t, []byte{'=', '5', ';', '2', 'u'}, "CsiEntry", "Ground", []string{"KittyKeyboard([= 5 2])"})
+	funcCallParamHelper(t, []byte{'?', 'u'}, "CsiEntry", "Ground", []string{"KittyKeyboard([? 0 0])"})
+	funcCallParamHelper(t, []byte{'1', 'u'}, "CsiEntry", "Ground", []string{})
+}
+
+func TestDeviceStatusReport(t *testing.T) {
+	funcCallParamHelper(t, []byte{'5', 'n'}, "CsiEntry", "Ground", []string{"DSR([5])"})
+	funcCallParamHelper(t, []byte{'6', 'n'}, "CsiEntry", "Ground", []string{"DSR([6])"})
+}
+
+func TestWithoutQueries(t *testing.T) {
+	evtHandler := CreateTestAnsiEventHandler()
+	parser := CreateParser("Ground", evtHandler, WithoutQueries())
+	parser.Parse([]byte("\x1b[c\x1b[6n\x1b[18t\x90$qr\x1b\\\x1bP+q436F\x1b\\\x1b[8;24;80t"))
+	validateState(t, parser.currState, "Ground")
+	validateFuncCalls(t, evtHandler.FunctionCalls, []string{"XTWINOPS([8 24 80])"})
+}
+
+func TestDcsDispatch(t *testing.T) {
+	funcCallParamHelper(t, []byte{'$', 'q', 'r', ANSI_ESCAPE_PRIMARY, '\\'}, "DcsEntry", "Ground", []string{"DECRQSS([r])"})
+	funcCallParamHelper(t, []byte{'$', 'q', '"', 'p', ANSI_ESCAPE_PRIMARY, '\\'}, "DcsEntry", "Ground", []string{"DECRQSS([\"p])"})
+	funcCallParamHelper(t, []byte{'1', '$', 'r', ANSI_ESCAPE_PRIMARY}, "DcsEntry", "Escape", []string{})
+	funcCallParamHelper(t, []byte("+q544E;636f6c6f7273;zz\x1b\\"), "DcsEntry", "Ground", []string{"XTGETTCAP([TN colors ])"})
+}
+
+func TestOscDispatch(t *testing.T) {
+	funcCallParamHelper(t, []byte{'0', ';', 't', 'i', 't', 'l', 'e', ANSI_BEL}, "OscString", "Ground", []string{"OSC([0 title])"})
+	funcCallParamHelper(t, []byte{'8', ';', ';', 'u', 'r', 'i', ANSI_ESCAPE_PRIMARY}, "OscString", "Escape", []string{"OSC([8 ;uri])"})
+	funcCallParamHelper(t, []byte{'2', ANSI_BEL}, "OscString", "Ground", []string{"OSC([2 ])"})
+	funcCallParamHelper(t, []byte("0;C:\\Windows\\System32\x07"), "OscString", "Ground", []string{"OSC([0 C:\\Windows\\System32])"})
+	funcCallParamHelper(t, []byte{'x', ';', 'y', ANSI_BEL}, "OscString", "Ground", []string{})
 }
@@ -0,0 +1,328 @@
+package ansiterm
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// PassthroughHandler is an AnsiEventHandler that writes the events it
+// receives back out as ANSI, for terminals that interpret it natively, such
+// as a Unix tty. Code parsing output with it runs the same on every
+// platform as code using a platform's handler, and the parser's options, such
+// as WithoutQueries, filter what reaches the terminal. Sequences are written
+// in a canonical form, which may differ in the spelling of default
+// parameters from the output parsed.
+type PassthroughHandler struct {
+	out    io.Writer
+	buffer bytes.Buffer
+}
+
+// NewPassthroughHandler creates a PassthroughHandler writing to out. Output
+// is buffered until Flush, which the parser calls at the end of each Parse.
+func NewPassthroughHandler(out io.Writer) *PassthroughHandler {
+	return &PassthroughHandler{out: out}
+}
+
+// csi writes a control sequence with integer parameters, omitting trailing
+// zero (default) parameters.
+func (h *PassthroughHandler) csi(final string, params ...int) error {
+	h.buffer.WriteString("\x1b[" + joinInts(trimDefaults(params)) + final)
+	return nil
+}
+
+// mode writes a DEC private mode set or reset.
+func (h *PassthroughHandler) mode(param int, set bool) error {
+	final := "l"
+	if set {
+		final = "h"
+	}
+
+	h.buffer.WriteString("\x1b[?" + strconv.Itoa(param) + final)
+	return nil
+}
+
+// esc writes an escape sequence.
+func (h *PassthroughHandler) esc(sequence string) error {
+	h.buffer.WriteString("\x1b" + sequence)
+	return nil
+}
+
+// trimDefaults returns params without trailing zero (default) parameters.
+func trimDefaults(params []int) []int {
+	for len(params) > 0 && params[len(params)-1] == 0 {
+		params = params[:len(params)-1]
+	}
+	return params
+}
+
+func joinInts(ints []int) string {
+	params := make([]string, len(ints))
+	for i, n := range ints {
+		params[i] = strconv.Itoa(n)
+	}
+	return strings.Join(params, ";")
+}
+
+func (h *PassthroughHandler) Print(b byte) error {
+	return h.buffer.WriteByte(b)
+}
+
+func (h *PassthroughHandler) Execute(b byte) error {
+	return h.buffer.WriteByte(b)
+}
+
+func (h *PassthroughHandler) CUU(param int) error {
+	return h.csi("A", param)
+}
+
+func (h *PassthroughHandler) CUD(param int) error {
+	return h.csi("B", param)
+}
+
+func (h *PassthroughHandler) CUF(param int) error {
+	return h.csi("C", param)
+}
+
+func (h *PassthroughHandler) CUB(param int) error {
+	return h.csi("D", param)
+}
+
+func (h *PassthroughHandler) CNL(param int) error {
+	return h.csi("E", param)
+}
+
+func (h *PassthroughHandler) CPL(param int) error {
+	return h.csi("F", param)
+}
+
+func (h *PassthroughHandler) CHA(param int) error {
+	return h.csi("G", param)
+}
+
+func (h *PassthroughHandler) CUP(row int, col int) error {
+	return h.csi("H", row, col)
+}
+
+func (h *PassthroughHandler) HVP(row int, col int) error {
+	return h.csi("f", row, col)
+}
+
+func (h *PassthroughHandler) VPA(param int) error {
+	return h.csi("d", param)
+}
+
+func (h *PassthroughHandler) CHT(param int) error {
+	return h.csi("I", param)
+}
+
+func (h *PassthroughHandler) CBT(param int) error {
+	return h.csi("Z", param)
+}
+
+func (h *PassthroughHandler) HTS() error {
+	return h.esc("H")
+}
+
+func (h *PassthroughHandler) TBC(param int) error {
+	return h.csi("g", param)
+}
+
+func (h *PassthroughHandler) DECTCEM(visible bool) error {
+	return h.mode(25, visible)
+}
+
+func (h *PassthroughHandler) DECSCUSR(param int) error {
+	return h.csi(" q", param)
+}
+
+func (h *PassthroughHandler) SynchronizedUpdate(enable bool) error {
+	return h.mode(2026, enable)
+}
+
+func (h *PassthroughHandler) IRM(enable bool) error {
+	if enable {
+		return h.csi("h", 4)
+	}
+	return h.csi("l", 4)
+}
+
+func (h *PassthroughHandler) DECOM(enable bool) error {
+	return h.mode(6, enable)
+}
+
+func (h *PassthroughHandler) DECAWM(enable bool) error {
+	return h.mode(7, enable)
+}
+
+func (h *PassthroughHandler) ED(param int) error {
+	return h.csi("J", param)
+}
+
+func (h *PassthroughHandler) EL(param int) error {
+	return h.csi("K", param)
+}
+
+func (h *PassthroughHandler) IL(param int) error {
+	return h.csi("L", param)
+}
+
+func (h *PassthroughHandler) DL(param int) error {
+	return h.csi("M", param)
+}
+
+// SGR writes every parameter, since a zero within extended colors is
+// significant.
+func (h *PassthroughHandler) SGR(params []int) error {
+	if len(params) == 1 && params[0] == 0 {
+		params = nil
+	}
+
+	h.buffer.WriteString("\x1b[" + joinInts(params) + "m")
+	return nil
+}
+
+func (h *PassthroughHandler) REP(param int) error {
+	return h.csi("b", param)
+}
+
+func (h *PassthroughHandler) SU(param int) error {
+	return h.csi("S", param)
+}
+
+func (h *PassthroughHandler) SD(param int) error {
+	return h.csi("T", param)
+}
+
+func (h *PassthroughHandler) DA(params []string) error {
+	h.buffer.WriteString("\x1b[" + strings.Join(params, ";") + "c")
+	return nil
+}
+
+func (h *PassthroughHandler) DSR(param int) error {
+	return h.csi("n", param)
+}
+
+func (h *PassthroughHandler) DECCKM(enable bool) error {
+	return h.mode(1, enable)
+}
+
+func (h *PassthroughHandler) DECKPAM(enable bool) error {
+	if enable {
+		return h.esc("=")
+	}
+	return h.esc(">")
+}
+
+func (h *PassthroughHandler) MouseMode(mode int, enable bool) error {
+	return h.mode(mode, enable)
+}
+
+func (h *PassthroughHandler) BracketedPaste(enable bool) error {
+	return h.mode(2004, enable)
+}
+
+func (h *PassthroughHandler) Win32InputMode(enable bool) error {
+	return h.mode(9001, enable)
+}
+
+func (h *PassthroughHandler) FocusReporting(enable bool) error {
+	return h.mode(1004, enable)
+}
+
+func (h *PassthroughHandler) AlternateScreen(mode int, enable bool) error {
+	return h.mode(mode, enable)
+}
+
+func (h *PassthroughHandler) XTMODKEYS(params []int) error {
+	h.buffer.WriteString("\x1b[>" + joinInts(trimDefaults(params)) + "m")
+	return nil
+}
+
+func (h *PassthroughHandler) KittyKeyboard(op byte, params []int) error {
+	h.buffer.WriteString("\x1b[" + string(op) + joinInts(trimDefaults(params)) + "u")
+	return nil
+}
+
+func (h *PassthroughHandler) DECSTBM(top int, bottom int) error {
+	return h.csi("r", top, bottom)
+}
+
+func (h *PassthroughHandler) DECLRMM(enable bool) error {
+	return h.mode(69, enable)
+}
+
+func (h *PassthroughHandler) DECSLRM(left int, right int) error {
+	return h.csi("s", left, right)
+}
+
+func (h *PassthroughHandler) DECRQSS(setting string) error {
+	h.buffer.WriteString("\x1bP$q" + setting + "\x1b\\")
+	return nil
+}
+
+func (h *PassthroughHandler) XTGETTCAP(names []string) error {
+	encoded := make([]string, len(names))
+	for i, name := range names {
+		encoded[i] = hexString(name)
+	}
+	h.buffer.WriteString("\x1bP+q" + strings.Join(encoded, ";") + "\x1b\\")
+	return nil
+}
+
+func (h *PassthroughHandler) XTWINOPS(params []int) error {
+	return h.csi("t", params...)
+}
+
+func (h *PassthroughHandler) RI() error {
+	return h.esc("M")
+}
+
+func (h *PassthroughHandler) RIS() error {
+	return h.esc("c")
+}
+
+func (h *PassthroughHandler) DECSTR() error {
+	return h.csi("!p")
+}
+
+func (h *PassthroughHandler) XTPUSHSGR(params []int) error {
+	h.buffer.WriteString("\x1b[" + joinInts(params) + "#{")
+	return nil
+}
+
+func (h *PassthroughHandler) XTPOPSGR() error {
+	return h.csi("#}")
+}
+
+func (h *PassthroughHandler) DECSC() error {
+	return h.esc("7")
+}
+
+func (h *PassthroughHandler) DECRC() error {
+	return h.esc("8")
+}
+
+func (h *PassthroughHandler) DECALN() error {
+	return h.esc("#8")
+}
+
+func (h *PassthroughHandler) SCS(g int, charset byte) error {
+	return h.esc(string(rune(ANSI_CMD_G0+g)) + string(charset))
+}
+
+func (h *PassthroughHandler) OSC(command int, text string) error {
+	return h.esc("]" + strconv.Itoa(command) + ";" + text + "\x1b\\")
+}
+
+// Flush writes the buffered output.
+func (h *PassthroughHandler) Flush() error {
+	if h.buffer.Len() == 0 {
+		return nil
+	}
+
+	_, err := h.out.Write(h.buffer.Bytes())
+	h.buffer.Reset()
+	return err
+}
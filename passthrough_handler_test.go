@@ -0,0 +1,39 @@
+package ansiterm
+
+import (
+	"bytes"
+	"testing"
+)
+
+func passthroughHelper(t *testing.T, output string, expected string, opts ...Option) {
+	var written bytes.Buffer
+	parser := CreateParser("Ground", NewPassthroughHandler(&written), opts...)
+	parser.Parse([]byte(output))
+
+	if actual := written.String(); actual != expected {
+		t.Errorf("%q: passed through %q, expected %q", output, actual, expected)
+	}
+}
+
+func TestPassthrough(t *testing.T) {
+	sequences := []string{
+		"text\r\n\a",
+		"\x1b[1A\x1b[2B\x1b[3C\x1b[1D\x1b[1E\x1b[1F\x1b[5G\x1b[3;4H\x1b[2;2f\x1b[7d",
+		"\x1b[J\x1b[1K\x1b[2L\x1b[1M\x1b[1S\x1b[3T\x1b[1I\x1b[1Z\x1b[4b",
+		"\x1b[m\x1b[1;38;5;0;48;2;0;0;0m",
+		"\x1b[?25l\x1b[?1h\x1b[?7l\x1b[?1002h\x1b[?2004h\x1b[?9001h\x1b[4h\x1b[?2026l",
+		"\x1b[2;20r\x1b[?69h\x1b[5;40s\x1b[4 q\x1b[!p\x1b[3g\x1bH",
+		"\x1b[#{\x1b[10;11#{\x1b[#}",
+		"\x1b[>4;2m\x1b[>1u\x1b[<u\x1b[=5;2u\x1b[8;24;80t",
+		"\x1b7\x1b8\x1bM\x1b#8\x1b(0\x1b)B\x1b=\x1b>\x1bc",
+		"\x1b[c\x1b[>c\x1b[6n\x1bP$qm\x1b\\\x1bP+q544E;436F\x1b\\",
+		"\x1b]8;id=1;http://example.com\x1b\\link\x1b]8;;\x1b\\",
+	}
+
+	for _, sequence := range sequences {
+		passthroughHelper(t, sequence, sequence)
+	}
+
+	passthroughHelper(t, "\x1b[A\x1b[H\x1b[0m\x1b]0;title\x07", "\x1b[1A\x1b[1;1H\x1b[m\x1b]0;title\x1b\\")
+	passthroughHelper(t, "\x1b[c\x1b[6nkept", "kept", WithoutQueries())
+}
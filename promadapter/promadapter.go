@@ -0,0 +1,62 @@
+// Package promadapter exposes the counters of an ansiterm.Stats as
+// Prometheus metrics, for operators who monitor terminal handling with
+// Prometheus; the ansiterm packages themselves do not depend on its client.
+//
+//	stats := new(ansiterm.Stats)
+//	prometheus.MustRegister(promadapter.NewCollector(stats))
+//	parser := ansiterm.CreateParser("Ground", handler, ansiterm.WithParserStats(stats))
+//
+// The metrics are the counters ansiterm_parsed_bytes_total,
+// ansiterm_flushes_total, ansiterm_unsupported_sequences_total,
+// ansiterm_events_total, labeled by the event handler method called, and
+// ansiterm_calls_total, labeled by the system function called.
+package promadapter
+
+import (
+	"github.com/Azure/go-ansiterm"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	bytesDesc       = prometheus.NewDesc("ansiterm_parsed_bytes_total", "Bytes parsed.", nil, nil)
+	flushesDesc     = prometheus.NewDesc("ansiterm_flushes_total", "Event handler flushes, one at the end of each parse.", nil, nil)
+	unsupportedDesc = prometheus.NewDesc("ansiterm_unsupported_sequences_total", "Sequences parsed that were not supported and ignored.", nil, nil)
+	eventsDesc      = prometheus.NewDesc("ansiterm_events_total", "Events dispatched to event handlers.", []string{"event"}, nil)
+	callsDesc       = prometheus.NewDesc("ansiterm_calls_total", "Calls made by event handlers to the system.", []string{"function"}, nil)
+)
+
+// collector reports the counters of a Stats when scraped.
+type collector struct {
+	stats *ansiterm.Stats
+}
+
+// NewCollector returns a prometheus.Collector reporting the counters of
+// stats. As the metric names are fixed, a process registers one, sharing its
+// Stats between parsers.
+func NewCollector(stats *ansiterm.Stats) prometheus.Collector {
+	return collector{stats}
+}
+
+func (c collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- bytesDesc
+	ch <- flushesDesc
+	ch <- unsupportedDesc
+	ch <- eventsDesc
+	ch <- callsDesc
+}
+
+func (c collector) Collect(ch chan<- prometheus.Metric) {
+	ch <- counter(bytesDesc, c.stats.Bytes())
+	ch <- counter(flushesDesc, c.stats.Flushes())
+	ch <- counter(unsupportedDesc, c.stats.Unsupported())
+	for event, n := range c.stats.Events() {
+		ch <- counter(eventsDesc, n, event)
+	}
+	for function, n := range c.stats.Calls() {
+		ch <- counter(callsDesc, n, function)
+	}
+}
+
+func counter(desc *prometheus.Desc, n uint64, labels ...string) prometheus.Metric {
+	return prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(n), labels...)
+}
@@ -0,0 +1,106 @@
+// Package ptybridge connects a terminal session to a remote peer over a
+// byte stream, such as an ssh channel or a websocket, carrying window size
+// changes alongside the session's input and output.
+//
+// One side of a bridge is local: a program running in a pseudo console on a
+// server, or the console of a client. The other is the remote peer's stream.
+// Run copies the remote's bytes to the local side and the local side's to
+// the remote, and resizes the local side as the remote requests. A Windows
+// ssh server serves a session from a pseudo console as
+//
+//	pty, err := winterm.NewPseudoConsole(cols, rows) // From the pty-req
+//	...
+//	process, err := pty.Start("cmd.exe")
+//	...
+//	go func() {
+//		process.Wait()
+//		pty.Close()
+//	}()
+//	err = ptybridge.Run(pty, channel, ptybridge.WithResizes(sizes)) // From window-change requests
+//
+// which ServePseudoConsole does. A client, or a server on Windows versions
+// without pseudo consoles, bridges a winterm.Terminal instead, whose reads
+// are the console's input translated to VT sequences and whose writes render
+// VT output; Connect presents the process's console to a remote session this
+// way, reporting its window changes.
+package ptybridge
+
+import (
+	"fmt"
+	"io"
+)
+
+// Size is the size of a terminal window in character cells.
+type Size struct {
+	Cols int
+	Rows int
+}
+
+// Resizer is implemented by local sides that can be resized, such as
+// winterm.PseudoConsole and winterm.Terminal.
+type Resizer interface {
+	Resize(cols int, rows int) error
+}
+
+// Option configures optional behavior of Run.
+type Option func(*bridge)
+
+// WithResizes resizes the local side, which must be a Resizer, to each size
+// received from sizes, such as the window changes an ssh client requests.
+func WithResizes(sizes <-chan Size) Option {
+	return func(b *bridge) {
+		b.resizes = sizes
+	}
+}
+
+// bridge is the configuration of a call to Run.
+type bridge struct {
+	resizes <-chan Size
+}
+
+// Run copies the remote's input to local and local's output to the remote
+// until either copy ends, returning the error that ended it, or nil if it
+// reached the end of its input. The other copy may remain blocked in a read
+// until its source is closed, which is left to the caller; a pseudo console
+// whose program has exited must be closed to end its output, for instance.
+func Run(local io.ReadWriter, remote io.ReadWriter, opts ...Option) error {
+	var b bridge
+	for _, opt := range opts {
+		opt(&b)
+	}
+
+	var resizer Resizer
+	if b.resizes != nil {
+		var ok bool
+		if resizer, ok = local.(Resizer); !ok {
+			return fmt.Errorf("ptybridge: %T cannot be resized", local)
+		}
+	}
+
+	done := make(chan error, 2)
+	go func() {
+		_, err := io.Copy(local, remote)
+		done <- err
+	}()
+	go func() {
+		_, err := io.Copy(remote, local)
+		done <- err
+	}()
+
+	for {
+		select {
+		case err := <-done:
+			return err
+
+		case size, ok := <-b.resizes:
+			if !ok {
+				b.resizes = nil
+				continue
+			}
+
+			if err := resizer.Resize(size.Cols, size.Rows); err != nil {
+				return err
+			}
+		}
+	}
+}
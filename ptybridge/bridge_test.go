@@ -0,0 +1,84 @@
+package ptybridge
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testTerminal is a local side whose output is written to a pipe by the
+// test, recording its input and resizes.
+type testTerminal struct {
+	*io.PipeReader
+	input   chan []byte
+	resizes []Size
+}
+
+func (term *testTerminal) Write(p []byte) (int, error) {
+	term.input <- append([]byte(nil), p...)
+	return len(p), nil
+}
+
+func (term *testTerminal) Resize(cols int, rows int) error {
+	term.resizes = append(term.resizes, Size{Cols: cols, Rows: rows})
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	output, program := io.Pipe()
+	local := &testTerminal{PipeReader: output, input: make(chan []byte)}
+	remote, client := net.Pipe()
+	defer client.Close()
+
+	sizes := make(chan Size)
+	done := make(chan error)
+	go func() {
+		done <- Run(local, remote, WithResizes(sizes))
+	}()
+
+	if _, err := client.Write([]byte("dir\r")); err != nil {
+		t.Fatal(err)
+	}
+	if input := <-local.input; string(input) != "dir\r" {
+		t.Errorf("input is %q, expected \"dir\\r\"", input)
+	}
+
+	sizes <- Size{Cols: 100, Rows: 30}
+
+	go program.Write([]byte("\x1b[1mhello"))
+	buffer := make([]byte, 64)
+	n, err := client.Read(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buffer[:n], []byte("\x1b[1mhello")) {
+		t.Errorf("output is %q, expected \"\\x1b[1mhello\"", buffer[:n])
+	}
+
+	// The end of the output ends the session
+	program.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []Size{{Cols: 100, Rows: 30}}; !reflect.DeepEqual(local.resizes, expected) {
+		t.Errorf("resized to %v, expected %v", local.resizes, expected)
+	}
+}
+
+func TestRunNotResizer(t *testing.T) {
+	local := struct {
+		io.Reader
+		io.Writer
+	}{strings.NewReader(""), ioutil.Discard}
+	remote, client := net.Pipe()
+	defer client.Close()
+
+	if err := Run(local, remote, WithResizes(make(chan Size))); err == nil {
+		t.Error("a local side that cannot be resized was accepted")
+	}
+}
@@ -0,0 +1,94 @@
+// +build windows
+
+package ptybridge
+
+import (
+	"io"
+	"os"
+	"syscall"
+
+	"github.com/Azure/go-ansiterm/winterm"
+)
+
+// ServePseudoConsole runs cmdline, a command line quoted as CreateProcess
+// expects, in a pseudo console of the given size on behalf of remote, such as
+// the channel of an ssh session. The remote's input is delivered to the
+// program, its output is sent to the remote, and the pseudo console is
+// resized to each size received from sizes, which may be nil. Pseudo
+// consoles are available from Windows 10 1809.
+//
+// It returns the program's exit code once it has exited and its last output
+// has been sent. If the remote's input ends first, or the output cannot be
+// sent, the program is terminated.
+func ServePseudoConsole(remote io.ReadWriter, cmdline string, size Size, sizes <-chan Size) (int, error) {
+	pty, err := winterm.NewPseudoConsole(size.Cols, size.Rows)
+	if err != nil {
+		return 0, err
+	}
+
+	process, err := pty.Start(cmdline)
+	if err != nil {
+		pty.Close()
+		return 0, err
+	}
+
+	// Closing the pseudo console once the program exits flushes its last
+	// output, then ends the copy to the remote
+	exited := make(chan *os.ProcessState, 1)
+	go func() {
+		state, _ := process.Wait()
+		pty.Close()
+		exited <- state
+	}()
+
+	var opts []Option
+	if sizes != nil {
+		opts = append(opts, WithResizes(sizes))
+	}
+
+	err = Run(pty, remote, opts...)
+
+	// Ended by the remote rather than by the program
+	process.Kill()
+
+	state := <-exited
+	if state == nil {
+		return 0, err
+	}
+	return state.ExitCode(), err
+}
+
+// Connect presents the console of the process, its standard input and
+// output, as the terminal of a remote session: the remote's output is
+// rendered on the console, and the console's input is sent to the remote as
+// VT sequences, encoded in the modes the output selects. The console is
+// switched to raw mode for the session. resize, if not nil, is called with
+// the window size at the start and whenever the window is resized, to
+// forward it to the remote, such as in an ssh window-change request.
+//
+// It returns when Run does, normally once the remote's output ends. The
+// console's input is read until the process exits, so Connect suits programs
+// that exit with the session.
+func Connect(remote io.ReadWriter, resize func(Size), opts ...winterm.TerminalOption) error {
+	if resize != nil {
+		opts = append(opts, winterm.WithReaderOptions(winterm.WithWindowSizeCallback(func(cols int, rows int) {
+			resize(Size{Cols: cols, Rows: rows})
+		})))
+	}
+
+	term, err := winterm.NewTerminal(syscall.Stdin, syscall.Stdout, append([]winterm.TerminalOption{winterm.WithRawMode()}, opts...)...)
+	if err != nil {
+		return err
+	}
+	defer term.Close()
+
+	if resize != nil {
+		cols, rows, err := term.Size()
+		if err != nil {
+			return err
+		}
+		resize(Size{Cols: cols, Rows: rows})
+	}
+
+	return Run(term, remote)
+}
@@ -0,0 +1,123 @@
+// +build windows
+
+package ptybridge
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/go-ansiterm"
+	"github.com/Azure/go-ansiterm/winterm"
+	"github.com/Azure/go-ansiterm/winterm/wintermtest"
+)
+
+// consoleTerminal is a local side assembled as winterm.Terminal assembles
+// one, from a handler rendering to an in-memory console and a reader
+// replaying console input, sharing their input modes. Input is read once
+// output has been rendered, as a user types in response to it.
+type consoleTerminal struct {
+	parser   *ansiterm.AnsiParser
+	reader   *winterm.AnsiReader
+	once     sync.Once
+	rendered chan struct{} // Closed once output has been rendered
+}
+
+func (term *consoleTerminal) Write(p []byte) (int, error) {
+	n, err := term.parser.Parse(p)
+	term.once.Do(func() { close(term.rendered) })
+	return n, err
+}
+
+func (term *consoleTerminal) Read(p []byte) (int, error) {
+	<-term.rendered
+	return term.reader.Read(p)
+}
+
+// The Up key, pressed and released.
+const upTrace = `
+key 1 1 0x26 0x48 0 0
+out 0 "\x1bOA"
+key 0 1 0x26 0x48 0 0
+out 0 ""
+`
+
+func TestRunModeSharing(t *testing.T) {
+	console := wintermtest.NewConsole(80, 24)
+	handler, err := winterm.NewConsoleEventHandler(console, console.Handle(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := winterm.NewReplayReader(strings.NewReader(upTrace), winterm.WithInputModes(handler.InputModes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := &consoleTerminal{
+		parser:   ansiterm.CreateParser("Ground", handler, ansiterm.WithUTF8()),
+		reader:   reader,
+		rendered: make(chan struct{}),
+	}
+	remote, client := net.Pipe()
+	defer client.Close()
+
+	done := make(chan error)
+	go func() {
+		done <- Run(local, remote)
+	}()
+
+	// The remote application selects application cursor keys (DECCKM)
+	if _, err := client.Write([]byte("\x1b[?1hvi\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	buffer := make([]byte, 64)
+	n, err := client.Read(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buffer[:n], []byte("\x1bOA")) {
+		t.Errorf("Up is sent as %q, expected \"\\x1bOA\"", buffer[:n])
+	}
+
+	// The end of the replayed input ends the session
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if line := console.Line(console.Handle(), 0); line != "vi" {
+		t.Errorf("output is rendered as %q, expected \"vi\"", line)
+	}
+}
+
+func TestServePseudoConsole(t *testing.T) {
+	if !winterm.IsPseudoConsoleSupported() {
+		t.Skip("pseudo consoles are not supported on this version of Windows")
+	}
+
+	remote, client := net.Pipe()
+	var output bytes.Buffer
+	copied := make(chan struct{})
+	go func() {
+		io.Copy(&output, client)
+		close(copied)
+	}()
+
+	code, err := ServePseudoConsole(remote, "cmd.exe /c echo hello & exit 3", Size{Cols: 80, Rows: 24}, nil)
+	remote.Close()
+	<-copied
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != 3 {
+		t.Errorf("exit code is %d, expected 3", code)
+	}
+	if !strings.Contains(output.String(), "hello") {
+		t.Errorf("output %q does not contain \"hello\"", output.String())
+	}
+}
@@ -0,0 +1,171 @@
+package ptybridge
+
+import (
+	"io"
+	"net"
+	"sync"
+
+	"github.com/Azure/go-ansiterm"
+)
+
+// Conn is the client end of a remote session over a network connection: the
+// output received from the peer is parsed and rendered by a handler, and the
+// input written to it, such as a console's input translated to VT sequences,
+// is sent to the peer. With WithTelnet the connection speaks the telnet
+// protocol, negotiating options and escaping its data. A Windows client
+// renders on the console and sends the console's input as
+//
+//	c := ptybridge.NewConn(netConn, ptybridge.WithTelnet())
+//	handler, err := winterm.CreateWinEventHandler(os.Stdout.Fd(), os.Stdout, winterm.WithResponseWriter(c))
+//	...
+//	input, err := winterm.NewAnsiReaderFromFile(os.Stdin,
+//		winterm.WithInputModes(handler.InputModes()),
+//		winterm.WithWindowSizeCallback(func(cols int, rows int) {
+//			c.Resize(cols, rows)
+//		}))
+//	...
+//	err = c.Serve(handler, input)
+//
+// The handler's replies to queries are written to c, and sent as input.
+type Conn struct {
+	conn       net.Conn
+	parserOpts []ansiterm.Option
+	telnet     *telnet
+
+	// Serializes the writes of input, replies and window sizes, and the
+	// telnet state they depend on
+	mutex sync.Mutex
+}
+
+// ConnOption configures optional behavior of a Conn.
+type ConnOption func(*Conn)
+
+// WithTelnet speaks the telnet protocol on the connection, as a client
+// reporting its window size. Commands are removed from the output before it
+// is parsed, and the input is escaped.
+func WithTelnet() ConnOption {
+	return func(c *Conn) {
+		if c.telnet == nil {
+			c.telnet = &telnet{}
+		}
+	}
+}
+
+// WithTerminalType speaks the telnet protocol, as WithTelnet does, and reports
+// name, such as "xterm-256color", as the terminal type when the peer asks.
+func WithTerminalType(name string) ConnOption {
+	return func(c *Conn) {
+		WithTelnet()(c)
+		c.telnet.termType = name
+	}
+}
+
+// WithParserOptions passes opts to the parser of the output, which is created
+// with WithUTF8.
+func WithParserOptions(opts ...ansiterm.Option) ConnOption {
+	return func(c *Conn) {
+		c.parserOpts = append(c.parserOpts, opts...)
+	}
+}
+
+// NewConn returns a Conn for a session over conn.
+func NewConn(conn net.Conn, opts ...ConnOption) *Conn {
+	c := &Conn{conn: conn}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Render reads the peer's output and renders it with handler until the
+// connection ends, returning nil if it ended normally.
+func (c *Conn) Render(handler ansiterm.AnsiEventHandler) error {
+	parser := ansiterm.CreateParser("Ground", handler, append([]ansiterm.Option{ansiterm.WithUTF8()}, c.parserOpts...)...)
+	buffer := make([]byte, 4096)
+	for {
+		n, err := c.conn.Read(buffer)
+		if n > 0 {
+			if rerr := c.render(parser, buffer[:n]); rerr != nil {
+				return rerr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// render renders output, first answering the telnet commands in it.
+func (c *Conn) render(parser *ansiterm.AnsiParser, output []byte) error {
+	if c.telnet != nil {
+		var reply []byte
+		c.mutex.Lock()
+		output, reply = c.telnet.decode(output)
+		if len(reply) > 0 {
+			if _, err := c.conn.Write(reply); err != nil {
+				c.mutex.Unlock()
+				return err
+			}
+		}
+		c.mutex.Unlock()
+	}
+
+	_, err := parser.Parse(output)
+	return err
+}
+
+// Write sends input to the peer.
+func (c *Conn) Write(p []byte) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	data := p
+	if c.telnet != nil {
+		data = c.telnet.encode(p)
+	}
+	if _, err := c.conn.Write(data); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Resize reports a change in the window size to the peer. Only telnet peers
+// that have enabled the window size option are told; the size is recorded
+// for those that enable it later.
+func (c *Conn) Resize(cols int, rows int) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.telnet == nil {
+		return nil
+	}
+	if report := c.telnet.resize(Size{Cols: cols, Rows: rows}); len(report) > 0 {
+		if _, err := c.conn.Write(report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Serve sends input to the peer while rendering its output with handler,
+// returning when Render does, or when sending input fails. As with Run, the
+// copy of input may remain blocked in a read afterwards.
+func (c *Conn) Serve(handler ansiterm.AnsiEventHandler, input io.Reader) error {
+	done := make(chan error, 2)
+	go func() {
+		done <- c.Render(handler)
+	}()
+	go func() {
+		if _, err := io.Copy(c, input); err != nil {
+			done <- err
+		}
+	}()
+	return <-done
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
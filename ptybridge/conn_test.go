@@ -0,0 +1,113 @@
+package ptybridge
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/Azure/go-ansiterm/vscreen"
+)
+
+// startConn renders the output of a Conn on a virtual screen, returning the
+// peer's end of the connection. The rendering ends when the peer closes it.
+func startConn(opts ...ConnOption) (*Conn, net.Conn, *vscreen.Screen, <-chan error) {
+	local, peer := net.Pipe()
+	c := NewConn(local, opts...)
+	screen := vscreen.New(20, 4)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Render(screen)
+	}()
+	return c, peer, screen, done
+}
+
+// expectRead reads from peer until expected has been received.
+func expectRead(t *testing.T, peer net.Conn, expected []byte) {
+	t.Helper()
+
+	actual := make([]byte, len(expected))
+	if _, err := io.ReadFull(peer, actual); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("received %q, expected %q", actual, expected)
+	}
+}
+
+func TestConnRender(t *testing.T) {
+	c, peer, screen, done := startConn()
+
+	go c.Write([]byte("ls\r"))
+	expectRead(t, peer, []byte("ls\r"))
+
+	if _, err := peer.Write([]byte("\x1b[1mhello\r\nw\xc3\xb6rld")); err != nil {
+		t.Fatal(err)
+	}
+	peer.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if line := screen.Line(0); line != "hello" {
+		t.Errorf("line 0 is %q, expected \"hello\"", line)
+	}
+	if line := screen.Line(1); line != "wörld" {
+		t.Errorf("line 1 is %q, expected \"wörld\"", line)
+	}
+}
+
+func TestConnTelnet(t *testing.T) {
+	c, peer, screen, done := startConn(WithTerminalType("xterm"))
+
+	if err := c.Resize(80, 255); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		command  []byte
+		expected []byte
+	}{
+		{"WillEcho", []byte{telnetIAC, telnetWILL, telnetEcho}, []byte{telnetIAC, telnetDO, telnetEcho}},
+		{"DoNAWS", []byte{telnetIAC, telnetDO, telnetNAWS}, []byte{telnetIAC, telnetWILL, telnetNAWS, telnetIAC, telnetSB, telnetNAWS, 0, 80, 0, 255, 255, telnetIAC, telnetSE}},
+		{"DoTTYPE", []byte{telnetIAC, telnetDO, telnetTTYPE}, []byte{telnetIAC, telnetWILL, telnetTTYPE}},
+		{"SendTTYPE", []byte{telnetIAC, telnetSB, telnetTTYPE, telnetSEND, telnetIAC, telnetSE}, []byte{telnetIAC, telnetSB, telnetTTYPE, telnetIS, 'x', 't', 'e', 'r', 'm', telnetIAC, telnetSE}},
+		{"DoUnknown", []byte{telnetIAC, telnetDO, 42}, []byte{telnetIAC, telnetWONT, 42}},
+		{"WillUnknown", []byte{telnetIAC, telnetWILL, 42}, []byte{telnetIAC, telnetDONT, 42}},
+		{"DontNAWS", []byte{telnetIAC, telnetDONT, telnetNAWS}, []byte{telnetIAC, telnetWONT, telnetNAWS}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			go peer.Write(test.command)
+			expectRead(t, peer, test.expected)
+		})
+	}
+
+	// Confirming an option already enabled is not answered, nor is resizing
+	// once the window size option is disabled; the input that follows is
+	// the next thing received
+	if _, err := peer.Write([]byte{telnetIAC, telnetWILL, telnetEcho}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Resize(100, 30); err != nil {
+		t.Fatal(err)
+	}
+	go c.Write([]byte{'a', telnetIAC, '\r', 'b', '\r', '\n'})
+	expectRead(t, peer, []byte{'a', telnetIAC, telnetIAC, '\r', 0, 'b', '\r', '\n'})
+
+	// Commands are removed from the output, and escaped data restored
+	if _, err := peer.Write([]byte("a\r\x00b\xff\xff\xff\xf1c")); err != nil {
+		t.Fatal(err)
+	}
+	peer.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if line := screen.Line(0); line != "b�c" {
+		t.Errorf("line 0 is %q, expected \"b\\ufffdc\"", line)
+	}
+}
@@ -0,0 +1,229 @@
+package ptybridge
+
+// Telnet commands and options.
+// See https://tools.ietf.org/html/rfc854 and https://tools.ietf.org/html/rfc855.
+const (
+	telnetSE   = 240
+	telnetSB   = 250
+	telnetWILL = 251
+	telnetWONT = 252
+	telnetDO   = 253
+	telnetDONT = 254
+	telnetIAC  = 255
+
+	telnetBinary = 0  // RFC 856
+	telnetEcho   = 1  // RFC 857
+	telnetSGA    = 3  // Suppress go ahead, RFC 858
+	telnetTTYPE  = 24 // Terminal type, RFC 1091
+	telnetNAWS   = 31 // Negotiate about window size, RFC 1073
+
+	telnetIS   = 0
+	telnetSEND = 1
+)
+
+// telnetState is the position of a telnet decoder in the commands it is
+// removing from the data.
+type telnetState int
+
+const (
+	telnetData telnetState = iota
+	telnetDataCR
+	telnetCommand
+	telnetOption
+	telnetSub
+	telnetSubIAC
+)
+
+// telnet separates the data of a telnet connection from its commands,
+// answering option negotiation as an NVT that displays the peer's output
+// and sends it keyboard input: it lets the peer echo and suppress go ahead,
+// and agrees to report the terminal type and window size and to exchange
+// binary data. Other options are refused. Negotiation is answered only when
+// it changes an option's state, so the two sides cannot loop.
+type telnet struct {
+	state  telnetState
+	verb   byte
+	sub    []byte
+	local  [256]bool // Options enabled on this side
+	remote [256]bool // Options enabled on the peer's side
+
+	termType string
+	size     Size
+}
+
+// decode returns the data in p, with the commands removed, and the replies
+// to send for the commands.
+func (t *telnet) decode(p []byte) (data []byte, reply []byte) {
+	data = make([]byte, 0, len(p))
+	for _, b := range p {
+		switch t.state {
+		case telnetData, telnetDataCR:
+			cr := t.state == telnetDataCR
+			t.state = telnetData
+			switch {
+			case b == telnetIAC:
+				t.state = telnetCommand
+			case b == 0 && cr:
+				// CR NUL is a bare carriage return
+			default:
+				data = append(data, b)
+				if b == '\r' && !t.remote[telnetBinary] {
+					t.state = telnetDataCR
+				}
+			}
+
+		case telnetCommand:
+			switch b {
+			case telnetIAC:
+				data = append(data, b)
+				t.state = telnetData
+			case telnetWILL, telnetWONT, telnetDO, telnetDONT:
+				t.verb = b
+				t.state = telnetOption
+			case telnetSB:
+				t.sub = t.sub[:0]
+				t.state = telnetSub
+			default:
+				// Go ahead, no operation, and the rest carry no data
+				t.state = telnetData
+			}
+
+		case telnetOption:
+			reply = append(reply, t.negotiate(t.verb, b)...)
+			t.state = telnetData
+
+		case telnetSub:
+			if b == telnetIAC {
+				t.state = telnetSubIAC
+			} else {
+				t.sub = append(t.sub, b)
+			}
+
+		case telnetSubIAC:
+			switch b {
+			case telnetSE:
+				reply = append(reply, t.subnegotiate(t.sub)...)
+				t.state = telnetData
+			case telnetIAC:
+				t.sub = append(t.sub, b)
+				t.state = telnetSub
+			default:
+				// A malformed subnegotiation is ignored
+				t.state = telnetData
+			}
+		}
+	}
+	return data, reply
+}
+
+// negotiate answers a request to enable or disable an option.
+func (t *telnet) negotiate(verb byte, option byte) []byte {
+	switch verb {
+	case telnetWILL:
+		if !t.acceptRemote(option) {
+			return []byte{telnetIAC, telnetDONT, option}
+		}
+		if t.remote[option] {
+			return nil
+		}
+		t.remote[option] = true
+		return []byte{telnetIAC, telnetDO, option}
+
+	case telnetWONT:
+		if !t.remote[option] {
+			return nil
+		}
+		t.remote[option] = false
+		return []byte{telnetIAC, telnetDONT, option}
+
+	case telnetDO:
+		if !t.acceptLocal(option) {
+			return []byte{telnetIAC, telnetWONT, option}
+		}
+		if t.local[option] {
+			return nil
+		}
+		t.local[option] = true
+		reply := []byte{telnetIAC, telnetWILL, option}
+		if option == telnetNAWS && t.size != (Size{}) {
+			reply = append(reply, t.windowSize()...)
+		}
+		return reply
+
+	case telnetDONT:
+		if !t.local[option] {
+			return nil
+		}
+		t.local[option] = false
+		return []byte{telnetIAC, telnetWONT, option}
+	}
+	return nil
+}
+
+// acceptRemote reports whether the peer may enable option.
+func (t *telnet) acceptRemote(option byte) bool {
+	return option == telnetBinary || option == telnetEcho || option == telnetSGA
+}
+
+// acceptLocal reports whether this side agrees to enable option.
+func (t *telnet) acceptLocal(option byte) bool {
+	switch option {
+	case telnetBinary, telnetSGA, telnetNAWS:
+		return true
+	case telnetTTYPE:
+		return t.termType != ""
+	}
+	return false
+}
+
+// subnegotiate answers a subnegotiation, of which only the terminal type
+// request needs a reply.
+func (t *telnet) subnegotiate(sub []byte) []byte {
+	if len(sub) < 2 || sub[0] != telnetTTYPE || sub[1] != telnetSEND || !t.local[telnetTTYPE] {
+		return nil
+	}
+
+	reply := []byte{telnetIAC, telnetSB, telnetTTYPE, telnetIS}
+	reply = append(reply, t.termType...)
+	return append(reply, telnetIAC, telnetSE)
+}
+
+// resize records the window size, returning the report to send if the
+// window size option is enabled.
+func (t *telnet) resize(size Size) []byte {
+	t.size = size
+	if !t.local[telnetNAWS] {
+		return nil
+	}
+	return t.windowSize()
+}
+
+// windowSize returns the subnegotiation reporting the window size.
+func (t *telnet) windowSize() []byte {
+	sub := []byte{byte(t.size.Cols >> 8), byte(t.size.Cols), byte(t.size.Rows >> 8), byte(t.size.Rows)}
+
+	reply := []byte{telnetIAC, telnetSB, telnetNAWS}
+	for _, b := range sub {
+		reply = append(reply, b)
+		if b == telnetIAC {
+			reply = append(reply, telnetIAC)
+		}
+	}
+	return append(reply, telnetIAC, telnetSE)
+}
+
+// encode returns data as it is sent: with IAC doubled, and outside binary
+// mode with a carriage return not followed by a line feed followed by NUL.
+func (t *telnet) encode(p []byte) []byte {
+	encoded := make([]byte, 0, len(p))
+	for i, b := range p {
+		encoded = append(encoded, b)
+		switch {
+		case b == telnetIAC:
+			encoded = append(encoded, telnetIAC)
+		case b == '\r' && !t.local[telnetBinary] && (i+1 == len(p) || p[i+1] != '\n'):
+			encoded = append(encoded, 0)
+		}
+	}
+	return encoded
+}
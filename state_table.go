@@ -0,0 +1,205 @@
+package ansiterm
+
+import (
+	"fmt"
+)
+
+// action is an action of the DEC parser's state diagram, performed on the
+// byte being parsed.
+type action uint8
+
+const (
+	actionNone action = iota
+	actionPrint
+	actionExecute
+	actionCollectParam
+	actionCollectInter
+	actionEscDispatch
+	actionCsiDispatch
+	actionOscPut
+	actionDcsPut
+	actionFail // The byte is invalid in the state
+)
+
+// transition is an entry of a state table: the state a byte leads to, and
+// the action it calls for. When the state changes, the action is performed
+// between the exit action of the old state and the entry action of the new.
+type transition struct {
+	action action
+	next   StateId
+}
+
+// stateTable holds the transition for each state and byte.
+type stateTable [stateCount][256]transition
+
+// stateTables are the tables of parsers without and with WithUTF8.
+var stateTables = [2]*stateTable{buildStateTable(false), buildStateTable(true)}
+
+// buildStateTable generates the state table from the rules of the state
+// diagram, after http://vt100.net/emu/dec_ansi_parser, with the byte ranges
+// of constants.go.
+func buildStateTable(utf8 bool) *stateTable {
+	var table stateTable
+	for s := StateId(0); s < stateCount; s++ {
+		for i := 0; i < 256; i++ {
+			b := byte(i)
+			t, ok := anywhereTransition(b, utf8)
+			if !ok {
+				t = stateTransition(s, b, utf8)
+			}
+			table[s][b] = t
+		}
+	}
+	return &table
+}
+
+// anywhereTransition returns the transition b leads to from every state,
+// if any. Bytes 0x80-0xFF are C1 controls only without WithUTF8.
+func anywhereTransition(b byte, utf8 bool) (transition, bool) {
+	if utf8 && b >= 0x80 {
+		return transition{}, false
+	}
+
+	switch {
+	case b == CSI_ENTRY:
+		return transition{actionNone, stateCsiEntry}, true
+	case b == DCS_ENTRY:
+		return transition{actionNone, stateDcsEntry}, true
+	case b == ANSI_ESCAPE_PRIMARY:
+		return transition{actionNone, stateEscape}, true
+	case b == OSC_STRING:
+		return transition{actionNone, stateOscString}, true
+	case b == 0x9C:
+		// String terminator
+		return transition{actionNone, stateGround}, true
+	case is(b, classToGround):
+		// CAN, SUB, and the other C1 controls are executed
+		return transition{actionExecute, stateGround}, true
+	}
+
+	return transition{}, false
+}
+
+// stateTransition returns the transition b leads to from s, other than those
+// from every state.
+func stateTransition(s StateId, b byte, utf8 bool) transition {
+	stay := func(a action) transition {
+		return transition{a, s}
+	}
+
+	switch s {
+	case stateGround:
+		switch {
+		case is(b, classPrintable), utf8 && b >= 0x80:
+			return stay(actionPrint)
+		case is(b, classExecute):
+			return stay(actionExecute)
+		}
+
+	case stateEscape:
+		switch {
+		case b == ANSI_ESCAPE_SECONDARY:
+			return transition{actionNone, stateCsiEntry}
+		case b == ANSI_OSC_STRING_ENTRY:
+			return transition{actionNone, stateOscString}
+		case b == ANSI_DCS_ENTRY:
+			return transition{actionNone, stateDcsEntry}
+		case is(b, classExecute):
+			return stay(actionExecute)
+		case is(b, classEscapeToGround):
+			return transition{actionEscDispatch, stateGround}
+		case is(b, classIntermediate):
+			return transition{actionCollectInter, stateEscapeIntermediate}
+		}
+
+	case stateEscapeIntermediate:
+		switch {
+		case is(b, classIntermediate):
+			return stay(actionCollectInter)
+		case is(b, classExecute):
+			return stay(actionExecute)
+		case is(b, classEscapeIntermediateToGround):
+			return transition{actionEscDispatch, stateGround}
+		}
+
+	case stateCsiEntry, stateCsiParam:
+		switch {
+		case is(b, classAlphabetic):
+			return transition{actionCsiDispatch, stateGround}
+		case is(b, classCsiCollectable):
+			return transition{actionCollectParam, stateCsiParam}
+		case is(b, classIntermediate):
+			return transition{actionCollectInter, stateCsiParam}
+		case is(b, classExecute):
+			return stay(actionExecute)
+		}
+
+	case stateOscString:
+		// The string ends with ST (ESC \ or 0x9C), a transition from every
+		// state, or as xterm accepts, with BEL. A backslash alone is part
+		// of it.
+		switch {
+		case b == ANSI_BEL:
+			return transition{actionNone, stateGround}
+		case b != 0x7F:
+			return stay(actionOscPut)
+		}
+
+	case stateDcsEntry:
+		if b != 0x7F {
+			return stay(actionDcsPut)
+		}
+
+	case stateError:
+		return stay(actionFail)
+	}
+
+	return stay(actionNone)
+}
+
+// perform performs an action on the current byte.
+func (ap *AnsiParser) perform(a action) error {
+	switch a {
+	case actionPrint:
+		return ap.print()
+	case actionExecute:
+		return ap.execute()
+	case actionCollectParam:
+		return ap.collectParam()
+	case actionCollectInter:
+		return ap.collectInter()
+	case actionEscDispatch:
+		return ap.escDispatch()
+	case actionCsiDispatch:
+		return ap.csiDispatch()
+	case actionOscPut:
+		ap.context.oscBuffer = append(ap.context.oscBuffer, ap.context.currentChar)
+	case actionDcsPut:
+		ap.context.dcsBuffer = append(ap.context.dcsBuffer, ap.context.currentChar)
+	case actionFail:
+		ap.logger.Errorf("byte %#x is invalid in the Error state", ap.context.currentChar)
+		return fmt.Errorf("ansiterm: byte %#x is invalid in the Error state", ap.context.currentChar)
+	}
+
+	return nil
+}
+
+// enter performs the entry action of s.
+func (ap *AnsiParser) enter(s StateId) error {
+	switch s {
+	case stateCsiEntry, stateDcsEntry, stateEscape, stateOscString:
+		return ap.clear()
+	}
+	return nil
+}
+
+// exit performs the exit action of s.
+func (ap *AnsiParser) exit(s StateId) error {
+	switch s {
+	case stateDcsEntry:
+		return ap.dcsDispatch()
+	case stateOscString:
+		return ap.oscDispatch()
+	}
+	return nil
+}
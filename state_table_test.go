@@ -0,0 +1,65 @@
+package ansiterm
+
+import (
+	"testing"
+)
+
+func TestStateTableAnywhere(t *testing.T) {
+	for _, table := range stateTables {
+		for s := StateId(0); s < stateCount; s++ {
+			for _, b := range []byte{0x18, 0x1A} {
+				if tr := table[s][b]; tr != (transition{actionExecute, stateGround}) {
+					t.Errorf("state %d, byte %#x: %+v, expected execute and Ground", s, b, tr)
+				}
+			}
+			if tr := table[s][ANSI_ESCAPE_PRIMARY]; tr.next != stateEscape || tr.action != actionNone {
+				t.Errorf("state %d, ESC: %+v, expected Escape", s, tr)
+			}
+		}
+	}
+
+	// C1 controls are text with WithUTF8
+	if tr := stateTables[0][stateGround][0x85]; tr != (transition{actionExecute, stateGround}) {
+		t.Errorf("NEL: %+v, expected execute", tr)
+	}
+	if tr := stateTables[1][stateGround][0x85]; tr != (transition{actionPrint, stateGround}) {
+		t.Errorf("NEL with WithUTF8: %+v, expected print", tr)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	// CAN and SUB abandon a sequence without dispatching it
+	funcCallParamHelper(t, []byte{'1', ';', 0x18}, "CsiEntry", "Ground", []string{"Execute([\x18])"})
+	funcCallParamHelper(t, []byte{'(', 0x1A}, "Escape", "Ground", []string{"Execute([\x1a])"})
+	funcCallParamHelper(t, []byte{0x18}, "Ground", "Ground", []string{"Execute([\x18])"})
+
+	// Strings are terminated by them, and dispatched
+	funcCallParamHelper(t, []byte{'2', ';', 't', 0x18}, "OscString", "Ground", []string{"OSC([2 t])", "Execute([\x18])"})
+}
+
+func TestStateMethods(t *testing.T) {
+	parser, evtHandler := createTestParser("Ground")
+
+	// Driving the states directly steps through the same table
+	if next, err := parser.Ground.Handle('a'); next != parser.Ground || err != nil {
+		t.Errorf("Ground.Handle('a') = %v, %v", next, err)
+	}
+	next, err := parser.Escape.Handle('[')
+	if next != parser.CsiEntry || err != nil {
+		t.Errorf("Escape.Handle('[') = %v, %v", next, err)
+	}
+	if next, _ := parser.CsiEntry.Handle('5'); next != parser.CsiParam {
+		t.Errorf("CsiEntry.Handle('5') = %v", next)
+	}
+	if err := parser.CsiEntry.Transition(parser.CsiParam); err != nil {
+		t.Fatal(err)
+	}
+	if next, _ := parser.CsiParam.Handle('n'); next != parser.Ground {
+		t.Errorf("CsiParam.Handle('n') = %v", next)
+	}
+	if err := parser.CsiParam.Transition(parser.Ground); err != nil {
+		t.Fatal(err)
+	}
+
+	validateFuncCalls(t, evtHandler.FunctionCalls, []string{"Print([a])", "DSR([5])"})
+}
@@ -1,7 +1,23 @@
 package ansiterm
 
+// StateId identifies a state of the parser, indexing its transition table.
 type StateId int
 
+const (
+	stateCsiEntry StateId = iota
+	stateCsiParam
+	stateDcsEntry
+	stateEscape
+	stateEscapeIntermediate
+	stateError
+	stateGround
+	stateOscString
+	stateCount
+)
+
+// State is a state of the parser. The parser itself steps through its
+// transition table; the states' methods apply the same table one step at a
+// time, for code driving a parser's states directly.
 type State interface {
 	Enter() error
 	Exit() error
@@ -12,60 +28,84 @@ type State interface {
 
 type BaseState struct {
 	name   string
+	id     StateId
 	parser *AnsiParser
 }
 
+// Enter performs the state's entry action.
 func (base BaseState) Enter() error {
-	return nil
+	return base.parser.enter(base.id)
 }
 
+// Exit performs the state's exit action.
 func (base BaseState) Exit() error {
-	return nil
+	return base.parser.exit(base.id)
 }
 
+// Handle returns the state b leads to. If it is this state, the action b
+// calls for is performed; otherwise it is left to the transition.
 func (base BaseState) Handle(b byte) (s State, e error) {
-
-	switch {
-	case b == CSI_ENTRY:
-		return base.parser.CsiEntry, nil
-	case b == DCS_ENTRY:
-		return base.parser.DcsEntry, nil
-	case b == ANSI_ESCAPE_PRIMARY:
-		return base.parser.Escape, nil
-	case b == OSC_STRING:
-		return base.parser.OscString, nil
-	case sliceContains(ToGroundBytes, b):
-		return base.parser.Ground, nil
+	base.parser.context.currentChar = b
+	t := base.parser.table[base.id][b]
+	if t.next != base.id {
+		return base.parser.stateMap[t.next], nil
 	}
 
-	return nil, nil
+	return base.parser.stateMap[base.id], base.parser.perform(t.action)
 }
 
 func (base BaseState) Name() string {
 	return base.name
 }
 
+// Transition performs the action of the transition to s on the byte last
+// handled.
 func (base BaseState) Transition(s State) error {
-	if s == base.parser.Ground {
-		execBytes := []byte{0x18}
-		execBytes = append(execBytes, 0x1A)
-		execBytes = append(execBytes, getByteRange(0x80, 0x8F)...)
-		execBytes = append(execBytes, getByteRange(0x91, 0x97)...)
-		execBytes = append(execBytes, 0x99)
-		execBytes = append(execBytes, 0x9A)
-
-		if sliceContains(execBytes, base.parser.context.currentChar) {
-			return base.parser.execute()
-		}
+	t := base.parser.table[base.id][base.parser.context.currentChar]
+	if next, ok := s.(interface{ stateId() StateId }); !ok || next.stateId() != t.next {
+		return nil
 	}
 
-	return nil
+	return base.parser.perform(t.action)
+}
+
+func (base BaseState) stateId() StateId {
+	return base.id
 }
 
+type CsiEntryState struct {
+	BaseState
+}
+
+type CsiParamState struct {
+	BaseState
+}
+
+// DcsEntryState collects a device control string up to its terminator. Like
+// the "unhook" action of the DEC parser, the string is dispatched when the
+// state is exited, so ST (ESC \\), CAN, and SUB all terminate it.
 type DcsEntryState struct {
 	BaseState
 }
 
+type EscapeState struct {
+	BaseState
+}
+
+type EscapeIntermediateState struct {
+	BaseState
+}
+
 type ErrorState struct {
 	BaseState
 }
+
+type GroundState struct {
+	BaseState
+}
+
+// OscStringState collects an operating system command up to its terminator,
+// dispatching it when the state is exited, as DcsEntryState does.
+type OscStringState struct {
+	BaseState
+}
@@ -0,0 +1,114 @@
+package ansiterm
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+)
+
+// Stats counts the work done by parsers created with WithParserStats, and by
+// event handlers given it, so that the overhead of terminal handling can be
+// monitored. A Stats may be shared by several parsers and read, from any
+// goroutine, while they update it. The zero value is ready to use.
+//
+// Stats implements expvar.Var, so it can be published with expvar.Publish;
+// the promadapter package exposes it as Prometheus metrics.
+type Stats struct {
+	bytes       uint64
+	flushes     uint64
+	unsupported uint64
+	events      sync.Map // Of *uint64, by AnsiEventHandler method
+	calls       sync.Map // Of *uint64, by function
+}
+
+// Bytes returns the number of bytes parsed.
+func (s *Stats) Bytes() uint64 {
+	return atomic.LoadUint64(&s.bytes)
+}
+
+// Flushes returns the number of times parsers flushed their handlers, which
+// they do at the end of each Parse.
+func (s *Stats) Flushes() uint64 {
+	return atomic.LoadUint64(&s.flushes)
+}
+
+// Unsupported returns the number of escape sequences, control sequences and
+// device control strings parsed that the parsers do not support and ignored.
+func (s *Stats) Unsupported() uint64 {
+	return atomic.LoadUint64(&s.unsupported)
+}
+
+// Events returns the number of events dispatched to handlers, by the name of
+// the AnsiEventHandler method called, such as "Print" or "CUP". Flushes are
+// counted by Flushes instead.
+func (s *Stats) Events() map[string]uint64 {
+	return counts(&s.events)
+}
+
+// Calls returns the number of calls made by event handlers to the system, by
+// the name of the function called, as counted with AddCall. winterm counts
+// its console API calls once given the Stats with CountConsoleCalls.
+func (s *Stats) Calls() map[string]uint64 {
+	return counts(&s.calls)
+}
+
+// AddCall counts a call to the system function named function, for event
+// handlers to report the calls they make.
+func (s *Stats) AddCall(function string) {
+	add(&s.calls, function)
+}
+
+// String returns the counters as a JSON object, as expvar.Var requires.
+func (s *Stats) String() string {
+	data, _ := json.Marshal(struct {
+		Bytes       uint64            `json:"bytes"`
+		Flushes     uint64            `json:"flushes"`
+		Unsupported uint64            `json:"unsupported"`
+		Events      map[string]uint64 `json:"events"`
+		Calls       map[string]uint64 `json:"calls"`
+	}{s.Bytes(), s.Flushes(), s.Unsupported(), s.Events(), s.Calls()})
+	return string(data)
+}
+
+// The methods below are called by parsers, which have a nil Stats unless
+// created with WithParserStats.
+
+func (s *Stats) parsed(n int) {
+	if s != nil {
+		atomic.AddUint64(&s.bytes, uint64(n))
+	}
+}
+
+func (s *Stats) flush() {
+	if s != nil {
+		atomic.AddUint64(&s.flushes, 1)
+	}
+}
+
+func (s *Stats) unsupportedSequence() {
+	if s != nil {
+		atomic.AddUint64(&s.unsupported, 1)
+	}
+}
+
+func (s *Stats) event(name string) {
+	add(&s.events, name)
+}
+
+// add increments the counter of name in counters.
+func add(counters *sync.Map, name string) {
+	c, ok := counters.Load(name)
+	if !ok {
+		c, _ = counters.LoadOrStore(name, new(uint64))
+	}
+	atomic.AddUint64(c.(*uint64), 1)
+}
+
+func counts(counters *sync.Map) map[string]uint64 {
+	m := map[string]uint64{}
+	counters.Range(func(name, c interface{}) bool {
+		m[name.(string)] = atomic.LoadUint64(c.(*uint64))
+		return true
+	})
+	return m
+}
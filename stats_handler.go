@@ -0,0 +1,298 @@
+package ansiterm
+
+// statsHandler counts the events a parser created with WithParserStats
+// dispatches to its handler.
+type statsHandler struct {
+	handler AnsiEventHandler
+	stats   *Stats
+}
+
+func (h statsHandler) Print(b byte) error {
+	h.stats.event("Print")
+	return h.handler.Print(b)
+}
+
+func (h statsHandler) Execute(b byte) error {
+	h.stats.event("Execute")
+	return h.handler.Execute(b)
+}
+
+func (h statsHandler) CUU(n int) error {
+	h.stats.event("CUU")
+	return h.handler.CUU(n)
+}
+
+func (h statsHandler) CUD(n int) error {
+	h.stats.event("CUD")
+	return h.handler.CUD(n)
+}
+
+func (h statsHandler) CUF(n int) error {
+	h.stats.event("CUF")
+	return h.handler.CUF(n)
+}
+
+func (h statsHandler) CUB(n int) error {
+	h.stats.event("CUB")
+	return h.handler.CUB(n)
+}
+
+func (h statsHandler) CNL(n int) error {
+	h.stats.event("CNL")
+	return h.handler.CNL(n)
+}
+
+func (h statsHandler) CPL(n int) error {
+	h.stats.event("CPL")
+	return h.handler.CPL(n)
+}
+
+func (h statsHandler) CHA(n int) error {
+	h.stats.event("CHA")
+	return h.handler.CHA(n)
+}
+
+func (h statsHandler) CUP(row int, col int) error {
+	h.stats.event("CUP")
+	return h.handler.CUP(row, col)
+}
+
+func (h statsHandler) HVP(row int, col int) error {
+	h.stats.event("HVP")
+	return h.handler.HVP(row, col)
+}
+
+func (h statsHandler) VPA(n int) error {
+	h.stats.event("VPA")
+	return h.handler.VPA(n)
+}
+
+func (h statsHandler) CHT(n int) error {
+	h.stats.event("CHT")
+	return h.handler.CHT(n)
+}
+
+func (h statsHandler) CBT(n int) error {
+	h.stats.event("CBT")
+	return h.handler.CBT(n)
+}
+
+func (h statsHandler) HTS() error {
+	h.stats.event("HTS")
+	return h.handler.HTS()
+}
+
+func (h statsHandler) TBC(n int) error {
+	h.stats.event("TBC")
+	return h.handler.TBC(n)
+}
+
+func (h statsHandler) DECTCEM(enable bool) error {
+	h.stats.event("DECTCEM")
+	return h.handler.DECTCEM(enable)
+}
+
+func (h statsHandler) DECSCUSR(n int) error {
+	h.stats.event("DECSCUSR")
+	return h.handler.DECSCUSR(n)
+}
+
+func (h statsHandler) SynchronizedUpdate(enable bool) error {
+	h.stats.event("SynchronizedUpdate")
+	return h.handler.SynchronizedUpdate(enable)
+}
+
+func (h statsHandler) IRM(enable bool) error {
+	h.stats.event("IRM")
+	return h.handler.IRM(enable)
+}
+
+func (h statsHandler) DECOM(enable bool) error {
+	h.stats.event("DECOM")
+	return h.handler.DECOM(enable)
+}
+
+func (h statsHandler) DECAWM(enable bool) error {
+	h.stats.event("DECAWM")
+	return h.handler.DECAWM(enable)
+}
+
+func (h statsHandler) ED(n int) error {
+	h.stats.event("ED")
+	return h.handler.ED(n)
+}
+
+func (h statsHandler) EL(n int) error {
+	h.stats.event("EL")
+	return h.handler.EL(n)
+}
+
+func (h statsHandler) IL(n int) error {
+	h.stats.event("IL")
+	return h.handler.IL(n)
+}
+
+func (h statsHandler) DL(n int) error {
+	h.stats.event("DL")
+	return h.handler.DL(n)
+}
+
+func (h statsHandler) SGR(params []int) error {
+	h.stats.event("SGR")
+	return h.handler.SGR(params)
+}
+
+func (h statsHandler) REP(n int) error {
+	h.stats.event("REP")
+	return h.handler.REP(n)
+}
+
+func (h statsHandler) SU(n int) error {
+	h.stats.event("SU")
+	return h.handler.SU(n)
+}
+
+func (h statsHandler) SD(n int) error {
+	h.stats.event("SD")
+	return h.handler.SD(n)
+}
+
+func (h statsHandler) DA(params []string) error {
+	h.stats.event("DA")
+	return h.handler.DA(params)
+}
+
+func (h statsHandler) DSR(n int) error {
+	h.stats.event("DSR")
+	return h.handler.DSR(n)
+}
+
+func (h statsHandler) DECCKM(enable bool) error {
+	h.stats.event("DECCKM")
+	return h.handler.DECCKM(enable)
+}
+
+func (h statsHandler) DECKPAM(enable bool) error {
+	h.stats.event("DECKPAM")
+	return h.handler.DECKPAM(enable)
+}
+
+func (h statsHandler) MouseMode(mode int, enable bool) error {
+	h.stats.event("MouseMode")
+	return h.handler.MouseMode(mode, enable)
+}
+
+func (h statsHandler) BracketedPaste(enable bool) error {
+	h.stats.event("BracketedPaste")
+	return h.handler.BracketedPaste(enable)
+}
+
+func (h statsHandler) Win32InputMode(enable bool) error {
+	h.stats.event("Win32InputMode")
+	return h.handler.Win32InputMode(enable)
+}
+
+func (h statsHandler) FocusReporting(enable bool) error {
+	h.stats.event("FocusReporting")
+	return h.handler.FocusReporting(enable)
+}
+
+func (h statsHandler) AlternateScreen(mode int, enable bool) error {
+	h.stats.event("AlternateScreen")
+	return h.handler.AlternateScreen(mode, enable)
+}
+
+func (h statsHandler) XTMODKEYS(params []int) error {
+	h.stats.event("XTMODKEYS")
+	return h.handler.XTMODKEYS(params)
+}
+
+func (h statsHandler) KittyKeyboard(op byte, params []int) error {
+	h.stats.event("KittyKeyboard")
+	return h.handler.KittyKeyboard(op, params)
+}
+
+func (h statsHandler) DECSTBM(top int, bottom int) error {
+	h.stats.event("DECSTBM")
+	return h.handler.DECSTBM(top, bottom)
+}
+
+func (h statsHandler) DECLRMM(enable bool) error {
+	h.stats.event("DECLRMM")
+	return h.handler.DECLRMM(enable)
+}
+
+func (h statsHandler) DECSLRM(left int, right int) error {
+	h.stats.event("DECSLRM")
+	return h.handler.DECSLRM(left, right)
+}
+
+func (h statsHandler) DECRQSS(setting string) error {
+	h.stats.event("DECRQSS")
+	return h.handler.DECRQSS(setting)
+}
+
+func (h statsHandler) XTGETTCAP(names []string) error {
+	h.stats.event("XTGETTCAP")
+	return h.handler.XTGETTCAP(names)
+}
+
+func (h statsHandler) XTWINOPS(params []int) error {
+	h.stats.event("XTWINOPS")
+	return h.handler.XTWINOPS(params)
+}
+
+func (h statsHandler) RI() error {
+	h.stats.event("RI")
+	return h.handler.RI()
+}
+
+func (h statsHandler) RIS() error {
+	h.stats.event("RIS")
+	return h.handler.RIS()
+}
+
+func (h statsHandler) DECSTR() error {
+	h.stats.event("DECSTR")
+	return h.handler.DECSTR()
+}
+
+func (h statsHandler) XTPUSHSGR(params []int) error {
+	h.stats.event("XTPUSHSGR")
+	return h.handler.XTPUSHSGR(params)
+}
+
+func (h statsHandler) XTPOPSGR() error {
+	h.stats.event("XTPOPSGR")
+	return h.handler.XTPOPSGR()
+}
+
+func (h statsHandler) DECSC() error {
+	h.stats.event("DECSC")
+	return h.handler.DECSC()
+}
+
+func (h statsHandler) DECRC() error {
+	h.stats.event("DECRC")
+	return h.handler.DECRC()
+}
+
+func (h statsHandler) DECALN() error {
+	h.stats.event("DECALN")
+	return h.handler.DECALN()
+}
+
+func (h statsHandler) SCS(set int, charset byte) error {
+	h.stats.event("SCS")
+	return h.handler.SCS(set, charset)
+}
+
+func (h statsHandler) OSC(command int, data string) error {
+	h.stats.event("OSC")
+	return h.handler.OSC(command, data)
+}
+
+func (h statsHandler) Flush() error {
+	h.stats.flush()
+	return h.handler.Flush()
+}
@@ -0,0 +1,43 @@
+package ansiterm
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParserStats(t *testing.T) {
+	stats := new(Stats)
+	parser := CreateParser("Ground", CreateTestAnsiEventHandler(), WithParserStats(stats))
+	parser.Parse([]byte("ab\x1b[2J\x1b[5z"))
+	parser.Parse([]byte("\x1b[1;2Hc\x1b#9"))
+
+	if n := stats.Bytes(); n != 20 {
+		t.Errorf("%d bytes counted, expected 20", n)
+	}
+	if n := stats.Flushes(); n != 2 {
+		t.Errorf("%d flushes counted, expected 2", n)
+	}
+	if n := stats.Unsupported(); n != 2 {
+		t.Errorf("%d unsupported sequences counted, expected 2", n)
+	}
+
+	expected := map[string]uint64{"Print": 3, "ED": 1, "CUP": 1}
+	if events := stats.Events(); !reflect.DeepEqual(events, expected) {
+		t.Errorf("events counted as %v, expected %v", events, expected)
+	}
+
+	stats.AddCall("WriteConsole")
+	stats.AddCall("WriteConsole")
+	var exported struct {
+		Bytes  uint64
+		Events map[string]uint64
+		Calls  map[string]uint64
+	}
+	if err := json.Unmarshal([]byte(stats.String()), &exported); err != nil {
+		t.Fatalf("%q: %v", stats.String(), err)
+	}
+	if exported.Bytes != 20 || exported.Events["Print"] != 3 || exported.Calls["WriteConsole"] != 2 {
+		t.Errorf("exported as %q", stats.String())
+	}
+}
@@ -0,0 +1,176 @@
+package ansiterm
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// StripHandler is an AnsiEventHandler that writes only the text of a stream,
+// dropping control sequences, for storing logs as plain text. Lines are
+// ended with LF alone: CR LF becomes LF, as do VT and FF, and a CR not
+// followed by LF, which a progress indicator uses to redraw its line,
+// discards the line so far when text follows, so that only its last state
+// is kept (see WithRedrawnLines). Backspace erases the previous character.
+// Text after the last LF is held until it ends or Close is called.
+type StripHandler struct {
+	out     io.Writer
+	line    []byte
+	cr      bool // A CR was received; the line is discarded unless LF follows
+	redrawn bool // Lines redrawn after a CR are kept, each on a line of its own
+}
+
+// StripOption configures optional behavior of a StripHandler.
+type StripOption func(*StripHandler)
+
+// WithRedrawnLines keeps each state of a line redrawn after a CR, ending it
+// as a line of its own, rather than only the last.
+func WithRedrawnLines() StripOption {
+	return func(h *StripHandler) {
+		h.redrawn = true
+	}
+}
+
+// NewStripHandler creates a StripHandler writing text to out.
+func NewStripHandler(out io.Writer, opts ...StripOption) *StripHandler {
+	h := &StripHandler{out: out}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// StripWriter returns a writer that parses UTF-8 terminal output and writes
+// its text to w, as a StripHandler does. Close writes any unterminated last
+// line.
+func StripWriter(w io.Writer, opts ...StripOption) io.WriteCloser {
+	h := NewStripHandler(w, opts...)
+	return &stripWriter{
+		parser:  CreateParser("Ground", h, WithUTF8()),
+		handler: h,
+	}
+}
+
+type stripWriter struct {
+	parser  *AnsiParser
+	handler *StripHandler
+}
+
+func (w *stripWriter) Write(p []byte) (int, error) {
+	return w.parser.Parse(p)
+}
+
+func (w *stripWriter) Close() error {
+	return w.handler.Close()
+}
+
+func (h *StripHandler) Print(b byte) error {
+	if h.cr {
+		h.cr = false
+		if !h.redrawn {
+			h.line = h.line[:0]
+		} else if len(h.line) > 0 {
+			h.line = append(h.line, '\n')
+			if err := h.writeLine(); err != nil {
+				return err
+			}
+		}
+	}
+
+	h.line = append(h.line, b)
+	return nil
+}
+
+func (h *StripHandler) Execute(b byte) error {
+	switch b {
+	case ANSI_CARRIAGE_RETURN:
+		h.cr = true
+	case ANSI_LINE_FEED, ANSI_VERTICAL_TAB, ANSI_FORM_FEED:
+		h.cr = false
+		h.line = append(h.line, '\n')
+		return h.writeLine()
+	case ANSI_TAB:
+		return h.Print(b)
+	case ANSI_BACKSPACE:
+		if !h.cr && len(h.line) > 0 {
+			_, size := utf8.DecodeLastRune(h.line)
+			h.line = h.line[:len(h.line)-size]
+		}
+	}
+	return nil
+}
+
+// Close writes the text of an unterminated last line.
+func (h *StripHandler) Close() error {
+	h.cr = false
+	return h.writeLine()
+}
+
+// writeLine writes the line held and starts a new one.
+func (h *StripHandler) writeLine() error {
+	if len(h.line) == 0 {
+		return nil
+	}
+
+	_, err := h.out.Write(h.line)
+	h.line = h.line[:0]
+	return err
+}
+
+// The rest of the stream has no text.
+
+func (h *StripHandler) CUU(int) error                   { return nil }
+func (h *StripHandler) CUD(int) error                   { return nil }
+func (h *StripHandler) CUF(int) error                   { return nil }
+func (h *StripHandler) CUB(int) error                   { return nil }
+func (h *StripHandler) CNL(int) error                   { return nil }
+func (h *StripHandler) CPL(int) error                   { return nil }
+func (h *StripHandler) CHA(int) error                   { return nil }
+func (h *StripHandler) CUP(int, int) error              { return nil }
+func (h *StripHandler) HVP(int, int) error              { return nil }
+func (h *StripHandler) VPA(int) error                   { return nil }
+func (h *StripHandler) CHT(int) error                   { return nil }
+func (h *StripHandler) CBT(int) error                   { return nil }
+func (h *StripHandler) HTS() error                      { return nil }
+func (h *StripHandler) TBC(int) error                   { return nil }
+func (h *StripHandler) DECTCEM(bool) error              { return nil }
+func (h *StripHandler) DECSCUSR(int) error              { return nil }
+func (h *StripHandler) SynchronizedUpdate(bool) error   { return nil }
+func (h *StripHandler) IRM(bool) error                  { return nil }
+func (h *StripHandler) DECOM(bool) error                { return nil }
+func (h *StripHandler) DECAWM(bool) error               { return nil }
+func (h *StripHandler) ED(int) error                    { return nil }
+func (h *StripHandler) EL(int) error                    { return nil }
+func (h *StripHandler) IL(int) error                    { return nil }
+func (h *StripHandler) DL(int) error                    { return nil }
+func (h *StripHandler) SGR([]int) error                 { return nil }
+func (h *StripHandler) REP(int) error                   { return nil }
+func (h *StripHandler) SU(int) error                    { return nil }
+func (h *StripHandler) SD(int) error                    { return nil }
+func (h *StripHandler) DA([]string) error               { return nil }
+func (h *StripHandler) DSR(int) error                   { return nil }
+func (h *StripHandler) DECCKM(bool) error               { return nil }
+func (h *StripHandler) DECKPAM(bool) error              { return nil }
+func (h *StripHandler) MouseMode(int, bool) error       { return nil }
+func (h *StripHandler) BracketedPaste(bool) error       { return nil }
+func (h *StripHandler) Win32InputMode(bool) error       { return nil }
+func (h *StripHandler) FocusReporting(bool) error       { return nil }
+func (h *StripHandler) AlternateScreen(int, bool) error { return nil }
+func (h *StripHandler) XTMODKEYS([]int) error           { return nil }
+func (h *StripHandler) KittyKeyboard(byte, []int) error { return nil }
+func (h *StripHandler) DECSTBM(int, int) error          { return nil }
+func (h *StripHandler) DECLRMM(bool) error              { return nil }
+func (h *StripHandler) DECSLRM(int, int) error          { return nil }
+func (h *StripHandler) DECRQSS(string) error            { return nil }
+func (h *StripHandler) XTGETTCAP([]string) error        { return nil }
+func (h *StripHandler) XTWINOPS([]int) error            { return nil }
+func (h *StripHandler) RI() error                       { return nil }
+func (h *StripHandler) RIS() error                      { return nil }
+func (h *StripHandler) DECSTR() error                   { return nil }
+func (h *StripHandler) XTPUSHSGR([]int) error           { return nil }
+func (h *StripHandler) XTPOPSGR() error                 { return nil }
+func (h *StripHandler) DECSC() error                    { return nil }
+func (h *StripHandler) DECRC() error                    { return nil }
+func (h *StripHandler) DECALN() error                   { return nil }
+func (h *StripHandler) SCS(int, byte) error             { return nil }
+func (h *StripHandler) OSC(int, string) error           { return nil }
+func (h *StripHandler) Flush() error                    { return nil }
@@ -0,0 +1,37 @@
+package ansiterm
+
+import (
+	"bytes"
+	"testing"
+)
+
+func stripHelper(t *testing.T, output string, expected string) {
+	var text bytes.Buffer
+	w := StripWriter(&text)
+	for i := 0; i < len(output); i++ {
+		w.Write([]byte{output[i]})
+	}
+	w.Close()
+
+	if actual := text.String(); actual != expected {
+		t.Errorf("%q: stripped to %q, expected %q", output, actual, expected)
+	}
+}
+
+func TestStripWriter(t *testing.T) {
+	stripHelper(t, "plain text", "plain text")
+	stripHelper(t, "\x1b[1;31mred\x1b[0m text\r\n", "red text\n")
+	stripHelper(t, "\x1b]0;title\x07\x1b[2J\x1b[Hhome\n", "home\n")
+	stripHelper(t, "10%\r50%\r100%\r\ndone", "100%\ndone")
+	stripHelper(t, "abc\bd\r", "abd")
+	stripHelper(t, "日本\b語\f\ttab\x1b[?25l", "日語\n\ttab")
+	stripHelper(t, "\x1b(0qqq\x1b(B", "qqq")
+
+	var text bytes.Buffer
+	w := StripWriter(&text, WithRedrawnLines())
+	w.Write([]byte("10%\r50%\r\x1b[K100%\r\ndone\r"))
+	w.Close()
+	if actual := text.String(); actual != "10%\n50%\n100%\ndone" {
+		t.Errorf("stripped with redrawn lines to %q", actual)
+	}
+}
@@ -0,0 +1,147 @@
+// +build tcell
+
+// Package tcellterm displays terminal output in a region of a tcell.Screen,
+// for applications built on tcell that show the output of subprocesses in
+// their UI. Build with the tcell tag to include it.
+package tcellterm
+
+import (
+	"github.com/Azure/go-ansiterm"
+	"github.com/Azure/go-ansiterm/vscreen"
+	"github.com/gdamore/tcell/v2"
+)
+
+// View renders terminal output into a rectangle of a tcell.Screen. It keeps
+// the terminal's state in a virtual screen, which it embeds, so it
+// implements AnsiEventHandler; output written to it, or events passed to its
+// Flush, are drawn by redrawing the cells they changed.
+//
+// A View is not safe for concurrent use. Output read from a subprocess on
+// another goroutine should be handed to the goroutine running the UI, such
+// as through tcell's PostEvent.
+type View struct {
+	*vscreen.Screen
+	target tcell.Screen
+	x, y   int
+}
+
+// New creates a view of cols by rows cells drawn onto target with its top
+// left cell at x, y. The options configure the virtual screen; its replies
+// to queries can be sent to the subprocess with WithResponseWriter.
+func New(target tcell.Screen, x int, y int, cols int, rows int, opts ...vscreen.ScreenOption) *View {
+	v := &View{
+		Screen: vscreen.New(cols, rows, opts...),
+		target: target,
+		x:      x,
+		y:      y,
+	}
+	v.Draw()
+	return v
+}
+
+// Write parses p as terminal output and draws the result.
+func (v *View) Write(p []byte) (int, error) {
+	n, err := v.Screen.Write(p)
+	v.Draw()
+	return n, err
+}
+
+// Flush completes the events passed to the view's handler methods and draws
+// the result.
+func (v *View) Flush() error {
+	err := v.Screen.Flush()
+	v.Draw()
+	return err
+}
+
+// Move moves the view so its top left cell is at x, y, and redraws it.
+func (v *View) Move(x int, y int) {
+	v.x, v.y = x, y
+	v.Redraw()
+}
+
+// Resize changes the size of the view to cols by rows, as the virtual
+// screen's Resize does, and redraws it.
+func (v *View) Resize(cols int, rows int) {
+	v.Screen.Resize(cols, rows)
+	v.Draw()
+}
+
+// Redraw draws every cell of the view, as after the target is cleared.
+func (v *View) Redraw() {
+	cols, rows := v.Size()
+	v.drawRect(vscreen.Rect{Left: 0, Top: 0, Right: cols - 1, Bottom: rows - 1})
+	v.finish()
+}
+
+// Draw draws the cells changed since the view was last drawn, places the
+// cursor, and shows the target.
+func (v *View) Draw() {
+	for _, rect := range v.Damage() {
+		v.drawRect(rect)
+	}
+	v.finish()
+}
+
+// finish marks the view as drawn, places the cursor, and shows the target.
+func (v *View) finish() {
+	v.Sync()
+
+	if v.Modes().CursorVisible {
+		x, y := v.Cursor()
+		v.target.ShowCursor(v.x+x, v.y+y)
+	} else {
+		v.target.HideCursor()
+	}
+
+	v.target.Show()
+}
+
+// drawRect draws the cells within a rectangle of the view.
+func (v *View) drawRect(rect vscreen.Rect) {
+	for y := rect.Top; y <= rect.Bottom; y++ {
+		// Draw from the start of a wide character cut by the rectangle
+		left := rect.Left
+		if left > 0 && v.Cell(left, y).Width == 0 {
+			left--
+		}
+
+		for x := left; x <= rect.Right; x++ {
+			cell := v.Cell(x, y)
+			if cell.Width == 0 {
+				continue
+			}
+
+			r, combining := cell.Rune, cell.Combining
+			if cell.Attrs&vscreen.AttrInvisible != 0 {
+				r, combining = ' ', nil
+			}
+			v.target.SetContent(v.x+x, v.y+y, r, combining, style(cell))
+		}
+	}
+}
+
+// style returns the tcell style of a cell.
+func style(cell vscreen.Cell) tcell.Style {
+	return tcell.StyleDefault.
+		Foreground(color(cell.Fg)).
+		Background(color(cell.Bg)).
+		Bold(cell.Attrs&vscreen.AttrBold != 0).
+		Dim(cell.Attrs&vscreen.AttrFaint != 0).
+		Italic(cell.Attrs&vscreen.AttrItalic != 0).
+		Underline(cell.Attrs&vscreen.AttrUnderline != 0).
+		Blink(cell.Attrs&vscreen.AttrBlink != 0).
+		Reverse(cell.Attrs&vscreen.AttrReverse != 0).
+		StrikeThrough(cell.Attrs&vscreen.AttrStrikethrough != 0)
+}
+
+// color returns the tcell color of a color.
+func color(c ansiterm.Color) tcell.Color {
+	if index, ok := c.Palette(); ok {
+		return tcell.PaletteColor(index)
+	}
+	if r, g, b, ok := c.RGB(); ok {
+		return tcell.NewRGBColor(int32(r), int32(g), int32(b))
+	}
+	return tcell.ColorDefault
+}
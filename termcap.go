@@ -0,0 +1,44 @@
+package ansiterm
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+// TermcapReply returns the reply to an XTGETTCAP query for the capability
+// name, looking it up in caps, which maps the termcap and terminfo names of
+// the capabilities a handler supports to their values; boolean capabilities
+// have empty values. As xterm does, a supported capability is reported as
+// DCS 1 + r name = value ST, and others as DCS 0 + r name ST, with the name
+// and value hex encoded.
+func TermcapReply(caps map[string]string, name string) string {
+	value, ok := caps[name]
+	if !ok || name == "" {
+		return "\x1bP0+r" + hexString(name) + "\x1b\\"
+	}
+
+	reply := "\x1bP1+r" + hexString(name)
+	if value != "" {
+		reply += "=" + hexString(value)
+	}
+	return reply + "\x1b\\"
+}
+
+// parseTermcapNames decodes the hex encoded, semicolon separated capability
+// names of an XTGETTCAP query. Names that are not valid hex are returned
+// empty, so that they are reported as unsupported.
+func parseTermcapNames(data string) []string {
+	var names []string
+	for _, encoded := range strings.Split(data, ";") {
+		name, err := hex.DecodeString(encoded)
+		if err != nil {
+			name = nil
+		}
+		names = append(names, string(name))
+	}
+	return names
+}
+
+func hexString(s string) string {
+	return strings.ToUpper(hex.EncodeToString([]byte(s)))
+}
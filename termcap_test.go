@@ -0,0 +1,20 @@
+package ansiterm
+
+import "testing"
+
+func TestTermcapReply(t *testing.T) {
+	caps := map[string]string{"Co": "256", "RGB": ""}
+
+	tests := map[string]string{
+		"Co":  "\x1bP1+r436F=323536\x1b\\",
+		"RGB": "\x1bP1+r524742\x1b\\",
+		"kbs": "\x1bP0+r6B6273\x1b\\",
+		"":    "\x1bP0+r\x1b\\",
+	}
+
+	for name, expected := range tests {
+		if actual := TermcapReply(caps, name); actual != expected {
+			t.Errorf("TermcapReply(%q) is %q, expected %q", name, actual, expected)
+		}
+	}
+}
@@ -77,11 +77,61 @@ func (h *TestAnsiEventHandler) HVP(x int, y int) error {
 	return nil
 }
 
+func (h *TestAnsiEventHandler) VPA(param int) error {
+	h.recordCall("VPA", []string{strconv.Itoa(param)})
+	return nil
+}
+
+func (h *TestAnsiEventHandler) CHT(param int) error {
+	h.recordCall("CHT", []string{strconv.Itoa(param)})
+	return nil
+}
+
+func (h *TestAnsiEventHandler) CBT(param int) error {
+	h.recordCall("CBT", []string{strconv.Itoa(param)})
+	return nil
+}
+
+func (h *TestAnsiEventHandler) HTS() error {
+	h.recordCall("HTS", nil)
+	return nil
+}
+
+func (h *TestAnsiEventHandler) TBC(param int) error {
+	h.recordCall("TBC", []string{strconv.Itoa(param)})
+	return nil
+}
+
 func (h *TestAnsiEventHandler) DECTCEM(visible bool) error {
 	h.recordCall("DECTCEM", []string{strconv.FormatBool(visible)})
 	return nil
 }
 
+func (h *TestAnsiEventHandler) SynchronizedUpdate(enable bool) error {
+	h.recordCall("SynchronizedUpdate", []string{strconv.FormatBool(enable)})
+	return nil
+}
+
+func (h *TestAnsiEventHandler) DECSCUSR(style int) error {
+	h.recordCall("DECSCUSR", []string{strconv.Itoa(style)})
+	return nil
+}
+
+func (h *TestAnsiEventHandler) IRM(insert bool) error {
+	h.recordCall("IRM", []string{strconv.FormatBool(insert)})
+	return nil
+}
+
+func (h *TestAnsiEventHandler) DECOM(enable bool) error {
+	h.recordCall("DECOM", []string{strconv.FormatBool(enable)})
+	return nil
+}
+
+func (h *TestAnsiEventHandler) DECAWM(enable bool) error {
+	h.recordCall("DECAWM", []string{strconv.FormatBool(enable)})
+	return nil
+}
+
 func (h *TestAnsiEventHandler) ED(param int) error {
 	h.recordCall("ED", []string{strconv.Itoa(param)})
 	return nil
@@ -112,6 +162,11 @@ func (h *TestAnsiEventHandler) SGR(params []int) error {
 	return nil
 }
 
+func (h *TestAnsiEventHandler) REP(param int) error {
+	h.recordCall("REP", []string{strconv.Itoa(param)})
+	return nil
+}
+
 func (h *TestAnsiEventHandler) SU(param int) error {
 	h.recordCall("SU", []string{strconv.Itoa(param)})
 	return nil
@@ -133,7 +188,151 @@ func (h *TestAnsiEventHandler) DECSTBM(top int, bottom int) error {
 	return nil
 }
 
+func (h *TestAnsiEventHandler) DECLRMM(enable bool) error {
+	h.recordCall("DECLRMM", []string{strconv.FormatBool(enable)})
+	return nil
+}
+
+func (h *TestAnsiEventHandler) DECSLRM(left int, right int) error {
+	leftS, rightS := strconv.Itoa(left), strconv.Itoa(right)
+	h.recordCall("DECSLRM", []string{leftS, rightS})
+	return nil
+}
+
+func (h *TestAnsiEventHandler) DSR(param int) error {
+	h.recordCall("DSR", []string{strconv.Itoa(param)})
+	return nil
+}
+
+func (h *TestAnsiEventHandler) DECCKM(value bool) error {
+	h.recordCall("DECCKM", []string{strconv.FormatBool(value)})
+	return nil
+}
+
+func (h *TestAnsiEventHandler) DECKPAM(value bool) error {
+	h.recordCall("DECKPAM", []string{strconv.FormatBool(value)})
+	return nil
+}
+
+func (h *TestAnsiEventHandler) MouseMode(mode int, enable bool) error {
+	h.recordCall("MouseMode", []string{strconv.Itoa(mode), strconv.FormatBool(enable)})
+	return nil
+}
+
+func (h *TestAnsiEventHandler) BracketedPaste(enable bool) error {
+	h.recordCall("BracketedPaste", []string{strconv.FormatBool(enable)})
+	return nil
+}
+
+func (h *TestAnsiEventHandler) Win32InputMode(enable bool) error {
+	h.recordCall("Win32InputMode", []string{strconv.FormatBool(enable)})
+	return nil
+}
+
+func (h *TestAnsiEventHandler) FocusReporting(enable bool) error {
+	h.recordCall("FocusReporting", []string{strconv.FormatBool(enable)})
+	return nil
+}
+
+func (h *TestAnsiEventHandler) AlternateScreen(mode int, enable bool) error {
+	h.recordCall("AlternateScreen", []string{strconv.Itoa(mode), strconv.FormatBool(enable)})
+	return nil
+}
+
+func (h *TestAnsiEventHandler) XTMODKEYS(params []int) error {
+	strings := []string{}
+	for _, v := range params {
+		strings = append(strings, strconv.Itoa(v))
+	}
+
+	h.recordCall("XTMODKEYS", strings)
+	return nil
+}
+
+func (h *TestAnsiEventHandler) KittyKeyboard(op byte, params []int) error {
+	strings := []string{string(op)}
+	for _, v := range params {
+		strings = append(strings, strconv.Itoa(v))
+	}
+
+	h.recordCall("KittyKeyboard", strings)
+	return nil
+}
+
+func (h *TestAnsiEventHandler) DECRQSS(setting string) error {
+	h.recordCall("DECRQSS", []string{setting})
+	return nil
+}
+
+func (h *TestAnsiEventHandler) XTGETTCAP(names []string) error {
+	h.recordCall("XTGETTCAP", names)
+	return nil
+}
+
+func (h *TestAnsiEventHandler) XTWINOPS(params []int) error {
+	strings := []string{}
+	for _, v := range params {
+		strings = append(strings, strconv.Itoa(v))
+	}
+
+	h.recordCall("XTWINOPS", strings)
+	return nil
+}
+
 func (h *TestAnsiEventHandler) RI() error {
 	h.recordCall("RI", nil)
 	return nil
 }
+
+func (h *TestAnsiEventHandler) RIS() error {
+	h.recordCall("RIS", nil)
+	return nil
+}
+
+func (h *TestAnsiEventHandler) DECSTR() error {
+	h.recordCall("DECSTR", nil)
+	return nil
+}
+
+func (h *TestAnsiEventHandler) XTPUSHSGR(params []int) error {
+	strings := []string{}
+	for _, v := range params {
+		strings = append(strings, strconv.Itoa(v))
+	}
+	h.recordCall("XTPUSHSGR", strings)
+	return nil
+}
+
+func (h *TestAnsiEventHandler) XTPOPSGR() error {
+	h.recordCall("XTPOPSGR", nil)
+	return nil
+}
+
+func (h *TestAnsiEventHandler) DECSC() error {
+	h.recordCall("DECSC", nil)
+	return nil
+}
+
+func (h *TestAnsiEventHandler) DECRC() error {
+	h.recordCall("DECRC", nil)
+	return nil
+}
+
+func (h *TestAnsiEventHandler) DECALN() error {
+	h.recordCall("DECALN", nil)
+	return nil
+}
+
+func (h *TestAnsiEventHandler) SCS(set int, charset byte) error {
+	h.recordCall("SCS", []string{strconv.Itoa(set), string(charset)})
+	return nil
+}
+
+func (h *TestAnsiEventHandler) OSC(command int, text string) error {
+	h.recordCall("OSC", []string{strconv.Itoa(command), text})
+	return nil
+}
+
+func (h *TestAnsiEventHandler) Flush() error {
+	return nil
+}
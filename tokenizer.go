@@ -0,0 +1,227 @@
+package ansiterm
+
+import (
+	"bufio"
+	"io"
+)
+
+// TokenKind classifies the tokens a Tokenizer returns.
+type TokenKind int
+
+const (
+	TokenText    TokenKind = iota // A run of printable characters
+	TokenControl                  // A C0 control character or DEL, in Final
+	TokenEscape                   // ESC Intermediates Final
+	TokenCSI                      // ESC [ Private Params Intermediates Final
+	TokenOSC                      // ESC ] Data, ended by ST or BEL
+	TokenDCS                      // ESC P Private Params Intermediates Final Data ST
+	TokenString                   // SOS, PM, or APC Data ST; Final is X, ^, or _
+)
+
+// Token is an escape sequence, control character, or run of text.
+type Token struct {
+	Kind          TokenKind
+	Raw           []byte // The bytes the token was read from
+	Private       byte   // A leading <, =, >, or ? of the parameters, or 0
+	Params        string
+	Intermediates string
+	Final         byte
+	Data          string // The string of OSC, DCS, SOS, PM, and APC
+}
+
+// Tokenizer splits a stream of Ansi output into tokens without interpreting
+// them, so that unknown sequences, which the parser drops, are seen too.
+//
+// Text is taken to be UTF-8, and C1 controls are recognized in their
+// 7-bit form, ESC followed by 0x40-0x5F. Sequences interrupted by CAN or
+// SUB are dropped, as terminals do, and ESC starts a new one. C0 controls
+// embedded in a sequence, which terminals execute as they arrive, are
+// returned after it.
+type Tokenizer struct {
+	r       *bufio.Reader
+	raw     []byte
+	pending []Token
+}
+
+// NewTokenizer returns a Tokenizer reading from r.
+func NewTokenizer(r io.Reader) *Tokenizer {
+	return &Tokenizer{r: bufio.NewReader(r)}
+}
+
+// Next returns the next token, or io.EOF after the last. A sequence cut
+// short by the end of the input returns io.ErrUnexpectedEOF.
+func (t *Tokenizer) Next() (Token, error) {
+	if len(t.pending) > 0 {
+		token := t.pending[0]
+		t.pending = t.pending[1:]
+		return token, nil
+	}
+
+	b, err := t.r.ReadByte()
+	if err != nil {
+		return Token{}, err
+	}
+
+	t.raw = []byte{b}
+	switch {
+	case b == ANSI_ESCAPE_PRIMARY:
+		return t.escape()
+	case isControl(b):
+		return t.token(Token{Kind: TokenControl, Final: b}), nil
+	}
+
+	for {
+		b, err := t.r.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Token{}, err
+		}
+		if isControl(b) {
+			t.r.UnreadByte()
+			break
+		}
+		t.raw = append(t.raw, b)
+	}
+	return t.token(Token{Kind: TokenText}), nil
+}
+
+func (t *Tokenizer) escape() (Token, error) {
+	token := Token{Kind: TokenEscape}
+	for {
+		b, err := t.next()
+		if err != nil {
+			return Token{}, err
+		}
+
+		switch {
+		case b == ANSI_ESCAPE_PRIMARY:
+			t.raw = []byte{b}
+			token = Token{Kind: TokenEscape}
+		case b == 0x18 || b == 0x1A:
+			return t.abort(b), nil
+		case isControl(b):
+			t.pend(b)
+		case 0x20 <= b && b <= 0x2F:
+			token.Intermediates += string(b)
+		case token.Intermediates != "":
+			token.Final = b
+			return t.token(token), nil
+		case b == ANSI_ESCAPE_SECONDARY:
+			return t.control(TokenCSI)
+		case b == ANSI_CMD_OSC:
+			return t.str(Token{Kind: TokenOSC})
+		case b == 'P':
+			return t.control(TokenDCS)
+		case b == 'X' || b == '^' || b == '_':
+			return t.str(Token{Kind: TokenString, Final: b})
+		default:
+			token.Final = b
+			return t.token(token), nil
+		}
+	}
+}
+
+// control reads the parameters, intermediates, and final character of a
+// control sequence or device control string.
+func (t *Tokenizer) control(kind TokenKind) (Token, error) {
+	token := Token{Kind: kind}
+	for {
+		b, err := t.next()
+		if err != nil {
+			return Token{}, err
+		}
+
+		switch {
+		case b == ANSI_ESCAPE_PRIMARY:
+			t.raw = []byte{b}
+			return t.escape()
+		case b == 0x18 || b == 0x1A:
+			return t.abort(b), nil
+		case isControl(b):
+			t.pend(b)
+		case 0x3C <= b && b <= 0x3F && token.Params == "" && token.Private == 0:
+			token.Private = b
+		case 0x30 <= b && b <= 0x3F:
+			token.Params += string(b)
+		case 0x20 <= b && b <= 0x2F:
+			token.Intermediates += string(b)
+		case ANSI_COMMAND_FIRST <= b && b <= ANSI_COMMAND_LAST:
+			token.Final = b
+			if kind == TokenDCS {
+				return t.str(token)
+			}
+			return t.token(token), nil
+		}
+	}
+}
+
+// str reads the string of an OSC, DCS, SOS, PM, or APC up to the string
+// terminator, or BEL for OSC.
+func (t *Tokenizer) str(token Token) (Token, error) {
+	start := len(t.raw)
+	for {
+		b, err := t.next()
+		if err != nil {
+			return Token{}, err
+		}
+
+		switch {
+		case b == ANSI_BEL && token.Kind == TokenOSC:
+			token.Data = string(t.raw[start : len(t.raw)-1])
+			return t.token(token), nil
+		case b == 0x18 || b == 0x1A:
+			return t.abort(b), nil
+		case b == ANSI_ESCAPE_PRIMARY:
+			next, err := t.next()
+			if err != nil {
+				return Token{}, err
+			}
+			if next != ANSI_CMD_STR_TERM {
+				t.r.UnreadByte()
+				t.raw = []byte{b}
+				return t.escape()
+			}
+			token.Data = string(t.raw[start : len(t.raw)-2])
+			return t.token(token), nil
+		}
+	}
+}
+
+// next returns the next byte of a sequence, adding it to the raw bytes.
+func (t *Tokenizer) next() (byte, error) {
+	b, err := t.r.ReadByte()
+	if err == io.EOF {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return 0, err
+	}
+	t.raw = append(t.raw, b)
+	return b, nil
+}
+
+// pend queues a control character embedded in a sequence.
+func (t *Tokenizer) pend(b byte) {
+	t.raw = t.raw[:len(t.raw)-1]
+	t.pending = append(t.pending, Token{Kind: TokenControl, Raw: []byte{b}, Final: b})
+}
+
+// abort drops the sequence being read, returning the CAN or SUB that
+// interrupted it.
+func (t *Tokenizer) abort(b byte) Token {
+	t.raw = []byte{b}
+	return t.token(Token{Kind: TokenControl, Final: b})
+}
+
+func (t *Tokenizer) token(token Token) Token {
+	token.Raw = t.raw
+	t.raw = nil
+	return token
+}
+
+// isControl reports whether b is a C0 control character or DEL.
+func isControl(b byte) bool {
+	return b < 0x20 || b == 0x7F
+}
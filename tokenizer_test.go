@@ -0,0 +1,73 @@
+package ansiterm
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func tokenizeHelper(t *testing.T, input string, expected []Token) {
+	tokenizer := NewTokenizer(strings.NewReader(input))
+
+	var actual []Token
+	for {
+		token, err := tokenizer.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Errorf("%q: %v", input, err)
+			return
+		}
+		actual = append(actual, token)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%q: tokenized to %+v, expected %+v", input, actual, expected)
+	}
+}
+
+func TestTokenizer(t *testing.T) {
+	tokenizeHelper(t, "plain 日本\r\n", []Token{
+		{Kind: TokenText, Raw: []byte("plain 日本")},
+		{Kind: TokenControl, Raw: []byte("\r"), Final: '\r'},
+		{Kind: TokenControl, Raw: []byte("\n"), Final: '\n'},
+	})
+	tokenizeHelper(t, "\x1b[1;31mred\x1b[?1049h", []Token{
+		{Kind: TokenCSI, Raw: []byte("\x1b[1;31m"), Params: "1;31", Final: 'm'},
+		{Kind: TokenText, Raw: []byte("red")},
+		{Kind: TokenCSI, Raw: []byte("\x1b[?1049h"), Private: '?', Params: "1049", Final: 'h'},
+	})
+	tokenizeHelper(t, "\x1b7\x1b(0\x1b[2 q", []Token{
+		{Kind: TokenEscape, Raw: []byte("\x1b7"), Final: '7'},
+		{Kind: TokenEscape, Raw: []byte("\x1b(0"), Intermediates: "(", Final: '0'},
+		{Kind: TokenCSI, Raw: []byte("\x1b[2 q"), Params: "2", Intermediates: " ", Final: 'q'},
+	})
+	tokenizeHelper(t, "\x1b]0;title\x07\x1b]8;;http://x\x1b\\", []Token{
+		{Kind: TokenOSC, Raw: []byte("\x1b]0;title\x07"), Data: "0;title"},
+		{Kind: TokenOSC, Raw: []byte("\x1b]8;;http://x\x1b\\"), Data: "8;;http://x"},
+	})
+	tokenizeHelper(t, "\x1bP$qm\x1b\\\x1b_apc\x1b\\", []Token{
+		{Kind: TokenDCS, Raw: []byte("\x1bP$qm\x1b\\"), Intermediates: "$", Final: 'q', Data: "m"},
+		{Kind: TokenString, Raw: []byte("\x1b_apc\x1b\\"), Final: '_', Data: "apc"},
+	})
+
+	// Embedded controls follow the sequence, and CAN drops it
+	tokenizeHelper(t, "\x1b[1\r2H\x1b[3\x18x", []Token{
+		{Kind: TokenCSI, Raw: []byte("\x1b[12H"), Params: "12", Final: 'H'},
+		{Kind: TokenControl, Raw: []byte("\r"), Final: '\r'},
+		{Kind: TokenControl, Raw: []byte("\x18"), Final: 0x18},
+		{Kind: TokenText, Raw: []byte("x")},
+	})
+
+	// ESC interrupts a string to start a new sequence
+	tokenizeHelper(t, "\x1b]2;abc\x1b[A", []Token{
+		{Kind: TokenCSI, Raw: []byte("\x1b[A"), Final: 'A'},
+	})
+
+	tokenizer := NewTokenizer(strings.NewReader("\x1b[1"))
+	if _, err := tokenizer.Next(); err != io.ErrUnexpectedEOF {
+		t.Errorf("truncated sequence returned %v", err)
+	}
+}
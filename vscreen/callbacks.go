@@ -0,0 +1,97 @@
+package vscreen
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// The callbacks below report events a Screen does not display itself, so an
+// embedding UI can surface them. They are called during Write, on the
+// goroutine writing to the screen.
+
+// WithBellCallback calls ring on BEL.
+func WithBellCallback(ring func()) ScreenOption {
+	return func(s *Screen) {
+		s.onBell = ring
+	}
+}
+
+// WithTitleCallback calls setTitle with the window title set by OSC 0 or 2.
+func WithTitleCallback(setTitle func(title string)) ScreenOption {
+	return func(s *Screen) {
+		s.onTitle = setTitle
+	}
+}
+
+// WithClipboardCallback calls copy with data written to the clipboard by
+// OSC 52, and the selections it is written to: any of c (clipboard), p
+// (primary), q (secondary), s (select) and 0-7 (cut buffers), by default
+// "s0". Requests to read the clipboard are ignored.
+func WithClipboardCallback(copy func(selections string, data []byte)) ScreenOption {
+	return func(s *Screen) {
+		s.onClipboard = copy
+	}
+}
+
+// WithNotificationCallback calls notify with a desktop notification sent by
+// OSC 9 ; body (iTerm2) or OSC 777 ; notify ; title ; body (urxvt).
+func WithNotificationCallback(notify func(title string, body string)) ScreenOption {
+	return func(s *Screen) {
+		s.onNotification = notify
+	}
+}
+
+// WithHyperlinkCallback calls linked when a hyperlink started by OSC 8 ends,
+// with the cells printed while it was open, a rectangle per run along a line,
+// for a UI to make them activate the link. The cells are where they were
+// printed; scrolling since moves them.
+func WithHyperlinkCallback(linked func(link Hyperlink, region []Rect)) ScreenOption {
+	return func(s *Screen) {
+		s.onHyperlink = linked
+	}
+}
+
+// clipboard handles OSC 52 ; selections ; base64 data.
+func (s *Screen) clipboard(text string) {
+	params := strings.SplitN(text, ";", 2)
+	if s.onClipboard == nil || len(params) < 2 || params[1] == "?" {
+		return
+	}
+
+	data, err := base64.StdEncoding.DecodeString(params[1])
+	if err != nil {
+		return
+	}
+
+	selections := params[0]
+	if selections == "" {
+		selections = "s0"
+	}
+	s.onClipboard(selections, data)
+}
+
+// linkPrinted adds cells printed from left to right of row y to the region
+// of the open hyperlink.
+func (s *Screen) linkPrinted(left int, right int, y int) {
+	if s.onHyperlink == nil || s.link == (Hyperlink{}) {
+		return
+	}
+
+	if n := len(s.linkRegion); n > 0 {
+		last := &s.linkRegion[n-1]
+		if last.Top == y && left == last.Right+1 {
+			last.Right = right
+			return
+		}
+	}
+	s.linkRegion = append(s.linkRegion, Rect{Left: left, Top: y, Right: right, Bottom: y})
+}
+
+// endLink closes the open hyperlink, reporting the cells printed in it.
+func (s *Screen) endLink() {
+	if s.onHyperlink != nil && len(s.linkRegion) > 0 {
+		s.onHyperlink(s.link, s.linkRegion)
+	}
+	s.link = Hyperlink{}
+	s.linkRegion = nil
+}
@@ -0,0 +1,65 @@
+package vscreen
+
+// Rect is a rectangle of cells, with inclusive, zero-based bounds.
+type Rect struct {
+	Left, Top, Right, Bottom int
+}
+
+// span is the range of columns changed in a row; it is empty if right < left.
+type span struct {
+	left, right int
+}
+
+var noDamage = span{left: 0, right: -1}
+
+// Damage returns the regions of the screen whose cells have changed since
+// the screen was created or last synced with Sync, so a renderer need only
+// redraw those. Adjacent rows changed over the same columns are combined
+// into a rectangle. Cursor movement alone causes no damage.
+func (s *Screen) Damage() []Rect {
+	var rects []Rect
+	for y, dirty := range s.dirty {
+		if dirty.right < dirty.left {
+			continue
+		}
+
+		if n := len(rects); n > 0 {
+			last := &rects[n-1]
+			if last.Bottom == y-1 && last.Left == dirty.left && last.Right == dirty.right {
+				last.Bottom = y
+				continue
+			}
+		}
+
+		rects = append(rects, Rect{Left: dirty.left, Top: y, Right: dirty.right, Bottom: y})
+	}
+
+	return rects
+}
+
+// Sync marks the screen as rendered, clearing its damage.
+func (s *Screen) Sync() {
+	for y := range s.dirty {
+		s.dirty[y] = noDamage
+	}
+}
+
+// damage records a change to the cells within the inclusive rectangle.
+func (s *Screen) damage(left int, top int, right int, bottom int) {
+	left = clamp(left, 0, s.cols-1)
+	right = clamp(right, 0, s.cols-1)
+	for y := clamp(top, 0, s.rows-1); y <= bottom && y < s.rows; y++ {
+		dirty := &s.dirty[y]
+		if dirty.right < dirty.left {
+			*dirty = span{left: left, right: right}
+			continue
+		}
+
+		if left < dirty.left {
+			dirty.left = left
+		}
+		if right > dirty.right {
+			dirty.right = right
+		}
+	}
+}
@@ -0,0 +1,749 @@
+package vscreen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	. "github.com/Azure/go-ansiterm"
+)
+
+func (s *Screen) Print(b byte) error {
+	s.utf8Buffer = append(s.utf8Buffer, b)
+	for len(s.utf8Buffer) != 0 && utf8.FullRune(s.utf8Buffer) {
+		// Invalid sequences decode a byte at a time to the replacement
+		// character
+		r, size := utf8.DecodeRune(s.utf8Buffer)
+		s.utf8Buffer = s.utf8Buffer[size:]
+		s.printRune(r)
+	}
+
+	return nil
+}
+
+// flushUTF8 prints an incomplete UTF-8 sequence as the replacement character
+// when a control function interrupts it, so that its bytes are not joined to
+// a later character.
+func (s *Screen) flushUTF8() {
+	if len(s.utf8Buffer) != 0 {
+		s.utf8Buffer = s.utf8Buffer[:0]
+		s.printRune(utf8.RuneError)
+	}
+}
+
+func (s *Screen) Execute(b byte) error {
+	s.flushUTF8()
+	switch b {
+	case ANSI_SHIFT_OUT:
+		s.gl = 1
+	case ANSI_SHIFT_IN:
+		s.gl = 0
+	case 0x08:
+		if s.x > s.leftLimit() {
+			s.moveTo(s.x-1, s.y)
+		}
+		s.pendingWrap = false
+	case ANSI_TAB:
+		return s.CHT(1)
+	case ANSI_LINE_FEED, 0x0B, 0x0C:
+		s.index()
+		s.pendingWrap = false
+	case ANSI_CARRIAGE_RETURN:
+		s.moveTo(s.leftLimit(), s.y)
+	case ANSI_BEL:
+		if s.onBell != nil {
+			s.onBell()
+		}
+	}
+
+	return nil
+}
+
+func (s *Screen) CUU(param int) error {
+	s.flushUTF8()
+	s.moveVertically(-atLeastOne(param))
+	return nil
+}
+
+func (s *Screen) CUD(param int) error {
+	s.flushUTF8()
+	s.moveVertically(atLeastOne(param))
+	return nil
+}
+
+func (s *Screen) CUF(param int) error {
+	s.flushUTF8()
+	s.moveHorizontally(atLeastOne(param))
+	return nil
+}
+
+func (s *Screen) CUB(param int) error {
+	s.flushUTF8()
+	s.moveHorizontally(-atLeastOne(param))
+	return nil
+}
+
+func (s *Screen) CNL(param int) error {
+	s.flushUTF8()
+	s.moveVertically(atLeastOne(param))
+	s.moveTo(s.leftLimit(), s.y)
+	return nil
+}
+
+func (s *Screen) CPL(param int) error {
+	s.flushUTF8()
+	s.moveVertically(-atLeastOne(param))
+	s.moveTo(s.leftLimit(), s.y)
+	return nil
+}
+
+func (s *Screen) CHA(param int) error {
+	s.flushUTF8()
+	s.moveTo(s.column(param), s.y)
+	return nil
+}
+
+func (s *Screen) CUP(row int, col int) error {
+	s.flushUTF8()
+	s.moveTo(s.column(col), s.row(row))
+	return nil
+}
+
+func (s *Screen) HVP(row int, col int) error {
+	s.flushUTF8()
+	return s.CUP(row, col)
+}
+
+func (s *Screen) VPA(param int) error {
+	s.flushUTF8()
+	s.moveTo(s.x, s.row(param))
+	return nil
+}
+
+// row and column convert one-based positions to screen positions, relative
+// to the margins and confined to them in origin mode.
+func (s *Screen) row(param int) int {
+	y := s.originTop() + atLeastOne(param) - 1
+	if s.modes.Origin {
+		y = clamp(y, s.top, s.bottom)
+	}
+	return y
+}
+
+func (s *Screen) column(param int) int {
+	x := s.originLeft() + atLeastOne(param) - 1
+	if s.modes.Origin {
+		x = clamp(x, s.left, s.right)
+	}
+	return x
+}
+
+func (s *Screen) CHT(param int) error {
+	s.flushUTF8()
+	right := s.rightLimit()
+	x := s.x
+	for i := 0; i < atLeastOne(param) && x < right; i++ {
+		x++
+		for x < right && !s.tabStops[x] {
+			x++
+		}
+	}
+
+	s.moveTo(x, s.y)
+	return nil
+}
+
+func (s *Screen) CBT(param int) error {
+	s.flushUTF8()
+	left := s.leftLimit()
+	x := s.x
+	for i := 0; i < atLeastOne(param) && x > left; i++ {
+		x--
+		for x > left && !s.tabStops[x] {
+			x--
+		}
+	}
+
+	s.moveTo(x, s.y)
+	return nil
+}
+
+func (s *Screen) HTS() error {
+	s.flushUTF8()
+	s.tabStops[s.x] = true
+	return nil
+}
+
+func (s *Screen) TBC(param int) error {
+	s.flushUTF8()
+	switch param {
+	case 0:
+		s.tabStops[s.x] = false
+	case 3:
+		for x := range s.tabStops {
+			s.tabStops[x] = false
+		}
+	}
+
+	return nil
+}
+
+func (s *Screen) DECTCEM(visible bool) error {
+	s.flushUTF8()
+	s.modes.CursorVisible = visible
+	return nil
+}
+
+func (s *Screen) DECSCUSR(style int) error {
+	s.flushUTF8()
+	s.modes.CursorStyle = style
+	return nil
+}
+
+func (s *Screen) SynchronizedUpdate(enable bool) error {
+	s.flushUTF8()
+	s.modes.SynchronizedUpdate = enable
+	return nil
+}
+
+func (s *Screen) IRM(insert bool) error {
+	s.flushUTF8()
+	s.modes.Insert = insert
+	return nil
+}
+
+func (s *Screen) DECOM(enable bool) error {
+	s.flushUTF8()
+	s.modes.Origin = enable
+	s.home()
+	return nil
+}
+
+func (s *Screen) DECAWM(enable bool) error {
+	s.flushUTF8()
+	s.modes.Autowrap = enable
+	s.pendingWrap = false
+	return nil
+}
+
+func (s *Screen) ED(param int) error {
+	s.flushUTF8()
+	// [J  -- Erases from the cursor to the end of the screen, including the cursor position.
+	// [1J -- Erases from the beginning of the screen to the cursor, including the cursor position.
+	// [2J -- Erases the complete display.
+	// [3J -- Erases the scrollback, of which there is none.
+	switch param {
+	case 0:
+		s.erase(s.x, s.y, s.cols-1, s.y)
+		if s.y+1 < s.rows {
+			s.erase(0, s.y+1, s.cols-1, s.rows-1)
+		}
+	case 1:
+		if s.y > 0 {
+			s.erase(0, 0, s.cols-1, s.y-1)
+		}
+		s.erase(0, s.y, s.x, s.y)
+	case 2:
+		s.erase(0, 0, s.cols-1, s.rows-1)
+	}
+
+	s.pendingWrap = false
+	return nil
+}
+
+func (s *Screen) EL(param int) error {
+	s.flushUTF8()
+	// [K  -- Erases from the cursor to the end of the line, including the cursor position.
+	// [1K -- Erases from the beginning of the line to the cursor, including the cursor position.
+	// [2K -- Erases the complete line.
+	switch param {
+	case 0:
+		s.erase(s.x, s.y, s.cols-1, s.y)
+	case 1:
+		s.erase(0, s.y, s.x, s.y)
+	case 2:
+		s.erase(0, s.y, s.cols-1, s.y)
+	}
+
+	s.pendingWrap = false
+	return nil
+}
+
+func (s *Screen) IL(param int) error {
+	s.flushUTF8()
+	if s.y < s.top || s.y > s.bottom || s.x < s.left || s.x > s.right {
+		return nil
+	}
+
+	s.shiftLines(s.y, s.bottom, atLeastOne(param))
+	s.moveTo(s.left, s.y)
+	return nil
+}
+
+func (s *Screen) DL(param int) error {
+	s.flushUTF8()
+	if s.y < s.top || s.y > s.bottom || s.x < s.left || s.x > s.right {
+		return nil
+	}
+
+	s.shiftLines(s.y, s.bottom, -atLeastOne(param))
+	s.moveTo(s.left, s.y)
+	return nil
+}
+
+func (s *Screen) SGR(params []int) error {
+	s.flushUTF8()
+	s.pen = applySGR(s.pen, params)
+	return nil
+}
+
+func (s *Screen) REP(param int) error {
+	s.flushUTF8()
+	if s.last == 0 {
+		return nil
+	}
+
+	// Repeats beyond the cells left before the bottom margin only scroll
+	// the same character through it
+	bottom := s.bottom
+	if s.y > bottom {
+		bottom = s.rows - 1
+	}
+	n := atLeastOne(param)
+	if cells := (bottom-s.y)*s.cols + s.cols - s.x; n > cells {
+		n = cells
+	}
+
+	for i := 0; i < n; i++ {
+		s.printRune(s.last)
+	}
+	return nil
+}
+
+func (s *Screen) SU(param int) error {
+	s.flushUTF8()
+	s.scrollUp(atLeastOne(param))
+	return nil
+}
+
+func (s *Screen) SD(param int) error {
+	s.flushUTF8()
+	s.scrollDown(atLeastOne(param))
+	return nil
+}
+
+func (s *Screen) DA(params []string) error {
+	s.flushUTF8()
+	if len(params) > 0 && len(params[0]) > 0 && params[0][0] == '>' {
+		// Secondary device attributes: a VT220, version 1.0
+		return s.respond("\x1b[>1;10;0c")
+	}
+
+	// Primary device attributes: a VT220 with 132 columns, printer port,
+	// selective erase, DRCS, UDK, and national replacement character sets
+	return s.respond("\x1b[?62;1;2;6;7;8;9c")
+}
+
+func (s *Screen) DSR(param int) error {
+	s.flushUTF8()
+	switch param {
+	case 5:
+		return s.respond("\x1b[0n")
+	case 6:
+		return s.respond(fmt.Sprintf("\x1b[%d;%dR", s.y-s.originTop()+1, s.x-s.originLeft()+1))
+	}
+
+	return nil
+}
+
+func (s *Screen) DECCKM(enable bool) error {
+	s.flushUTF8()
+	s.modes.ApplicationCursorKeys = enable
+	return nil
+}
+
+func (s *Screen) DECKPAM(enable bool) error {
+	s.flushUTF8()
+	s.modes.ApplicationKeypad = enable
+	return nil
+}
+
+func (s *Screen) MouseMode(mode int, enable bool) error {
+	s.flushUTF8()
+	switch {
+	case mode == 1006:
+		s.modes.SGRMouse = enable
+	case enable:
+		s.modes.MouseTracking = mode
+	case s.modes.MouseTracking == mode:
+		s.modes.MouseTracking = 0
+	}
+
+	return nil
+}
+
+func (s *Screen) BracketedPaste(enable bool) error {
+	s.flushUTF8()
+	s.modes.BracketedPaste = enable
+	return nil
+}
+
+func (s *Screen) Win32InputMode(enable bool) error {
+	s.flushUTF8()
+	s.modes.Win32Input = enable
+	return nil
+}
+
+func (s *Screen) FocusReporting(enable bool) error {
+	s.flushUTF8()
+	s.modes.FocusReporting = enable
+	return nil
+}
+
+func (s *Screen) AlternateScreen(mode int, enable bool) error {
+	s.flushUTF8()
+	if enable == s.modes.AlternateScreen {
+		return nil
+	}
+
+	// As in xterm, 1049 saves the cursor and clears the alternate screen on
+	// entering it, restoring the cursor on leaving; 1047 clears it on
+	// leaving; 47 only switches.
+	switch {
+	case enable && mode == 1049:
+		s.DECSC()
+		s.switchBuffer()
+		s.eraseRect(0, 0, s.cols-1, s.rows-1)
+	case !enable && mode == 1049:
+		s.switchBuffer()
+		s.DECRC()
+	case !enable && mode == 1047:
+		s.eraseRect(0, 0, s.cols-1, s.rows-1)
+		s.switchBuffer()
+	default:
+		s.switchBuffer()
+	}
+
+	return nil
+}
+
+func (s *Screen) XTMODKEYS(params []int) error {
+	s.flushUTF8()
+	if params[0] == 4 {
+		s.modes.ModifyOtherKeys = params[1]
+	}
+	return nil
+}
+
+func (s *Screen) KittyKeyboard(op byte, params []int) error {
+	s.flushUTF8()
+	// The flags in effect are the top of a stack
+	switch op {
+	case '>':
+		s.kittyStack = append(s.kittyStack, params[0])
+	case '<':
+		n := atLeastOne(params[0])
+		if n > len(s.kittyStack) {
+			n = len(s.kittyStack)
+		}
+		s.kittyStack = s.kittyStack[:len(s.kittyStack)-n]
+	case '=':
+		if len(s.kittyStack) == 0 {
+			s.kittyStack = []int{0}
+		}
+
+		// The second parameter selects whether to set (1), add (2), or
+		// remove (3) the flags
+		current := &s.kittyStack[len(s.kittyStack)-1]
+		switch params[1] {
+		case 2:
+			*current |= params[0]
+		case 3:
+			*current &^= params[0]
+		default:
+			*current = params[0]
+		}
+	case '?':
+		return s.respond(fmt.Sprintf("\x1b[?%du", s.modes.KittyFlags))
+	}
+
+	s.modes.KittyFlags = 0
+	if len(s.kittyStack) > 0 {
+		s.modes.KittyFlags = s.kittyStack[len(s.kittyStack)-1]
+	}
+	return nil
+}
+
+func (s *Screen) DECSTBM(top int, bottom int) error {
+	s.flushUTF8()
+	if top < 1 {
+		top = 1
+	}
+	if bottom < 1 || bottom > s.rows {
+		bottom = s.rows
+	}
+	if top >= bottom {
+		return nil
+	}
+
+	s.top, s.bottom = top-1, bottom-1
+	s.home()
+	return nil
+}
+
+func (s *Screen) DECLRMM(enable bool) error {
+	s.flushUTF8()
+	s.modes.LeftRightMargins = enable
+	if !enable {
+		s.left, s.right = 0, s.cols-1
+	}
+	return nil
+}
+
+func (s *Screen) DECSLRM(left int, right int) error {
+	s.flushUTF8()
+	if !s.modes.LeftRightMargins {
+		return nil
+	}
+
+	if left < 1 {
+		left = 1
+	}
+	if right < 1 || right > s.cols {
+		right = s.cols
+	}
+	if left >= right {
+		return nil
+	}
+
+	s.left, s.right = left-1, right-1
+	s.home()
+	return nil
+}
+
+func (s *Screen) DECRQSS(setting string) error {
+	s.flushUTF8()
+	// See http://vt100.net/docs/vt510-rm/DECRQSS
+	switch setting {
+	case "r":
+		return s.respond(fmt.Sprintf("\x1bP1$r%d;%dr\x1b\\", s.top+1, s.bottom+1))
+	case "s":
+		return s.respond(fmt.Sprintf("\x1bP1$r%d;%ds\x1b\\", s.left+1, s.right+1))
+	case "m":
+		return s.respond(fmt.Sprintf("\x1bP1$r%sm\x1b\\", sgrString(s.pen)))
+	}
+
+	return s.respond("\x1bP0$r\x1b\\")
+}
+
+// termcap is the capabilities reported by XTGETTCAP.
+var termcap = map[string]string{
+	"Co":     "256",
+	"colors": "256",
+	"RGB":    "",
+	"Tc":     "",
+	"TN":     "xterm-256color",
+	"name":   "xterm-256color",
+}
+
+func (s *Screen) XTGETTCAP(names []string) error {
+	s.flushUTF8()
+	// See http://invisible-island.net/xterm/ctlseqs/ctlseqs.html#h3-Device-Control-functions
+	// 256 colors and 24-bit colors, which are kept, are reported
+	for _, name := range names {
+		if err := s.respond(TermcapReply(termcap, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Screen) XTWINOPS(params []int) error {
+	s.flushUTF8()
+	// [8;r;ct -- Resizes the screen to r rows and c columns (0 keeps the current size).
+	// [18t    -- Reports the size in characters as CSI 8 ; rows ; columns t.
+	// [19t    -- Reports the largest possible size, the same, as CSI 9 ; rows ; columns t.
+	// Other operations are ignored.
+	switch params[0] {
+	case 8:
+		rows, cols := params[1], params[2]
+		if rows == 0 {
+			rows = s.rows
+		}
+		if cols == 0 {
+			cols = s.cols
+		}
+		s.Resize(cols, rows)
+	case 18:
+		return s.respond(fmt.Sprintf("\x1b[8;%d;%dt", s.rows, s.cols))
+	case 19:
+		return s.respond(fmt.Sprintf("\x1b[9;%d;%dt", s.rows, s.cols))
+	}
+
+	return nil
+}
+
+func (s *Screen) RI() error {
+	s.flushUTF8()
+	s.reverseIndex()
+	s.pendingWrap = false
+	return nil
+}
+
+func (s *Screen) RIS() error {
+	s.flushUTF8()
+	s.reset()
+	s.record(Operation{Kind: OpErase, Rect: Rect{Right: s.cols - 1, Bottom: s.rows - 1}})
+	return nil
+}
+
+func (s *Screen) DECSTR() error {
+	s.flushUTF8()
+	s.softReset()
+	return nil
+}
+
+func (s *Screen) XTPUSHSGR(params []int) error {
+	s.flushUTF8()
+	if len(s.sgrStack) < maxSGRStack {
+		s.sgrStack = append(s.sgrStack, pushPen(s.pen, params))
+	}
+	return nil
+}
+
+func (s *Screen) XTPOPSGR() error {
+	s.flushUTF8()
+	if len(s.sgrStack) == 0 {
+		return nil
+	}
+
+	s.pen = s.sgrStack[len(s.sgrStack)-1].pop(s.pen)
+	s.sgrStack = s.sgrStack[:len(s.sgrStack)-1]
+	return nil
+}
+
+func (s *Screen) DECSC() error {
+	s.flushUTF8()
+	s.saved = savedCursor{
+		x:           s.x,
+		y:           s.y,
+		pen:         s.pen,
+		originMode:  s.modes.Origin,
+		pendingWrap: s.pendingWrap,
+		charsets:    s.charsets,
+		gl:          s.gl,
+	}
+	return nil
+}
+
+func (s *Screen) DECRC() error {
+	s.flushUTF8()
+	saved := s.saved
+	s.moveTo(saved.x, saved.y)
+	s.pen = saved.pen
+	s.modes.Origin = saved.originMode
+	s.pendingWrap = saved.pendingWrap
+	s.charsets = saved.charsets
+	s.gl = saved.gl
+	return nil
+}
+
+func (s *Screen) DECALN() error {
+	s.flushUTF8()
+	for y := range s.cells {
+		row := s.writableRow(y)
+		for x := range row {
+			row[x] = Cell{Rune: 'E', Width: 1}
+		}
+	}
+	s.damage(0, 0, s.cols-1, s.rows-1)
+	s.record(Operation{Kind: OpFill, Rect: Rect{Right: s.cols - 1, Bottom: s.rows - 1}, Text: "E"})
+
+	// DECALN also resets the margins and homes the cursor
+	s.top, s.bottom = 0, s.rows-1
+	s.left, s.right = 0, s.cols-1
+	s.modes.Origin = false
+	s.moveTo(0, 0)
+	return nil
+}
+
+func (s *Screen) SCS(set int, charset byte) error {
+	s.flushUTF8()
+	if set < 0 || len(s.charsets) <= set {
+		return nil
+	}
+
+	s.charsets[set] = charset
+	return nil
+}
+
+func (s *Screen) OSC(command int, text string) error {
+	s.flushUTF8()
+	switch command {
+	case 0, 2:
+		if s.onTitle != nil {
+			s.onTitle(text)
+		}
+	case 8:
+		// OSC 8 ; params ; URI, where params are key=value pairs separated by
+		// colons; an empty URI closes the link
+		s.endLink()
+		params := strings.SplitN(text, ";", 2)
+		if len(params) < 2 || params[1] == "" {
+			return nil
+		}
+
+		s.link = Hyperlink{URI: params[1]}
+		for _, param := range strings.Split(params[0], ":") {
+			if strings.HasPrefix(param, "id=") {
+				s.link.ID = param[len("id="):]
+			}
+		}
+	case 133:
+		// OSC 133 ; A, B, C or D marks the start of a prompt, the command
+		// input, the command output, and the end of the command
+		switch strings.SplitN(text, ";", 2)[0] {
+		case "A":
+			s.zone = ZonePrompt
+		case "B":
+			s.zone = ZoneInput
+		case "C":
+			s.zone = ZoneOutput
+		case "D":
+			s.zone = ZoneNone
+		}
+	case 9:
+		// ConEmu's OSC 9 ; n ; ... commands, such as progress, are not
+		// notifications
+		code := strings.SplitN(text, ";", 2)[0]
+		if _, err := strconv.Atoi(code); err != nil && s.onNotification != nil {
+			s.onNotification("", text)
+		}
+	case 52:
+		s.clipboard(text)
+	case 777:
+		// OSC 777 ; notify ; title ; body
+		params := strings.SplitN(text, ";", 3)
+		if s.onNotification != nil && len(params) == 3 && params[0] == "notify" {
+			s.onNotification(params[1], params[2])
+		}
+	}
+
+	return nil
+}
+
+func (s *Screen) Flush() error {
+	if s.publish {
+		s.latest.Store(s.Snapshot())
+	}
+	return nil
+}
+
+// atLeastOne returns param, or 1 if it is 0, the default of most parameters.
+func atLeastOne(param int) int {
+	if param < 1 {
+		return 1
+	}
+	return param
+}
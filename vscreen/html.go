@@ -0,0 +1,131 @@
+package vscreen
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	. "github.com/Azure/go-ansiterm"
+)
+
+// HTML returns the screen as a <pre> element, styled as HTML does for a
+// Snapshot.
+func (s *Screen) HTML() string {
+	return screenHTML(s.cells)
+}
+
+// HTML returns the screen as a <pre class="vscreen"> element holding its
+// text as String does, with spans carrying inline styles for the colors and
+// attributes of each run of cells in the same rendition, and anchors for
+// OSC 8 hyperlinks. Default colors are
+// left to the page; reversed cells in a default color use ANSI white on
+// black. To capture a whole log, such as a build's output, make the screen
+// tall enough that no line scrolls off it.
+func (snap Snapshot) HTML() string {
+	return screenHTML(snap.cells)
+}
+
+// screenHTML renders the rows of a screen as a <pre> element.
+func screenHTML(cells [][]Cell) string {
+	return `<pre class="vscreen">` + screenText(cells, lineHTML) + "</pre>"
+}
+
+// lineHTML returns the text of a row, escaped for HTML, with spans styling
+// runs of cells not in the default rendition and anchors around linked runs,
+// without trailing blanks in the default rendition.
+func lineHTML(row []Cell) string {
+	end := len(row)
+	for end > 0 && row[end-1].Rune == ' ' && len(row[end-1].Combining) == 0 && cellPen(row[end-1]) == (pen{}) && row[end-1].Link == (Hyperlink{}) {
+		end--
+	}
+
+	var line, run strings.Builder
+	current, link := pen{}, Hyperlink{}
+	flush := func() {
+		text := html.EscapeString(run.String())
+		if current != (pen{}) {
+			text = `<span style="` + penStyle(current) + `">` + text + "</span>"
+		}
+		if link.URI != "" && text != "" {
+			text = `<a href="` + html.EscapeString(link.URI) + `">` + text + "</a>"
+		}
+		line.WriteString(text)
+		run.Reset()
+	}
+
+	for _, cell := range row[:end] {
+		if cell.Width == 0 {
+			continue
+		}
+
+		if p := cellPen(cell); p != current || cell.Link != link {
+			flush()
+			current, link = p, cell.Link
+		}
+		writeCell(&run, cell)
+	}
+
+	flush()
+	return line.String()
+}
+
+// penStyle returns the CSS declarations for a rendition.
+func penStyle(p pen) string {
+	fg, bg := displayColors(p)
+
+	var style []string
+	if fg != DefaultColor {
+		style = append(style, "color:"+cssColor(fg))
+	}
+	if bg != DefaultColor {
+		style = append(style, "background-color:"+cssColor(bg))
+	}
+	if p.attrs&AttrBold != 0 {
+		style = append(style, "font-weight:bold")
+	}
+	if p.attrs&AttrFaint != 0 {
+		style = append(style, "opacity:0.5")
+	}
+	if p.attrs&AttrItalic != 0 {
+		style = append(style, "font-style:italic")
+	}
+
+	var decorations []string
+	if p.attrs&AttrUnderline != 0 {
+		decorations = append(decorations, "underline")
+	}
+	if p.attrs&AttrStrikethrough != 0 {
+		decorations = append(decorations, "line-through")
+	}
+	if len(decorations) > 0 {
+		style = append(style, "text-decoration:"+strings.Join(decorations, " "))
+	}
+
+	if p.attrs&AttrInvisible != 0 {
+		style = append(style, "visibility:hidden")
+	}
+
+	return strings.Join(style, ";")
+}
+
+// displayColors returns the foreground and background a rendition displays,
+// swapped by AttrReverse. Reversed default colors become ANSI white on black.
+func displayColors(p pen) (fg Color, bg Color) {
+	fg, bg = p.fg, p.bg
+	if p.attrs&AttrReverse != 0 {
+		if fg == DefaultColor {
+			fg = BasicColor(7)
+		}
+		if bg == DefaultColor {
+			bg = BasicColor(0)
+		}
+		fg, bg = bg, fg
+	}
+	return fg, bg
+}
+
+// cssColor returns the CSS color for a color other than the default.
+func cssColor(c Color) string {
+	r, g, b, _ := c.Components()
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
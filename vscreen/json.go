@@ -0,0 +1,411 @@
+package vscreen
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	. "github.com/Azure/go-ansiterm"
+)
+
+// stateVersion is the version of the JSON encoding of screens. Fields are
+// only ever added to it, as optional ones; it changes if an existing field
+// changes meaning.
+const stateVersion = 1
+
+// stateJSON is the JSON encoding of a screen's state. Snapshots encode the
+// fields they hold; screens encode all of them.
+type stateJSON struct {
+	Version     int          `json:"version"`
+	Cols        int          `json:"cols"`
+	Rows        int          `json:"rows"`
+	Lines       [][]cellJSON `json:"lines"`
+	Wrapped     []int        `json:"wrapped,omitempty"` // Rows autowrapped onto the next
+	Cursor      [2]int       `json:"cursor"`
+	PendingWrap bool         `json:"pendingWrap,omitempty"`
+	Margins     [4]int       `json:"margins"` // Top, bottom, left and right
+	Pen         penJSON      `json:"pen"`
+	Modes       Modes        `json:"modes"`
+	Charsets    string       `json:"charsets"` // G0-G3
+	GL          int          `json:"gl,omitempty"`
+
+	TabStops   *[]int       `json:"tabStops,omitempty"`
+	Saved      *savedJSON   `json:"saved,omitempty"`
+	KittyStack []int        `json:"kittyStack,omitempty"`
+	SGRStack   []pushedJSON `json:"sgrStack,omitempty"`
+	Link       *Hyperlink   `json:"link,omitempty"`
+	Zone       Zone         `json:"zone,omitempty"`
+	Last       string       `json:"last,omitempty"`
+	Main       *mainJSON    `json:"main,omitempty"` // While the alternate screen is displayed
+}
+
+// cellJSON encodes a cell, or a wide character and the cell it extends into.
+// Erased cells in the default rendition encode as {}.
+type cellJSON struct {
+	Text  string `json:"t,omitempty"` // The character and its combining characters; a space if empty
+	Wide  bool   `json:"w,omitempty"`
+	Fg    Color  `json:"fg,omitempty"`
+	Bg    Color  `json:"bg,omitempty"`
+	Attrs Attr   `json:"a,omitempty"` // The bits of the Attr constants
+	URI   string `json:"href,omitempty"`
+	ID    string `json:"id,omitempty"`
+	Zone  Zone   `json:"z,omitempty"`
+}
+
+type penJSON struct {
+	Fg    Color `json:"fg,omitempty"`
+	Bg    Color `json:"bg,omitempty"`
+	Attrs Attr  `json:"a,omitempty"`
+}
+
+// mainJSON encodes the main screen while the alternate screen is displayed.
+type mainJSON struct {
+	Lines   [][]cellJSON `json:"lines"`
+	Wrapped []int        `json:"wrapped,omitempty"`
+	Saved   savedJSON    `json:"saved"`
+}
+
+// pushedJSON encodes an entry of the XTPUSHSGR stack.
+type pushedJSON struct {
+	Pen   penJSON `json:"pen"`
+	Attrs Attr    `json:"a,omitempty"` // The attributes restored
+	Fg    bool    `json:"fg,omitempty"`
+	Bg    bool    `json:"bg,omitempty"`
+}
+
+type savedJSON struct {
+	Cursor      [2]int  `json:"cursor"`
+	PendingWrap bool    `json:"pendingWrap,omitempty"`
+	Pen         penJSON `json:"pen"`
+	Origin      bool    `json:"origin,omitempty"`
+	Charsets    string  `json:"charsets"`
+	GL          int     `json:"gl,omitempty"`
+}
+
+// MarshalJSON encodes the screen's state: its cells, cursor, modes, margins,
+// rendition and pushed renditions, character sets, tab stops, saved cursor
+// and keyboard modes, and while the alternate screen is displayed, the main
+// screen it returns to. The encoding is stable, for persisting sessions and
+// handing a screen to another process. Cells' Meta is not encoded, nor is
+// output not yet parsed, such as an incomplete escape sequence, nor the
+// alternate screen while the main one is displayed.
+func (s *Screen) MarshalJSON() ([]byte, error) {
+	state := s.Snapshot().state()
+	tabStops := []int{}
+	for x, stop := range s.tabStops {
+		if stop {
+			tabStops = append(tabStops, x)
+		}
+	}
+	state.TabStops = &tabStops
+	saved := encodeSaved(s.saved)
+	state.Saved = &saved
+	state.KittyStack = s.kittyStack
+	for _, pushed := range s.sgrStack {
+		state.SGRStack = append(state.SGRStack, pushedJSON{Pen: encodePen(pushed.pen), Attrs: pushed.attrs, Fg: pushed.fg, Bg: pushed.bg})
+	}
+	if s.link != (Hyperlink{}) {
+		state.Link = &s.link
+	}
+	state.Zone = s.zone
+	if s.last != 0 {
+		state.Last = string(s.last)
+	}
+	if s.modes.AlternateScreen {
+		state.Main = &mainJSON{
+			Lines:   encodeLines(s.other.cells),
+			Wrapped: encodeWrapped(s.other.wrapped),
+			Saved:   encodeSaved(s.other.saved),
+		}
+	}
+
+	return json.Marshal(state)
+}
+
+// UnmarshalJSON replaces the screen's state with one encoded by MarshalJSON,
+// keeping the options it was created with. State a snapshot's encoding
+// lacks is reset, as RIS does.
+func (s *Screen) UnmarshalJSON(data []byte) error {
+	var state stateJSON
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	snap, err := state.snapshot()
+	if err != nil {
+		return err
+	}
+
+	if s.parser == nil {
+		// A zero Screen gets the defaults of New
+		s.runeWidth = RuneWidth
+		s.parser = CreateParser("Ground", s, WithUTF8())
+	}
+	s.Resize(snap.cols, snap.rows)
+	s.reset()
+	if state.Main != nil && snap.modes.AlternateScreen {
+		cells, err := decodeLines(state.Main.Lines, snap.cols)
+		if err != nil {
+			return err
+		}
+		wrapped, err := decodeWrapped(state.Main.Wrapped, snap.rows)
+		if err != nil {
+			return err
+		}
+		if len(cells) != snap.rows {
+			return fmt.Errorf("%d main screen lines do not fill a %dx%d screen", len(cells), snap.cols, snap.rows)
+		}
+		s.other = buffer{cells: cells, wrapped: wrapped, shared: make([]bool, snap.rows), saved: state.Main.Saved.saved()}
+	}
+
+	copy(s.cells, snap.cells)
+	copy(s.wrapped, snap.wrapped)
+	s.x, s.y = snap.x, snap.y
+	s.pendingWrap = snap.pendingWrap
+	s.top, s.bottom, s.left, s.right = snap.top, snap.bottom, snap.left, snap.right
+	s.pen = snap.pen
+	s.modes = snap.modes
+	s.charsets, s.gl = snap.charsets, snap.gl
+
+	if state.TabStops != nil {
+		for x := range s.tabStops {
+			s.tabStops[x] = false
+		}
+		for _, x := range *state.TabStops {
+			if x < 0 || x >= s.cols {
+				return fmt.Errorf("tab stop %d is outside the screen", x)
+			}
+			s.tabStops[x] = true
+		}
+	}
+	if state.Saved != nil {
+		s.saved = state.Saved.saved()
+	}
+	s.kittyStack = state.KittyStack
+	for _, pushed := range state.SGRStack {
+		s.sgrStack = append(s.sgrStack, pushedPen{pen: pushed.Pen.pen(), attrs: pushed.Attrs, fg: pushed.Fg, bg: pushed.Bg})
+	}
+	if state.Link != nil {
+		s.link = *state.Link
+	}
+	s.zone = state.Zone
+	for _, r := range state.Last {
+		s.last = r
+	}
+
+	s.damage(0, 0, s.cols-1, s.rows-1)
+	return s.Flush()
+}
+
+// MarshalJSON encodes the snapshot as Screen.MarshalJSON does, without the
+// state a snapshot does not hold.
+func (snap Snapshot) MarshalJSON() ([]byte, error) {
+	return json.Marshal(snap.state())
+}
+
+// UnmarshalJSON decodes a snapshot encoded by Snapshot.MarshalJSON or
+// Screen.MarshalJSON.
+func (snap *Snapshot) UnmarshalJSON(data []byte) error {
+	var state stateJSON
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	decoded, err := state.snapshot()
+	if err != nil {
+		return err
+	}
+	*snap = decoded
+	return nil
+}
+
+// state returns the encoding of the snapshot.
+func (snap Snapshot) state() stateJSON {
+	state := stateJSON{
+		Version:     stateVersion,
+		Cols:        snap.cols,
+		Rows:        snap.rows,
+		Lines:       encodeLines(snap.cells),
+		Wrapped:     encodeWrapped(snap.wrapped),
+		Cursor:      [2]int{snap.x, snap.y},
+		PendingWrap: snap.pendingWrap,
+		Margins:     [4]int{snap.top, snap.bottom, snap.left, snap.right},
+		Pen:         encodePen(snap.pen),
+		Modes:       snap.modes,
+		Charsets:    string(snap.charsets[:]),
+		GL:          snap.gl,
+	}
+
+	return state
+}
+
+// snapshot returns the snapshot a state encodes, checking it is consistent.
+func (state stateJSON) snapshot() (Snapshot, error) {
+	if state.Version != stateVersion {
+		return Snapshot{}, fmt.Errorf("unsupported screen encoding version %d", state.Version)
+	}
+	if state.Cols < 1 || state.Rows < 1 || len(state.Lines) != state.Rows {
+		return Snapshot{}, fmt.Errorf("%d lines do not fill a %dx%d screen", len(state.Lines), state.Cols, state.Rows)
+	}
+
+	cells, err := decodeLines(state.Lines, state.Cols)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	wrapped, err := decodeWrapped(state.Wrapped, state.Rows)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	snap := Snapshot{
+		cols:        state.Cols,
+		rows:        state.Rows,
+		cells:       cells,
+		wrapped:     wrapped,
+		x:           state.Cursor[0],
+		y:           state.Cursor[1],
+		modes:       state.Modes,
+		top:         state.Margins[0],
+		bottom:      state.Margins[1],
+		left:        state.Margins[2],
+		right:       state.Margins[3],
+		pendingWrap: state.PendingWrap,
+		pen:         state.Pen.pen(),
+		gl:          state.GL,
+	}
+	copy(snap.charsets[:], state.Charsets)
+
+	if snap.x < 0 || snap.x >= snap.cols || snap.y < 0 || snap.y >= snap.rows {
+		return Snapshot{}, fmt.Errorf("cursor %d,%d is outside the screen", snap.x, snap.y)
+	}
+	if snap.top < 0 || snap.top > snap.bottom || snap.bottom >= snap.rows ||
+		snap.left < 0 || snap.left > snap.right || snap.right >= snap.cols {
+		return Snapshot{}, fmt.Errorf("margins %v are outside the screen", state.Margins)
+	}
+
+	return snap, nil
+}
+
+// encodeLines returns the encoding of the cells of a screen.
+func encodeLines(cells [][]Cell) [][]cellJSON {
+	lines := make([][]cellJSON, len(cells))
+	for y, row := range cells {
+		lines[y] = []cellJSON{}
+		for _, cell := range row {
+			if cell.Width == 0 {
+				continue
+			}
+
+			var text strings.Builder
+			writeCell(&text, cell)
+			encoded := cellJSON{
+				Text:  text.String(),
+				Wide:  cell.Width == 2,
+				Fg:    cell.Fg,
+				Bg:    cell.Bg,
+				Attrs: cell.Attrs,
+				URI:   cell.Link.URI,
+				ID:    cell.Link.ID,
+				Zone:  cell.Zone,
+			}
+			if encoded.Text == " " {
+				encoded.Text = ""
+			}
+			lines[y] = append(lines[y], encoded)
+		}
+	}
+	return lines
+}
+
+// decodeLines returns the cells encoded by lines, checking each fills cols.
+func decodeLines(lines [][]cellJSON, cols int) ([][]Cell, error) {
+	cells := make([][]Cell, len(lines))
+	for y, line := range lines {
+		row := make([]Cell, 0, cols)
+		for _, encoded := range line {
+			cell := Cell{
+				Rune:  ' ',
+				Width: 1,
+				Fg:    encoded.Fg,
+				Bg:    encoded.Bg,
+				Attrs: encoded.Attrs,
+				Link:  Hyperlink{ID: encoded.ID, URI: encoded.URI},
+				Zone:  encoded.Zone,
+			}
+			if runes := []rune(encoded.Text); len(runes) > 0 {
+				cell.Rune = runes[0]
+				if len(runes) > 1 {
+					cell.Combining = runes[1:]
+				}
+			}
+
+			row = append(row, cell)
+			if encoded.Wide {
+				row[len(row)-1].Width = 2
+				cell.Rune, cell.Combining, cell.Width = 0, nil, 0
+				row = append(row, cell)
+			}
+		}
+
+		if len(row) != cols {
+			return nil, fmt.Errorf("line %d is %d cells wide, not %d", y, len(row), cols)
+		}
+		cells[y] = row
+	}
+	return cells, nil
+}
+
+// encodeWrapped returns the rows marked as wrapped.
+func encodeWrapped(wrapped []bool) []int {
+	var rows []int
+	for y, mark := range wrapped {
+		if mark {
+			rows = append(rows, y)
+		}
+	}
+	return rows
+}
+
+// decodeWrapped returns the wrapped marks of a screen of rows lines.
+func decodeWrapped(rows []int, height int) ([]bool, error) {
+	wrapped := make([]bool, height)
+	for _, y := range rows {
+		if y < 0 || y >= height {
+			return nil, fmt.Errorf("wrapped line %d is outside the screen", y)
+		}
+		wrapped[y] = true
+	}
+	return wrapped, nil
+}
+
+func encodeSaved(saved savedCursor) savedJSON {
+	return savedJSON{
+		Cursor:      [2]int{saved.x, saved.y},
+		PendingWrap: saved.pendingWrap,
+		Pen:         encodePen(saved.pen),
+		Origin:      saved.originMode,
+		Charsets:    string(saved.charsets[:]),
+		GL:          saved.gl,
+	}
+}
+
+func (saved savedJSON) saved() savedCursor {
+	cursor := savedCursor{
+		x:           saved.Cursor[0],
+		y:           saved.Cursor[1],
+		pen:         saved.Pen.pen(),
+		originMode:  saved.Origin,
+		pendingWrap: saved.PendingWrap,
+		gl:          saved.GL,
+	}
+	copy(cursor.charsets[:], saved.Charsets)
+	return cursor
+}
+
+func encodePen(p pen) penJSON {
+	return penJSON{Fg: p.fg, Bg: p.bg, Attrs: p.attrs}
+}
+
+func (p penJSON) pen() pen {
+	return pen{fg: p.Fg, bg: p.Bg, attrs: p.Attrs}
+}
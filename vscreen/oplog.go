@@ -0,0 +1,118 @@
+package vscreen
+
+// OpKind is the kind of change an Operation made to the screen.
+type OpKind int
+
+const (
+	OpPrint  OpKind = iota // Characters printed, or combined with the one before
+	OpErase                // Cells erased, by ED, EL or RIS
+	OpScroll               // Lines shifted down, or up if Count is negative, within Rect
+	OpShift                // Cells of a line shifted right, or left if Count is negative, within Rect
+	OpFill                 // The screen filled with E by DECALN
+	OpResize               // The screen resized to Rect
+	OpSwitch               // The display switched to or from the alternate screen
+)
+
+var opKindNames = [...]string{
+	OpPrint:  "print",
+	OpErase:  "erase",
+	OpScroll: "scroll",
+	OpShift:  "shift",
+	OpFill:   "fill",
+	OpResize: "resize",
+	OpSwitch: "switch",
+}
+
+func (k OpKind) String() string {
+	if k < 0 || int(k) >= len(opKindNames) {
+		return "unknown"
+	}
+	return opKindNames[k]
+}
+
+// Operation is a change to the cells of a screen, as recorded by
+// WithOperationLog.
+type Operation struct {
+	Kind  OpKind
+	Rect  Rect   // The cells changed
+	Text  string // For OpPrint, the characters printed
+	Count int    // For OpScroll and OpShift, the lines or columns shifted
+
+	// Offset is the offset, in the output written to the screen, of the byte
+	// completing the character or sequence that made the change; for a run
+	// of characters, the first of them. Changes made by calling the
+	// screen's AnsiEventHandler methods directly have the offset of the
+	// next byte to be written.
+	Offset int64
+}
+
+// WithOperationLog records each change made to the screen's cells, in the
+// order made, for Operations to return. Characters printed one after another
+// along a line are recorded as a single run. To attribute each change to the
+// byte causing it, writes are parsed a byte at a time, so this is meant for
+// debugging rather than for every screen.
+func WithOperationLog() ScreenOption {
+	return func(s *Screen) {
+		s.logging = true
+	}
+}
+
+// Operations returns the changes recorded since the screen was created or
+// the log last cleared, in order. Replaying the same output to a new screen
+// with the same options records the same operations.
+func (s *Screen) Operations() []Operation {
+	return append([]Operation(nil), s.operations...)
+}
+
+// OperationsAt returns the recorded changes to the cell at x, y, in order,
+// answering which characters or sequences changed it.
+func (s *Screen) OperationsAt(x int, y int) []Operation {
+	var ops []Operation
+	for _, op := range s.operations {
+		if op.Rect.Left <= x && x <= op.Rect.Right && op.Rect.Top <= y && y <= op.Rect.Bottom {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// ClearOperations discards the recorded changes, bounding the memory the log
+// of a long running screen uses. Offsets continue to count from the start of
+// the output.
+func (s *Screen) ClearOperations() {
+	s.operations = nil
+}
+
+// record appends an operation to the log, if it is being kept.
+func (s *Screen) record(op Operation) {
+	if !s.logging {
+		return
+	}
+
+	op.Offset = s.offset
+	s.operations = append(s.operations, op)
+}
+
+// recordPrint records printing r over columns left to right of row y,
+// extending the run printed before it. A combining character extends the run
+// holding the character it combines with.
+func (s *Screen) recordPrint(left int, right int, y int, r rune, combining bool) {
+	if !s.logging {
+		return
+	}
+
+	if n := len(s.operations); n > 0 {
+		last := &s.operations[n-1]
+		within := last.Rect.Left <= left && right <= last.Rect.Right
+		if last.Kind == OpPrint && last.Rect.Top == y &&
+			(combining && within || !combining && left == last.Rect.Right+1) {
+			if !combining {
+				last.Rect.Right = right
+			}
+			last.Text += string(r)
+			return
+		}
+	}
+
+	s.record(Operation{Kind: OpPrint, Rect: Rect{Left: left, Top: y, Right: right, Bottom: y}, Text: string(r)})
+}
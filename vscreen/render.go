@@ -0,0 +1,232 @@
+package vscreen
+
+import (
+	"bytes"
+	"fmt"
+
+	. "github.com/Azure/go-ansiterm"
+)
+
+// maxRewrite is the longest run of unchanged cells between changes that Diff
+// rewrites rather than moving the cursor over.
+const maxRewrite = 4
+
+// Diff returns output that changes a terminal displaying from to display to.
+// The terminal is assumed to have the cursor where from has it, the default
+// rendition, and the ASCII character set in G0, with no scrolling margins;
+// the output leaves it the same way, with the cursor where to has it. Only
+// the cells that differ are written, with the cheapest cursor movements and
+// EL erasing blank line endings. Snapshots of different sizes, such as a zero
+// Snapshot, cause the screen to be cleared and redrawn in full. Linked cells
+// are written in OSC 8 hyperlinks.
+func Diff(from Snapshot, to Snapshot) []byte {
+	r := &renderer{x: from.x, y: from.y, cols: to.cols}
+
+	redraw := from.cols != to.cols || from.rows != to.rows
+	if redraw {
+		r.buf.WriteString("\x1b[H\x1b[2J")
+		r.x, r.y = 0, 0
+		from = blankSnapshot(to.cols, to.rows, from.modes)
+	}
+
+	for y := 0; y < to.rows; y++ {
+		r.renderRow(from.cells[y], to.cells[y], y)
+	}
+
+	r.setPen(pen{})
+	r.setLink(Hyperlink{})
+	r.moveTo(to.x, to.y)
+
+	if redraw || from.modes.CursorVisible != to.modes.CursorVisible {
+		if to.modes.CursorVisible {
+			r.buf.WriteString("\x1b[?25h")
+		} else {
+			r.buf.WriteString("\x1b[?25l")
+		}
+	}
+
+	return r.buf.Bytes()
+}
+
+// blankSnapshot returns a snapshot of an erased screen.
+func blankSnapshot(cols int, rows int, modes Modes) Snapshot {
+	cells := make([][]Cell, rows)
+	for y := range cells {
+		cells[y] = make([]Cell, cols)
+		for x := range cells[y] {
+			cells[y][x] = pen{}.blank()
+		}
+	}
+
+	return Snapshot{cols: cols, rows: rows, cells: cells, modes: modes}
+}
+
+// renderer accumulates the output of Diff, tracking the terminal's cursor
+// and rendition.
+type renderer struct {
+	buf  bytes.Buffer
+	x, y int // The cursor, or x < 0 if it is pending a wrap
+	cols int
+	pen  pen
+	link Hyperlink
+}
+
+// renderRow writes the cells of row y that differ between old and new.
+func (r *renderer) renderRow(old []Cell, new []Cell, y int) {
+	cols := len(new)
+
+	// The row ends in blanks that EL can erase
+	tail := cols
+	for tail > 0 && isBlank(new[tail-1]) && new[tail-1].Bg == new[cols-1].Bg {
+		tail--
+	}
+
+	x := 0
+	for x < cols {
+		if cellsEqual(old[x], new[x]) {
+			x++
+			continue
+		}
+
+		if x >= tail {
+			r.moveTo(x, y)
+			r.setPen(pen{bg: new[cols-1].Bg})
+			r.setLink(Hyperlink{})
+			r.buf.WriteString("\x1b[K")
+			return
+		}
+
+		// Write from the start of a wide character through the changes,
+		// bridging short runs of unchanged cells
+		start := x
+		if new[start].Width == 0 && start > 0 {
+			start--
+		}
+
+		end, unchanged := start, 0
+		for end < tail && unchanged <= maxRewrite {
+			if cellsEqual(old[end], new[end]) {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+			end++
+		}
+		end -= unchanged
+		for end < cols && new[end].Width == 0 {
+			end++
+		}
+
+		r.moveTo(start, y)
+		for _, cell := range new[start:end] {
+			r.writeCell(cell)
+		}
+		x = end
+	}
+}
+
+// writeCell writes a cell at the cursor.
+func (r *renderer) writeCell(cell Cell) {
+	if cell.Width == 0 {
+		return
+	}
+
+	r.setPen(cellPen(cell))
+	r.setLink(cell.Link)
+	r.buf.WriteRune(cell.Rune)
+	for _, c := range cell.Combining {
+		r.buf.WriteRune(c)
+	}
+
+	r.x += cell.Width
+	if r.x >= r.cols {
+		// The cursor stays in the last column until the next character
+		r.x = -1
+	}
+}
+
+// setPen changes the rendition.
+func (r *renderer) setPen(p pen) {
+	if p == r.pen {
+		return
+	}
+
+	r.buf.WriteString("\x1b[" + sgrString(p) + "m")
+	r.pen = p
+}
+
+// setLink opens a hyperlink, or closes the open one for the zero Hyperlink.
+func (r *renderer) setLink(link Hyperlink) {
+	if link == r.link {
+		return
+	}
+
+	params := ""
+	if link.ID != "" {
+		params = "id=" + link.ID
+	}
+	r.buf.WriteString("\x1b]8;" + params + ";" + link.URI + "\x1b\\")
+	r.link = link
+}
+
+// moveTo moves the cursor to x, y with the shortest sequence that does.
+func (r *renderer) moveTo(x int, y int) {
+	if r.x == x && r.y == y {
+		return
+	}
+
+	moves := []string{fmt.Sprintf("\x1b[%d;%dH", y+1, x+1)}
+	switch {
+	case r.x < 0:
+	case y == r.y && x == 0:
+		moves = append(moves, "\r")
+	case y == r.y && x > r.x:
+		moves = append(moves, cursorMove(x-r.x, 'C'))
+	case y == r.y && x < r.x:
+		moves = append(moves, cursorMove(r.x-x, 'D'), "\r"+cursorMove(x, 'C'))
+	case x == r.x && y > r.y:
+		moves = append(moves, cursorMove(y-r.y, 'B'))
+	case x == r.x && y < r.y:
+		moves = append(moves, cursorMove(r.y-y, 'A'))
+	case y == r.y+1 && x == 0:
+		moves = append(moves, "\r\n")
+	}
+
+	shortest := moves[0]
+	for _, move := range moves[1:] {
+		if len(move) < len(shortest) {
+			shortest = move
+		}
+	}
+
+	r.buf.WriteString(shortest)
+	r.x, r.y = x, y
+}
+
+// cursorMove returns the sequence moving the cursor n cells in the direction
+// final selects (CUU, CUD, CUF or CUB).
+func cursorMove(n int, final byte) string {
+	if n == 1 {
+		return "\x1b[" + string(final)
+	}
+	return fmt.Sprintf("\x1b[%d%c", n, final)
+}
+
+// isBlank reports whether a cell is as EL leaves it, in some background.
+func isBlank(cell Cell) bool {
+	return cell.Rune == ' ' && cell.Width == 1 && len(cell.Combining) == 0 && cell.Fg == DefaultColor && cell.Attrs == 0 && cell.Link == (Hyperlink{})
+}
+
+// cellsEqual reports whether two cells display the same.
+func cellsEqual(a Cell, b Cell) bool {
+	if a.Rune != b.Rune || a.Width != b.Width || cellPen(a) != cellPen(b) || a.Link != b.Link || len(a.Combining) != len(b.Combining) {
+		return false
+	}
+
+	for i := range a.Combining {
+		if a.Combining[i] != b.Combining[i] {
+			return false
+		}
+	}
+	return true
+}
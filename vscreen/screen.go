@@ -0,0 +1,448 @@
+// Package vscreen implements an AnsiEventHandler over an in-memory grid of
+// character cells, independent of any console, for testing the parser's
+// semantics and capturing the output of terminal programs headlessly.
+package vscreen
+
+import (
+	"io"
+	"sync/atomic"
+
+	. "github.com/Azure/go-ansiterm"
+)
+
+// Attr is a set of text attributes.
+type Attr uint16
+
+const (
+	AttrBold Attr = 1 << iota
+	AttrFaint
+	AttrItalic
+	AttrUnderline
+	AttrBlink
+	AttrReverse
+	AttrInvisible
+	AttrStrikethrough
+)
+
+// Hyperlink is the target of a link opened by OSC 8; see
+// https://gist.github.com/egmontkob/eb114294efbcd5adb1944c9f3cb5feda. Cells
+// printed with the same ID and URI belong to the same link, even when not
+// adjacent. The zero Hyperlink is no link.
+type Hyperlink struct {
+	ID  string `json:"id,omitempty"`
+	URI string `json:"uri"`
+}
+
+// Zone is the semantic zone of shell integration (OSC 133) a cell was
+// printed in.
+type Zone int
+
+const (
+	ZoneNone   Zone = iota
+	ZonePrompt      // OSC 133 ; A
+	ZoneInput       // OSC 133 ; B
+	ZoneOutput      // OSC 133 ; C
+)
+
+// Cell is a character cell of the screen.
+type Cell struct {
+	Rune      rune   // The character; a space in erased cells
+	Combining []rune // Zero-width characters combined with Rune
+	Width     int    // 1, or 2 for a wide character; 0 in the cell a wide character extends into
+	Fg        Color
+	Bg        Color
+	Attrs     Attr
+	Link      Hyperlink   // The OSC 8 link the character was printed in
+	Zone      Zone        // The OSC 133 zone the character was printed in
+	Meta      interface{} // The value of SetMetadata when the character was printed
+}
+
+// pen is the rendition applied to printed and erased cells.
+type pen struct {
+	fg    Color
+	bg    Color
+	attrs Attr
+}
+
+// blank returns an erased cell in the pen's colors. As in xterm, erasing
+// keeps the background color but not the attributes.
+func (p pen) blank() Cell {
+	return Cell{Rune: ' ', Width: 1, Bg: p.bg}
+}
+
+// savedCursor is the state saved by DECSC.
+type savedCursor struct {
+	x, y        int
+	pen         pen
+	originMode  bool
+	pendingWrap bool
+	charsets    [4]byte
+	gl          int
+}
+
+// buffer is the inactive one of the main and alternate screens, whose
+// contents and saved cursor are swapped with the screen's when switching.
+type buffer struct {
+	cells   [][]Cell
+	wrapped []bool
+	shared  []bool
+	saved   savedCursor
+}
+
+// Modes reports the terminal modes tracked by a Screen.
+type Modes struct {
+	Insert                bool // IRM
+	Origin                bool // DECOM
+	Autowrap              bool // DECAWM
+	LeftRightMargins      bool // DECLRMM
+	CursorVisible         bool // DECTCEM
+	CursorStyle           int  // DECSCUSR
+	SynchronizedUpdate    bool // Private mode 2026
+	ApplicationCursorKeys bool // DECCKM
+	ApplicationKeypad     bool // DECKPAM
+	MouseTracking         int  // Private mode 1000, 1002 or 1003, or 0
+	SGRMouse              bool // Private mode 1006
+	BracketedPaste        bool // Private mode 2004
+	FocusReporting        bool // Private mode 1004
+	Win32Input            bool // Private mode 9001
+	ModifyOtherKeys       int  // XTMODKEYS resource 4
+	KittyFlags            int  // Kitty keyboard protocol
+	AlternateScreen       bool // Private mode 47, 1047 or 1049
+}
+
+// Screen is a virtual terminal screen. It implements AnsiEventHandler, and
+// io.Writer by parsing what is written to it.
+//
+// A Screen is not safe for concurrent use, except for Latest, which samples
+// it from other goroutines without blocking the one writing to it.
+type Screen struct {
+	cols, rows  int
+	cells       [][]Cell
+	dirty       []span // Changed columns of each row, for Damage
+	wrapped     []bool // Whether each row was autowrapped onto the next
+	shared      []bool // Whether each row is shared with a snapshot, and must be copied to change
+	x, y        int
+	pendingWrap bool // The last column was printed; the next character wraps
+	pen         pen
+	link        Hyperlink
+	zone        Zone
+	meta        interface{}
+
+	// Margins, inclusive and zero-based
+	top, bottom int
+	left, right int
+
+	modes      Modes
+	kittyStack []int
+	sgrStack   []pushedPen
+	tabStops   []bool
+	charsets   [4]byte
+	gl         int
+	saved      savedCursor
+	utf8Buffer []byte
+	last       rune   // The most recently printed character, for REP
+	other      buffer // The main screen while the alternate is displayed, and vice versa
+
+	publish bool
+	latest  atomic.Value // The Snapshot published by the last Flush
+
+	logging    bool
+	operations []Operation
+	offset     int64 // Of the byte being parsed, in the output written
+
+	onBell         func()
+	onTitle        func(string)
+	onClipboard    func(string, []byte)
+	onNotification func(string, string)
+	onHyperlink    func(Hyperlink, []Rect)
+	linkRegion     []Rect // The cells printed in the open hyperlink
+
+	parser    *AnsiParser
+	responses io.Writer
+	runeWidth func(rune) int
+}
+
+// ScreenOption configures optional behavior of a Screen.
+type ScreenOption func(*Screen)
+
+// WithResponseWriter sends replies to queries (DA, DSR, DECRQSS, XTGETTCAP,
+// window reports) to w, typically the input of the program whose output is
+// being written. Replies are otherwise discarded.
+func WithResponseWriter(w io.Writer) ScreenOption {
+	return func(s *Screen) {
+		s.responses = w
+	}
+}
+
+// WithRuneWidth overrides the function used to compute how many cells a rune
+// occupies; see RuneWidth. Runes that extend the grapheme cluster before
+// them, as ExtendsCluster reports, share its cells whatever their width.
+func WithRuneWidth(width func(rune) int) ScreenOption {
+	return func(s *Screen) {
+		s.runeWidth = width
+	}
+}
+
+// WithConcurrentSnapshots publishes a snapshot of the screen on every Flush,
+// which the parser does at the end of each Write, for Latest to return. As
+// snapshots share the screen's rows until they change, this costs copying
+// the rows changed between flushes.
+func WithConcurrentSnapshots() ScreenOption {
+	return func(s *Screen) {
+		s.publish = true
+	}
+}
+
+// New creates a blank screen of cols by rows cells.
+func New(cols int, rows int, opts ...ScreenOption) *Screen {
+	s := &Screen{runeWidth: RuneWidth}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.parser = CreateParser("Ground", s, WithUTF8())
+	s.Resize(cols, rows)
+	s.reset()
+	s.Flush()
+	return s
+}
+
+// Write parses p as terminal output, applying it to the screen.
+func (s *Screen) Write(p []byte) (int, error) {
+	if !s.logging {
+		n, err := s.parser.Parse(p)
+		s.offset += int64(n)
+		return n, err
+	}
+
+	// Parsing a byte at a time attributes each operation to its byte
+	for i := range p {
+		if _, err := s.parser.Parse(p[i : i+1]); err != nil {
+			return i, err
+		}
+		s.offset++
+	}
+	return len(p), nil
+}
+
+// Size returns the size of the screen in cells.
+func (s *Screen) Size() (cols int, rows int) {
+	return s.cols, s.rows
+}
+
+// Cursor returns the zero-based cursor position.
+func (s *Screen) Cursor() (x int, y int) {
+	return s.x, s.y
+}
+
+// Cell returns the cell at the zero-based position x, y.
+func (s *Screen) Cell(x int, y int) Cell {
+	return s.cells[y][x]
+}
+
+// Modes reports the terminal modes.
+func (s *Screen) Modes() Modes {
+	return s.modes
+}
+
+// Margins returns the zero-based, inclusive scrolling margins.
+func (s *Screen) Margins() (top int, bottom int, left int, right int) {
+	return s.top, s.bottom, s.left, s.right
+}
+
+// PendingWrap reports whether a character was printed in the last column,
+// leaving the next to wrap onto the next line.
+func (s *Screen) PendingWrap() bool {
+	return s.pendingWrap
+}
+
+// Rendition returns the colors and attributes, set by SGR, that characters
+// are printed in.
+func (s *Screen) Rendition() (fg Color, bg Color, attrs Attr) {
+	return s.pen.fg, s.pen.bg, s.pen.attrs
+}
+
+// Charsets returns the final characters of the character sets designated to
+// G0-G3 (e.g. 'B' for ASCII, '0' for DEC Special Graphics), and which of them
+// is invoked into GL by SI and SO.
+func (s *Screen) Charsets() (charsets [4]byte, gl int) {
+	return s.charsets, s.gl
+}
+
+// Line returns the text of row y, without trailing blanks.
+func (s *Screen) Line(y int) string {
+	return lineText(s.cells[y])
+}
+
+// String returns the text of the screen, a line per row, without trailing
+// blanks or blank lines.
+func (s *Screen) String() string {
+	return screenText(s.cells, lineText)
+}
+
+// DumpWithAttributes returns the text of the screen as String does, with SGR
+// sequences marking changes of rendition; see Snapshot.DumpWithAttributes.
+func (s *Screen) DumpWithAttributes() string {
+	return screenText(s.cells, lineWithAttributes)
+}
+
+// SetMetadata attaches v to the characters printed from now on, in their
+// Cell's Meta, until it is set again; nil attaches nothing. It lets a caller
+// tag output with its origin, such as the command or stream that wrote it.
+// A reset (RIS) clears it.
+func (s *Screen) SetMetadata(v interface{}) {
+	s.meta = v
+}
+
+// Resize changes the size of the screen to cols by rows, keeping the top left
+// of its contents. The margins are reset and the cursor kept within the
+// screen.
+func (s *Screen) Resize(cols int, rows int) {
+	if cols < 1 {
+		cols = 1
+	}
+	if rows < 1 {
+		rows = 1
+	}
+
+	if s.cells != nil {
+		s.record(Operation{Kind: OpResize, Rect: Rect{Right: cols - 1, Bottom: rows - 1}})
+	}
+	s.cells, s.wrapped = s.resizeBuffer(s.cells, s.wrapped, cols, rows)
+	s.other.cells, s.other.wrapped = s.resizeBuffer(s.other.cells, s.other.wrapped, cols, rows)
+	s.shared = make([]bool, rows)
+	s.other.shared = make([]bool, rows)
+
+	tabStops := make([]bool, cols)
+	for x := range tabStops {
+		if x < len(s.tabStops) {
+			tabStops[x] = s.tabStops[x]
+		} else {
+			tabStops[x] = x%8 == 0 && x != 0
+		}
+	}
+
+	s.tabStops = tabStops
+	s.cols, s.rows = cols, rows
+	s.dirty = make([]span, rows)
+	s.damage(0, 0, cols-1, rows-1)
+	s.top, s.bottom = 0, rows-1
+	s.left, s.right = 0, cols-1
+	s.x = clamp(s.x, 0, cols-1)
+	s.y = clamp(s.y, 0, rows-1)
+	s.pendingWrap = false
+}
+
+// resizeBuffer returns the cells and wrapped marks of a screen buffer resized
+// from the screen's size to cols by rows, keeping the top left of its cells.
+func (s *Screen) resizeBuffer(cells [][]Cell, wrapped []bool, cols int, rows int) ([][]Cell, []bool) {
+	resized := make([][]Cell, rows)
+	for y := range resized {
+		resized[y] = make([]Cell, cols)
+		for x := range resized[y] {
+			switch {
+			case y < len(cells) && x < s.cols:
+				resized[y][x] = cells[y][x]
+			default:
+				resized[y][x] = s.pen.blank()
+			}
+		}
+
+		// A wide character cut in half by the new width is erased
+		if cols < s.cols && y < len(cells) && resized[y][cols-1].Width == 2 {
+			resized[y][cols-1] = s.pen.blank()
+		}
+	}
+
+	// Rows only stay wrapped at the width they were wrapped at
+	marks := make([]bool, rows)
+	if cols == s.cols {
+		copy(marks, wrapped)
+	}
+
+	return resized, marks
+}
+
+// reset returns the screen to its initial state, blank with default modes.
+func (s *Screen) reset() {
+	s.pen = pen{}
+	s.endLink()
+	s.zone = ZoneNone
+	s.meta = nil
+	if s.modes.AlternateScreen {
+		s.switchBuffer()
+	}
+	s.softReset()
+	s.modes.CursorStyle = 0
+	s.modes.SynchronizedUpdate = false
+	s.modes.MouseTracking = 0
+	s.modes.SGRMouse = false
+	s.modes.BracketedPaste = false
+	s.modes.FocusReporting = false
+	s.modes.Win32Input = false
+	s.modes.ModifyOtherKeys = 0
+	s.modes.KittyFlags = 0
+	s.kittyStack = nil
+	s.sgrStack = nil
+	s.utf8Buffer = nil
+	s.last = 0
+
+	for x := range s.tabStops {
+		s.tabStops[x] = x%8 == 0 && x != 0
+	}
+
+	s.eraseRect(0, 0, s.cols-1, s.rows-1)
+	s.x, s.y = 0, 0
+
+	// The alternate screen is blanked too, replacing rows snapshots may share
+	for y := range s.other.cells {
+		row := make([]Cell, s.cols)
+		for x := range row {
+			row[x] = s.pen.blank()
+		}
+		s.other.cells[y] = row
+		s.other.wrapped[y] = false
+		s.other.shared[y] = false
+	}
+	s.other.saved = s.saved
+}
+
+// softReset resets the state DECSTR does; see
+// http://vt100.net/docs/vt220-rm/table4-10.html. Autowrap returns to its
+// initial setting, on, rather than the VT220's off.
+func (s *Screen) softReset() {
+	s.modes.Insert = false
+	s.modes.Origin = false
+	s.modes.Autowrap = true
+	s.modes.LeftRightMargins = false
+	s.modes.CursorVisible = true
+	s.modes.ApplicationCursorKeys = false
+	s.modes.ApplicationKeypad = false
+	s.top, s.bottom = 0, s.rows-1
+	s.left, s.right = 0, s.cols-1
+	s.pen = pen{}
+	s.charsets = [4]byte{ANSI_CHARSET_ASCII, ANSI_CHARSET_ASCII, ANSI_CHARSET_ASCII, ANSI_CHARSET_ASCII}
+	s.gl = 0
+	s.saved = savedCursor{charsets: s.charsets}
+	s.pendingWrap = false
+}
+
+// respond sends a reply to a query.
+func (s *Screen) respond(reply string) error {
+	if s.responses == nil {
+		return nil
+	}
+
+	_, err := io.WriteString(s.responses, reply)
+	return err
+}
+
+func clamp(n int, min int, max int) int {
+	if n < min {
+		return min
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
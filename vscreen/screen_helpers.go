@@ -0,0 +1,378 @@
+package vscreen
+
+import (
+	"unicode/utf8"
+
+	. "github.com/Azure/go-ansiterm"
+)
+
+// printRune writes a decoded character at the cursor, wrapping and scrolling
+// as needed.
+func (s *Screen) printRune(r rune) {
+	if r < utf8.RuneSelf {
+		r = TranslateCharset(s.charsets[s.gl], r)
+	}
+
+	width := s.runeWidth(r)
+	if width == 0 {
+		s.combine(r)
+		return
+	}
+	if x := s.clusterColumn(); x >= 0 && ExtendsCluster(cellRunes(s.cells[s.y][x]), r, s.runeWidth) {
+		// Joined emoji and flags share the cells of the cluster they extend
+		s.combine(r)
+		return
+	}
+
+	right := s.rightLimit()
+	if width > right-s.leftLimit()+1 {
+		// Too wide to fit between the margins at all
+		return
+	}
+
+	if s.pendingWrap && s.modes.Autowrap {
+		s.wrap()
+		right = s.rightLimit()
+	}
+	s.pendingWrap = false
+
+	if s.x+width-1 > right {
+		// A wide character that does not fit wraps early, or without
+		// autowrap is printed as far right as it fits
+		if s.modes.Autowrap {
+			s.wrap()
+			right = s.rightLimit()
+		} else {
+			s.x = right - width + 1
+		}
+	}
+
+	if s.modes.Insert {
+		s.insertCells(width, right)
+	}
+
+	cell := s.printed(Cell{Rune: r, Width: width})
+	if width == 2 {
+		s.put(s.x, s.y, cell, s.printed(Cell{Width: 0}))
+	} else {
+		s.put(s.x, s.y, cell)
+	}
+	s.recordPrint(s.x, s.x+width-1, s.y, r, false)
+	s.linkPrinted(s.x, s.x+width-1, s.y)
+	s.last = r
+
+	if s.x+width > right {
+		s.x = right
+		s.pendingWrap = true
+		return
+	}
+	s.x += width
+}
+
+// printed returns cell in the pen's rendition, with the link, zone and
+// metadata characters are printed with.
+func (s *Screen) printed(cell Cell) Cell {
+	cell.Fg, cell.Bg, cell.Attrs = s.pen.fg, s.pen.bg, s.pen.attrs
+	cell.Link, cell.Zone, cell.Meta = s.link, s.zone, s.meta
+	return cell
+}
+
+// combine attaches a character to the grapheme cluster before the cursor.
+func (s *Screen) combine(r rune) {
+	x := s.clusterColumn()
+	if x < 0 {
+		return
+	}
+
+	s.damage(x, s.y, x, s.y)
+	cell := &s.writableRow(s.y)[x]
+	cell.Combining = append(cell.Combining[:len(cell.Combining):len(cell.Combining)], r)
+	s.recordPrint(x, x+cell.Width-1, s.y, r, true)
+}
+
+// clusterColumn returns the column of the character before the cursor, which
+// the next may combine with, or -1 at the start of a line.
+func (s *Screen) clusterColumn() int {
+	x := s.x
+	if !s.pendingWrap {
+		x--
+	}
+	if x >= 0 && s.cells[s.y][x].Width == 0 && x > 0 {
+		x--
+	}
+	return x
+}
+
+// cellRunes returns the characters of a cell.
+func cellRunes(cell Cell) []rune {
+	return append([]rune{cell.Rune}, cell.Combining...)
+}
+
+// put writes the cells of a character from column x, erasing what remains of
+// any wide character they overwrite part of.
+func (s *Screen) put(x int, y int, cells ...Cell) {
+	row := s.writableRow(y)
+	if row[x].Width == 0 && x > 0 && row[x-1].Width == 2 {
+		row[x-1] = s.pen.blank()
+		s.damage(x-1, y, x-1, y)
+	}
+	if end := x + len(cells) - 1; row[end].Width == 2 && end+1 < s.cols {
+		row[end+1] = s.pen.blank()
+		s.damage(end+1, y, end+1, y)
+	}
+
+	copy(row[x:], cells)
+	s.damage(x, y, x+len(cells)-1, y)
+}
+
+// wrap moves the cursor to the left margin of the next line, marking the line
+// as continuing on it.
+func (s *Screen) wrap() {
+	if s.y == s.bottom || s.y < s.rows-1 {
+		s.wrapped[s.y] = true
+	}
+	s.x = s.leftLimit()
+	s.index()
+}
+
+// index moves the cursor down a line, scrolling at the bottom margin.
+func (s *Screen) index() {
+	switch {
+	case s.y == s.bottom:
+		s.scrollUp(1)
+	case s.y < s.rows-1:
+		s.y++
+	}
+}
+
+// reverseIndex moves the cursor up a line, scrolling at the top margin.
+func (s *Screen) reverseIndex() {
+	switch {
+	case s.y == s.top:
+		s.scrollDown(1)
+	case s.y > 0:
+		s.y--
+	}
+}
+
+// leftLimit returns the leftmost column the cursor moves to: the left margin,
+// unless the cursor is left of it.
+func (s *Screen) leftLimit() int {
+	if s.x < s.left {
+		return 0
+	}
+	return s.left
+}
+
+// rightLimit returns the rightmost column the cursor moves to: the right
+// margin, unless the cursor is right of it.
+func (s *Screen) rightLimit() int {
+	if s.x > s.right {
+		return s.cols - 1
+	}
+	return s.right
+}
+
+// moveTo moves the cursor to x, y, limited to the screen.
+func (s *Screen) moveTo(x int, y int) {
+	s.x = clamp(x, 0, s.cols-1)
+	s.y = clamp(y, 0, s.rows-1)
+	s.pendingWrap = false
+}
+
+// moveVertically moves the cursor n lines down, or up if n is negative,
+// stopping at the margins if it starts within them.
+func (s *Screen) moveVertically(n int) {
+	top, bottom := 0, s.rows-1
+	if s.top <= s.y && s.y <= s.bottom {
+		top, bottom = s.top, s.bottom
+	}
+	s.moveTo(s.x, clamp(s.y+n, top, bottom))
+}
+
+// moveHorizontally moves the cursor n columns right, or left if n is
+// negative, stopping at the margins if it starts within them.
+func (s *Screen) moveHorizontally(n int) {
+	s.moveTo(clamp(s.x+n, s.leftLimit(), s.rightLimit()), s.y)
+}
+
+// originTop and originLeft return the position CUP counts from: the top left
+// margin in origin mode, and the top left of the screen otherwise.
+func (s *Screen) originTop() int {
+	if s.modes.Origin {
+		return s.top
+	}
+	return 0
+}
+
+func (s *Screen) originLeft() int {
+	if s.modes.Origin {
+		return s.left
+	}
+	return 0
+}
+
+// home moves the cursor to the origin.
+func (s *Screen) home() {
+	s.moveTo(s.originLeft(), s.originTop())
+}
+
+// scrollUp scrolls the lines within the margins up n lines, blanking those
+// uncovered at the bottom.
+func (s *Screen) scrollUp(n int) {
+	s.shiftLines(s.top, s.bottom, -n)
+}
+
+// scrollDown scrolls the lines within the margins down n lines, blanking
+// those uncovered at the top.
+func (s *Screen) scrollDown(n int) {
+	s.shiftLines(s.top, s.bottom, n)
+}
+
+// shiftLines moves the columns within the margins of lines top to bottom down
+// n lines, or up if n is negative, blanking the lines uncovered.
+func (s *Screen) shiftLines(top int, bottom int, n int) {
+	left, right := s.left, s.right
+	height := bottom - top + 1
+	if n > height {
+		n = height
+	}
+	if n < -height {
+		n = -height
+	}
+
+	s.damage(left, top, right, bottom)
+	s.record(Operation{Kind: OpScroll, Rect: Rect{Left: left, Top: top, Right: right, Bottom: bottom}, Count: n})
+	if left == 0 && right == s.cols-1 {
+		s.shiftWrapped(top, bottom, n)
+	} else {
+		// Lines shifted in part no longer continue on the line below
+		for y := top; y <= bottom; y++ {
+			s.wrapped[y] = false
+		}
+	}
+
+	if n > 0 {
+		for y := bottom; y >= top+n; y-- {
+			copy(s.writableRow(y)[left:right+1], s.cells[y-n][left:right+1])
+		}
+		s.eraseRect(left, top, right, top+n-1)
+	} else if n < 0 {
+		for y := top; y <= bottom+n; y++ {
+			copy(s.writableRow(y)[left:right+1], s.cells[y-n][left:right+1])
+		}
+		s.eraseRect(left, bottom+n+1, right, bottom)
+	}
+}
+
+// shiftWrapped moves the wrapped marks of lines top to bottom down n lines, or
+// up if n is negative, as shiftLines does the lines. The lines on either side
+// of the region no longer continue across its edges.
+func (s *Screen) shiftWrapped(top int, bottom int, n int) {
+	if n > 0 {
+		copy(s.wrapped[top+n:bottom+1], s.wrapped[top:bottom+1-n])
+		for y := top; y < top+n; y++ {
+			s.wrapped[y] = false
+		}
+	} else if n < 0 {
+		copy(s.wrapped[top:bottom+1+n], s.wrapped[top-n:bottom+1])
+		for y := bottom + n + 1; y <= bottom; y++ {
+			s.wrapped[y] = false
+		}
+	}
+	s.wrapped[bottom] = false
+	if top > 0 {
+		s.wrapped[top-1] = false
+	}
+}
+
+// insertCells shifts the cells from the cursor to right along by n, losing
+// those pushed past right.
+func (s *Screen) insertCells(n int, right int) {
+	row := s.writableRow(s.y)
+	if n > right-s.x+1 {
+		n = right - s.x + 1
+	}
+
+	copy(row[s.x+n:right+1], row[s.x:right+1-n])
+	s.damage(s.x, s.y, right, s.y)
+	s.record(Operation{Kind: OpShift, Rect: Rect{Left: s.x, Top: s.y, Right: right, Bottom: s.y}, Count: n})
+	s.eraseRect(s.x, s.y, s.x+n-1, s.y)
+	s.fixWide(s.y, right)
+}
+
+// deleteCells shifts the cells right of the cursor left by n, blanking those
+// uncovered before right.
+func (s *Screen) deleteCells(n int, right int) {
+	row := s.writableRow(s.y)
+	if n > right-s.x+1 {
+		n = right - s.x + 1
+	}
+
+	copy(row[s.x:right+1-n], row[s.x+n:right+1])
+	s.damage(s.x, s.y, right, s.y)
+	s.record(Operation{Kind: OpShift, Rect: Rect{Left: s.x, Top: s.y, Right: right, Bottom: s.y}, Count: -n})
+	s.eraseRect(right-n+1, s.y, right, s.y)
+	s.fixWide(s.y, s.x)
+}
+
+// switchBuffer switches between the main and alternate screens, each keeping
+// its own contents and saved cursor. The cursor and rendition carry over.
+func (s *Screen) switchBuffer() {
+	s.cells, s.other.cells = s.other.cells, s.cells
+	s.wrapped, s.other.wrapped = s.other.wrapped, s.wrapped
+	s.shared, s.other.shared = s.other.shared, s.shared
+	s.saved, s.other.saved = s.other.saved, s.saved
+	s.modes.AlternateScreen = !s.modes.AlternateScreen
+
+	s.damage(0, 0, s.cols-1, s.rows-1)
+	s.record(Operation{Kind: OpSwitch, Rect: Rect{Right: s.cols - 1, Bottom: s.rows - 1}})
+}
+
+// writableRow returns row y for changing, first copying it if a snapshot shares it.
+// The copy shares the cells' combining characters, which are only ever
+// replaced, never changed in place.
+func (s *Screen) writableRow(y int) []Cell {
+	if s.shared[y] {
+		s.cells[y] = append([]Cell(nil), s.cells[y]...)
+		s.shared[y] = false
+	}
+	return s.cells[y]
+}
+
+// fixWide erases a wide character split at column x of row y.
+func (s *Screen) fixWide(y int, x int) {
+	row := s.cells[y]
+	split := row[x].Width == 2 && (x+1 >= s.cols || row[x+1].Width != 0) ||
+		row[x].Width == 0 && (x == 0 || row[x-1].Width != 2)
+	if split {
+		s.writableRow(y)[x] = s.pen.blank()
+		s.damage(x, y, x, y)
+	}
+}
+
+// erase blanks the cells within the inclusive rectangle, as ED and EL do.
+func (s *Screen) erase(left int, top int, right int, bottom int) {
+	s.record(Operation{Kind: OpErase, Rect: Rect{Left: left, Top: top, Right: right, Bottom: bottom}})
+	s.eraseRect(left, top, right, bottom)
+}
+
+// eraseRect blanks the cells within the inclusive rectangle.
+func (s *Screen) eraseRect(left int, top int, right int, bottom int) {
+	s.damage(left, top, right, bottom)
+	for y := top; y <= bottom; y++ {
+		row := s.writableRow(y)
+		for x := left; x <= right; x++ {
+			row[x] = s.pen.blank()
+		}
+		if right == s.cols-1 {
+			s.wrapped[y] = false
+		}
+		if left > 0 {
+			s.fixWide(y, left-1)
+		}
+		if right+1 < s.cols {
+			s.fixWide(y, right+1)
+		}
+	}
+}
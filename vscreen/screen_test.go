@@ -0,0 +1,686 @@
+package vscreen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/Azure/go-ansiterm"
+)
+
+// screenHelper writes output to a new screen of cols by rows and checks its
+// text and cursor position.
+func screenHelper(t *testing.T, cols int, rows int, output string, expected string, x int, y int) *Screen {
+	s := New(cols, rows)
+	s.Write([]byte(output))
+
+	if actual := s.String(); actual != expected {
+		t.Errorf("%q: screen is %q, expected %q", output, actual, expected)
+	}
+	if actualX, actualY := s.Cursor(); actualX != x || actualY != y {
+		t.Errorf("%q: cursor at %d,%d, expected %d,%d", output, actualX, actualY, x, y)
+	}
+
+	return s
+}
+
+func TestPrint(t *testing.T) {
+	screenHelper(t, 10, 3, "hello", "hello", 5, 0)
+	screenHelper(t, 10, 3, "hello\r\nworld", "hello\nworld", 5, 1)
+	screenHelper(t, 5, 3, "abcde", "abcde", 4, 0)
+	screenHelper(t, 5, 3, "abcdef", "abcde\nf", 1, 1)
+	screenHelper(t, 5, 3, "\x1b[?7labcdef", "abcdf", 4, 0)
+	screenHelper(t, 5, 2, "a\r\nb\r\nc", "b\nc", 1, 1)
+	screenHelper(t, 10, 1, "ab\bc", "ac", 2, 0)
+	screenHelper(t, 10, 1, "a\tb", "a       b", 9, 0)
+	screenHelper(t, 10, 1, "\x1b(0qx\x1b(Bq", "─│q", 3, 0)
+	screenHelper(t, 10, 1, "ab\x1b[3b", "abbbb", 5, 0)
+	screenHelper(t, 5, 2, "ab\x1b[999999999b", "abbbb\nbbbbb", 4, 1)
+
+	// A control function interrupting a UTF-8 sequence ends it
+	screenHelper(t, 10, 1, "a\xe4\xb8\x1b[Cb\xad", "a\ufffd b\ufffd", 5, 0)
+	screenHelper(t, 10, 1, "a\xe4\xb8\rb", "b\ufffd", 1, 0)
+	s := New(10, 1)
+	s.Write([]byte("\xe4\xb8"))
+	s.Write([]byte("\xad"))
+	if line := s.Line(0); line != "中" {
+		t.Errorf("a character split between writes is printed as %q, expected \"中\"", line)
+	}
+}
+
+func TestWideCharacters(t *testing.T) {
+	s := screenHelper(t, 5, 2, "a中b", "a中b", 4, 0)
+	if cell := s.Cell(1, 0); cell.Rune != '中' || cell.Width != 2 {
+		t.Errorf("Cell(1, 0) = %+v, expected a wide character", cell)
+	}
+	if cell := s.Cell(2, 0); cell.Width != 0 {
+		t.Errorf("Cell(2, 0) = %+v, expected the wide character's second half", cell)
+	}
+
+	screenHelper(t, 5, 2, "abcd中", "abcd\n中", 2, 1)
+	screenHelper(t, 5, 2, "a中\x1b[2Gx", "ax", 2, 0)
+	screenHelper(t, 5, 2, "e\u0301", "e\u0301", 1, 0)
+
+	// Wide characters can be redrawn in place, and overwrite parts of
+	// others
+	screenHelper(t, 5, 1, "😀中\r中", "中中", 2, 0)
+	screenHelper(t, 5, 1, "中中\x1b[2G😀", " 😀", 3, 0)
+
+	// Joined emoji and flags share the cells of their cluster
+	s = screenHelper(t, 10, 1, "👩\u200d👧x👍🏽y", "👩\u200d👧x👍🏽y", 6, 0)
+	if cell := s.Cell(0, 0); cell.Width != 2 || string(cell.Combining) != "\u200d👧" {
+		t.Errorf("Cell(0, 0) = %+v, expected a ZWJ sequence", cell)
+	}
+	screenHelper(t, 10, 1, "🇯🇵🇺🇸!", "🇯🇵🇺🇸!", 3, 0)
+	screenHelper(t, 10, 1, "👩\u200d x", "👩\u200d x", 4, 0)
+	screenHelper(t, 10, 1, "\x1b[3G🏽", "  🏽", 4, 0)
+}
+
+func TestCursorMovement(t *testing.T) {
+	screenHelper(t, 10, 5, "\x1b[3;4Hx", "\n\n   x", 4, 2)
+	screenHelper(t, 10, 5, "\x1b[20;20H", "", 9, 4)
+	screenHelper(t, 10, 5, "\x1b[3;3H\x1b[A\x1b[2C\x1b[B\x1b[D", "", 3, 2)
+	screenHelper(t, 10, 5, "\x1b[2;4r\x1b[?6h\x1b[10;1Hx", "\n\n\nx", 1, 3)
+	screenHelper(t, 10, 5, "\x1b[5G\x1b[3d", "", 4, 2)
+}
+
+func TestErase(t *testing.T) {
+	fill := "abc\r\ndef\r\nghi\x1b[2;2H"
+	screenHelper(t, 3, 3, fill+"\x1b[J", "abc\nd", 1, 1)
+	screenHelper(t, 3, 3, fill+"\x1b[1J", "\n  f\nghi", 1, 1)
+	screenHelper(t, 3, 3, fill+"\x1b[2J", "", 1, 1)
+	screenHelper(t, 3, 3, fill+"\x1b[K", "abc\nd\nghi", 1, 1)
+	screenHelper(t, 3, 3, fill+"\x1b[1K", "abc\n  f\nghi", 1, 1)
+	screenHelper(t, 3, 3, fill+"\x1b[2K", "abc\n\nghi", 1, 1)
+}
+
+func TestScrolling(t *testing.T) {
+	fill := "1\r\n2\r\n3\r\n4"
+	screenHelper(t, 3, 4, fill+"\x1b[2;3r\x1b[3H\n", "1\n3\n\n4", 0, 2)
+	screenHelper(t, 3, 4, fill+"\x1b[2;3r\x1b[2H\x1bM", "1\n\n2\n4", 0, 1)
+	screenHelper(t, 3, 4, fill+"\x1b[S", "2\n3\n4", 1, 3)
+	screenHelper(t, 3, 4, fill+"\x1b[T", "\n1\n2\n3", 1, 3)
+	screenHelper(t, 3, 4, fill+"\x1b[2H\x1b[L", "1\n\n2\n3", 0, 1)
+	screenHelper(t, 3, 4, fill+"\x1b[2H\x1b[M", "1\n3\n4", 0, 1)
+	screenHelper(t, 4, 2, "abcd\r\nefgh\x1b[?69h\x1b[2;3s\x1b[1;1H\x1b[S", "afgd\ne  h", 0, 0)
+}
+
+func TestInsertMode(t *testing.T) {
+	screenHelper(t, 5, 1, "abcd\x1b[2G\x1b[4hx", "axbcd", 2, 0)
+	screenHelper(t, 5, 1, "abcde\x1b[2G\x1b[4hxy", "axybc", 3, 0)
+}
+
+func TestTabStops(t *testing.T) {
+	screenHelper(t, 20, 1, "\x1b[3g\x1b[4G\x1bH\r\tx", "   x", 4, 0)
+	screenHelper(t, 20, 1, "\x1b[2I", "", 16, 0)
+	screenHelper(t, 20, 1, "\x1b[12G\x1b[Z", "", 8, 0)
+}
+
+func TestSelectGraphicRendition(t *testing.T) {
+	s := New(10, 1)
+	s.Write([]byte("\x1b[1;31;42ma\x1b[22;38;5;200;48;2;1;2;3mb\x1b[0mc"))
+
+	a, b, c := s.Cell(0, 0), s.Cell(1, 0), s.Cell(2, 0)
+	if a.Attrs != AttrBold || a.Fg != BasicColor(1) || a.Bg != BasicColor(2) {
+		t.Errorf("a = %+v", a)
+	}
+	if b.Attrs != 0 || b.Fg != PaletteColor(200) || b.Bg != RGBColor(1, 2, 3) {
+		t.Errorf("b = %+v", b)
+	}
+	if c.Attrs != 0 || c.Fg != DefaultColor || c.Bg != DefaultColor {
+		t.Errorf("c = %+v", c)
+	}
+}
+
+func TestSGRStack(t *testing.T) {
+	s := New(10, 1)
+
+	// All renditions are pushed by default; only the foreground and bold
+	// when selected
+	s.Write([]byte("\x1b[1;31;42m\x1b[#{\x1b[0;4;34ma\x1b[#}b\x1b[10;1#p\x1b[0;4;35;44mc\x1b[#qd\x1b[#}e"))
+
+	tests := []struct {
+		attrs  Attr
+		fg, bg Color
+	}{
+		{AttrUnderline, BasicColor(4), DefaultColor},
+		{AttrBold, BasicColor(1), BasicColor(2)},
+		{AttrUnderline, BasicColor(5), BasicColor(4)},
+		{AttrBold | AttrUnderline, BasicColor(1), BasicColor(4)},
+		{AttrBold | AttrUnderline, BasicColor(1), BasicColor(4)},
+	}
+
+	for x, expected := range tests {
+		if cell := s.Cell(x, 0); cell.Attrs != expected.attrs || cell.Fg != expected.fg || cell.Bg != expected.bg {
+			t.Errorf("cell %d = %+v, expected %+v", x, cell, expected)
+		}
+	}
+}
+
+func TestModes(t *testing.T) {
+	s := New(10, 1)
+	s.Write([]byte("\x1b[?1h\x1b=\x1b[?1002h\x1b[?1006h\x1b[?2004h\x1b[?25l\x1b[>1u"))
+
+	expected := Modes{
+		Autowrap:              true,
+		ApplicationCursorKeys: true,
+		ApplicationKeypad:     true,
+		MouseTracking:         1002,
+		SGRMouse:              true,
+		BracketedPaste:        true,
+		KittyFlags:            1,
+	}
+	if actual := s.Modes(); actual != expected {
+		t.Errorf("Modes() = %+v, expected %+v", actual, expected)
+	}
+
+	s.Write([]byte("\x1b[!p\x1b[<u"))
+	if modes := s.Modes(); modes.ApplicationCursorKeys || !modes.CursorVisible || modes.KittyFlags != 0 {
+		t.Errorf("Modes() after reset = %+v", modes)
+	}
+}
+
+func TestResponses(t *testing.T) {
+	var responses bytes.Buffer
+	s := New(10, 5, WithResponseWriter(&responses))
+
+	queries := map[string]string{
+		"\x1b[2;3H\x1b[6n": "\x1b[2;3R",
+		"\x1b[5n":          "\x1b[0n",
+		"\x1b[18t":         "\x1b[8;5;10t",
+	}
+
+	for query, expected := range queries {
+		responses.Reset()
+		s.Write([]byte(query))
+		if actual := responses.String(); actual != expected {
+			t.Errorf("%q: responded %q, expected %q", query, actual, expected)
+		}
+	}
+
+	s.Write([]byte("\x1b[2;4r\x1b[1;31m"))
+	controlStrings := map[string]string{
+		"\x1bP$qr\x1b\\": "\x1bP1$r2;4r\x1b\\",
+		"\x1bP$qm\x1b\\": "\x1bP1$r0;1;31m\x1b\\",
+		"\x1bP$qq\x1b\\": "\x1bP0$r\x1b\\",
+
+		// XTGETTCAP for Co, RGB and kbs
+		"\x1bP+q436F;524742;6B6273\x1b\\": "\x1bP1+r436F=323536\x1b\\\x1bP1+r524742\x1b\\\x1bP0+r6B6273\x1b\\",
+	}
+
+	for query, expected := range controlStrings {
+		responses.Reset()
+		s.Write([]byte(query))
+		if actual := responses.String(); actual != expected {
+			t.Errorf("%q: responded %q, expected %q", query, actual, expected)
+		}
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	s := New(10, 2)
+	s.Write([]byte("e\u0301\x1b[1;31mred\x1b[0m plain\r\n\x1b[44m  \x1b[0m"))
+
+	snap := s.Snapshot()
+	s.Write([]byte("\x1b[2J\x1b[Hchanged"))
+
+	if actual, expected := snap.String(), "e\u0301red plain"; actual != expected {
+		t.Errorf("String() = %q, expected %q", actual, expected)
+	}
+	if x, y := snap.Cursor(); x != 2 || y != 1 {
+		t.Errorf("Cursor() = %d,%d, expected 2,1", x, y)
+	}
+
+	expected := "e\u0301\x1b[0;1;31mred\x1b[0m plain\n\x1b[0;44m  \x1b[0m"
+	if actual := snap.DumpWithAttributes(); actual != expected {
+		t.Errorf("DumpWithAttributes() = %q, expected %q", actual, expected)
+	}
+
+	cell := snap.Cell(0, 0)
+	cell.Combining[0] = 'x'
+	if snap.Line(0) != "e\u0301red plain" {
+		t.Errorf("modifying a cell changed the snapshot: %q", snap.Line(0))
+	}
+}
+
+func TestState(t *testing.T) {
+	s := New(10, 5)
+	s.Write([]byte("\x1b[2;4r\x1b[1;10Hx\x1b[?25l\x1b[5 q\x1b[1;32m\x1b)0\x0e"))
+
+	snap := s.Snapshot()
+	s.Write([]byte("\x1bc"))
+
+	if modes := snap.Modes(); modes.CursorVisible || modes.CursorStyle != 5 {
+		t.Errorf("Modes() = %+v, expected a hidden bar cursor", modes)
+	}
+	if top, bottom, left, right := snap.Margins(); top != 1 || bottom != 3 || left != 0 || right != 9 {
+		t.Errorf("Margins() = %d,%d,%d,%d, expected 1,3,0,9", top, bottom, left, right)
+	}
+	if !snap.PendingWrap() || s.PendingWrap() {
+		t.Errorf("PendingWrap() = %v, after RIS %v", snap.PendingWrap(), s.PendingWrap())
+	}
+	if fg, bg, attrs := snap.Rendition(); fg != BasicColor(2) || bg != DefaultColor || attrs != AttrBold {
+		t.Errorf("Rendition() = %v,%v,%v, expected bold green", fg, bg, attrs)
+	}
+	if charsets, gl := snap.Charsets(); charsets != [4]byte{'B', '0', 'B', 'B'} || gl != 1 {
+		t.Errorf("Charsets() = %q,%d, expected G1 DEC Special Graphics invoked", charsets, gl)
+	}
+	if charsets, gl := s.Charsets(); charsets != [4]byte{'B', 'B', 'B', 'B'} || gl != 0 {
+		t.Errorf("Charsets() after RIS = %q,%d", charsets, gl)
+	}
+}
+
+func TestDamage(t *testing.T) {
+	s := New(10, 5)
+	if damage := s.Damage(); len(damage) != 1 || damage[0] != (Rect{0, 0, 9, 4}) {
+		t.Errorf("Damage() of a new screen = %v", damage)
+	}
+
+	tests := []struct {
+		output   string
+		expected []Rect
+	}{
+		{"\x1b[2;3Hab", []Rect{{2, 1, 3, 1}}},
+		{"\x1b[3;2Hx\x1b[4;2Hy", []Rect{{1, 2, 1, 3}}},
+		{"\x1b[5;5H\x1b[A\x1b[C", nil},
+		{"\x1b[2;4r\x1b[4H\n", []Rect{{0, 1, 9, 3}}},
+		{"\x1b[1;4H\x1b[K", []Rect{{3, 0, 9, 0}}},
+	}
+
+	for _, test := range tests {
+		s.Sync()
+		s.Write([]byte(test.output))
+		if actual := s.Damage(); fmt.Sprint(actual) != fmt.Sprint(test.expected) {
+			t.Errorf("%q: Damage() = %v, expected %v", test.output, actual, test.expected)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	screens := []string{
+		"hello\r\n\x1b[1;31mworld\x1b[0m",
+		"hellO\r\n\x1b[1;31mworld\x1b[0m there",
+		"\x1b[2;1H\x1b[K\x1b[3;4H\x1b[44m  \x1b[0m\x1b[H中",
+		"\x1b[1;10Hz\x1b[?25l\x1b[2;2H",
+		"\x1b[2J\x1b[3;1Hé\x1b[38;5;200mtail\x1b[H",
+		"中😀\x1b[H😀中",
+	}
+
+	s, replay := New(10, 3), New(10, 3)
+	var from Snapshot
+	for _, output := range screens {
+		s.Write([]byte(output))
+		to := s.Snapshot()
+		replay.Write(Diff(from, to))
+
+		if actual, expected := replay.DumpWithAttributes(), to.DumpWithAttributes(); actual != expected {
+			t.Errorf("%q: replayed %q, expected %q", output, actual, expected)
+		}
+		if x, y := replay.Cursor(); x != s.x || y != s.y {
+			t.Errorf("%q: replayed cursor at %d,%d, expected %d,%d", output, x, y, s.x, s.y)
+		}
+		if replay.Modes().CursorVisible != to.Modes().CursorVisible {
+			t.Errorf("%q: replayed cursor visibility %v", output, replay.Modes().CursorVisible)
+		}
+		from = to
+	}
+
+	if diff := Diff(from, from); len(diff) != 0 {
+		t.Errorf("Diff of a snapshot with itself = %q", diff)
+	}
+
+	s = New(12, 1)
+	s.Write([]byte("abcdefghijk"))
+	from = s.Snapshot()
+	s.Write([]byte("\x1b[2GB\x1b[10GJ\x1b[H"))
+	if diff, expected := string(Diff(from, s.Snapshot())), "\r\x1b[CB\x1b[7CJ\r"; diff != expected {
+		t.Errorf("Diff = %q, expected %q", diff, expected)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	s := New(20, 3)
+	s.Write([]byte("a<b\x1b[1;31mred\x1b[0m \x1b[4;38;5;196;48;2;1;2;3mx\x1b[0m\r\n\x1b[7mrev\x1b[0m   "))
+
+	expected := `<pre class="vscreen">a&lt;b<span style="color:#cd0000;font-weight:bold">red</span> ` +
+		`<span style="color:#ff0000;background-color:#010203;text-decoration:underline">x</span>` + "\n" +
+		`<span style="color:#000000;background-color:#e5e5e5">rev</span></pre>`
+	if actual := s.HTML(); actual != expected {
+		t.Errorf("HTML() = %q, expected %q", actual, expected)
+	}
+}
+
+func TestSVG(t *testing.T) {
+	s := New(4, 2)
+	s.Write([]byte("a&\x1b[1;44m中\x1b[0m\r\n\x1b[4m \x1b[0m\x1b[4 q"))
+
+	expected := `<svg xmlns="http://www.w3.org/2000/svg" width="36" height="36" viewBox="0 0 36 36" font-family="monospace" font-size="15" xml:space="preserve">` +
+		`<rect width="36" height="36" fill="#000000"/>` +
+		`<rect x="18" y="0" width="18" height="18" fill="#0000ee"/>` +
+		`<text x="0 9" y="14" fill="#e5e5e5">a&amp;</text>` +
+		`<text x="18" y="14" fill="#e5e5e5" font-weight="bold">中</text>` +
+		`<text x="0" y="32" fill="#e5e5e5" text-decoration="underline"> </text>` +
+		`<rect class="cursor" x="9" y="34" width="9" height="2" fill="#e5e5e5" opacity="0.6"/>` +
+		`</svg>`
+	if actual := s.SVG(); actual != expected {
+		t.Errorf("SVG() = %q, expected %q", actual, expected)
+	}
+}
+
+func TestHyperlinks(t *testing.T) {
+	s := New(12, 2)
+	s.SetMetadata("build")
+	s.Write([]byte("\x1b]133;A\x07$ \x1b]133;B\x07ls\x1b]133;C\x07\r\n\x1b]8;id=1;http://a.b/?x&y\x1b\\li\x1b[1mnk\x1b]8;;\x1b\\ x"))
+
+	cells := []struct {
+		x, y int
+		link Hyperlink
+		zone Zone
+	}{
+		{0, 0, Hyperlink{}, ZonePrompt},
+		{2, 0, Hyperlink{}, ZoneInput},
+		{0, 1, Hyperlink{"1", "http://a.b/?x&y"}, ZoneOutput},
+		{3, 1, Hyperlink{"1", "http://a.b/?x&y"}, ZoneOutput},
+		{5, 1, Hyperlink{}, ZoneOutput},
+	}
+	for _, c := range cells {
+		if cell := s.Cell(c.x, c.y); cell.Link != c.link || cell.Zone != c.zone || cell.Meta != "build" {
+			t.Errorf("Cell(%d, %d) = %+v, expected link %v in zone %d", c.x, c.y, cell, c.link, c.zone)
+		}
+	}
+	if cell := s.Cell(8, 1); cell.Link != (Hyperlink{}) || cell.Zone != ZoneNone || cell.Meta != nil {
+		t.Errorf("Cell(8, 1) = %+v, expected an erased cell", cell)
+	}
+
+	expected := `<pre class="vscreen">$ ls` + "\n" + `<a href="http://a.b/?x&amp;y">li</a>` +
+		`<a href="http://a.b/?x&amp;y"><span style="font-weight:bold">nk</span></a><span style="font-weight:bold"> x</span></pre>`
+	if actual := s.HTML(); actual != expected {
+		t.Errorf("HTML() = %q, expected %q", actual, expected)
+	}
+
+	if svg := s.SVG(); !strings.Contains(svg, `<a href="http://a.b/?x&amp;y"><text x="0 9" y="32" fill="#e5e5e5">li</text></a>`) {
+		t.Errorf("SVG() = %q, expected a linked text", svg)
+	}
+
+	expectedDiff := "\x1b[H\x1b[2J$ ls\r\n\x1b]8;id=1;http://a.b/?x&y\x1b\\li\x1b[0;1mnk\x1b]8;;\x1b\\ x\x1b[0m\x1b[?25h"
+	if diff := string(Diff(Snapshot{}, s.Snapshot())); diff != expectedDiff {
+		t.Errorf("Diff = %q, expected %q", diff, expectedDiff)
+	}
+
+	s.Write([]byte("\x1bc"))
+	s.Write([]byte("a"))
+	if cell := s.Cell(0, 0); cell.Meta != nil || cell.Zone != ZoneNone {
+		t.Errorf("Cell(0, 0) after RIS = %+v", cell)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	s := New(10, 4)
+	s.Write([]byte("0123456789abc\r\nfoo  bar  \r\n中文 x"))
+
+	selections := []struct {
+		sel      Selection
+		expected string
+	}{
+		{Selection{EndX: 9, EndY: 3}, "0123456789abc\nfoo  bar\n中文 x"},
+		{Selection{EndX: 9, EndY: 3, HardWraps: true}, "0123456789\nabc\nfoo  bar\n中文 x"},
+		{Selection{StartX: 2, StartY: 1, EndX: 5}, "56789abc"},
+		{Selection{StartX: 4, StartY: 2, EndX: 9, EndY: 2}, " bar"},
+		{Selection{StartX: 4, StartY: 2, EndX: 9, EndY: 2, KeepBlanks: true}, " bar  "},
+		{Selection{StartX: 4, StartY: 2, EndX: 5, EndY: 2}, " b"},
+		{Selection{StartX: 3, StartY: 2, EndX: 1, EndY: 3, Block: true}, "oo \n中文"},
+		{Selection{StartX: 7, StartY: 1, EndX: 9, EndY: 3, Block: true}, "\nr\n"},
+	}
+	for _, test := range selections {
+		if actual := s.Extract(test.sel); actual != test.expected {
+			t.Errorf("Extract(%+v) = %q, expected %q", test.sel, actual, test.expected)
+		}
+	}
+
+	if !s.Wrapped(0) || s.Wrapped(1) {
+		t.Errorf("Wrapped(0), Wrapped(1) = %v, %v, expected true, false", s.Wrapped(0), s.Wrapped(1))
+	}
+
+	// Wrapping at the bottom scrolls the mark with the line
+	s = New(10, 2)
+	s.Write([]byte("x\r\n0123456789ab"))
+	snap := s.Snapshot()
+	if actual := snap.Extract(Selection{EndX: 9, EndY: 1}); actual != "0123456789ab" {
+		t.Errorf("Extract after scrolling = %q", actual)
+	}
+
+	s.Write([]byte("\x1b[H\x1b[K"))
+	if s.Wrapped(0) || !snap.Wrapped(0) {
+		t.Errorf("Wrapped(0) after EL = %v, snapshot %v", s.Wrapped(0), snap.Wrapped(0))
+	}
+}
+
+func TestAlternateScreen(t *testing.T) {
+	s := New(10, 3)
+	s.Write([]byte("shell $ \x1b[?1049h\x1b[Hfull\r\nscreen"))
+	if actual := s.String(); actual != "full\nscreen" || !s.Modes().AlternateScreen {
+		t.Errorf("alternate screen is %q, alternate %v", actual, s.Modes().AlternateScreen)
+	}
+
+	// Leaving restores the main screen and the cursor saved on entering
+	s.Write([]byte("\x1b[?1049l"))
+	if x, y := s.Cursor(); s.String() != "shell $" || x != 8 || y != 0 {
+		t.Errorf("main screen is %q with cursor %d,%d", s.String(), x, y)
+	}
+
+	// 47 keeps the alternate screen's contents, which 1047 clears on leaving
+	s.Write([]byte("\x1b[?47h\x1b[Hkept\x1b[?47l\x1b[?47h"))
+	if actual := s.String(); actual != "kept\nscreen" {
+		t.Errorf("alternate screen after 47 is %q", actual)
+	}
+	s.Write([]byte("\x1b[?1047l\x1b[?1047h"))
+	if actual := s.String(); actual != "" {
+		t.Errorf("alternate screen after 1047 is %q", actual)
+	}
+
+	// The main screen survives encoding while the alternate is displayed
+	var decoded Screen
+	if err := json.Unmarshal(mustMarshal(t, s), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	decoded.Write([]byte("\x1b[?1047l"))
+	if actual := decoded.String(); actual != "shell $" {
+		t.Errorf("main screen after decoding is %q", actual)
+	}
+
+	s.Write([]byte("\x1bc"))
+	if s.Modes().AlternateScreen || s.String() != "" {
+		t.Errorf("after RIS, screen is %q, alternate %v", s.String(), s.Modes().AlternateScreen)
+	}
+}
+
+func TestCallbacks(t *testing.T) {
+	var events []string
+	s := New(10, 3,
+		WithBellCallback(func() {
+			events = append(events, "bell")
+		}),
+		WithTitleCallback(func(title string) {
+			events = append(events, "title "+title)
+		}),
+		WithClipboardCallback(func(selections string, data []byte) {
+			events = append(events, "clipboard "+selections+" "+string(data))
+		}),
+		WithNotificationCallback(func(title string, body string) {
+			events = append(events, "notify "+title+": "+body)
+		}),
+		WithHyperlinkCallback(func(link Hyperlink, region []Rect) {
+			events = append(events, fmt.Sprintf("link %s %v", link.URI, region))
+		}),
+	)
+	s.Write([]byte("\a\x1b]2;vim\a\x1b]0;C:\\Windows\x1b\\\x1b]52;c;aGVsbG8=\a\x1b]52;;d29ybGQ=\a\x1b]52;c;?\a" +
+		"\x1b]9;done\a\x1b]9;4;1;50\a\x1b]777;notify;make;failed\a" +
+		"\x1b]8;;http://a\x1b\\see\r\nthis\x1b]8;;http://b\x1b\\\x1b]8;;\x1b\\x"))
+
+	expected := []string{
+		"bell",
+		"title vim",
+		"title C:\\Windows",
+		"clipboard c hello",
+		"clipboard s0 world",
+		"notify : done",
+		"notify make: failed",
+		"link http://a [{0 0 2 0} {0 1 3 1}]",
+	}
+	if fmt.Sprint(events) != fmt.Sprint(expected) {
+		t.Errorf("events are %q, expected %q", events, expected)
+	}
+}
+
+func TestOperationLog(t *testing.T) {
+	output := "abc\u0301\r\nd\x1b[1K\r\n\n"
+	expected := []Operation{
+		{Kind: OpPrint, Rect: Rect{Left: 0, Top: 0, Right: 2, Bottom: 0}, Text: "abc\u0301", Offset: 0},
+		{Kind: OpPrint, Rect: Rect{Left: 0, Top: 1, Right: 0, Bottom: 1}, Text: "d", Offset: 7},
+		{Kind: OpErase, Rect: Rect{Left: 0, Top: 1, Right: 1, Bottom: 1}, Offset: 11},
+		{Kind: OpScroll, Rect: Rect{Left: 0, Top: 0, Right: 5, Bottom: 2}, Count: -1, Offset: 14},
+	}
+
+	s := New(6, 3, WithOperationLog())
+	s.Write([]byte(output))
+	if actual := s.Operations(); fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("Operations() = %+v, expected %+v", actual, expected)
+	}
+	if actual := s.OperationsAt(0, 1); fmt.Sprint(actual) != fmt.Sprint(expected[1:]) {
+		t.Errorf("OperationsAt(0, 1) = %+v, expected %+v", actual, expected[1:])
+	}
+
+	// Offsets count across writes, so replaying output in other pieces
+	// records the same operations
+	replay := New(6, 3, WithOperationLog())
+	for i := 0; i < len(output); i += 4 {
+		end := i + 4
+		if end > len(output) {
+			end = len(output)
+		}
+		replay.Write([]byte(output[i:end]))
+	}
+	if actual := replay.Operations(); fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("Operations() of replay = %+v, expected %+v", actual, expected)
+	}
+
+	replay.ClearOperations()
+	replay.Write([]byte("\x1b#8"))
+	fill := []Operation{{Kind: OpFill, Rect: Rect{Right: 5, Bottom: 2}, Text: "E", Offset: int64(len(output)) + 2}}
+	if actual := replay.Operations(); fmt.Sprint(actual) != fmt.Sprint(fill) {
+		t.Errorf("Operations() after DECALN = %+v, expected %+v", actual, fill)
+	}
+
+	if ops := New(6, 3).Operations(); ops != nil {
+		t.Errorf("Operations() without a log = %+v", ops)
+	}
+}
+
+func TestConcurrentSnapshots(t *testing.T) {
+	s := New(20, 5, WithConcurrentSnapshots())
+	if cols, rows := s.Latest().Size(); cols != 20 || rows != 5 {
+		t.Fatalf("Latest().Size() = %d,%d before any output", cols, rows)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 500; i++ {
+			fmt.Fprintf(s, "\r\nline %d \x1b[1mbold\x1b[0m", i)
+		}
+	}()
+
+	// Every published snapshot holds whole lines, each written by one Write
+	for sampling := true; sampling; {
+		select {
+		case <-done:
+			sampling = false
+		default:
+		}
+
+		snap := s.Latest()
+		for y := 1; y < 5; y++ {
+			if line := snap.Line(y); line != "" && !strings.HasSuffix(line, " bold") {
+				t.Fatalf("Latest().Line(%d) = %q", y, line)
+			}
+		}
+	}
+
+	if actual := s.Latest().Line(4); actual != "line 499 bold" {
+		t.Errorf("Latest().Line(4) = %q after the last Write", actual)
+	}
+	if actual := New(5, 1).Latest(); actual.cells != nil {
+		t.Errorf("Latest() without WithConcurrentSnapshots = %+v", actual)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	output := "\x1b[3g\x1b[5G\x1bH\r\x1b[1;31;48;5;200mab\x1b[0m中e\u0301\x1b]8;id=x;http://a\x1b\\l\x1b]8;;\x1b\\" +
+		"\x1b[2;4r\x1b)0\x0e\x1b[?1h\x1b[>1u\x1b[6 q\x1b[3;1H\x1b[38;2;1;2;3m\x1b7\x1b[4;1H0123456789x\x1b[34m\x1b[10#{\x1b[m"
+	more := "\x1b8\x1b[#}\tq\x1b[?1l\x1b[4;10Hyz\x1b[<u\x1b[b"
+
+	s := New(10, 5)
+	s.Write([]byte(output))
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var restored Screen
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatalf("Unmarshal(%s): %v", data, err)
+	}
+
+	s.Write([]byte(more))
+	restored.Write([]byte(more))
+	expected, actual := s.Snapshot(), restored.Snapshot()
+	if actual.DumpWithAttributes() != expected.DumpWithAttributes() {
+		t.Errorf("restored screen is %q, expected %q", actual.DumpWithAttributes(), expected.DumpWithAttributes())
+	}
+	if cell := restored.Cell(5, 0); cell.Link != (Hyperlink{"x", "http://a"}) || string(cell.Combining) != "" {
+		t.Errorf("restored Cell(5, 0) = %+v", cell)
+	}
+	if expectedJSON, _ := json.Marshal(expected); string(expectedJSON) != string(mustMarshal(t, actual)) {
+		t.Errorf("restored snapshot is %s, expected %s", mustMarshal(t, actual), expectedJSON)
+	}
+
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		t.Fatal(err)
+	}
+	if modes := snap.Modes(); snap.Line(0) != "ab中e\u0301l" || !snap.Wrapped(2) || !modes.ApplicationCursorKeys || modes.KittyFlags != 1 {
+		t.Errorf("decoded snapshot is %q, wrapped %v, modes %+v", snap.String(), snap.Wrapped(2), snap.Modes())
+	}
+
+	blank := `{"version":1,"cols":2,"rows":1,"lines":[[{},{}]],"cursor":[0,0],"margins":[0,0,0,1],"pen":{},` +
+		`"modes":{"Insert":false,"Origin":false,"Autowrap":true,"LeftRightMargins":false,"CursorVisible":true,"CursorStyle":0,` +
+		`"SynchronizedUpdate":false,"ApplicationCursorKeys":false,"ApplicationKeypad":false,"MouseTracking":0,"SGRMouse":false,` +
+		`"BracketedPaste":false,"FocusReporting":false,"Win32Input":false,"ModifyOtherKeys":0,"KittyFlags":0,"AlternateScreen":false},"charsets":"BBBB"}`
+	if actual := string(mustMarshal(t, New(2, 1).Snapshot())); actual != blank {
+		t.Errorf("Marshal of a blank snapshot = %s, expected %s", actual, blank)
+	}
+
+	invalid := []string{
+		`{"version":2,"cols":2,"rows":1,"lines":[[{},{}]],"margins":[0,0,0,1]}`,
+		`{"version":1,"cols":2,"rows":1,"lines":[[{}]],"margins":[0,0,0,1]}`,
+		`{"version":1,"cols":2,"rows":1,"lines":[[{},{"w":true}]],"margins":[0,0,0,1]}`,
+		`{"version":1,"cols":2,"rows":1,"lines":[[{},{}]],"cursor":[2,0],"margins":[0,0,0,1]}`,
+		`{"version":1,"cols":2,"rows":1,"lines":[[{},{}]],"margins":[0,1,0,1]}`,
+		`{"version":1,"cols":2,"rows":1,"lines":[[{"fg":"red"},{}]],"margins":[0,0,0,1]}`,
+	}
+	for _, data := range invalid {
+		if err := json.Unmarshal([]byte(data), &snap); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded", data)
+		}
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
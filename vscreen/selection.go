@@ -0,0 +1,122 @@
+package vscreen
+
+import (
+	"strings"
+)
+
+// Selection is a range of cells to extract the text of, for copying.
+type Selection struct {
+	// The zero-based cells the selection starts and ends at, inclusive. They
+	// may be given in either order.
+	StartX, StartY int
+	EndX, EndY     int
+
+	// Block selects the rectangle with the two cells at its corners, rather
+	// than the stream of text from one to the other.
+	Block bool
+
+	// KeepBlanks keeps the blanks ending rows that are selected, which are
+	// otherwise trimmed.
+	KeepBlanks bool
+
+	// HardWraps ends every row of a stream with a newline, including rows
+	// autowrapped onto the next, which are otherwise joined to it.
+	HardWraps bool
+}
+
+// Wrapped reports whether row y continues on the row below it, having been
+// autowrapped onto it.
+func (s *Screen) Wrapped(y int) bool {
+	return s.wrapped[y]
+}
+
+// Extract returns the text of the cells sel selects; see Snapshot.Extract.
+func (s *Screen) Extract(sel Selection) string {
+	return extract(s.cells, s.wrapped, sel)
+}
+
+// Wrapped reports whether row y continues on the row below it, having been
+// autowrapped onto it.
+func (snap Snapshot) Wrapped(y int) bool {
+	return y < len(snap.wrapped) && snap.wrapped[y]
+}
+
+// Extract returns the text of the cells sel selects, with a newline ending
+// each row but the last. A stream runs from its start to the end of its row,
+// through whole rows, to its end; a block takes the same columns of each
+// row. A wide character is selected by either of its cells.
+func (snap Snapshot) Extract(sel Selection) string {
+	return extract(snap.cells, snap.wrapped, sel)
+}
+
+// extract returns the text of the cells of a screen sel selects.
+func extract(cells [][]Cell, wrapped []bool, sel Selection) string {
+	if len(cells) == 0 {
+		return ""
+	}
+
+	rows, cols := len(cells), len(cells[0])
+	startX, startY := clamp(sel.StartX, 0, cols-1), clamp(sel.StartY, 0, rows-1)
+	endX, endY := clamp(sel.EndX, 0, cols-1), clamp(sel.EndY, 0, rows-1)
+	if sel.Block {
+		if startX > endX {
+			startX, endX = endX, startX
+		}
+	} else if startY > endY || startY == endY && startX > endX {
+		startX, startY, endX, endY = endX, endY, startX, startY
+	}
+	if startY > endY {
+		startY, endY = endY, startY
+	}
+
+	var text strings.Builder
+	for y := startY; y <= endY; y++ {
+		left, right := startX, endX
+		if !sel.Block {
+			if y != startY {
+				left = 0
+			}
+			if y != endY {
+				right = cols - 1
+			}
+		}
+
+		joined := !sel.Block && !sel.HardWraps && y < len(wrapped) && wrapped[y]
+		if end := textEnd(cells[y]); !sel.KeepBlanks && !joined && right >= end {
+			right = end - 1
+		}
+
+		text.WriteString(rowText(cells[y], left, right))
+		if y != endY && !joined {
+			text.WriteByte('\n')
+		}
+	}
+
+	return text.String()
+}
+
+// textEnd returns the column after the last cell of a row that is not blank.
+func textEnd(row []Cell) int {
+	end := len(row)
+	for end > 0 && row[end-1].Rune == ' ' && len(row[end-1].Combining) == 0 {
+		end--
+	}
+	return end
+}
+
+// rowText returns the text of the cells left to right of a row, including
+// the whole of a wide character either end cuts in half.
+func rowText(row []Cell, left int, right int) string {
+	if right < left {
+		return ""
+	}
+	if row[left].Width == 0 && left > 0 {
+		left--
+	}
+
+	var line strings.Builder
+	for _, cell := range row[left : right+1] {
+		writeCell(&line, cell)
+	}
+	return line.String()
+}
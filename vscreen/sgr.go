@@ -0,0 +1,159 @@
+package vscreen
+
+import (
+	"strconv"
+	"strings"
+
+	. "github.com/Azure/go-ansiterm"
+)
+
+// sgrAttrs maps the SGR parameters setting and clearing attributes onto them.
+var sgrAttrs = map[int]struct {
+	set   Attr
+	clear Attr
+}{
+	ANSI_SGR_BOLD:            {set: AttrBold},
+	ANSI_SGR_DIM:             {set: AttrFaint},
+	ANSI_SGR_ITALIC:          {set: AttrItalic},
+	ANSI_SGR_UNDERLINE:       {set: AttrUnderline},
+	ANSI_SGR_BLINKSLOW:       {set: AttrBlink},
+	ANSI_SGR_BLINKFAST:       {set: AttrBlink},
+	ANSI_SGR_REVERSE:         {set: AttrReverse},
+	8:                        {set: AttrInvisible},
+	ANSI_SGR_LINETHROUGH:     {set: AttrStrikethrough},
+	ANSI_SGR_BOLD_DIM_OFF:    {clear: AttrBold | AttrFaint},
+	ANSI_SGR_ITALIC_OFF:      {clear: AttrItalic},
+	ANSI_SGR_UNDERLINE_OFF:   {clear: AttrUnderline},
+	ANSI_SGR_BLINK_OFF:       {clear: AttrBlink},
+	ANSI_SGR_REVERSE_OFF:     {clear: AttrReverse},
+	28:                       {clear: AttrInvisible},
+	ANSI_SGR_LINETHROUGH_OFF: {clear: AttrStrikethrough},
+}
+
+// applySGR returns the pen with the renditions selected by SGR parameters.
+func applySGR(p pen, params []int) pen {
+	if len(params) == 0 {
+		return pen{}
+	}
+
+	for i := 0; i < len(params); i++ {
+		param := params[i]
+		if attr, ok := sgrAttrs[param]; ok {
+			p.attrs = p.attrs&^attr.clear | attr.set
+			continue
+		}
+
+		switch {
+		case param == ANSI_SGR_RESET:
+			p = pen{}
+		case ANSI_SGR_FOREGROUND_BLACK <= param && param <= ANSI_SGR_FOREGROUND_WHITE:
+			p.fg = BasicColor(param - ANSI_SGR_FOREGROUND_BLACK)
+		case ANSI_SGR_BACKGROUND_BLACK <= param && param <= ANSI_SGR_BACKGROUND_WHITE:
+			p.bg = BasicColor(param - ANSI_SGR_BACKGROUND_BLACK)
+		case ANSI_SGR_FOREGROUND_BRIGHT_BLACK <= param && param <= ANSI_SGR_FOREGROUND_BRIGHT_WHITE:
+			p.fg = BasicColor(param - ANSI_SGR_FOREGROUND_BRIGHT_BLACK + 8)
+		case ANSI_SGR_BACKGROUND_BRIGHT_BLACK <= param && param <= ANSI_SGR_BACKGROUND_BRIGHT_WHITE:
+			p.bg = BasicColor(param - ANSI_SGR_BACKGROUND_BRIGHT_BLACK + 8)
+		case param == ANSI_SGR_FOREGROUND_DEFAULT:
+			p.fg = DefaultColor
+		case param == ANSI_SGR_BACKGROUND_DEFAULT:
+			p.bg = DefaultColor
+		case param == 38 || param == 48:
+			color, n := ExtendedColor(params[i+1:])
+			i += n
+			if n == 0 {
+				// Without a valid color the rest cannot be interpreted
+				return p
+			}
+			if param == 38 {
+				p.fg = color
+			} else {
+				p.bg = color
+			}
+		}
+	}
+
+	return p
+}
+
+// maxSGRStack is the depth of the XTPUSHSGR stack; as in xterm, further
+// pushes are ignored.
+const maxSGRStack = 10
+
+// pushedPen is an entry of the XTPUSHSGR stack: a pen, and which of its
+// renditions XTPOPSGR restores.
+type pushedPen struct {
+	pen   pen
+	attrs Attr // The attributes restored
+	fg    bool
+	bg    bool
+}
+
+// pushPen returns the entry pushing the renditions of p selected by the
+// parameters of XTPUSHSGR, all of them if there are none.
+func pushPen(p pen, params []int) pushedPen {
+	if len(params) == 0 {
+		return pushedPen{pen: p, attrs: ^Attr(0), fg: true, bg: true}
+	}
+
+	pushed := pushedPen{pen: p}
+	for _, param := range params {
+		switch param {
+		case 10:
+			pushed.fg = true
+		case 11:
+			pushed.bg = true
+		default:
+			pushed.attrs |= sgrAttrs[param].set
+		}
+	}
+	return pushed
+}
+
+// pop returns p with the renditions of the entry restored.
+func (pushed pushedPen) pop(p pen) pen {
+	p.attrs = p.attrs&^pushed.attrs | pushed.pen.attrs&pushed.attrs
+	if pushed.fg {
+		p.fg = pushed.pen.fg
+	}
+	if pushed.bg {
+		p.bg = pushed.pen.bg
+	}
+	return p
+}
+
+// sgrString returns the SGR parameters selecting the pen's renditions, as
+// reported by DECRQSS.
+func sgrString(p pen) string {
+	params := []string{"0"}
+	for _, param := range []int{ANSI_SGR_BOLD, ANSI_SGR_DIM, ANSI_SGR_ITALIC, ANSI_SGR_UNDERLINE, ANSI_SGR_BLINKSLOW, ANSI_SGR_REVERSE, 8, ANSI_SGR_LINETHROUGH} {
+		if p.attrs&sgrAttrs[param].set != 0 {
+			params = append(params, strconv.Itoa(param))
+		}
+	}
+
+	params = append(params, colorParams(p.fg, ANSI_SGR_FOREGROUND_BLACK)...)
+	params = append(params, colorParams(p.bg, ANSI_SGR_BACKGROUND_BLACK)...)
+	return strings.Join(params, ";")
+}
+
+// colorParams returns the SGR parameters selecting a color in its model,
+// given the parameter selecting black (30 for foreground, 40 for background).
+func colorParams(c Color, black int) []string {
+	if index, ok := c.Basic(); ok {
+		if index < 8 {
+			return []string{strconv.Itoa(black + index)}
+		}
+		return []string{strconv.Itoa(black + 60 + index - 8)}
+	}
+
+	if index, ok := c.Palette(); ok {
+		return []string{strconv.Itoa(black + 8), "5", strconv.Itoa(index)}
+	}
+
+	if r, g, b, ok := c.RGB(); ok {
+		return []string{strconv.Itoa(black + 8), "2", strconv.Itoa(int(r)), strconv.Itoa(int(g)), strconv.Itoa(int(b))}
+	}
+
+	return nil
+}
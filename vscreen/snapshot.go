@@ -0,0 +1,200 @@
+package vscreen
+
+import (
+	"strings"
+
+	. "github.com/Azure/go-ansiterm"
+)
+
+// Snapshot is an immutable copy of a screen's contents and state, taken by
+// Screen.Snapshot, for asserting on what was rendered.
+type Snapshot struct {
+	cols, rows int
+	cells      [][]Cell
+	wrapped    []bool
+	x, y       int
+	modes      Modes
+
+	top, bottom, left, right int
+	pendingWrap              bool
+	pen                      pen
+	charsets                 [4]byte
+	gl                       int
+}
+
+// Snapshot returns a copy of the screen as it is now, unaffected by later
+// output. Taking one is cheap: it shares the screen's rows, which the screen
+// copies before it next changes them.
+func (s *Screen) Snapshot() Snapshot {
+	cells := make([][]Cell, s.rows)
+	copy(cells, s.cells)
+	for y := range s.shared {
+		s.shared[y] = true
+	}
+
+	return Snapshot{
+		cols:    s.cols,
+		rows:    s.rows,
+		cells:   cells,
+		wrapped: append([]bool(nil), s.wrapped...),
+		x:       s.x,
+		y:       s.y,
+		modes:   s.modes,
+
+		top:         s.top,
+		bottom:      s.bottom,
+		left:        s.left,
+		right:       s.right,
+		pendingWrap: s.pendingWrap,
+		pen:         s.pen,
+		charsets:    s.charsets,
+		gl:          s.gl,
+	}
+}
+
+// Latest returns the snapshot of the screen published by its last Flush, with
+// WithConcurrentSnapshots, and otherwise a zero Snapshot. Unlike the rest of
+// a Screen, it may be called from any goroutine, such as one streaming the
+// screen to a browser while another writes a build's output to it.
+func (s *Screen) Latest() Snapshot {
+	snap, _ := s.latest.Load().(Snapshot)
+	return snap
+}
+
+// Size returns the size of the screen in cells.
+func (snap Snapshot) Size() (cols int, rows int) {
+	return snap.cols, snap.rows
+}
+
+// Cursor returns the zero-based cursor position.
+func (snap Snapshot) Cursor() (x int, y int) {
+	return snap.x, snap.y
+}
+
+// Cell returns the cell at the zero-based position x, y.
+func (snap Snapshot) Cell(x int, y int) Cell {
+	return copyCell(snap.cells[y][x])
+}
+
+// Modes reports the terminal modes.
+func (snap Snapshot) Modes() Modes {
+	return snap.modes
+}
+
+// Margins returns the zero-based, inclusive scrolling margins.
+func (snap Snapshot) Margins() (top int, bottom int, left int, right int) {
+	return snap.top, snap.bottom, snap.left, snap.right
+}
+
+// PendingWrap reports whether a character was printed in the last column,
+// leaving the next to wrap onto the next line.
+func (snap Snapshot) PendingWrap() bool {
+	return snap.pendingWrap
+}
+
+// Rendition returns the colors and attributes characters are printed in.
+func (snap Snapshot) Rendition() (fg Color, bg Color, attrs Attr) {
+	return snap.pen.fg, snap.pen.bg, snap.pen.attrs
+}
+
+// Charsets returns the character sets designated to G0-G3, and which of them
+// is invoked into GL.
+func (snap Snapshot) Charsets() (charsets [4]byte, gl int) {
+	return snap.charsets, snap.gl
+}
+
+// Line returns the text of row y, without trailing blanks.
+func (snap Snapshot) Line(y int) string {
+	return lineText(snap.cells[y])
+}
+
+// String returns the text of the screen, a line per row, without trailing
+// blanks or blank lines.
+func (snap Snapshot) String() string {
+	return screenText(snap.cells, lineText)
+}
+
+// DumpWithAttributes returns the text of the screen as String does, with an
+// SGR sequence (e.g. "\x1b[0;1;31m") before each cell whose rendition differs
+// from the cell before it, and "\x1b[0m" ending lines that end in another
+// rendition. Each line starts in the default rendition, and only blanks in
+// the default rendition are trimmed. Written to a terminal, the dump
+// reproduces the screen.
+func (snap Snapshot) DumpWithAttributes() string {
+	return screenText(snap.cells, lineWithAttributes)
+}
+
+// copyCell returns a copy of a cell that shares no memory with it.
+func copyCell(cell Cell) Cell {
+	if cell.Combining != nil {
+		cell.Combining = append([]rune(nil), cell.Combining...)
+	}
+	return cell
+}
+
+// screenText renders the rows of a screen with line, without trailing blank
+// lines.
+func screenText(cells [][]Cell, line func([]Cell) string) string {
+	lines := make([]string, len(cells))
+	for y, row := range cells {
+		lines[y] = line(row)
+	}
+
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+// lineText returns the text of a row, without trailing blanks.
+func lineText(row []Cell) string {
+	var line strings.Builder
+	for _, cell := range row {
+		writeCell(&line, cell)
+	}
+
+	return strings.TrimRight(line.String(), " ")
+}
+
+// lineWithAttributes returns the text of a row with SGR sequences marking
+// changes of rendition, without trailing blanks in the default rendition.
+func lineWithAttributes(row []Cell) string {
+	end := len(row)
+	for end > 0 && row[end-1].Rune == ' ' && len(row[end-1].Combining) == 0 && cellPen(row[end-1]) == (pen{}) {
+		end--
+	}
+
+	var line strings.Builder
+	current := pen{}
+	for _, cell := range row[:end] {
+		if cell.Width == 0 {
+			continue
+		}
+
+		if p := cellPen(cell); p != current {
+			line.WriteString("\x1b[" + sgrString(p) + "m")
+			current = p
+		}
+		writeCell(&line, cell)
+	}
+
+	if current != (pen{}) {
+		line.WriteString("\x1b[0m")
+	}
+	return line.String()
+}
+
+// writeCell writes the characters of a cell, of which there are none in the
+// cell a wide character extends into.
+func writeCell(line *strings.Builder, cell Cell) {
+	if cell.Width == 0 {
+		return
+	}
+
+	line.WriteRune(cell.Rune)
+	for _, r := range cell.Combining {
+		line.WriteRune(r)
+	}
+}
+
+// cellPen returns the rendition of a cell.
+func cellPen(cell Cell) pen {
+	return pen{fg: cell.Fg, bg: cell.Bg, attrs: cell.Attrs}
+}
@@ -0,0 +1,164 @@
+package vscreen
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	. "github.com/Azure/go-ansiterm"
+)
+
+// The geometry of a cell in SVG, in pixels.
+const (
+	svgCellWidth  = 9
+	svgCellHeight = 18
+	svgBaseline   = 14
+	svgFontSize   = 15
+)
+
+// The colors of the default foreground and background in SVG.
+var (
+	svgForeground = BasicColor(7)
+	svgBackground = BasicColor(0)
+)
+
+// SVG returns the screen as an SVG image; see Snapshot.SVG.
+func (s *Screen) SVG() string {
+	return s.Snapshot().SVG()
+}
+
+// SVG returns the screen as an SVG image of a grid of monospace cells, in
+// ANSI white on black by default, with the colors and attributes of each
+// cell and the cursor, if it is visible, drawn in its DECSCUSR style. Text
+// in OSC 8 hyperlinks is wrapped in anchors. Each
+// character is positioned in its cell, so the grid holds whatever font the
+// viewer substitutes.
+func (snap Snapshot) SVG() string {
+	var svg strings.Builder
+	width, height := snap.cols*svgCellWidth, snap.rows*svgCellHeight
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="monospace" font-size="%d" xml:space="preserve">`, width, height, width, height, svgFontSize)
+	fmt.Fprintf(&svg, `<rect width="%d" height="%d" fill="%s"/>`, width, height, cssColor(svgBackground))
+
+	for y, row := range snap.cells {
+		svgBackgrounds(&svg, row, y)
+	}
+	for y, row := range snap.cells {
+		svgText(&svg, row, y)
+	}
+
+	if snap.modes.CursorVisible {
+		svgCursor(&svg, snap.x, snap.y, snap.modes.CursorStyle)
+	}
+
+	svg.WriteString("</svg>")
+	return svg.String()
+}
+
+// svgBackgrounds draws the runs of a row whose background is not the
+// default.
+func svgBackgrounds(svg *strings.Builder, row []Cell, y int) {
+	for x := 0; x < len(row); {
+		_, bg := displayColors(cellPen(row[x]))
+		end := x + 1
+		for end < len(row) {
+			if _, next := displayColors(cellPen(row[end])); next != bg {
+				break
+			}
+			end++
+		}
+
+		if bg != DefaultColor {
+			fmt.Fprintf(svg, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`, x*svgCellWidth, y*svgCellHeight, (end-x)*svgCellWidth, svgCellHeight, cssColor(bg))
+		}
+		x = end
+	}
+}
+
+// svgText draws the characters of a row, a text element per run of cells in
+// the same rendition and hyperlink.
+func svgText(svg *strings.Builder, row []Cell, y int) {
+	for x := 0; x < len(row); {
+		p, link := cellPen(row[x]), row[x].Link
+		end := x + 1
+		for end < len(row) && (row[end].Width == 0 || cellPen(row[end]) == p && row[end].Link == link) {
+			end++
+		}
+
+		var text strings.Builder
+		var positions []string
+		for i, cell := range row[x:end] {
+			if cell.Width == 0 || cell.Rune == ' ' && len(cell.Combining) == 0 {
+				continue
+			}
+			writeCell(&text, cell)
+			for range cell.Combining {
+				positions = append(positions, fmt.Sprint((x+i)*svgCellWidth))
+			}
+			positions = append(positions, fmt.Sprint((x+i)*svgCellWidth))
+		}
+
+		decorated := p.attrs&(AttrUnderline|AttrStrikethrough) != 0
+		if (len(positions) > 0 || decorated) && p.attrs&AttrInvisible == 0 {
+			if len(positions) == 0 {
+				// Decorations of blanks are drawn by a text of spaces
+				text.WriteString(strings.Repeat(" ", end-x))
+				positions = []string{fmt.Sprint(x * svgCellWidth)}
+			}
+			if link.URI != "" {
+				fmt.Fprintf(svg, `<a href="%s">`, html.EscapeString(link.URI))
+			}
+			fmt.Fprintf(svg, `<text x="%s" y="%d"%s>%s</text>`, strings.Join(positions, " "), y*svgCellHeight+svgBaseline, svgAttributes(p), html.EscapeString(text.String()))
+			if link.URI != "" {
+				svg.WriteString("</a>")
+			}
+		}
+		x = end
+	}
+}
+
+// svgAttributes returns the presentation attributes of a rendition.
+func svgAttributes(p pen) string {
+	fg, _ := displayColors(p)
+	if fg == DefaultColor {
+		fg = svgForeground
+	}
+
+	attributes := fmt.Sprintf(` fill="%s"`, cssColor(fg))
+	if p.attrs&AttrBold != 0 {
+		attributes += ` font-weight="bold"`
+	}
+	if p.attrs&AttrFaint != 0 {
+		attributes += ` opacity="0.5"`
+	}
+	if p.attrs&AttrItalic != 0 {
+		attributes += ` font-style="italic"`
+	}
+
+	var decorations []string
+	if p.attrs&AttrUnderline != 0 {
+		decorations = append(decorations, "underline")
+	}
+	if p.attrs&AttrStrikethrough != 0 {
+		decorations = append(decorations, "line-through")
+	}
+	if len(decorations) > 0 {
+		attributes += ` text-decoration="` + strings.Join(decorations, " ") + `"`
+	}
+
+	return attributes
+}
+
+// svgCursor draws the cursor at x, y in a DECSCUSR style: a block (0-2),
+// an underline (3, 4) or a bar (5, 6).
+func svgCursor(svg *strings.Builder, x int, y int, style int) {
+	left, top := x*svgCellWidth, y*svgCellHeight
+	width, height := svgCellWidth, svgCellHeight
+	switch style {
+	case 3, 4:
+		top, height = top+svgCellHeight-2, 2
+	case 5, 6:
+		width = 2
+	}
+
+	fmt.Fprintf(svg, `<rect class="cursor" x="%d" y="%d" width="%d" height="%d" fill="%s" opacity="0.6"/>`, left, top, width, height, cssColor(svgForeground))
+}
@@ -0,0 +1,229 @@
+// Package vscreentest checks the screens that terminal output produces
+// against golden dumps, for testing handlers and the programs whose output
+// they render.
+//
+// A fixture is a file of raw output, name.in, beside its golden dump,
+// name.golden.json, which records the size of the screen the output is
+// written to and the expected contents. Running the tests with -update
+// rewrites the golden dumps from the screens produced.
+package vscreentest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/go-ansiterm"
+	"github.com/Azure/go-ansiterm/vscreen"
+)
+
+var update = flag.Bool("update", false, "rewrite golden screen dumps")
+
+// The size of the screen for fixtures without a golden dump.
+const (
+	DefaultCols = 80
+	DefaultRows = 24
+)
+
+// Golden is a screen dump, as stored in golden files.
+type Golden struct {
+	Cols   int      `json:"cols"`
+	Rows   int      `json:"rows"`
+	Cursor [2]int   `json:"cursor"`
+	Lines  []string `json:"lines"` // Each row, as Snapshot.DumpWithAttributes writes it
+}
+
+// Dump returns the golden dump of a snapshot.
+func Dump(snap vscreen.Snapshot) Golden {
+	cols, rows := snap.Size()
+	x, y := snap.Cursor()
+
+	golden := Golden{Cols: cols, Rows: rows, Cursor: [2]int{x, y}}
+	if dump := snap.DumpWithAttributes(); dump != "" {
+		golden.Lines = strings.Split(dump, "\n")
+	}
+	return golden
+}
+
+// Render writes output to a new screen of the golden dump's size and returns
+// the dump of the result.
+func Render(output []byte, cols int, rows int) Golden {
+	s := vscreen.New(cols, rows)
+	s.Write(output)
+	return Dump(s.Snapshot())
+}
+
+// Compare returns the differences between an expected and an actual dump,
+// one per line that differs, or nil if they are the same. A line whose text
+// matches but whose renditions do not is reported with the first cell that
+// differs, described.
+func Compare(expected Golden, actual Golden) []string {
+	var diffs []string
+	if expected.Cols != actual.Cols || expected.Rows != actual.Rows {
+		diffs = append(diffs, fmt.Sprintf("size is %dx%d, expected %dx%d", actual.Cols, actual.Rows, expected.Cols, expected.Rows))
+	}
+	if expected.Cursor != actual.Cursor {
+		diffs = append(diffs, fmt.Sprintf("cursor at %d,%d, expected %d,%d", actual.Cursor[0], actual.Cursor[1], expected.Cursor[0], expected.Cursor[1]))
+	}
+
+	for y := 0; y < len(expected.Lines) || y < len(actual.Lines); y++ {
+		e, a := line(expected.Lines, y), line(actual.Lines, y)
+		if e == a {
+			continue
+		}
+
+		eCells, aCells := cells(e, expected.Cols), cells(a, expected.Cols)
+		if text(eCells) != text(aCells) {
+			diffs = append(diffs, fmt.Sprintf("line %d is %q, expected %q", y, text(aCells), text(eCells)))
+			continue
+		}
+
+		for x := range eCells {
+			if describe(eCells[x]) != describe(aCells[x]) {
+				diffs = append(diffs, fmt.Sprintf("line %d column %d is %s, expected %s", y, x, describe(aCells[x]), describe(eCells[x])))
+				break
+			}
+		}
+	}
+
+	return diffs
+}
+
+// CheckFixture renders the output in the fixture name.in and compares it
+// with the golden dump name.golden.json, or with -update rewrites the dump.
+func CheckFixture(t testing.TB, name string) {
+	t.Helper()
+
+	output, err := ioutil.ReadFile(name + ".in")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	goldenFile := name + ".golden.json"
+	expected := Golden{Cols: DefaultCols, Rows: DefaultRows}
+	data, err := ioutil.ReadFile(goldenFile)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &expected); err != nil {
+			t.Fatalf("%s: %v", goldenFile, err)
+		}
+	case !os.IsNotExist(err) || !*update:
+		t.Fatal(err)
+	}
+
+	actual := Render(output, expected.Cols, expected.Rows)
+	if *update {
+		var data bytes.Buffer
+		encoder := json.NewEncoder(&data)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "\t")
+		if err := encoder.Encode(actual); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(goldenFile, data.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	for _, diff := range Compare(expected, actual) {
+		t.Errorf("%s: %s", name, diff)
+	}
+}
+
+// CheckDir checks every fixture in dir, each in a subtest.
+func CheckDir(t *testing.T, dir string) {
+	inputs, err := filepath.Glob(filepath.Join(dir, "*.in"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(input, ".in")
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			CheckFixture(t, name)
+		})
+	}
+}
+
+func line(lines []string, y int) string {
+	if y < len(lines) {
+		return lines[y]
+	}
+	return ""
+}
+
+// cells parses a dumped line back into cells.
+func cells(line string, cols int) []vscreen.Cell {
+	s := vscreen.New(cols, 1)
+	s.Write([]byte(line))
+
+	row := make([]vscreen.Cell, cols)
+	for x := range row {
+		row[x] = s.Cell(x, 0)
+	}
+	return row
+}
+
+func text(row []vscreen.Cell) string {
+	var line strings.Builder
+	for _, cell := range row {
+		if cell.Width == 0 {
+			continue
+		}
+		line.WriteRune(cell.Rune)
+		for _, r := range cell.Combining {
+			line.WriteRune(r)
+		}
+	}
+	return strings.TrimRight(line.String(), " ")
+}
+
+var attrNames = []struct {
+	attr vscreen.Attr
+	name string
+}{
+	{vscreen.AttrBold, "bold"},
+	{vscreen.AttrFaint, "faint"},
+	{vscreen.AttrItalic, "italic"},
+	{vscreen.AttrUnderline, "underline"},
+	{vscreen.AttrBlink, "blink"},
+	{vscreen.AttrReverse, "reverse"},
+	{vscreen.AttrInvisible, "invisible"},
+	{vscreen.AttrStrikethrough, "strikethrough"},
+}
+
+// describe returns a cell's character and rendition, e.g. 'a' (bold, fg 1).
+func describe(cell vscreen.Cell) string {
+	var rendition []string
+	for _, a := range attrNames {
+		if cell.Attrs&a.attr != 0 {
+			rendition = append(rendition, a.name)
+		}
+	}
+	if cell.Fg != ansiterm.DefaultColor {
+		rendition = append(rendition, "fg "+colorName(cell.Fg))
+	}
+	if cell.Bg != ansiterm.DefaultColor {
+		rendition = append(rendition, "bg "+colorName(cell.Bg))
+	}
+	if len(rendition) == 0 {
+		rendition = append(rendition, "default")
+	}
+
+	return fmt.Sprintf("%q (%s)", string(cell.Rune)+string(cell.Combining), strings.Join(rendition, ", "))
+}
+
+func colorName(c ansiterm.Color) string {
+	if r, g, b, ok := c.RGB(); ok {
+		return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+	}
+	index, _ := c.Palette()
+	return fmt.Sprint(index)
+}
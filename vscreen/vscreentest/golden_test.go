@@ -0,0 +1,30 @@
+package vscreentest
+
+import (
+	"testing"
+)
+
+func TestCorpus(t *testing.T) {
+	CheckDir(t, "testdata")
+}
+
+func TestCompare(t *testing.T) {
+	expected := Render([]byte("plain \x1b[1;31mred\x1b[m"), 20, 2)
+
+	tests := []struct {
+		output   string
+		expected []string
+	}{
+		{"plain \x1b[1;31mred\x1b[m", nil},
+		{"plain \x1b[1;31mrod\x1b[m", []string{`line 0 is "plain rod", expected "plain red"`}},
+		{"plain \x1b[1;31mre\x1b[22md\x1b[m", []string{`line 0 column 8 is "d" (fg 1), expected "d" (bold, fg 1)`}},
+		{"plain \x1b[1;31mred\x1b[m\r\n", []string{"cursor at 0,1, expected 9,0"}},
+	}
+
+	for _, test := range tests {
+		actual := Compare(expected, Render([]byte(test.output), 20, 2))
+		if len(actual) != len(test.expected) || len(actual) > 0 && actual[0] != test.expected[0] {
+			t.Errorf("%q: Compare() = %q, expected %q", test.output, actual, test.expected)
+		}
+	}
+}
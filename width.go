@@ -0,0 +1,140 @@
+package ansiterm
+
+import (
+	"unicode"
+)
+
+// runeRange is an inclusive range of code points.
+type runeRange struct {
+	first rune
+	last  rune
+}
+
+// wideRunes lists the East Asian Wide (W) and Fullwidth (F) ranges, which
+// occupy two terminal cells.
+// See http://www.unicode.org/reports/tr11/ and http://www.unicode.org/Public/UCD/latest/ucd/EastAsianWidth.txt.
+var wideRunes = []runeRange{
+	{0x1100, 0x115F}, {0x231A, 0x231B}, {0x2329, 0x232A}, {0x23E9, 0x23EC},
+	{0x23F0, 0x23F0}, {0x23F3, 0x23F3}, {0x25FD, 0x25FE}, {0x2614, 0x2615},
+	{0x2648, 0x2653}, {0x267F, 0x267F}, {0x2693, 0x2693}, {0x26A1, 0x26A1},
+	{0x26AA, 0x26AB}, {0x26BD, 0x26BE}, {0x26C4, 0x26C5}, {0x26CE, 0x26CE},
+	{0x26D4, 0x26D4}, {0x26EA, 0x26EA}, {0x26F2, 0x26F3}, {0x26F5, 0x26F5},
+	{0x26FA, 0x26FA}, {0x26FD, 0x26FD}, {0x2705, 0x2705}, {0x270A, 0x270B},
+	{0x2728, 0x2728}, {0x274C, 0x274C}, {0x274E, 0x274E}, {0x2753, 0x2755},
+	{0x2757, 0x2757}, {0x2795, 0x2797}, {0x27B0, 0x27B0}, {0x27BF, 0x27BF},
+	{0x2B1B, 0x2B1C}, {0x2B50, 0x2B50}, {0x2B55, 0x2B55}, {0x2E80, 0x303E},
+	{0x3041, 0x33FF}, {0x3400, 0x4DBF}, {0x4E00, 0x9FFF}, {0xA000, 0xA4CF},
+	{0xA960, 0xA97F}, {0xAC00, 0xD7A3}, {0xF900, 0xFAFF}, {0xFE10, 0xFE19},
+	{0xFE30, 0xFE6F}, {0xFF00, 0xFF60}, {0xFFE0, 0xFFE6}, {0x16FE0, 0x16FE4},
+	{0x17000, 0x18AFF}, {0x1B000, 0x1B2FF}, {0x1F004, 0x1F004}, {0x1F0CF, 0x1F0CF},
+	{0x1F18E, 0x1F18E}, {0x1F191, 0x1F19A}, {0x1F200, 0x1F202}, {0x1F210, 0x1F23B},
+	{0x1F240, 0x1F248}, {0x1F250, 0x1F251}, {0x1F260, 0x1F265}, {0x1F300, 0x1F320},
+	{0x1F32D, 0x1F335}, {0x1F337, 0x1F37C}, {0x1F37E, 0x1F393}, {0x1F3A0, 0x1F3CA},
+	{0x1F3CF, 0x1F3D3}, {0x1F3E0, 0x1F3F0}, {0x1F3F4, 0x1F3F4}, {0x1F3F8, 0x1F43E},
+	{0x1F440, 0x1F440}, {0x1F442, 0x1F4FC}, {0x1F4FF, 0x1F53D}, {0x1F54B, 0x1F54E},
+	{0x1F550, 0x1F567}, {0x1F57A, 0x1F57A}, {0x1F595, 0x1F596}, {0x1F5A4, 0x1F5A4},
+	{0x1F5FB, 0x1F64F}, {0x1F680, 0x1F6C5}, {0x1F6CC, 0x1F6CC}, {0x1F6D0, 0x1F6D2},
+	{0x1F6D5, 0x1F6D7}, {0x1F6EB, 0x1F6EC}, {0x1F6F4, 0x1F6FC}, {0x1F7E0, 0x1F7EB},
+	{0x1F90C, 0x1F93A}, {0x1F93C, 0x1F945}, {0x1F947, 0x1F9FF}, {0x1FA70, 0x1FAFF},
+	{0x20000, 0x2FFFD}, {0x30000, 0x3FFFD},
+}
+
+// pictographicRunes lists the Extended_Pictographic ranges, the emoji and
+// symbols a zero width joiner joins into sequences.
+// See http://www.unicode.org/reports/tr29/ and http://www.unicode.org/Public/UCD/latest/ucd/emoji/emoji-data.txt.
+var pictographicRunes = []runeRange{
+	{0x00A9, 0x00A9}, {0x00AE, 0x00AE}, {0x203C, 0x203C}, {0x2049, 0x2049},
+	{0x2122, 0x2122}, {0x2139, 0x2139}, {0x2194, 0x2199}, {0x21A9, 0x21AA},
+	{0x231A, 0x231B}, {0x2328, 0x2328}, {0x2388, 0x2388}, {0x23CF, 0x23CF},
+	{0x23E9, 0x23F3}, {0x23F8, 0x23FA}, {0x24C2, 0x24C2}, {0x25AA, 0x25AB},
+	{0x25B6, 0x25B6}, {0x25C0, 0x25C0}, {0x25FB, 0x25FE}, {0x2600, 0x2605},
+	{0x2607, 0x2612}, {0x2614, 0x2685}, {0x2690, 0x2705}, {0x2708, 0x2712},
+	{0x2714, 0x2714}, {0x2716, 0x2716}, {0x271D, 0x271D}, {0x2721, 0x2721},
+	{0x2728, 0x2728}, {0x2733, 0x2734}, {0x2744, 0x2744}, {0x2747, 0x2747},
+	{0x274C, 0x274C}, {0x274E, 0x274E}, {0x2753, 0x2755}, {0x2757, 0x2757},
+	{0x2763, 0x2767}, {0x2795, 0x2797}, {0x27A1, 0x27A1}, {0x27B0, 0x27B0},
+	{0x27BF, 0x27BF}, {0x2934, 0x2935}, {0x2B05, 0x2B07}, {0x2B1B, 0x2B1C},
+	{0x2B50, 0x2B50}, {0x2B55, 0x2B55}, {0x3030, 0x3030}, {0x303D, 0x303D},
+	{0x3297, 0x3297}, {0x3299, 0x3299}, {0x1F000, 0x1F0FF}, {0x1F10D, 0x1F10F},
+	{0x1F12F, 0x1F12F}, {0x1F16C, 0x1F171}, {0x1F17E, 0x1F17F}, {0x1F18E, 0x1F18E},
+	{0x1F191, 0x1F19A}, {0x1F1AD, 0x1F1E5}, {0x1F201, 0x1F20F}, {0x1F21A, 0x1F21A},
+	{0x1F22F, 0x1F22F}, {0x1F232, 0x1F23A}, {0x1F23C, 0x1F23F}, {0x1F249, 0x1F3FA},
+	{0x1F400, 0x1F53D}, {0x1F546, 0x1F64F}, {0x1F680, 0x1F6FF}, {0x1F774, 0x1F77F},
+	{0x1F7D5, 0x1F7FF}, {0x1F80C, 0x1F80F}, {0x1F848, 0x1F84F}, {0x1F85A, 0x1F85F},
+	{0x1F888, 0x1F88F}, {0x1F8AE, 0x1F8FF}, {0x1F90C, 0x1F93A}, {0x1F93C, 0x1F945},
+	{0x1F947, 0x1FAFF}, {0x1FC00, 0x1FFFD},
+}
+
+// RuneWidth returns the number of terminal cells occupied by r. Combining
+// marks and other zero-width runes, which attach to the preceding character,
+// return 0.
+func RuneWidth(r rune) int {
+	switch {
+	case r == 0xAD:
+		// Soft hyphen is a format character but is rendered
+		return 1
+	case 0x1160 <= r && r <= 0x11FF:
+		// Hangul medial vowels and final consonants combine with the initial
+		return 0
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case inRanges(wideRunes, r):
+		return 2
+	}
+
+	return 1
+}
+
+// ExtendsCluster reports whether r joins the grapheme cluster it follows,
+// sharing its cells, rather than starting a new one: runes that width, such
+// as RuneWidth, reports as zero-width, a pictograph after a zero width joiner
+// in a cluster starting with one (UAX #29 rule GB11), emoji skin-tone
+// modifiers following a wide character, and the second regional indicator of
+// a flag.
+// An empty cluster is never extended.
+func ExtendsCluster(cluster []rune, r rune, width func(rune) int) bool {
+	n := len(cluster)
+	if n == 0 {
+		return false
+	}
+
+	switch {
+	case width(r) == 0:
+		return true
+	case cluster[n-1] == 0x200D:
+		return inRanges(pictographicRunes, r) && inRanges(pictographicRunes, cluster[0])
+	case 0x1F3FB <= r && r <= 0x1F3FF:
+		return width(cluster[n-1]) == 2
+	case isRegionalIndicator(r):
+		// Regional indicators pair up into flags
+		indicators := 0
+		for i := n - 1; i >= 0 && isRegionalIndicator(cluster[i]); i-- {
+			indicators++
+		}
+		return indicators%2 == 1
+	}
+
+	return false
+}
+
+func isRegionalIndicator(r rune) bool {
+	return 0x1F1E6 <= r && r <= 0x1F1FF
+}
+
+// inRanges reports whether r falls within one of the sorted ranges.
+func inRanges(ranges []runeRange, r rune) bool {
+	lo, hi := 0, len(ranges)-1
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		switch {
+		case r < ranges[mid].first:
+			hi = mid - 1
+		case r > ranges[mid].last:
+			lo = mid + 1
+		default:
+			return true
+		}
+	}
+
+	return false
+}
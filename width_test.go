@@ -0,0 +1,58 @@
+package ansiterm
+
+import (
+	"testing"
+)
+
+func TestRuneWidth(t *testing.T) {
+	widths := map[rune]int{
+		'a':     1,
+		'é':     1,
+		'─':     1,
+		'　':     2,
+		'中':     2,
+		'가':     2,
+		'Ａ':     2,
+		'｡':     1,
+		0x0301:  0,
+		0x200D:  0,
+		0xFE0F:  0,
+		0x1F600: 2,
+		0x20000: 2,
+	}
+
+	for r, expected := range widths {
+		if actual := RuneWidth(r); actual != expected {
+			t.Errorf("RuneWidth(%U) = %d, expected %d", r, actual, expected)
+		}
+	}
+}
+
+func TestExtendsCluster(t *testing.T) {
+	tests := []struct {
+		cluster  string
+		r        rune
+		expected bool
+	}{
+		{"", 0x0301, false},
+		{"e", 0x0301, true},
+		{"a", 'b', false},
+		{"👩\u200d", '👧', true},
+		{"\u2764\ufe0f\u200d", '🔥', true},
+		{"👩\u200d", 'a', false},
+		{"👩\u200d", ' ', false},
+		{"\u0915\u094d\u200d", '\u0937', false},
+		{"a\u200d", '👧', false},
+		{"👍", 0x1F3FD, true},
+		{" ", 0x1F3FD, false},
+		{"🇯", '🇵', true},
+		{"🇯🇵", '🇺', false},
+		{"a", '🇯', false},
+	}
+
+	for _, test := range tests {
+		if actual := ExtendsCluster([]rune(test.cluster), test.r, RuneWidth); actual != test.expected {
+			t.Errorf("ExtendsCluster(%q, %U) = %v, expected %v", test.cluster, test.r, actual, test.expected)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+// +build windows
+
+package windowsconsole
+
+import (
+	"io"
+
+	"github.com/Azure/go-ansiterm/winterm"
+)
+
+// NewAnsiReader returns a reader translating the console input of the
+// standard handle nFile (syscall.STD_INPUT_HANDLE) to ANSI sequences.
+func NewAnsiReader(nFile int) io.ReadCloser {
+	return winterm.NewAnsiReader(nFile)
+}
+
+// NewAnsiWriter returns a writer rendering ANSI output to the console screen
+// buffer of the standard handle nFile (syscall.STD_OUTPUT_HANDLE or
+// syscall.STD_ERROR_HANDLE).
+func NewAnsiWriter(nFile int) io.Writer {
+	return winterm.NewAnsiWriter(nFile)
+}
+
+// GetHandleInfo returns the handle of in, which may be an *os.File or a
+// stream returned by NewAnsiReader or NewAnsiWriter, and whether it is a
+// console. For other values, it returns 0 and false.
+func GetHandleInfo(in interface{}) (uintptr, bool) {
+	file, ok := in.(interface{ Fd() uintptr })
+	if !ok {
+		return 0, false
+	}
+
+	fd := file.Fd()
+	return fd, IsConsole(fd)
+}
+
+// IsConsole reports whether fd is a handle to a console, which GetConsoleMode
+// fails for otherwise.
+func IsConsole(fd uintptr) bool {
+	_, err := winterm.GetConsoleMode(fd)
+	return err == nil
+}
@@ -0,0 +1,11 @@
+// Package windowsconsole provides ANSI-aware standard streams for the Windows
+// console with the API of Docker's pkg/term/windows package, so that code
+// using that package can import this one instead.
+//
+// The streams are those of winterm: input read from a console is translated
+// to ANSI sequences, and ANSI output written to a console is rendered with
+// console API calls. Streams that are not consoles, e.g. because they are
+// redirected, are returned unchanged.
+//
+// As with Docker's package, the API is only built on Windows.
+package windowsconsole
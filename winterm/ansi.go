@@ -9,39 +9,58 @@ import (
 	"strings"
 	"syscall"
 
-	. "github.com/docker/docker/vendor/src/github.com/Azure/go-ansiterm"
+	. "github.com/Azure/go-ansiterm"
 )
 
 // Windows keyboard constants
 // See https://msdn.microsoft.com/en-us/library/windows/desktop/dd375731(v=vs.85).aspx.
 const (
-	VK_PRIOR    = 0x21 // PAGE UP key
-	VK_NEXT     = 0x22 // PAGE DOWN key
-	VK_END      = 0x23 // END key
-	VK_HOME     = 0x24 // HOME key
-	VK_LEFT     = 0x25 // LEFT ARROW key
-	VK_UP       = 0x26 // UP ARROW key
-	VK_RIGHT    = 0x27 // RIGHT ARROW key
-	VK_DOWN     = 0x28 // DOWN ARROW key
-	VK_SELECT   = 0x29 // SELECT key
-	VK_PRINT    = 0x2A // PRINT key
-	VK_EXECUTE  = 0x2B // EXECUTE key
-	VK_SNAPSHOT = 0x2C // PRINT SCREEN key
-	VK_INSERT   = 0x2D // INS key
-	VK_DELETE   = 0x2E // DEL key
-	VK_HELP     = 0x2F // HELP key
-	VK_F1       = 0x70 // F1 key
-	VK_F2       = 0x71 // F2 key
-	VK_F3       = 0x72 // F3 key
-	VK_F4       = 0x73 // F4 key
-	VK_F5       = 0x74 // F5 key
-	VK_F6       = 0x75 // F6 key
-	VK_F7       = 0x76 // F7 key
-	VK_F8       = 0x77 // F8 key
-	VK_F9       = 0x78 // F9 key
-	VK_F10      = 0x79 // F10 key
-	VK_F11      = 0x7A // F11 key
-	VK_F12      = 0x7B // F12 key
+	VK_BACK       = 0x08 // BACKSPACE key
+	VK_TAB        = 0x09 // TAB key
+	VK_RETURN     = 0x0D // ENTER key
+	VK_MENU       = 0x12 // ALT key
+	VK_ESCAPE     = 0x1B // ESC key
+	VK_SPACE      = 0x20 // SPACEBAR
+	VK_PRIOR      = 0x21 // PAGE UP key
+	VK_NEXT       = 0x22 // PAGE DOWN key
+	VK_END        = 0x23 // END key
+	VK_HOME       = 0x24 // HOME key
+	VK_LEFT       = 0x25 // LEFT ARROW key
+	VK_UP         = 0x26 // UP ARROW key
+	VK_RIGHT      = 0x27 // RIGHT ARROW key
+	VK_DOWN       = 0x28 // DOWN ARROW key
+	VK_SELECT     = 0x29 // SELECT key
+	VK_PRINT      = 0x2A // PRINT key
+	VK_EXECUTE    = 0x2B // EXECUTE key
+	VK_SNAPSHOT   = 0x2C // PRINT SCREEN key
+	VK_INSERT     = 0x2D // INS key
+	VK_DELETE     = 0x2E // DEL key
+	VK_HELP       = 0x2F // HELP key
+	VK_NUMPAD0    = 0x60 // Numeric keypad 0 key
+	VK_NUMPAD9    = 0x69 // Numeric keypad 9 key
+	VK_MULTIPLY   = 0x6A // Multiply key
+	VK_ADD        = 0x6B // Add key
+	VK_SEPARATOR  = 0x6C // Separator key
+	VK_SUBTRACT   = 0x6D // Subtract key
+	VK_DECIMAL    = 0x6E // Decimal key
+	VK_DIVIDE     = 0x6F // Divide key
+	VK_F1         = 0x70 // F1 key
+	VK_F2         = 0x71 // F2 key
+	VK_F3         = 0x72 // F3 key
+	VK_F4         = 0x73 // F4 key
+	VK_F5         = 0x74 // F5 key
+	VK_F6         = 0x75 // F6 key
+	VK_F7         = 0x76 // F7 key
+	VK_F8         = 0x77 // F8 key
+	VK_F9         = 0x78 // F9 key
+	VK_F10        = 0x79 // F10 key
+	VK_F11        = 0x7A // F11 key
+	VK_F12        = 0x7B // F12 key
+	VK_F13        = 0x7C // F13 key
+	VK_F24        = 0x87 // F24 key
+	VK_OEM_MINUS  = 0xBD // '-' key on any country/region
+	VK_PROCESSKEY = 0xE5 // IME PROCESS key
+	VK_PACKET     = 0xE7 // Unicode characters not typed on a key
 
 	RIGHT_ALT_PRESSED  = 0x0001
 	LEFT_ALT_PRESSED   = 0x0002
@@ -148,18 +167,6 @@ func bytesToHex(b []byte) string {
 	return strings.Join(hex, "")
 }
 
-// ensureInRange adjusts the passed value, if necessary, to ensure it is within
-// the passed min / max range.
-func ensureInRange(n SHORT, min SHORT, max SHORT) SHORT {
-	if n < min {
-		return min
-	} else if n > max {
-		return max
-	} else {
-		return n
-	}
-}
-
 func GetStdFile(nFile int) (*os.File, uintptr) {
 	var file *os.File
 	switch nFile {
@@ -0,0 +1,250 @@
+// +build windows
+
+package winterm
+
+import (
+	"fmt"
+	"io"
+	"syscall"
+
+	. "github.com/Azure/go-ansiterm"
+)
+
+// AnsiReader reads from a console input buffer and translates key, mouse and
+// focus events into the byte sequences a Unix terminal would produce, so the
+// console can drive a remote shell or pseudo-terminal. Other input events are
+// ignored.
+//
+// Mouse events are delivered by the console only if ENABLE_MOUSE_INPUT is set
+// and ENABLE_QUICK_EDIT_MODE is cleared in the input mode, as MakeRaw does.
+type AnsiReader struct {
+	source     inputSource
+	records    []INPUT_RECORD
+	pending    []byte // Translated input not yet returned by Read
+	modes      *InputModes
+	keymap     Keymap
+	screen     syscall.Handle // Screen buffer locating mouse positions, or 0
+	buttons    DWORD          // Mouse buttons held
+	pasting    bool           // Within a paste
+	bracketing bool           // The paste is bracketed
+
+	// surrogate holds a high surrogate awaiting the low surrogate that
+	// completes the character, or 0
+	surrogate rune
+
+	eightBitMeta    bool
+	pasteThreshold  int
+	pasteFilter     PasteFilter
+	win32Input      bool
+	onResize        func(cols int, rows int)
+	reportResize    bool
+	size            COORD // Size last reported
+	trace           io.Writer
+	coalesceRepeats bool
+	onControl       func(event ControlEvent) bool
+	control         *controlHandler // Registered for onControl, or nil
+
+	// waiting is the number of records found waiting after the last read,
+	// if the paste handling asked
+	waiting uint32
+}
+
+// inputSource supplies the input records an AnsiReader translates: the
+// console, or a trace being replayed.
+type inputSource interface {
+	// read blocks until records are available, returning them
+	read(buffer []INPUT_RECORD) ([]INPUT_RECORD, error)
+
+	// pending returns the number of records that can be read without
+	// blocking
+	pending() (uint32, error)
+}
+
+// consoleInput reads records from a console input buffer.
+type consoleInput syscall.Handle
+
+func (handle consoleInput) read(buffer []INPUT_RECORD) ([]INPUT_RECORD, error) {
+	var count uint32
+	if err := ReadConsoleInput(uintptr(handle), buffer, &count); err != nil {
+		return nil, err
+	}
+	return buffer[:count], nil
+}
+
+func (handle consoleInput) pending() (uint32, error) {
+	return GetNumberOfConsoleInputEvents(uintptr(handle))
+}
+
+// NewAnsiReaderFromHandle creates a reader for the console input buffer
+// identified by handle. If handle is not a console, the returned error is a
+// *NotConsoleError.
+//
+// The reader does not change the console mode; line input and echo should be
+// disabled for keys to be delivered as they are pressed (see MakeRaw).
+func NewAnsiReaderFromHandle(handle syscall.Handle, opts ...ReaderOption) (*AnsiReader, error) {
+	if _, err := GetConsoleMode(uintptr(handle)); err != nil {
+		return nil, &NotConsoleError{Err: err}
+	}
+
+	ar := newAnsiReader(consoleInput(handle), opts)
+
+	if ar.onControl != nil {
+		ar.control = &controlHandler{handle: ar.onControl}
+		if err := addControlHandler(ar.control); err != nil {
+			return nil, err
+		}
+	}
+
+	return ar, nil
+}
+
+// newAnsiReader creates a reader translating the records from source.
+func newAnsiReader(source inputSource, opts []ReaderOption) *AnsiReader {
+	ar := &AnsiReader{
+		source:  source,
+		records: make([]INPUT_RECORD, MAX_INPUT_EVENTS),
+		modes:   &InputModes{},
+		keymap:  XtermKeymap(),
+
+		pasteThreshold: defaultPasteThreshold,
+	}
+
+	for _, opt := range opts {
+		opt(ar)
+	}
+
+	return ar
+}
+
+// InputModes returns the modes selecting how input is encoded, which may be
+// set explicitly when they are not shared with a handler.
+func (ar *AnsiReader) InputModes() *InputModes {
+	return ar.modes
+}
+
+// Read blocks until input producing at least one byte is available, then
+// returns as much of it as fits in p.
+func (ar *AnsiReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	for len(ar.pending) == 0 {
+		if err := ar.fill(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, ar.pending)
+	ar.pending = ar.pending[n:]
+	return n, nil
+}
+
+// fill reads a batch of records, blocking until one is available, and
+// translates them into pending input. The batch may produce no input.
+func (ar *AnsiReader) fill() error {
+	records, err := ar.source.read(ar.records)
+	if err != nil {
+		return err
+	}
+
+	ar.waiting = 0
+	input := ar.translateInput(records)
+	ar.pending = append(ar.pending, input...)
+
+	if ar.trace != nil {
+		// The trace is a diagnostic aid; failing to write it does not
+		// hold up the input
+		writeTraceBatch(ar.trace, records, ar.waiting, input)
+	}
+	return nil
+}
+
+// Close unregisters the reader's control handler, if any (see
+// WithControlHandler). It does not close the console input buffer.
+func (ar *AnsiReader) Close() error {
+	if ar.control == nil {
+		return nil
+	}
+
+	control := ar.control
+	ar.control = nil
+	return removeControlHandler(control)
+}
+
+// focusSequence returns the report of a focus change, if the application
+// asked for focus reporting.
+func (ar *AnsiReader) focusSequence(focus *FOCUS_EVENT_RECORD) []byte {
+	if !ar.modes.FocusReporting() {
+		return nil
+	}
+
+	if focus.SetFocus != 0 {
+		return []byte(KEY_ESC_CSI + "I")
+	}
+	return []byte(KEY_ESC_CSI + "O")
+}
+
+// resizeSequence notifies the resize callback of a change in window size,
+// returning an in-band report of it if requested (see WithResizeReports).
+func (ar *AnsiReader) resizeSequence(resize *WINDOW_BUFFER_SIZE) []byte {
+	// The event gives the size of the screen buffer; the window, which is
+	// what the application sees, may be smaller
+	size := resize.Size
+	if ar.screen != 0 {
+		if info, err := GetConsoleScreenBufferInfo(uintptr(ar.screen)); err == nil {
+			size = windowSize(info.Window)
+		}
+	}
+
+	if size == ar.size {
+		return nil
+	}
+	ar.size = size
+
+	if ar.onResize != nil {
+		ar.onResize(int(size.X), int(size.Y))
+	}
+
+	if ar.reportResize {
+		return []byte(fmt.Sprintf("%s8;%d;%dt", KEY_ESC_CSI, size.Y, size.X))
+	}
+	return nil
+}
+
+// translateInput returns the bytes produced by a sequence of input records.
+func (ar *AnsiReader) translateInput(records []INPUT_RECORD) []byte {
+	var input []byte
+	var held *KEY_EVENT_RECORD // The key last pressed in the batch, while it is held
+	for i := range records {
+		if records[i].EventType != KEY_EVENT {
+			held = nil
+		}
+
+		switch records[i].EventType {
+		case KEY_EVENT:
+			key := &records[i].KeyEvent
+			switch {
+			case ar.win32Input && ar.modes.Win32Input():
+				input = append(input, win32KeySequence(key)...)
+			case ar.coalesceRepeats && isRepeat(held, key):
+				// The key was already sent for this batch
+			default:
+				input = append(input, ar.repeatKey(key)...)
+			}
+
+			held = nil
+			if key.KeyDown != 0 {
+				held = key
+			}
+		case MOUSE_EVENT:
+			input = append(input, ar.mouseSequence(records[i].MouseEvent())...)
+		case FOCUS_EVENT:
+			input = append(input, ar.focusSequence(records[i].FocusEvent())...)
+		case WINDOW_BUFFER_SIZE_EVENT:
+			input = append(input, ar.resizeSequence(records[i].WindowBufferSizeEvent())...)
+		}
+	}
+
+	return ar.handlePaste(records, input)
+}
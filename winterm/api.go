@@ -3,324 +3,314 @@
 package winterm
 
 import (
-	"fmt"
 	"syscall"
 	"unsafe"
+
+	"github.com/Azure/go-ansiterm/winterm/consoleapi"
+	"golang.org/x/sys/windows"
 )
 
-//===========================================================================================================
-// IMPORTANT NOTE:
-//
-//	The methods below make extensive use of the "unsafe" package to obtain the required pointers.
-//	Beginning in Go 1.3, the garbage collector may release local variables (e.g., incoming arguments, stack
-//	variables) the pointers reference *before* the API completes.
-//
-//  As a result, in those cases, the code must hint that the variables remain in active by invoking the
-//	dummy method "use" (see below). Newer versions of Go are planned to change the mechanism to no longer
-//	require unsafe pointers.
-//
-//	If you add or modify methods, ENSURE protection of local variables through the "use" builtin to inform
-//	the garbage collector the variables remain in use if:
-//
-//	-- The value is not a pointer (e.g., int32, struct)
-//	-- The value is not referenced by the method after passing the pointer to Windows
-//
-//	See http://golang.org/doc/go1.3.
-//===========================================================================================================
+// The console functions are bound by golang.org/x/sys/windows and, for the
+// screen buffer functions it lacks, the consoleapi package; the few others
+// are loaded from the system directory below.
 
 var (
-	kernel32DLL = syscall.NewLazyDLL("kernel32.dll")
-
-	getConsoleCursorInfoProc       = kernel32DLL.NewProc("GetConsoleCursorInfo")
-	setConsoleCursorInfoProc       = kernel32DLL.NewProc("SetConsoleCursorInfo")
-	setConsoleCursorPositionProc   = kernel32DLL.NewProc("SetConsoleCursorPosition")
-	setConsoleModeProc             = kernel32DLL.NewProc("SetConsoleMode")
-	getConsoleScreenBufferInfoProc = kernel32DLL.NewProc("GetConsoleScreenBufferInfo")
-	setConsoleScreenBufferSizeProc = kernel32DLL.NewProc("SetConsoleScreenBufferSize")
-	scrollConsoleScreenBufferProc  = kernel32DLL.NewProc("ScrollConsoleScreenBufferA")
-	setConsoleTextAttributeProc    = kernel32DLL.NewProc("SetConsoleTextAttribute")
-	setConsoleWindowInfoProc       = kernel32DLL.NewProc("SetConsoleWindowInfo")
-	getCurrentConsoleFontProc      = kernel32DLL.NewProc("GetCurrentConsoleFont")
-	writeConsoleOutputProc         = kernel32DLL.NewProc("WriteConsoleOutputW")
-	readConsoleInputProc           = kernel32DLL.NewProc("ReadConsoleInputW")
-	waitForSingleObjectProc        = kernel32DLL.NewProc("WaitForSingleObject")
-)
-
-// Windows Console constants
-const (
-	// Console modes
-	// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms686033(v=vs.85).aspx.
-	ENABLE_PROCESSED_INPUT = 0x0001
-	ENABLE_LINE_INPUT      = 0x0002
-	ENABLE_ECHO_INPUT      = 0x0004
-	ENABLE_WINDOW_INPUT    = 0x0008
-	ENABLE_MOUSE_INPUT     = 0x0010
-	ENABLE_INSERT_MODE     = 0x0020
-	ENABLE_QUICK_EDIT_MODE = 0x0040
-	ENABLE_EXTENDED_FLAGS  = 0x0080
-
-	ENABLE_PROCESSED_OUTPUT   = 0x0001
-	ENABLE_WRAP_AT_EOL_OUTPUT = 0x0002
-
-	// Character attributes
-	// Note:
-	// -- The attributes are combined to produce various colors (e.g., Blue + Green will create Cyan).
-	//    Clearing all foreground or background colors results in black; setting all creates white.
-	// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms682088(v=vs.85).aspx#_win32_character_attributes.
-	FOREGROUND_BLUE      WORD = 0x0001
-	FOREGROUND_GREEN     WORD = 0x0002
-	FOREGROUND_RED       WORD = 0x0004
-	FOREGROUND_INTENSITY WORD = 0x0008
-	FOREGROUND_MASK      WORD = 0x000F
-
-	BACKGROUND_BLUE      WORD = 0x0010
-	BACKGROUND_GREEN     WORD = 0x0020
-	BACKGROUND_RED       WORD = 0x0040
-	BACKGROUND_INTENSITY WORD = 0x0080
-	BACKGROUND_MASK      WORD = 0x00F0
-
-	COMMON_LVB_MASK          WORD = 0xFF00
-	COMMON_LVB_REVERSE_VIDEO WORD = 0x4000
-	COMMON_LVB_UNDERSCORE    WORD = 0x8000
-
-	// Input event types
-	// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms683499(v=vs.85).aspx.
-	KEY_EVENT                = 0x0001
-	MOUSE_EVENT              = 0x0002
-	WINDOW_BUFFER_SIZE_EVENT = 0x0004
-	MENU_EVENT               = 0x0008
-	FOCUS_EVENT              = 0x0010
-
-	// WaitForSingleObject return codes
-	WAIT_ABANDONED = 0x00000080
-	WAIT_FAILED    = 0xFFFFFFFF
-	WAIT_SIGNALED  = 0x0000000
-	WAIT_TIMEOUT   = 0x00000102
-
-	// WaitForSingleObject wait duration
-	WAIT_INFINITE       = 0xFFFFFFFF
-	WAIT_ONE_SECOND     = 1000
-	WAIT_HALF_SECOND    = 500
-	WAIT_QUARTER_SECOND = 250
+	kernel32DLL = windows.NewLazySystemDLL("kernel32.dll")
+	user32DLL   = windows.NewLazySystemDLL("user32.dll")
+
+	setConsoleCtrlHandlerProc         = kernel32DLL.NewProc("SetConsoleCtrlHandler")
+	getConsoleOutputCPProc            = kernel32DLL.NewProc("GetConsoleOutputCP")
+	getConsoleTitleProc               = kernel32DLL.NewProc("GetConsoleTitleW")
+	getConsoleWindowProc              = kernel32DLL.NewProc("GetConsoleWindow")
+	setConsoleTitleProc               = kernel32DLL.NewProc("SetConsoleTitleW")
+	readConsoleInputProc              = kernel32DLL.NewProc("ReadConsoleInputW")
+	getNumberOfConsoleInputEventsProc = kernel32DLL.NewProc("GetNumberOfConsoleInputEvents")
+	wideCharToMultiByteProc           = kernel32DLL.NewProc("WideCharToMultiByte")
+
+	flashWindowExProc = user32DLL.NewProc("FlashWindowEx")
 )
 
-// Windows API Console types
-// -- See https://msdn.microsoft.com/en-us/library/windows/desktop/aa383751(v=vs.85).aspx for core types (e.g., SHORT)
-// -- See https://msdn.microsoft.com/en-us/library/windows/desktop/ms682101(v=vs.85).aspx for Console specific types (e.g., COORD)
-// -- See https://msdn.microsoft.com/en-us/library/aa296569(v=vs.60).aspx for comments on alignment
-type (
-	SHORT int16
-	BOOL  int32
-	WORD  uint16
-	WCHAR uint16
-	DWORD uint32
-
-	CHAR_INFO struct {
-		UnicodeChar WCHAR
-		Attributes  WORD
-	}
-
-	CONSOLE_CURSOR_INFO struct {
-		Size    DWORD
-		Visible BOOL
-	}
-
-	CONSOLE_FONT_INFO struct {
-		Font     DWORD
-		FontSize COORD
-	}
-
-	CONSOLE_SCREEN_BUFFER_INFO struct {
-		Size              COORD
-		CursorPosition    COORD
-		Attributes        WORD
-		Window            SMALL_RECT
-		MaximumWindowSize COORD
-	}
-
-	COORD struct {
-		X SHORT
-		Y SHORT
-	}
-
-	SMALL_RECT struct {
-		Left   SHORT
-		Top    SHORT
-		Right  SHORT
-		Bottom SHORT
-	}
-
-	// INPUT_RECORD is a C/C++ union of which KEY_EVENT_RECORD is one case, it is also the largest
-	// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms683499(v=vs.85).aspx.
-	INPUT_RECORD struct {
-		EventType WORD
-		KeyEvent  KEY_EVENT_RECORD
-	}
-
-	KEY_EVENT_RECORD struct {
-		KeyDown         BOOL
-		RepeatCount     WORD
-		VirtualKeyCode  WORD
-		VirtualScanCode WORD
-		UnicodeChar     WCHAR
-		ControlKeyState DWORD
-	}
+// CreateConsoleScreenBuffer creates a console screen buffer, which is not displayed until made active.
+// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms682122(v=vs.85).aspx.
+func CreateConsoleScreenBuffer() (uintptr, error) {
+	countCall("CreateConsoleScreenBuffer")
+	handle, err := consoleapi.CreateConsoleScreenBuffer()
+	return uintptr(handle), err
+}
 
-	WINDOW_BUFFER_SIZE struct {
-		Size COORD
-	}
-)
+// FillConsoleOutputCharacter writes the character to the console screen buffer count times, starting at coord.
+// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms682663(v=vs.85).aspx.
+func FillConsoleOutputCharacter(handle uintptr, char WCHAR, count uint32, coord COORD) error {
+	countCall("FillConsoleOutputCharacter")
+	_, err := consoleapi.FillConsoleOutputCharacter(windows.Handle(handle), char, count, windows.Coord(coord))
+	return err
+}
 
-// boolToBOOL converts a Go bool into a Windows BOOL.
-func boolToBOOL(f bool) BOOL {
-	if f {
-		return BOOL(1)
-	} else {
-		return BOOL(0)
-	}
+// FillConsoleOutputAttribute sets the attributes of count character cells, starting at coord.
+// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms682662(v=vs.85).aspx.
+func FillConsoleOutputAttribute(handle uintptr, attribute WORD, count uint32, coord COORD) error {
+	countCall("FillConsoleOutputAttribute")
+	_, err := consoleapi.FillConsoleOutputAttribute(windows.Handle(handle), attribute, count, windows.Coord(coord))
+	return err
 }
 
 // GetConsoleCursorInfo retrieves information about the size and visiblity of the console cursor.
 // See https://msdn.microsoft.com/en-us/library/windows/desktop/ms683163(v=vs.85).aspx.
 func GetConsoleCursorInfo(handle uintptr, cursorInfo *CONSOLE_CURSOR_INFO) error {
-	r1, r2, err := getConsoleCursorInfoProc.Call(handle, uintptr(unsafe.Pointer(cursorInfo)), 0)
-	return checkError(r1, r2, err)
+	countCall("GetConsoleCursorInfo")
+	info, err := consoleapi.GetConsoleCursorInfo(windows.Handle(handle))
+	if err != nil {
+		return err
+	}
+	*cursorInfo = info
+	return nil
 }
 
 // SetConsoleCursorInfo sets the size and visiblity of the console cursor.
 // See https://msdn.microsoft.com/en-us/library/windows/desktop/ms686019(v=vs.85).aspx.
 func SetConsoleCursorInfo(handle uintptr, cursorInfo *CONSOLE_CURSOR_INFO) error {
-	r1, r2, err := setConsoleCursorInfoProc.Call(handle, uintptr(unsafe.Pointer(cursorInfo)), 0)
-	return checkError(r1, r2, err)
+	countCall("SetConsoleCursorInfo")
+	return consoleapi.SetConsoleCursorInfo(windows.Handle(handle), *cursorInfo)
+}
+
+// SetConsoleActiveScreenBuffer makes the specified screen buffer the displayed console screen buffer.
+// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms686010(v=vs.85).aspx.
+func SetConsoleActiveScreenBuffer(handle uintptr) error {
+	countCall("SetConsoleActiveScreenBuffer")
+	return consoleapi.SetConsoleActiveScreenBuffer(windows.Handle(handle))
 }
 
 // SetConsoleCursorPosition location of the console cursor.
 // See https://msdn.microsoft.com/en-us/library/windows/desktop/ms686025(v=vs.85).aspx.
 func SetConsoleCursorPosition(handle uintptr, coord COORD) error {
-	r1, r2, err := setConsoleCursorPositionProc.Call(handle, coordToPointer(coord))
-	use(coord)
-	return checkError(r1, r2, err)
+	countCall("SetConsoleCursorPosition")
+	return windows.SetConsoleCursorPosition(windows.Handle(handle), windows.Coord(coord))
 }
 
 // GetConsoleMode gets the console mode for given file descriptor
 // See http://msdn.microsoft.com/en-us/library/windows/desktop/ms683167(v=vs.85).aspx.
 func GetConsoleMode(handle uintptr) (mode uint32, err error) {
-	err = syscall.GetConsoleMode(syscall.Handle(handle), &mode)
+	countCall("GetConsoleMode")
+	err = windows.GetConsoleMode(windows.Handle(handle), &mode)
 	return mode, err
 }
 
 // SetConsoleMode sets the console mode for given file descriptor
 // See http://msdn.microsoft.com/en-us/library/windows/desktop/ms686033(v=vs.85).aspx.
 func SetConsoleMode(handle uintptr, mode uint32) error {
-	r1, r2, err := setConsoleModeProc.Call(handle, uintptr(mode), 0)
-	use(mode)
-	return checkError(r1, r2, err)
+	countCall("SetConsoleMode")
+	return windows.SetConsoleMode(windows.Handle(handle), mode)
 }
 
 // GetConsoleScreenBufferInfo retrieves information about the specified console screen buffer.
 // See http://msdn.microsoft.com/en-us/library/windows/desktop/ms683171(v=vs.85).aspx.
 func GetConsoleScreenBufferInfo(handle uintptr) (*CONSOLE_SCREEN_BUFFER_INFO, error) {
-	info := CONSOLE_SCREEN_BUFFER_INFO{}
-	err := checkError(getConsoleScreenBufferInfoProc.Call(handle, uintptr(unsafe.Pointer(&info)), 0))
+	countCall("GetConsoleScreenBufferInfo")
+	info, err := consoleapi.GetConsoleScreenBufferInfo(windows.Handle(handle))
 	if err != nil {
 		return nil, err
 	}
-	return &info, nil
+	return &CONSOLE_SCREEN_BUFFER_INFO{
+		Size:              COORD(info.Size),
+		CursorPosition:    COORD(info.CursorPosition),
+		Attributes:        info.Attributes,
+		Window:            SMALL_RECT(info.Window),
+		MaximumWindowSize: COORD(info.MaximumWindowSize),
+	}, nil
 }
 
+// ScrollConsoleScreenBuffer moves the cells of scrollRect to destOrigin, filling the cells left behind with
+// char, and changing only cells within clipRect.
+// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms685107(v=vs.85).aspx.
 func ScrollConsoleScreenBuffer(handle uintptr, scrollRect SMALL_RECT, clipRect SMALL_RECT, destOrigin COORD, char CHAR_INFO) error {
-	r1, r2, err := scrollConsoleScreenBufferProc.Call(handle, uintptr(unsafe.Pointer(&scrollRect)), uintptr(unsafe.Pointer(&clipRect)), coordToPointer(destOrigin), uintptr(unsafe.Pointer(&char)))
-	use(scrollRect)
-	use(clipRect)
-	use(destOrigin)
-	use(char)
-	return checkError(r1, r2, err)
+	countCall("ScrollConsoleScreenBuffer")
+	clip := windows.SmallRect(clipRect)
+	return consoleapi.ScrollConsoleScreenBuffer(windows.Handle(handle), windows.SmallRect(scrollRect), &clip, windows.Coord(destOrigin), char)
 }
 
 // SetConsoleScreenBufferSize sets the size of the console screen buffer.
 // See https://msdn.microsoft.com/en-us/library/windows/desktop/ms686044(v=vs.85).aspx.
 func SetConsoleScreenBufferSize(handle uintptr, coord COORD) error {
-	r1, r2, err := setConsoleScreenBufferSizeProc.Call(handle, coordToPointer(coord))
-	use(coord)
-	return checkError(r1, r2, err)
+	countCall("SetConsoleScreenBufferSize")
+	return consoleapi.SetConsoleScreenBufferSize(windows.Handle(handle), windows.Coord(coord))
 }
 
 // SetConsoleTextAttribute sets the attributes of characters written to the
 // console screen buffer by the WriteFile or WriteConsole function.
 // See http://msdn.microsoft.com/en-us/library/windows/desktop/ms686047(v=vs.85).aspx.
 func SetConsoleTextAttribute(handle uintptr, attribute WORD) error {
-	r1, r2, err := setConsoleTextAttributeProc.Call(handle, uintptr(attribute), 0)
-	use(attribute)
-	return checkError(r1, r2, err)
+	countCall("SetConsoleTextAttribute")
+	return consoleapi.SetConsoleTextAttribute(windows.Handle(handle), attribute)
 }
 
 // SetConsoleWindowInfo sets the size and position of the console screen buffer's window.
 // Note that the size and location must be within and no larger than the backing console screen buffer.
 // See https://msdn.microsoft.com/en-us/library/windows/desktop/ms686125(v=vs.85).aspx.
 func SetConsoleWindowInfo(handle uintptr, isAbsolute bool, rect SMALL_RECT) error {
-	r1, r2, err := setConsoleWindowInfoProc.Call(handle, uintptr(boolToBOOL(isAbsolute)), uintptr(unsafe.Pointer(&rect)))
-	use(isAbsolute)
-	use(rect)
+	countCall("SetConsoleWindowInfo")
+	return consoleapi.SetConsoleWindowInfo(windows.Handle(handle), isAbsolute, windows.SmallRect(rect))
+}
+
+// SetConsoleCtrlHandler adds or removes a console control handler, a callback
+// created with syscall.NewCallback.
+// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms686016(v=vs.85).aspx.
+func SetConsoleCtrlHandler(handler uintptr, add bool) error {
+	countCall("SetConsoleCtrlHandler")
+	r1, r2, err := setConsoleCtrlHandlerProc.Call(handler, uintptr(boolToBOOL(add)))
 	return checkError(r1, r2, err)
 }
 
 // GetCurrentConsoleFont retrieves the pixel dimensions of the font for the current window.
 // See https://msdn.microsoft.com/en-us/library/windows/desktop/ms683176(v=vs.85).aspx.
 func GetCurrentConsoleFont(handle uintptr) (*CONSOLE_FONT_INFO, error) {
-	info := CONSOLE_FONT_INFO{}
-	err := checkError(getCurrentConsoleFontProc.Call(handle, uintptr(BOOL(0)), uintptr(unsafe.Pointer(&info))))
+	countCall("GetCurrentConsoleFont")
+	info, err := consoleapi.GetCurrentConsoleFont(windows.Handle(handle), false)
 	if err != nil {
 		return nil, err
 	}
-	return &info, nil
+	return &CONSOLE_FONT_INFO{Font: info.Font, FontSize: COORD(info.FontSize)}, nil
+}
+
+// GetConsoleOutputCP retrieves the code page the console uses to interpret bytes written to it.
+// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms683169(v=vs.85).aspx.
+func GetConsoleOutputCP() (uint32, error) {
+	countCall("GetConsoleOutputCP")
+	r1, r2, err := getConsoleOutputCPProc.Call()
+	if err := checkError(r1, r2, err); err != nil {
+		return 0, err
+	}
+	return uint32(r1), nil
+}
+
+// WideCharToMultiByte converts UTF-16 text to the specified code page. Characters the code page
+// cannot represent are replaced with its default character.
+// See https://msdn.microsoft.com/en-us/library/windows/desktop/dd374130(v=vs.85).aspx.
+func WideCharToMultiByte(codepage uint32, text []uint16) ([]byte, error) {
+	countCall("WideCharToMultiByte")
+	if len(text) == 0 {
+		return nil, nil
+	}
+
+	r1, r2, err := wideCharToMultiByteProc.Call(uintptr(codepage), 0, uintptr(unsafe.Pointer(&text[0])), uintptr(len(text)), 0, 0, 0, 0)
+	if err := checkError(r1, r2, err); err != nil {
+		return nil, err
+	}
+
+	buffer := make([]byte, r1)
+	r1, r2, err = wideCharToMultiByteProc.Call(uintptr(codepage), 0, uintptr(unsafe.Pointer(&text[0])), uintptr(len(text)), uintptr(unsafe.Pointer(&buffer[0])), uintptr(len(buffer)), 0, 0)
+	if err := checkError(r1, r2, err); err != nil {
+		return nil, err
+	}
+	return buffer[:r1], nil
+}
+
+// GetLargestConsoleWindowSize retrieves the size of the largest possible console window, based on the
+// current font and the size of the display.
+// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms683193(v=vs.85).aspx.
+func GetLargestConsoleWindowSize(handle uintptr) (COORD, error) {
+	countCall("GetLargestConsoleWindowSize")
+	size, err := consoleapi.GetLargestConsoleWindowSize(windows.Handle(handle))
+	return COORD(size), err
+}
+
+// GetConsoleTitle retrieves the title of the current console window.
+// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms683174(v=vs.85).aspx.
+func GetConsoleTitle() (string, error) {
+	countCall("GetConsoleTitle")
+	buffer := make([]uint16, 1024)
+	r1, r2, err := getConsoleTitleProc.Call(uintptr(unsafe.Pointer(&buffer[0])), uintptr(len(buffer)))
+	if err := checkError(r1, r2, err); err != nil {
+		return "", err
+	}
+	return windows.UTF16ToString(buffer[:r1]), nil
+}
+
+// SetConsoleTitle sets the title of the current console window.
+// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms686050(v=vs.85).aspx.
+func SetConsoleTitle(title string) error {
+	countCall("SetConsoleTitle")
+	buffer, err := windows.UTF16PtrFromString(title)
+	if err != nil {
+		return err
+	}
+	r1, r2, err := setConsoleTitleProc.Call(uintptr(unsafe.Pointer(buffer)))
+	return checkError(r1, r2, err)
+}
+
+// GetConsoleWindow retrieves the window handle used by the console, or 0 if there is none (e.g., when headless).
+// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms683175(v=vs.85).aspx.
+func GetConsoleWindow() uintptr {
+	countCall("GetConsoleWindow")
+	hwnd, _, _ := getConsoleWindowProc.Call()
+	return hwnd
+}
+
+// FlashWindowEx flashes the specified window.
+// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms679347(v=vs.85).aspx.
+func FlashWindowEx(info *FLASHWINFO) {
+	countCall("FlashWindowEx")
+	// The return value is the window's previous state, not a success indicator
+	flashWindowExProc.Call(uintptr(unsafe.Pointer(info)))
+}
+
+// WriteConsole writes the UTF-16 characters from the provided buffer to the console at the current cursor position.
+// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms687401(v=vs.85).aspx.
+func WriteConsole(handle uintptr, buffer []uint16) error {
+	countCall("WriteConsole")
+	if len(buffer) == 0 {
+		return nil
+	}
+
+	var written uint32
+	return windows.WriteConsole(windows.Handle(handle), &buffer[0], uint32(len(buffer)), &written, nil)
 }
 
 // WriteConsoleOutput writes the CHAR_INFOs from the provided buffer to the active console buffer.
 // See https://msdn.microsoft.com/en-us/library/windows/desktop/ms687404(v=vs.85).aspx.
 func WriteConsoleOutput(handle uintptr, buffer []CHAR_INFO, bufferSize COORD, bufferCoord COORD, writeRegion *SMALL_RECT) error {
-	r1, r2, err := writeConsoleOutputProc.Call(handle, uintptr(unsafe.Pointer(&buffer[0])), coordToPointer(bufferSize), coordToPointer(bufferCoord), uintptr(unsafe.Pointer(writeRegion)))
-	use(buffer)
-	use(bufferSize)
-	use(bufferCoord)
-	return checkError(r1, r2, err)
+	countCall("WriteConsoleOutput")
+	return consoleapi.WriteConsoleOutput(windows.Handle(handle), buffer, windows.Coord(bufferSize), windows.Coord(bufferCoord), (*windows.SmallRect)(writeRegion))
+}
+
+// ReadConsoleOutput reads the CHAR_INFOs in readRegion of the active console buffer into the provided buffer.
+// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms684965(v=vs.85).aspx.
+func ReadConsoleOutput(handle uintptr, buffer []CHAR_INFO, bufferSize COORD, bufferCoord COORD, readRegion *SMALL_RECT) error {
+	countCall("ReadConsoleOutput")
+	return consoleapi.ReadConsoleOutput(windows.Handle(handle), buffer, windows.Coord(bufferSize), windows.Coord(bufferCoord), (*windows.SmallRect)(readRegion))
 }
 
 // ReadConsoleInput reads (and removes) data from the console input buffer.
 // See https://msdn.microsoft.com/en-us/library/windows/desktop/ms684961(v=vs.85).aspx.
 func ReadConsoleInput(handle uintptr, buffer []INPUT_RECORD, count *uint32) error {
+	countCall("ReadConsoleInput")
 	r1, r2, err := readConsoleInputProc.Call(handle, uintptr(unsafe.Pointer(&buffer[0])), uintptr(len(buffer)), uintptr(unsafe.Pointer(count)))
-	use(buffer)
 	return checkError(r1, r2, err)
 }
 
+// GetNumberOfConsoleInputEvents returns the number of unread records in the console input buffer.
+// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms683207(v=vs.85).aspx.
+func GetNumberOfConsoleInputEvents(handle uintptr) (uint32, error) {
+	countCall("GetNumberOfConsoleInputEvents")
+	var count uint32
+	r1, r2, err := getNumberOfConsoleInputEventsProc.Call(handle, uintptr(unsafe.Pointer(&count)))
+	return count, checkError(r1, r2, err)
+}
+
 // WaitForSingleObject waits for the passed handle to be signaled.
 // It returns true if the handle was signaled; false otherwise.
 // See https://msdn.microsoft.com/en-us/library/windows/desktop/ms687032(v=vs.85).aspx.
 func WaitForSingleObject(handle uintptr, msWait uint32) (bool, error) {
-	r1, _, err := waitForSingleObjectProc.Call(handle, uintptr(DWORD(msWait)))
-	switch r1 {
+	countCall("WaitForSingleObject")
+	event, err := windows.WaitForSingleObject(windows.Handle(handle), msWait)
+	switch event {
 	case WAIT_ABANDONED, WAIT_TIMEOUT:
 		return false, nil
 	case WAIT_SIGNALED:
 		return true, nil
 	}
-	use(msWait)
 	return false, err
 }
 
-// String helpers
-func (info CONSOLE_SCREEN_BUFFER_INFO) String() string {
-	return fmt.Sprintf("Size(%v) Cursor(%v) Window(%v) Max(%v)", info.Size, info.CursorPosition, info.Window, info.MaximumWindowSize)
-}
-
-func (coord COORD) String() string {
-	return fmt.Sprintf("%v,%v", coord.X, coord.Y)
-}
-
-func (rect SMALL_RECT) String() string {
-	return fmt.Sprintf("(%v,%v),(%v,%v)", rect.Left, rect.Top, rect.Right, rect.Bottom)
-}
-
 // checkError evaluates the results of a Windows API call and returns the error if it failed.
 func checkError(r1, r2 uintptr, err error) error {
 	// Windows APIs return non-zero to indicate success
@@ -329,18 +319,8 @@ func checkError(r1, r2 uintptr, err error) error {
 	}
 
 	// Return the error if provided, otherwise default to EINVAL
-	if err != nil {
+	if errno, ok := err.(syscall.Errno); ok && errno != 0 {
 		return err
 	}
 	return syscall.EINVAL
 }
-
-// coordToPointer converts a COORD into a uintptr (by fooling the type system).
-func coordToPointer(c COORD) uintptr {
-	// Note: This code assumes the two SHORTs are correctly laid out; the "cast" to DWORD is just to get a pointer to pass.
-	return uintptr(*((*DWORD)(unsafe.Pointer(&c))))
-}
-
-// use is a no-op, but the compiler cannot see that it is.
-// Calling use(p) ensures that p is kept live until that point.
-func use(p interface{}) {}
@@ -1,30 +1,48 @@
-// +build windows
-
 package winterm
 
 import (
 	. "github.com/Azure/go-ansiterm"
 )
 
+// ansiColors maps the eight ANSI colors, in SGR order, onto the foreground
+// color bits; the background bits are the same shifted left by four.
+var ansiColors = [8]WORD{
+	0,
+	FOREGROUND_RED,
+	FOREGROUND_GREEN,
+	FOREGROUND_RED | FOREGROUND_GREEN,
+	FOREGROUND_BLUE,
+	FOREGROUND_RED | FOREGROUND_BLUE,
+	FOREGROUND_GREEN | FOREGROUND_BLUE,
+	FOREGROUND_RED | FOREGROUND_GREEN | FOREGROUND_BLUE,
+}
+
 // collectAnsiIntoWindowsAttributes modifies the passed Windows text mode flags to reflect the
 // request represented by the passed ANSI mode.
 func collectAnsiIntoWindowsAttributes(windowsMode WORD, baseMode WORD, ansiMode SHORT) WORD {
+	// Bright colors
+	switch {
+	case ANSI_SGR_FOREGROUND_BRIGHT_BLACK <= ansiMode && ansiMode <= ANSI_SGR_FOREGROUND_BRIGHT_WHITE:
+		color := ansiColors[ansiMode-ANSI_SGR_FOREGROUND_BRIGHT_BLACK] | FOREGROUND_INTENSITY
+		return (windowsMode & ^FOREGROUND_MASK) | color
+
+	case ANSI_SGR_BACKGROUND_BRIGHT_BLACK <= ansiMode && ansiMode <= ANSI_SGR_BACKGROUND_BRIGHT_WHITE:
+		color := ansiColors[ansiMode-ANSI_SGR_BACKGROUND_BRIGHT_BLACK] | FOREGROUND_INTENSITY
+		return (windowsMode & ^BACKGROUND_MASK) | color<<4
+	}
+
 	switch ansiMode {
 
 	// Mode styles
 	case ANSI_SGR_BOLD:
 		windowsMode = windowsMode | FOREGROUND_INTENSITY
 
-	case ANSI_SGR_DIM, ANSI_SGR_BOLD_DIM_OFF:
+	case ANSI_SGR_BOLD_DIM_OFF:
 		windowsMode &^= FOREGROUND_INTENSITY
 
 	case ANSI_SGR_UNDERLINE:
 		windowsMode = windowsMode | COMMON_LVB_UNDERSCORE
 
-	case ANSI_SGR_REVERSE, ANSI_SGR_REVERSE_OFF:
-		// Note: Windows does not support a native reverse. Simply swap the foreground / background color / intensity.
-		windowsMode = (COMMON_LVB_MASK & windowsMode) | ((FOREGROUND_MASK & windowsMode) << 4) | ((BACKGROUND_MASK & windowsMode) >> 4)
-
 	case ANSI_SGR_UNDERLINE_OFF:
 		windowsMode &^= COMMON_LVB_UNDERSCORE
 
@@ -88,3 +106,134 @@ func collectAnsiIntoWindowsAttributes(windowsMode WORD, baseMode WORD, ansiMode
 
 	return windowsMode
 }
+
+// colorIntoWindowsAttributes modifies the passed Windows text mode flags to
+// display color, downsampled to the closest of the console's 16 colors, in
+// the foreground or background.
+func colorIntoWindowsAttributes(windowsMode WORD, color Color, background bool) WORD {
+	index, _ := color.ToBasic().Basic()
+	bits := ansiColors[index%8]
+	if index >= 8 {
+		bits |= FOREGROUND_INTENSITY
+	}
+
+	if background {
+		return (windowsMode & ^BACKGROUND_MASK) | bits<<4
+	}
+	return (windowsMode & ^FOREGROUND_MASK) | bits
+}
+
+// sgrStyle maps an SGR parameter to the unsupported style it turns on or off.
+func sgrStyle(ansiMode SHORT) (style SGRStyle, on bool, ok bool) {
+	switch ansiMode {
+	case ANSI_SGR_DIM:
+		return StyleFaint, true, true
+	case ANSI_SGR_BOLD_DIM_OFF:
+		return StyleFaint, false, true
+	case ANSI_SGR_ITALIC:
+		return StyleItalic, true, true
+	case ANSI_SGR_ITALIC_OFF:
+		return StyleItalic, false, true
+	case ANSI_SGR_BLINKSLOW, ANSI_SGR_BLINKFAST:
+		return StyleBlink, true, true
+	case ANSI_SGR_BLINK_OFF:
+		return StyleBlink, false, true
+	case ANSI_SGR_LINETHROUGH:
+		return StyleStrikethrough, true, true
+	case ANSI_SGR_LINETHROUGH_OFF:
+		return StyleStrikethrough, false, true
+	}
+
+	return 0, false, false
+}
+
+// applyStyleFallback modifies the passed Windows text mode flags to approximate
+// turning an unsupported style on or off. Since the console cannot tell a
+// fallback apart from an explicit request for the same attribute, turning the
+// style off clears the attribute regardless of how it was set.
+func applyStyleFallback(windowsMode WORD, fallback StyleFallback, on bool) WORD {
+	var mask WORD
+	switch fallback {
+	case FallbackDim:
+		// Clearing intensity is the only darkening available; it cannot be undone
+		if on {
+			windowsMode &^= FOREGROUND_INTENSITY
+		}
+		return windowsMode
+
+	case FallbackBright:
+		mask = FOREGROUND_INTENSITY
+
+	case FallbackUnderline:
+		mask = COMMON_LVB_UNDERSCORE
+
+	case FallbackBrightBackground:
+		mask = BACKGROUND_INTENSITY
+
+	default:
+		return windowsMode
+	}
+
+	if on {
+		return windowsMode | mask
+	}
+	return windowsMode &^ mask
+}
+
+// invertAttributes swaps the foreground and background color and intensity.
+// Note: Windows does not support a native reverse.
+func invertAttributes(windowsMode WORD) WORD {
+	return (COMMON_LVB_MASK & windowsMode) | ((FOREGROUND_MASK & windowsMode) << 4) | ((BACKGROUND_MASK & windowsMode) >> 4)
+}
+
+// maxSGRStack is the depth of the XTPUSHSGR stack; as in xterm, further
+// pushes are ignored.
+const maxSGRStack = 10
+
+// pushedAttributes is an entry of the XTPUSHSGR stack: the attributes, as
+// if not inverted, and which of them XTPOPSGR restores.
+type pushedAttributes struct {
+	attributes WORD
+	mask       WORD
+	inverse    bool
+	popInverse bool
+}
+
+// pushAttributes returns the entry pushing the attributes selected by the
+// parameters of XTPUSHSGR, all of them if there are none. Of the renditions
+// xterm can push, the console has bold (as intensity), underline, reverse
+// video, and the colors.
+func pushAttributes(attributes WORD, inverse bool, params []int) pushedAttributes {
+	pushed := pushedAttributes{attributes: attributes, inverse: inverse}
+	if len(params) == 0 {
+		pushed.mask = FOREGROUND_MASK | BACKGROUND_MASK | COMMON_LVB_UNDERSCORE
+		pushed.popInverse = true
+		return pushed
+	}
+
+	for _, param := range params {
+		switch param {
+		case ANSI_SGR_BOLD:
+			pushed.mask |= FOREGROUND_INTENSITY
+		case ANSI_SGR_UNDERLINE:
+			pushed.mask |= COMMON_LVB_UNDERSCORE
+		case ANSI_SGR_REVERSE:
+			pushed.popInverse = true
+		case 10:
+			pushed.mask |= FOREGROUND_MASK
+		case 11:
+			pushed.mask |= BACKGROUND_MASK
+		}
+	}
+	return pushed
+}
+
+// pop returns attributes, as if not inverted, and whether they are inverted,
+// with those of the entry restored.
+func (pushed pushedAttributes) pop(attributes WORD, inverse bool) (WORD, bool) {
+	attributes = attributes&^pushed.mask | pushed.attributes&pushed.mask
+	if pushed.popInverse {
+		inverse = pushed.inverse
+	}
+	return attributes, inverse
+}
@@ -0,0 +1,76 @@
+package winterm
+
+import (
+	"time"
+	"unsafe"
+
+	. "github.com/Azure/go-ansiterm"
+)
+
+// screenFlashDuration is how long the window stays inverted for BellFlashScreen.
+const screenFlashDuration = 100 * time.Millisecond
+
+// ringBell renders BEL in each of the configured bell styles.
+func (h *WindowsAnsiEventHandler) ringBell() error {
+	if h.bell&BellFlashWindow != 0 {
+		h.flashWindow()
+	}
+
+	if h.bell&BellFlashScreen != 0 {
+		if err := h.flashScreen(); err != nil {
+			return err
+		}
+	}
+
+	if h.bell&BellAudible != 0 {
+		return h.writeRunes([]rune{ANSI_BEL})
+	}
+
+	return nil
+}
+
+// flashWindow flashes the console window's caption and taskbar button once.
+// Headless consoles have no window, in which case nothing happens.
+func (h *WindowsAnsiEventHandler) flashWindow() {
+	hwnd := h.console.GetConsoleWindow()
+	if hwnd == 0 {
+		return
+	}
+
+	info := FLASHWINFO{Hwnd: hwnd, Flags: FLASHW_ALL, Count: 1}
+	info.Size = uint32(unsafe.Sizeof(info))
+	h.console.FlashWindowEx(&info)
+}
+
+// flashScreen inverts the colors of the visible window, waits briefly, and
+// then restores the original contents.
+func (h *WindowsAnsiEventHandler) flashScreen() error {
+	info, err := h.getConsoleScreenBufferInfo()
+	if err != nil {
+		return err
+	}
+
+	window := info.Window
+	size := COORD{X: window.Right - window.Left + 1, Y: window.Bottom - window.Top + 1}
+	saved := make([]CHAR_INFO, int(size.X)*int(size.Y))
+
+	region := window
+	if err := h.console.ReadConsoleOutput(h.fd, saved, size, COORD{}, &region); err != nil {
+		return err
+	}
+
+	inverted := make([]CHAR_INFO, len(saved))
+	for i, c := range saved {
+		inverted[i] = CHAR_INFO{UnicodeChar: c.UnicodeChar, Attributes: invertAttributes(c.Attributes)}
+	}
+
+	region = window
+	if err := h.console.WriteConsoleOutput(h.fd, inverted, size, COORD{}, &region); err != nil {
+		return err
+	}
+
+	time.Sleep(screenFlashDuration)
+
+	region = window
+	return h.console.WriteConsoleOutput(h.fd, saved, size, COORD{}, &region)
+}
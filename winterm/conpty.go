@@ -0,0 +1,141 @@
+// +build windows
+
+package winterm
+
+import (
+	"fmt"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Pseudo consoles (ConPTY) are available from Windows 10 1809. They perform
+// the terminal emulation themselves, exchanging VT sequences with the host
+// over a pair of pipes, so hosts using one can forward output untouched
+// rather than rendering it with WindowsAnsiEventHandler.
+// See https://docs.microsoft.com/en-us/windows/console/creating-a-pseudoconsole-session.
+
+var createPseudoConsoleProc = kernel32DLL.NewProc("CreatePseudoConsole")
+
+// PseudoConsole is a Windows pseudo console. Reading returns the VT output
+// of the processes attached to it, and writing delivers VT input to them.
+type PseudoConsole struct {
+	handle uintptr
+	input  *os.File // Input to the console, written by the host
+	output *os.File // Output from the console, read by the host
+}
+
+// IsPseudoConsoleSupported reports whether this version of Windows provides
+// pseudo consoles.
+func IsPseudoConsoleSupported() bool {
+	return createPseudoConsoleProc.Find() == nil
+}
+
+// NewPseudoConsole creates a pseudo console with the given size in cells.
+// Attach a process to it with Start, or by passing Handle as the
+// PROC_THREAD_ATTRIBUTE_PSEUDOCONSOLE attribute when creating the process.
+func NewPseudoConsole(cols int, rows int) (*PseudoConsole, error) {
+	if !IsPseudoConsoleSupported() {
+		return nil, fmt.Errorf("winterm: pseudo consoles are not supported on this version of Windows")
+	}
+
+	inputRead, inputWrite, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+
+	outputRead, outputWrite, err := os.Pipe()
+	if err != nil {
+		inputRead.Close()
+		inputWrite.Close()
+		return nil, err
+	}
+
+	var handle windows.Handle
+	err = windows.CreatePseudoConsole(windows.Coord{X: SHORT(cols), Y: SHORT(rows)}, windows.Handle(inputRead.Fd()), windows.Handle(outputWrite.Fd()), 0, &handle)
+
+	// The pseudo console holds its own references to its ends of the pipes
+	inputRead.Close()
+	outputWrite.Close()
+
+	if err != nil {
+		inputWrite.Close()
+		outputRead.Close()
+		return nil, err
+	}
+
+	return &PseudoConsole{handle: uintptr(handle), input: inputWrite, output: outputRead}, nil
+}
+
+// Start starts a process running cmdline, a command line quoted as
+// CreateProcess expects, attached to the pseudo console.
+func (pc *PseudoConsole) Start(cmdline string) (*os.Process, error) {
+	attributes, err := windows.NewProcThreadAttributeList(1)
+	if err != nil {
+		return nil, err
+	}
+	defer attributes.Delete()
+
+	// The attribute's value is the pseudo console handle itself, which is
+	// not a Go pointer
+	if err := attributes.Update(windows.PROC_THREAD_ATTRIBUTE_PSEUDOCONSOLE, *(*unsafe.Pointer)(unsafe.Pointer(&pc.handle)), unsafe.Sizeof(pc.handle)); err != nil {
+		return nil, err
+	}
+
+	// Standard handles are given explicitly, as none, so the process does
+	// not inherit the host's and bypass the pseudo console
+	var si windows.StartupInfoEx
+	si.Cb = uint32(unsafe.Sizeof(si))
+	si.Flags = windows.STARTF_USESTDHANDLES
+	si.ProcThreadAttributeList = attributes.List()
+
+	commandLine, err := windows.UTF16PtrFromString(cmdline)
+	if err != nil {
+		return nil, err
+	}
+
+	var pi windows.ProcessInformation
+	if err := windows.CreateProcess(nil, commandLine, nil, nil, false, windows.EXTENDED_STARTUPINFO_PRESENT|windows.CREATE_UNICODE_ENVIRONMENT, nil, nil, &si.StartupInfo, &pi); err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(pi.Process)
+	windows.CloseHandle(pi.Thread)
+
+	// The process handle is held until the process is found, so its ID
+	// cannot be reused before then
+	return os.FindProcess(int(pi.ProcessId))
+}
+
+// Handle returns the pseudo console handle (HPCON).
+func (pc *PseudoConsole) Handle() uintptr {
+	return pc.handle
+}
+
+// Read reads VT output from the pseudo console.
+func (pc *PseudoConsole) Read(p []byte) (int, error) {
+	return pc.output.Read(p)
+}
+
+// Write writes VT input to the pseudo console.
+func (pc *PseudoConsole) Write(p []byte) (int, error) {
+	return pc.input.Write(p)
+}
+
+// Resize changes the size of the pseudo console in cells.
+func (pc *PseudoConsole) Resize(cols int, rows int) error {
+	return windows.ResizePseudoConsole(windows.Handle(pc.handle), windows.Coord{X: SHORT(cols), Y: SHORT(rows)})
+}
+
+// Close closes the pseudo console, terminating attached processes, and
+// releases its pipes. Output must keep being read until Close returns, since
+// closing may flush final output to the host.
+func (pc *PseudoConsole) Close() error {
+	windows.ClosePseudoConsole(windows.Handle(pc.handle))
+
+	err := pc.input.Close()
+	if outputErr := pc.output.Close(); err == nil {
+		err = outputErr
+	}
+	return err
+}
@@ -0,0 +1,42 @@
+package winterm
+
+// Console is the Windows console API as a WindowsAnsiEventHandler uses it,
+// rendering to screen buffers identified by handles. The handlers created by
+// NewWinEventHandler and the other Windows constructors call the console
+// itself; NewConsoleEventHandler accepts any implementation, such as the
+// in-memory console of the wintermtest package, so that the handler can be
+// tested without a console, on any platform.
+//
+// Each method behaves as the package function of the same name, which calls
+// the console function documented with it.
+type Console interface {
+	CreateConsoleScreenBuffer() (uintptr, error)
+	CloseHandle(handle uintptr) error
+	SetConsoleActiveScreenBuffer(handle uintptr) error
+	GetConsoleScreenBufferInfo(handle uintptr) (*CONSOLE_SCREEN_BUFFER_INFO, error)
+	SetConsoleScreenBufferSize(handle uintptr, coord COORD) error
+	SetConsoleWindowInfo(handle uintptr, isAbsolute bool, rect SMALL_RECT) error
+	GetLargestConsoleWindowSize(handle uintptr) (COORD, error)
+	GetCurrentConsoleFont(handle uintptr) (*CONSOLE_FONT_INFO, error)
+
+	GetConsoleMode(handle uintptr) (uint32, error)
+	SetConsoleMode(handle uintptr, mode uint32) error
+	GetConsoleCursorInfo(handle uintptr, cursorInfo *CONSOLE_CURSOR_INFO) error
+	SetConsoleCursorInfo(handle uintptr, cursorInfo *CONSOLE_CURSOR_INFO) error
+	SetConsoleCursorPosition(handle uintptr, coord COORD) error
+	SetConsoleTextAttribute(handle uintptr, attribute WORD) error
+
+	WriteConsole(handle uintptr, buffer []uint16) error
+	FillConsoleOutputCharacter(handle uintptr, char WCHAR, count uint32, coord COORD) error
+	FillConsoleOutputAttribute(handle uintptr, attribute WORD, count uint32, coord COORD) error
+	ScrollConsoleScreenBuffer(handle uintptr, scrollRect SMALL_RECT, clipRect SMALL_RECT, destOrigin COORD, char CHAR_INFO) error
+	ReadConsoleOutput(handle uintptr, buffer []CHAR_INFO, bufferSize COORD, bufferCoord COORD, readRegion *SMALL_RECT) error
+	WriteConsoleOutput(handle uintptr, buffer []CHAR_INFO, bufferSize COORD, bufferCoord COORD, writeRegion *SMALL_RECT) error
+
+	GetConsoleOutputCP() (uint32, error)
+	WideCharToMultiByte(codepage uint32, text []uint16) ([]byte, error)
+	GetConsoleTitle() (string, error)
+	SetConsoleTitle(title string) error
+	GetConsoleWindow() uintptr
+	FlashWindowEx(info *FLASHWINFO)
+}
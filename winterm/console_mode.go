@@ -0,0 +1,86 @@
+// +build windows
+
+package winterm
+
+import "syscall"
+
+// ConsoleState holds console input and output modes captured by SaveState or
+// MakeRaw, for Restore.
+type ConsoleState struct {
+	in      syscall.Handle
+	out     syscall.Handle
+	inMode  uint32
+	outMode uint32
+}
+
+// SaveState captures the modes of the console input buffer in and the screen
+// buffer out. Either handle may be 0 to leave that side alone.
+func SaveState(in syscall.Handle, out syscall.Handle) (*ConsoleState, error) {
+	state := &ConsoleState{in: in, out: out}
+
+	if in != 0 {
+		mode, err := GetConsoleMode(uintptr(in))
+		if err != nil {
+			return nil, &NotConsoleError{Err: err}
+		}
+		state.inMode = mode
+	}
+
+	if out != 0 {
+		mode, err := GetConsoleMode(uintptr(out))
+		if err != nil {
+			return nil, &NotConsoleError{Err: err}
+		}
+		state.outMode = mode
+	}
+
+	return state, nil
+}
+
+// MakeRaw captures the console modes as SaveState does, then switches the
+// console to the modes expected by an AnsiReader and WindowsAnsiEventHandler
+// pair. Input is delivered key by key, without echo or processing of Ctrl+C,
+// along with window, mouse and focus events; Quick Edit is disabled so mouse
+// events reach the reader. Output is processed and wraps at the end of lines.
+func MakeRaw(in syscall.Handle, out syscall.Handle) (*ConsoleState, error) {
+	state, err := SaveState(in, out)
+	if err != nil {
+		return nil, err
+	}
+
+	if in != 0 {
+		mode := state.inMode
+		mode &^= ENABLE_LINE_INPUT | ENABLE_ECHO_INPUT | ENABLE_PROCESSED_INPUT | ENABLE_QUICK_EDIT_MODE
+		mode |= ENABLE_WINDOW_INPUT | ENABLE_MOUSE_INPUT | ENABLE_EXTENDED_FLAGS
+		if err := SetConsoleMode(uintptr(in), mode); err != nil {
+			return nil, err
+		}
+	}
+
+	if out != 0 {
+		mode := state.outMode | ENABLE_PROCESSED_OUTPUT | ENABLE_WRAP_AT_EOL_OUTPUT
+		if err := SetConsoleMode(uintptr(out), mode); err != nil {
+			state.Restore()
+			return nil, err
+		}
+	}
+
+	return state, nil
+}
+
+// Restore returns the console to the captured modes.
+func (state *ConsoleState) Restore() error {
+	if state.in != 0 {
+		if err := SetConsoleMode(uintptr(state.in), state.inMode); err != nil {
+			return err
+		}
+	}
+
+	if state.out != 0 {
+		if err := SetConsoleMode(uintptr(state.out), state.outMode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
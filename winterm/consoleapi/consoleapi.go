@@ -0,0 +1,253 @@
+// +build windows
+
+package consoleapi
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	kernel32 = windows.NewLazySystemDLL("kernel32.dll")
+
+	procCreateConsoleScreenBuffer    = kernel32.NewProc("CreateConsoleScreenBuffer")
+	procFillConsoleOutputAttribute   = kernel32.NewProc("FillConsoleOutputAttribute")
+	procFillConsoleOutputCharacterW  = kernel32.NewProc("FillConsoleOutputCharacterW")
+	procGetConsoleCursorInfo         = kernel32.NewProc("GetConsoleCursorInfo")
+	procGetConsoleScreenBufferInfoEx = kernel32.NewProc("GetConsoleScreenBufferInfoEx")
+	procGetCurrentConsoleFont        = kernel32.NewProc("GetCurrentConsoleFont")
+	procGetLargestConsoleWindowSize  = kernel32.NewProc("GetLargestConsoleWindowSize")
+	procReadConsoleOutputW           = kernel32.NewProc("ReadConsoleOutputW")
+	procScrollConsoleScreenBufferW   = kernel32.NewProc("ScrollConsoleScreenBufferW")
+	procSetConsoleActiveScreenBuffer = kernel32.NewProc("SetConsoleActiveScreenBuffer")
+	procSetConsoleCursorInfo         = kernel32.NewProc("SetConsoleCursorInfo")
+	procSetConsoleScreenBufferInfoEx = kernel32.NewProc("SetConsoleScreenBufferInfoEx")
+	procSetConsoleScreenBufferSize   = kernel32.NewProc("SetConsoleScreenBufferSize")
+	procSetConsoleTextAttribute      = kernel32.NewProc("SetConsoleTextAttribute")
+	procSetConsoleWindowInfo         = kernel32.NewProc("SetConsoleWindowInfo")
+	procWriteConsoleOutputW          = kernel32.NewProc("WriteConsoleOutputW")
+)
+
+// CreateConsoleScreenBuffer access, sharing, and flags
+// See https://docs.microsoft.com/en-us/windows/console/createconsolescreenbuffer.
+const CONSOLE_TEXTMODE_BUFFER = 0x00000001
+
+// ConsoleFontInfo is CONSOLE_FONT_INFO, the index and cell size of a font.
+// See https://docs.microsoft.com/en-us/windows/console/console-font-info-str.
+type ConsoleFontInfo struct {
+	Font     uint32
+	FontSize windows.Coord
+}
+
+// ConsoleScreenBufferInfoEx is CONSOLE_SCREEN_BUFFER_INFOEX, which extends
+// windows.ConsoleScreenBufferInfo with the popup attributes and the color
+// table the 16 attribute colors are drawn with, as 0x00BBGGRR values.
+// See https://docs.microsoft.com/en-us/windows/console/console-screen-buffer-infoex.
+type ConsoleScreenBufferInfoEx struct {
+	Size                uint32 // The size of the structure, set by the functions
+	ScreenBufferSize    windows.Coord
+	CursorPosition      windows.Coord
+	Attributes          uint16
+	Window              windows.SmallRect
+	MaximumWindowSize   windows.Coord
+	PopupAttributes     uint16
+	FullscreenSupported int32
+	ColorTable          [16]uint32
+}
+
+// GetConsoleScreenBufferInfo returns the size, cursor position, attributes,
+// and window of a screen buffer.
+// See https://docs.microsoft.com/en-us/windows/console/getconsolescreenbufferinfo.
+func GetConsoleScreenBufferInfo(console windows.Handle) (windows.ConsoleScreenBufferInfo, error) {
+	var info windows.ConsoleScreenBufferInfo
+	err := windows.GetConsoleScreenBufferInfo(console, &info)
+	return info, err
+}
+
+// GetConsoleScreenBufferInfoEx returns the extended information of a screen
+// buffer, including its color table.
+// See https://docs.microsoft.com/en-us/windows/console/getconsolescreenbufferinfoex.
+func GetConsoleScreenBufferInfoEx(console windows.Handle) (ConsoleScreenBufferInfoEx, error) {
+	info := ConsoleScreenBufferInfoEx{Size: uint32(unsafe.Sizeof(ConsoleScreenBufferInfoEx{}))}
+	r1, _, err := procGetConsoleScreenBufferInfoEx.Call(uintptr(console), uintptr(unsafe.Pointer(&info)))
+	return info, result(r1, err)
+}
+
+// SetConsoleScreenBufferInfoEx sets the extended information of a screen
+// buffer, such as its color table. The window Windows reports is one cell
+// smaller than it sets, so info.Window.Right and info.Window.Bottom are
+// usually incremented when passing back information that was read.
+// See https://docs.microsoft.com/en-us/windows/console/setconsolescreenbufferinfoex.
+func SetConsoleScreenBufferInfoEx(console windows.Handle, info ConsoleScreenBufferInfoEx) error {
+	info.Size = uint32(unsafe.Sizeof(info))
+	r1, _, err := procSetConsoleScreenBufferInfoEx.Call(uintptr(console), uintptr(unsafe.Pointer(&info)))
+	return result(r1, err)
+}
+
+// CreateConsoleScreenBuffer creates a text mode screen buffer, which is not
+// displayed until made active. Close it with windows.CloseHandle.
+// See https://docs.microsoft.com/en-us/windows/console/createconsolescreenbuffer.
+func CreateConsoleScreenBuffer() (windows.Handle, error) {
+	r1, _, err := procCreateConsoleScreenBuffer.Call(
+		windows.GENERIC_READ|windows.GENERIC_WRITE,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE,
+		0,
+		CONSOLE_TEXTMODE_BUFFER,
+		0)
+	if windows.Handle(r1) == windows.InvalidHandle {
+		return 0, result(0, err)
+	}
+	return windows.Handle(r1), nil
+}
+
+// SetConsoleActiveScreenBuffer displays a screen buffer.
+// See https://docs.microsoft.com/en-us/windows/console/setconsoleactivescreenbuffer.
+func SetConsoleActiveScreenBuffer(console windows.Handle) error {
+	r1, _, err := procSetConsoleActiveScreenBuffer.Call(uintptr(console))
+	return result(r1, err)
+}
+
+// SetConsoleScreenBufferSize sets the size of a screen buffer in cells, which
+// cannot be smaller than its window.
+// See https://docs.microsoft.com/en-us/windows/console/setconsolescreenbuffersize.
+func SetConsoleScreenBufferSize(console windows.Handle, size windows.Coord) error {
+	r1, _, err := procSetConsoleScreenBufferSize.Call(uintptr(console), coord(size))
+	return result(r1, err)
+}
+
+// SetConsoleWindowInfo sets the position and size of the window of a screen
+// buffer, which must lie within it, either as absolute coordinates or
+// relative to the current window.
+// See https://docs.microsoft.com/en-us/windows/console/setconsolewindowinfo.
+func SetConsoleWindowInfo(console windows.Handle, absolute bool, window windows.SmallRect) error {
+	r1, _, err := procSetConsoleWindowInfo.Call(uintptr(console), boolean(absolute), uintptr(unsafe.Pointer(&window)))
+	return result(r1, err)
+}
+
+// GetLargestConsoleWindowSize returns the size of the largest window possible
+// for a screen buffer, given its font and the size of the display.
+// See https://docs.microsoft.com/en-us/windows/console/getlargestconsolewindowsize.
+func GetLargestConsoleWindowSize(console windows.Handle) (windows.Coord, error) {
+	r1, _, err := procGetLargestConsoleWindowSize.Call(uintptr(console))
+	if err := result(r1, err); err != nil {
+		return windows.Coord{}, err
+	}
+	return windows.Coord{X: int16(r1 & 0xFFFF), Y: int16(r1 >> 16 & 0xFFFF)}, nil
+}
+
+// ScrollConsoleScreenBuffer moves the cells of scroll so its top left cell
+// is at destination, filling the cells left behind with fill. Only cells
+// within clip, if it is not nil, are changed.
+// See https://docs.microsoft.com/en-us/windows/console/scrollconsolescreenbuffer.
+func ScrollConsoleScreenBuffer(console windows.Handle, scroll windows.SmallRect, clip *windows.SmallRect, destination windows.Coord, fill CharInfo) error {
+	r1, _, err := procScrollConsoleScreenBufferW.Call(
+		uintptr(console),
+		uintptr(unsafe.Pointer(&scroll)),
+		uintptr(unsafe.Pointer(clip)),
+		coord(destination),
+		uintptr(unsafe.Pointer(&fill)))
+	return result(r1, err)
+}
+
+// WriteConsoleOutput writes the cells of buffer, a grid of bufferSize cells,
+// from bufferCoord onwards to region of a screen buffer. On return, region
+// holds the cells actually written.
+// See https://docs.microsoft.com/en-us/windows/console/writeconsoleoutput.
+func WriteConsoleOutput(console windows.Handle, buffer []CharInfo, bufferSize windows.Coord, bufferCoord windows.Coord, region *windows.SmallRect) error {
+	if len(buffer) == 0 {
+		return windows.ERROR_INVALID_PARAMETER
+	}
+	r1, _, err := procWriteConsoleOutputW.Call(uintptr(console), uintptr(unsafe.Pointer(&buffer[0])), coord(bufferSize), coord(bufferCoord), uintptr(unsafe.Pointer(region)))
+	return result(r1, err)
+}
+
+// ReadConsoleOutput reads the cells of region of a screen buffer into
+// buffer, a grid of bufferSize cells, from bufferCoord onwards. On return,
+// region holds the cells actually read.
+// See https://docs.microsoft.com/en-us/windows/console/readconsoleoutput.
+func ReadConsoleOutput(console windows.Handle, buffer []CharInfo, bufferSize windows.Coord, bufferCoord windows.Coord, region *windows.SmallRect) error {
+	if len(buffer) == 0 {
+		return windows.ERROR_INVALID_PARAMETER
+	}
+	r1, _, err := procReadConsoleOutputW.Call(uintptr(console), uintptr(unsafe.Pointer(&buffer[0])), coord(bufferSize), coord(bufferCoord), uintptr(unsafe.Pointer(region)))
+	return result(r1, err)
+}
+
+// FillConsoleOutputCharacter writes char to count cells of a screen buffer
+// from origin onwards, wrapping at the end of each row, and returns the
+// number of cells written.
+// See https://docs.microsoft.com/en-us/windows/console/fillconsoleoutputcharacter.
+func FillConsoleOutputCharacter(console windows.Handle, char uint16, count uint32, origin windows.Coord) (uint32, error) {
+	var written uint32
+	r1, _, err := procFillConsoleOutputCharacterW.Call(uintptr(console), uintptr(char), uintptr(count), coord(origin), uintptr(unsafe.Pointer(&written)))
+	return written, result(r1, err)
+}
+
+// FillConsoleOutputAttribute sets the attributes of count cells of a screen
+// buffer from origin onwards, wrapping at the end of each row, and returns
+// the number of cells set.
+// See https://docs.microsoft.com/en-us/windows/console/fillconsoleoutputattribute.
+func FillConsoleOutputAttribute(console windows.Handle, attributes uint16, count uint32, origin windows.Coord) (uint32, error) {
+	var written uint32
+	r1, _, err := procFillConsoleOutputAttribute.Call(uintptr(console), uintptr(attributes), uintptr(count), coord(origin), uintptr(unsafe.Pointer(&written)))
+	return written, result(r1, err)
+}
+
+// SetConsoleTextAttribute sets the attributes of text subsequently written
+// to a screen buffer.
+// See https://docs.microsoft.com/en-us/windows/console/setconsoletextattribute.
+func SetConsoleTextAttribute(console windows.Handle, attributes uint16) error {
+	r1, _, err := procSetConsoleTextAttribute.Call(uintptr(console), uintptr(attributes))
+	return result(r1, err)
+}
+
+// GetConsoleCursorInfo returns the size and visibility of the cursor of a
+// screen buffer.
+// See https://docs.microsoft.com/en-us/windows/console/getconsolecursorinfo.
+func GetConsoleCursorInfo(console windows.Handle) (ConsoleCursorInfo, error) {
+	var info ConsoleCursorInfo
+	r1, _, err := procGetConsoleCursorInfo.Call(uintptr(console), uintptr(unsafe.Pointer(&info)))
+	return info, result(r1, err)
+}
+
+// SetConsoleCursorInfo sets the size and visibility of the cursor of a
+// screen buffer.
+// See https://docs.microsoft.com/en-us/windows/console/setconsolecursorinfo.
+func SetConsoleCursorInfo(console windows.Handle, info ConsoleCursorInfo) error {
+	r1, _, err := procSetConsoleCursorInfo.Call(uintptr(console), uintptr(unsafe.Pointer(&info)))
+	return result(r1, err)
+}
+
+// GetCurrentConsoleFont returns the font of a screen buffer, with its size
+// for the maximum window size if maximumWindow is set.
+// See https://docs.microsoft.com/en-us/windows/console/getcurrentconsolefont.
+func GetCurrentConsoleFont(console windows.Handle, maximumWindow bool) (ConsoleFontInfo, error) {
+	var info ConsoleFontInfo
+	r1, _, err := procGetCurrentConsoleFont.Call(uintptr(console), boolean(maximumWindow), uintptr(unsafe.Pointer(&info)))
+	return info, result(r1, err)
+}
+
+// coord packs a COORD into an argument, as the functions take it by value.
+func coord(c windows.Coord) uintptr {
+	return uintptr(*(*uint32)(unsafe.Pointer(&c)))
+}
+
+// boolean converts a bool into a BOOL argument.
+func boolean(b bool) uintptr {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// result returns the error of a call whose result r1 is zero on failure.
+func result(r1 uintptr, err error) error {
+	if r1 != 0 {
+		return nil
+	}
+	if errno, ok := err.(windows.Errno); ok && errno != 0 {
+		return errno
+	}
+	return windows.ERROR_INVALID_PARAMETER
+}
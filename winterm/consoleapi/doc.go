@@ -0,0 +1,14 @@
+// Package consoleapi provides Go bindings for the Windows console screen
+// buffer functions that golang.org/x/sys/windows lacks, such as
+// ScrollConsoleScreenBuffer, WriteConsoleOutput, and
+// GetConsoleScreenBufferInfoEx, using its types (windows.Handle,
+// windows.Coord, windows.SmallRect) so the two can be used together.
+//
+// The functions return the error Windows reports, and take and return
+// values where the Windows functions use pointers only to pass structures.
+// See https://docs.microsoft.com/en-us/windows/console/console-functions.
+//
+// The functions are only built on Windows; the CharInfo and
+// ConsoleCursorInfo structures are available on every platform, for code
+// that works with screen buffer contents without calling the console.
+package consoleapi
@@ -0,0 +1,16 @@
+package consoleapi
+
+// CharInfo is CHAR_INFO, a character cell of a screen buffer.
+// See https://docs.microsoft.com/en-us/windows/console/char-info-str.
+type CharInfo struct {
+	UnicodeChar uint16
+	Attributes  uint16
+}
+
+// ConsoleCursorInfo is CONSOLE_CURSOR_INFO. Size is the percentage of the
+// cell the cursor fills, and Visible is nonzero if it is shown.
+// See https://docs.microsoft.com/en-us/windows/console/console-cursor-info-str.
+type ConsoleCursorInfo struct {
+	Size    uint32
+	Visible int32
+}
@@ -0,0 +1,79 @@
+// +build windows
+
+package winterm
+
+import (
+	"sync"
+	"syscall"
+)
+
+// ControlEvent identifies a console control event: CTRL_C_EVENT,
+// CTRL_BREAK_EVENT, CTRL_CLOSE_EVENT, CTRL_LOGOFF_EVENT or CTRL_SHUTDOWN_EVENT.
+type ControlEvent uint32
+
+// controlHandler is a function registered with WithControlHandler.
+type controlHandler struct {
+	handle func(event ControlEvent) bool
+}
+
+var (
+	controlMutex    sync.Mutex
+	controlHandlers []*controlHandler
+
+	// controlCallback dispatches control events to controlHandlers. It is
+	// created once, since callbacks cannot be freed.
+	controlCallback uintptr
+)
+
+// dispatchControlEvent offers a control event to the registered handlers,
+// most recent first, until one handles it. The console calls it on a thread
+// of its own.
+func dispatchControlEvent(event uintptr) uintptr {
+	controlMutex.Lock()
+	handlers := append([]*controlHandler(nil), controlHandlers...)
+	controlMutex.Unlock()
+
+	for i := len(handlers) - 1; i >= 0; i-- {
+		if handlers[i].handle(ControlEvent(event)) {
+			return 1
+		}
+	}
+	return 0
+}
+
+// addControlHandler registers a handler, installing the console control
+// handler for the first.
+func addControlHandler(handler *controlHandler) error {
+	controlMutex.Lock()
+	defer controlMutex.Unlock()
+
+	if len(controlHandlers) == 0 {
+		if controlCallback == 0 {
+			controlCallback = syscall.NewCallback(dispatchControlEvent)
+		}
+		if err := SetConsoleCtrlHandler(controlCallback, true); err != nil {
+			return err
+		}
+	}
+
+	controlHandlers = append(controlHandlers, handler)
+	return nil
+}
+
+// removeControlHandler unregisters a handler, removing the console control
+// handler with the last.
+func removeControlHandler(handler *controlHandler) error {
+	controlMutex.Lock()
+	defer controlMutex.Unlock()
+
+	for i, h := range controlHandlers {
+		if h == handler {
+			controlHandlers = append(controlHandlers[:i], controlHandlers[i+1:]...)
+			if len(controlHandlers) == 0 {
+				return SetConsoleCtrlHandler(controlCallback, false)
+			}
+			break
+		}
+	}
+	return nil
+}
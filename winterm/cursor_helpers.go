@@ -1,5 +1,3 @@
-// +build windows
-
 package winterm
 
 const (
@@ -11,7 +9,53 @@ const (
 func (h *WindowsAnsiEventHandler) setCursorPosition(position COORD, sizeBuffer COORD) error {
 	position.X = ensureInRange(position.X, 0, sizeBuffer.X-1)
 	position.Y = ensureInRange(position.Y, 0, sizeBuffer.Y-1)
-	return SetConsoleCursorPosition(h.fd, position)
+	if err := h.console.SetConsoleCursorPosition(h.fd, position); err != nil {
+		h.Invalidate()
+		return err
+	}
+
+	if err := h.restoreViewport(); err != nil {
+		h.Invalidate()
+		return err
+	}
+
+	// The console scrolls the window to keep the cursor visible
+	if info := h.info; info != nil {
+		if position.X < info.Window.Left || position.X > info.Window.Right ||
+			position.Y < info.Window.Top || position.Y > info.Window.Bottom {
+			h.Invalidate()
+		} else {
+			info.CursorPosition = position
+		}
+	}
+
+	return nil
+}
+
+// verticalBounds returns the rows, in buffer coordinates, within which the cursor
+// may be addressed: the scroll region in origin mode, otherwise the whole window.
+func (h *WindowsAnsiEventHandler) verticalBounds(window SMALL_RECT) (SHORT, SHORT) {
+	if h.originMode {
+		return window.Top + SHORT(h.sr.top), window.Top + SHORT(h.sr.bottom)
+	}
+
+	return window.Top, window.Bottom
+}
+
+// verticalMoveBounds returns the rows, in buffer coordinates, within which
+// relative movement from row y is confined. As on the VT100, movement down
+// stops at the bottom margin if y is not below it, and movement up at the top
+// margin if y is not above it; otherwise at the edge of the window.
+func (h *WindowsAnsiEventHandler) verticalMoveBounds(window SMALL_RECT, y SHORT) (SHORT, SHORT) {
+	top, bottom := window.Top, window.Bottom
+	if marginTop := window.Top + SHORT(h.sr.top); h.originMode || y >= marginTop {
+		top = marginTop
+	}
+	if marginBottom := window.Top + SHORT(h.sr.bottom); h.originMode || y <= marginBottom {
+		bottom = marginBottom
+	}
+
+	return top, bottom
 }
 
 func (h *WindowsAnsiEventHandler) moveCursorVertical(param int) error {
@@ -23,7 +67,7 @@ func (h *WindowsAnsiEventHandler) moveCursorHorizontal(param int) error {
 }
 
 func (h *WindowsAnsiEventHandler) moveCursor(moveMode int, param int) error {
-	info, err := GetConsoleScreenBufferInfo(h.fd)
+	info, err := h.getConsoleScreenBufferInfo()
 	if err != nil {
 		return err
 	}
@@ -33,27 +77,29 @@ func (h *WindowsAnsiEventHandler) moveCursor(moveMode int, param int) error {
 	case Horizontal:
 		position.X = AddInRange(position.X, SHORT(param), info.Window.Left, info.Window.Right)
 	case Vertical:
-		position.Y = AddInRange(position.Y, SHORT(param), info.Window.Top, info.Window.Bottom)
+		top, bottom := h.verticalMoveBounds(info.Window, position.Y)
+		position.Y = AddInRange(position.Y, SHORT(param), top, bottom)
 	}
 
 	if err = h.setCursorPosition(position, info.Size); err != nil {
 		return err
 	}
 
-	logger.Infof("Cursor position set: (%d, %d)", position.X, position.Y)
+	h.logger.Infof("Cursor position set: (%d, %d)", position.X, position.Y)
 
 	return nil
 }
 
 func (h *WindowsAnsiEventHandler) moveCursorLine(param int) error {
-	info, err := GetConsoleScreenBufferInfo(h.fd)
+	info, err := h.getConsoleScreenBufferInfo()
 	if err != nil {
 		return err
 	}
 
 	position := info.CursorPosition
+	top, bottom := h.verticalMoveBounds(info.Window, position.Y)
 	position.X = 0
-	position.Y = AddInRange(position.Y, SHORT(param), info.Window.Top, info.Window.Bottom)
+	position.Y = AddInRange(position.Y, SHORT(param), top, bottom)
 
 	if err = h.setCursorPosition(position, info.Size); err != nil {
 		return err
@@ -63,7 +109,7 @@ func (h *WindowsAnsiEventHandler) moveCursorLine(param int) error {
 }
 
 func (h *WindowsAnsiEventHandler) moveCursorColumn(param int) error {
-	info, err := GetConsoleScreenBufferInfo(h.fd)
+	info, err := h.getConsoleScreenBufferInfo()
 	if err != nil {
 		return err
 	}
@@ -77,3 +123,20 @@ func (h *WindowsAnsiEventHandler) moveCursorColumn(param int) error {
 
 	return nil
 }
+
+func (h *WindowsAnsiEventHandler) moveCursorRow(param int) error {
+	info, err := h.getConsoleScreenBufferInfo()
+	if err != nil {
+		return err
+	}
+
+	top, bottom := h.verticalBounds(info.Window)
+	position := info.CursorPosition
+	position.Y = AddInRange(SHORT(param-1), top, top, bottom)
+
+	if err = h.setCursorPosition(position, info.Size); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -1,12 +1,12 @@
-// +build windows
-
 package winterm
 
 import (
 	. "github.com/Azure/go-ansiterm"
 )
 
-func (h *WindowsAnsiEventHandler) clearRange(attributes WORD, fromCoord COORD, toCoord COORD) error {
+// clearRange erases the cells from fromCoord through toCoord, inclusive, in
+// reading order. Rows run from the left to the right edge of bounds.
+func (h *WindowsAnsiEventHandler) clearRange(attributes WORD, fromCoord COORD, toCoord COORD, bounds SMALL_RECT) error {
 	// Ignore an invalid (negative area) request
 	if toCoord.Y < fromCoord.Y {
 		return nil
@@ -21,30 +21,30 @@ func (h *WindowsAnsiEventHandler) clearRange(attributes WORD, fromCoord COORD, t
 	xEnd, yEnd := toCoord.X, toCoord.Y
 
 	// Clear any partial initial line
-	if xCurrent > 0 {
+	if xCurrent > bounds.Left && yCurrent < yEnd {
 		coordStart.X, coordStart.Y = xCurrent, yCurrent
-		coordEnd.X, coordEnd.Y = xEnd, yCurrent
+		coordEnd.X, coordEnd.Y = bounds.Right, yCurrent
 
 		err = h.clearRect(attributes, coordStart, coordEnd)
 		if err != nil {
 			return err
 		}
 
-		xCurrent = 0
+		xCurrent = bounds.Left
 		yCurrent += 1
 	}
 
 	// Clear intervening rectangular section
 	if yCurrent < yEnd {
 		coordStart.X, coordStart.Y = xCurrent, yCurrent
-		coordEnd.X, coordEnd.Y = xEnd, yEnd-1
+		coordEnd.X, coordEnd.Y = bounds.Right, yEnd-1
 
 		err = h.clearRect(attributes, coordStart, coordEnd)
 		if err != nil {
 			return err
 		}
 
-		xCurrent = 0
+		xCurrent = bounds.Left
 		yCurrent = yEnd
 	}
 
@@ -72,15 +72,61 @@ func (h *WindowsAnsiEventHandler) clearRect(attributes WORD, fromCoord COORD, to
 
 	buffer := make([]CHAR_INFO, size)
 
-	char := CHAR_INFO{WCHAR(FILL_CHARACTER), attributes}
+	char := CHAR_INFO{UnicodeChar: WCHAR(FILL_CHARACTER), Attributes: attributes}
 	for i := 0; i < int(size); i++ {
 		buffer[i] = char
 	}
 
-	err := WriteConsoleOutput(h.fd, buffer, COORD{X: width, Y: height}, COORD{X: 0, Y: 0}, &region)
+	err := h.console.WriteConsoleOutput(h.fd, buffer, COORD{X: width, Y: height}, COORD{X: 0, Y: 0}, &region)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// eraseBounds returns the area, in buffer coordinates, that ED and EL are
+// confined to: the whole backing buffer, or with WithWindowErase only the
+// visible window. Note that cursor positions are also buffer coordinates,
+// but are addressed relative to the window's origin.
+func (h *WindowsAnsiEventHandler) eraseBounds(info *CONSOLE_SCREEN_BUFFER_INFO) SMALL_RECT {
+	if h.windowErase {
+		return info.Window
+	}
+
+	return SMALL_RECT{Left: 0, Top: 0, Right: info.Size.X - 1, Bottom: info.Size.Y - 1}
+}
+
+// clearScrollback discards the backing buffer outside the window by moving the
+// window contents to the top of the buffer and blanking everything below them.
+// The window and cursor move along with the contents.
+func (h *WindowsAnsiEventHandler) clearScrollback(info *CONSOLE_SCREEN_BUFFER_INFO) error {
+	window := info.Window
+	height := window.Bottom - window.Top + 1
+
+	if window.Top > 0 {
+		scrollRect := SMALL_RECT{Top: window.Top, Bottom: window.Bottom, Left: 0, Right: info.Size.X - 1}
+		clipRegion := SMALL_RECT{Top: 0, Bottom: info.Size.Y - 1, Left: 0, Right: info.Size.X - 1}
+		char := CHAR_INFO{UnicodeChar: WCHAR(FILL_CHARACTER), Attributes: info.Attributes}
+
+		if err := h.console.ScrollConsoleScreenBuffer(h.fd, scrollRect, clipRegion, COORD{0, 0}, char); err != nil {
+			return err
+		}
+	}
+
+	if height < info.Size.Y {
+		buffer := SMALL_RECT{Left: 0, Top: 0, Right: info.Size.X - 1, Bottom: info.Size.Y - 1}
+		if err := h.clearRange(info.Attributes, COORD{0, height}, COORD{info.Size.X - 1, info.Size.Y - 1}, buffer); err != nil {
+			return err
+		}
+	}
+
+	h.Invalidate()
+	if err := h.console.SetConsoleWindowInfo(h.fd, true, SMALL_RECT{Left: window.Left, Top: 0, Right: window.Right, Bottom: height - 1}); err != nil {
+		return err
+	}
+
+	position := info.CursorPosition
+	position.Y -= window.Top
+	return h.setCursorPosition(position, info.Size)
+}
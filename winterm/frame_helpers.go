@@ -0,0 +1,157 @@
+package winterm
+
+// A frame renders the handler's effects to a hidden console screen buffer
+// the size of the window. Sync copies the hidden buffer to the window, or
+// with WithBufferSwap makes it the active buffer, so a full-screen redraw
+// appears at once rather than line by line. Lines scrolled off the top of the
+// hidden buffer are not added to the console's scrollback.
+
+// beginFrame redirects the handler to a hidden copy of the window.
+func (h *WindowsAnsiEventHandler) beginFrame() error {
+	if h.front != 0 {
+		return nil
+	}
+
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	info, err := h.getConsoleScreenBufferInfo()
+	if err != nil {
+		return err
+	}
+
+	back, err := h.console.CreateConsoleScreenBuffer()
+	if err != nil {
+		return err
+	}
+
+	size := windowSize(info.Window)
+	err = h.console.SetConsoleScreenBufferSize(back, size)
+	if err == nil {
+		err = h.copyConsole(back, h.fd, SMALL_RECT{Left: 0, Top: 0, Right: size.X - 1, Bottom: size.Y - 1})
+	}
+
+	if err != nil {
+		h.console.CloseHandle(back)
+		return err
+	}
+
+	h.logger.Infof("beginFrame: %v", info.Window)
+
+	h.primary = h.fd
+	h.front, h.fd = h.fd, back
+	h.Invalidate()
+	return nil
+}
+
+// Sync writes any pending output and, while a frame is being rendered,
+// presents it in the console window. With WithDeferredUpdates, output is only
+// displayed when Sync is called.
+func (h *WindowsAnsiEventHandler) Sync() error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	if h.front == 0 {
+		return nil
+	}
+
+	front, err := h.console.GetConsoleScreenBufferInfo(h.front)
+	if err != nil {
+		return err
+	}
+
+	h.logger.Infof("Sync: %v", front.Window)
+
+	if !h.bufferSwap {
+		return h.copyConsole(h.front, h.fd, front.Window)
+	}
+
+	if err := h.console.SetConsoleActiveScreenBuffer(h.fd); err != nil {
+		return err
+	}
+
+	h.front, h.fd = h.fd, h.front
+	h.Invalidate()
+
+	// Bring the newly hidden buffer up to date for the next frame
+	return h.copyConsole(h.fd, h.front, front.Window)
+}
+
+// endFrame presents the frame and returns the handler to the console.
+func (h *WindowsAnsiEventHandler) endFrame() error {
+	if h.front == 0 {
+		return nil
+	}
+
+	if err := h.Sync(); err != nil {
+		return err
+	}
+
+	// Processes writing to the console expect the handler's own buffer to
+	// be displayed; after a swap it is hidden but up to date
+	back := h.fd
+	if back == h.primary {
+		if err := h.console.SetConsoleActiveScreenBuffer(h.primary); err != nil {
+			return err
+		}
+		back = h.front
+	}
+
+	h.fd, h.front, h.primary = h.primary, 0, 0
+	h.Invalidate()
+
+	h.logger.Infof("endFrame")
+	return h.console.CloseHandle(back)
+}
+
+// copyConsole copies the window contents of src into window of dst, along
+// with the cursor, attributes, and modes.
+func (h *WindowsAnsiEventHandler) copyConsole(dst uintptr, src uintptr, window SMALL_RECT) error {
+	info, err := h.console.GetConsoleScreenBufferInfo(src)
+	if err != nil {
+		return err
+	}
+
+	size := windowSize(info.Window)
+	cells := make([]CHAR_INFO, int(size.X)*int(size.Y))
+
+	region := info.Window
+	if err := h.console.ReadConsoleOutput(src, cells, size, COORD{}, &region); err != nil {
+		return err
+	}
+
+	region = window
+	if err := h.console.WriteConsoleOutput(dst, cells, size, COORD{}, &region); err != nil {
+		return err
+	}
+
+	position := COORD{
+		X: info.CursorPosition.X - info.Window.Left + window.Left,
+		Y: info.CursorPosition.Y - info.Window.Top + window.Top,
+	}
+	if err := h.console.SetConsoleCursorPosition(dst, position); err != nil {
+		return err
+	}
+
+	if err := h.console.SetConsoleTextAttribute(dst, info.Attributes); err != nil {
+		return err
+	}
+
+	var cursorInfo CONSOLE_CURSOR_INFO
+	if err := h.console.GetConsoleCursorInfo(src, &cursorInfo); err != nil {
+		return err
+	}
+
+	if err := h.console.SetConsoleCursorInfo(dst, &cursorInfo); err != nil {
+		return err
+	}
+
+	mode, err := h.console.GetConsoleMode(src)
+	if err != nil {
+		return err
+	}
+
+	return h.console.SetConsoleMode(dst, mode)
+}
@@ -0,0 +1,227 @@
+package winterm
+
+import "sync"
+
+// MouseTracking selects which mouse events are reported to the application.
+type MouseTracking int
+
+const (
+	// MouseOff reports no mouse events.
+	MouseOff MouseTracking = 0
+
+	// MouseClicks reports button presses and releases and the wheel (?1000).
+	MouseClicks MouseTracking = 1000
+
+	// MouseDrags also reports motion while a button is held (?1002).
+	MouseDrags MouseTracking = 1002
+
+	// MouseMotion also reports all motion (?1003).
+	MouseMotion MouseTracking = 1003
+)
+
+// InputModes holds the modes, set by the application through its output, that
+// select how input is encoded. A handler and a reader share them so keys are
+// sent in the encodings the application asked for; they may also be set
+// explicitly. InputModes is safe for concurrent use.
+type InputModes struct {
+	mutex             sync.Mutex
+	applicationCursor bool
+	applicationKeypad bool
+	mouseTracking     MouseTracking
+	sgrMouse          bool
+	bracketedPaste    bool
+	win32Input        bool
+	focusReporting    bool
+	modifyOtherKeys   int
+	kittyFlags        []int // Stack of kitty keyboard flags; the last is current
+}
+
+// ApplicationCursorKeys reports whether cursor keys are sent as SS3 sequences
+// (DECCKM set).
+func (m *InputModes) ApplicationCursorKeys() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.applicationCursor
+}
+
+// SetApplicationCursorKeys selects SS3 (true) or CSI (false) cursor keys.
+func (m *InputModes) SetApplicationCursorKeys(enable bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.applicationCursor = enable
+}
+
+// ApplicationKeypad reports whether keypad keys are sent as SS3 sequences
+// (DECKPAM) rather than the characters on them (DECKPNM).
+func (m *InputModes) ApplicationKeypad() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.applicationKeypad
+}
+
+// SetApplicationKeypad selects application (true) or numeric (false) keypad keys.
+func (m *InputModes) SetApplicationKeypad(enable bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.applicationKeypad = enable
+}
+
+// MouseTracking returns the mouse events reported to the application.
+func (m *InputModes) MouseTracking() MouseTracking {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.mouseTracking
+}
+
+// SetMouseTracking selects the mouse events reported to the application.
+func (m *InputModes) SetMouseTracking(tracking MouseTracking) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.mouseTracking = tracking
+}
+
+// SGRMouse reports whether mouse events are reported as CSI < b ; x ; y M/m
+// (?1006) rather than in the original CSI M b x y encoding.
+func (m *InputModes) SGRMouse() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.sgrMouse
+}
+
+// SetSGRMouse selects the SGR (true) or original (false) mouse encoding.
+func (m *InputModes) SetSGRMouse(enable bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.sgrMouse = enable
+}
+
+// BracketedPaste reports whether pasted text is enclosed in CSI 200 ~ and
+// CSI 201 ~ (?2004).
+func (m *InputModes) BracketedPaste() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.bracketedPaste
+}
+
+// SetBracketedPaste selects whether pasted text is bracketed.
+func (m *InputModes) SetBracketedPaste(enable bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.bracketedPaste = enable
+}
+
+// Win32Input reports whether the application asked for key events in the
+// ConPTY win32-input-mode encoding (?9001).
+func (m *InputModes) Win32Input() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.win32Input
+}
+
+// SetWin32Input selects whether key events are sent in win32-input-mode.
+// They are only if the reader also allows it; see WithWin32InputMode.
+func (m *InputModes) SetWin32Input(enable bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.win32Input = enable
+}
+
+// FocusReporting reports whether focus changes are sent as CSI I and CSI O
+// (?1004).
+func (m *InputModes) FocusReporting() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.focusReporting
+}
+
+// SetFocusReporting selects whether focus changes are reported.
+func (m *InputModes) SetFocusReporting(enable bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.focusReporting = enable
+}
+
+// ModifyOtherKeys returns xterm's modifyOtherKeys level: 0 sends chords in
+// the traditional encodings, 1 sends ambiguous chords such as Ctrl+Shift+letter
+// as CSI 27 ; modifier ; code ~, and 2 sends all chords that way.
+func (m *InputModes) ModifyOtherKeys() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.modifyOtherKeys
+}
+
+// SetModifyOtherKeys selects xterm's modifyOtherKeys level.
+func (m *InputModes) SetModifyOtherKeys(level int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.modifyOtherKeys = level
+}
+
+// KittyFlags returns the kitty progressive keyboard enhancement flags in
+// effect. Of these, the reader implements only 1, disambiguating chords by
+// sending them as CSI code ; modifier u.
+func (m *InputModes) KittyFlags() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(m.kittyFlags) == 0 {
+		return 0
+	}
+	return m.kittyFlags[len(m.kittyFlags)-1]
+}
+
+// SetKittyFlags replaces the kitty keyboard enhancement flags in effect.
+func (m *InputModes) SetKittyFlags(flags int) {
+	m.updateKittyFlags(flags, 1)
+}
+
+// pushKittyFlags makes flags current, saving the current flags.
+func (m *InputModes) pushKittyFlags(flags int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.kittyFlags = append(m.kittyFlags, flags)
+}
+
+// popKittyFlags restores the flags saved by the last n pushes.
+func (m *InputModes) popKittyFlags(n int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if n > len(m.kittyFlags) {
+		n = len(m.kittyFlags)
+	}
+	m.kittyFlags = m.kittyFlags[:len(m.kittyFlags)-n]
+}
+
+// updateKittyFlags changes the current flags: mode 1 replaces them, 2 sets
+// the given flags, and 3 clears them.
+func (m *InputModes) updateKittyFlags(flags int, mode int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(m.kittyFlags) == 0 {
+		m.kittyFlags = []int{0}
+	}
+
+	current := &m.kittyFlags[len(m.kittyFlags)-1]
+	switch mode {
+	case 1:
+		*current = flags
+	case 2:
+		*current |= flags
+	case 3:
+		*current &^= flags
+	}
+}
+
+// reset returns the modes to their initial values.
+func (m *InputModes) reset() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.applicationCursor = false
+	m.applicationKeypad = false
+	m.mouseTracking = MouseOff
+	m.sgrMouse = false
+	m.bracketedPaste = false
+	m.win32Input = false
+	m.focusReporting = false
+	m.modifyOtherKeys = 0
+	m.kittyFlags = nil
+}
@@ -0,0 +1,238 @@
+// +build windows
+
+package winterm
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// An input trace records what an AnsiReader read from the console and what it
+// translated it to, one line per input record followed by a line with the
+// bytes produced from the batch:
+//
+//	key <down> <repeat> <vk> <scan> <char> <state>
+//	mouse <x> <y> <buttons> <state> <flags>
+//	focus <set>
+//	size <cols> <rows>
+//	event <type>
+//	out <waiting> <bytes>
+//
+// where <waiting> is the number of records found waiting after the batch was
+// read, which decides where a paste ends, and <bytes> is a quoted Go string.
+// Other event types are recorded without their contents. Blank lines and
+// lines starting with # are ignored, so traces can be annotated.
+
+// traceBatch is the records read at once and the input translated from them.
+type traceBatch struct {
+	records []INPUT_RECORD
+	waiting uint32
+	output  []byte
+	line    int // Line of the trace ending the batch
+}
+
+// writeTraceBatch writes a batch of records and the input translated from
+// them to an input trace.
+func writeTraceBatch(w io.Writer, records []INPUT_RECORD, waiting uint32, output []byte) error {
+	var buf bytes.Buffer
+	for i := range records {
+		record := &records[i]
+		switch record.EventType {
+		case KEY_EVENT:
+			key := &record.KeyEvent
+			fmt.Fprintf(&buf, "key %d %d %#x %#x %#x %#x\n",
+				key.KeyDown, key.RepeatCount, key.VirtualKeyCode, key.VirtualScanCode, key.UnicodeChar, key.ControlKeyState)
+		case MOUSE_EVENT:
+			mouse := record.MouseEvent()
+			fmt.Fprintf(&buf, "mouse %d %d %#x %#x %#x\n",
+				mouse.MousePosition.X, mouse.MousePosition.Y, mouse.ButtonState, mouse.ControlKeyState, mouse.EventFlags)
+		case FOCUS_EVENT:
+			fmt.Fprintf(&buf, "focus %d\n", record.FocusEvent().SetFocus)
+		case WINDOW_BUFFER_SIZE_EVENT:
+			size := record.WindowBufferSizeEvent().Size
+			fmt.Fprintf(&buf, "size %d %d\n", size.X, size.Y)
+		default:
+			fmt.Fprintf(&buf, "event %#x\n", record.EventType)
+		}
+	}
+	fmt.Fprintf(&buf, "out %d %s\n", waiting, strconv.Quote(string(output)))
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// readTrace parses an input trace into its batches.
+func readTrace(r io.Reader) ([]traceBatch, error) {
+	var batches []traceBatch
+	var records []INPUT_RECORD
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(text, "out ") {
+			batch, err := parseTraceOutput(text)
+			if err != nil {
+				return nil, fmt.Errorf("input trace line %d: %v", line, err)
+			}
+			batch.records = records
+			batch.line = line
+			batches = append(batches, batch)
+			records = nil
+			continue
+		}
+
+		record, err := parseTraceRecord(text)
+		if err != nil {
+			return nil, fmt.Errorf("input trace line %d: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(records) != 0 {
+		return nil, fmt.Errorf("input trace ends within a batch")
+	}
+	return batches, nil
+}
+
+// parseTraceOutput parses the line ending a batch.
+func parseTraceOutput(text string) (traceBatch, error) {
+	fields := strings.SplitN(text, " ", 3)
+	if len(fields) != 3 {
+		return traceBatch{}, fmt.Errorf("malformed output %q", text)
+	}
+
+	waiting, err := strconv.ParseUint(fields[1], 0, 32)
+	if err != nil {
+		return traceBatch{}, err
+	}
+
+	output, err := strconv.Unquote(fields[2])
+	if err != nil {
+		return traceBatch{}, fmt.Errorf("malformed output %q", text)
+	}
+
+	return traceBatch{waiting: uint32(waiting), output: []byte(output)}, nil
+}
+
+// parseTraceRecord parses a line recording an input record.
+func parseTraceRecord(text string) (INPUT_RECORD, error) {
+	var record INPUT_RECORD
+
+	fields := strings.Fields(text)
+	counts := map[string]int{"key": 6, "mouse": 5, "focus": 1, "size": 2, "event": 1}
+	if count, ok := counts[fields[0]]; !ok || len(fields)-1 != count {
+		return record, fmt.Errorf("malformed record %q", text)
+	}
+
+	values := make([]int64, len(fields)-1)
+	for i, field := range fields[1:] {
+		value, err := strconv.ParseInt(field, 0, 64)
+		if err != nil {
+			return record, err
+		}
+		values[i] = value
+	}
+
+	switch fields[0] {
+	case "key":
+		record.EventType = KEY_EVENT
+		record.KeyEvent = KEY_EVENT_RECORD{
+			KeyDown:         BOOL(values[0]),
+			RepeatCount:     WORD(values[1]),
+			VirtualKeyCode:  WORD(values[2]),
+			VirtualScanCode: WORD(values[3]),
+			UnicodeChar:     WCHAR(values[4]),
+			ControlKeyState: DWORD(values[5]),
+		}
+	case "mouse":
+		record.EventType = MOUSE_EVENT
+		*record.MouseEvent() = MOUSE_EVENT_RECORD{
+			MousePosition:   COORD{X: SHORT(values[0]), Y: SHORT(values[1])},
+			ButtonState:     DWORD(values[2]),
+			ControlKeyState: DWORD(values[3]),
+			EventFlags:      DWORD(values[4]),
+		}
+	case "focus":
+		record.EventType = FOCUS_EVENT
+		record.FocusEvent().SetFocus = BOOL(values[0])
+	case "size":
+		record.EventType = WINDOW_BUFFER_SIZE_EVENT
+		record.WindowBufferSizeEvent().Size = COORD{X: SHORT(values[0]), Y: SHORT(values[1])}
+	case "event":
+		record.EventType = WORD(values[0])
+	}
+
+	return record, nil
+}
+
+// replayInput supplies the records of a trace in the batches they were read.
+type replayInput struct {
+	batches []traceBatch
+	current *traceBatch
+}
+
+func (replay *replayInput) read(buffer []INPUT_RECORD) ([]INPUT_RECORD, error) {
+	if len(replay.batches) == 0 {
+		return nil, io.EOF
+	}
+
+	replay.current = &replay.batches[0]
+	replay.batches = replay.batches[1:]
+	return replay.current.records, nil
+}
+
+func (replay *replayInput) pending() (uint32, error) {
+	if replay.current == nil {
+		return 0, nil
+	}
+	return replay.current.waiting, nil
+}
+
+// NewReplayReader creates a reader that translates the input records of a
+// trace written with WithInputTrace instead of reading from a console, and
+// returns io.EOF once they are exhausted. Options apply as they do to a
+// console reader; the trace does not record them.
+func NewReplayReader(trace io.Reader, opts ...ReaderOption) (*AnsiReader, error) {
+	batches, err := readTrace(trace)
+	if err != nil {
+		return nil, err
+	}
+
+	return newAnsiReader(&replayInput{batches: batches}, opts), nil
+}
+
+// VerifyInputTrace replays a trace through a reader configured by opts and
+// checks that each batch of records translates to the input recorded for it,
+// so a trace captured from a live console can serve as a regression test of
+// key translation. The error identifies the first batch that differs.
+func VerifyInputTrace(trace io.Reader, opts ...ReaderOption) error {
+	batches, err := readTrace(trace)
+	if err != nil {
+		return err
+	}
+
+	replay := &replayInput{batches: batches}
+	ar := newAnsiReader(replay, opts)
+	for {
+		records, err := replay.read(nil)
+		if err == io.EOF {
+			return nil
+		}
+
+		if output := ar.translateInput(records); !bytes.Equal(output, replay.current.output) {
+			return fmt.Errorf("input trace line %d: translated to %q, recorded %q",
+				replay.current.line, output, replay.current.output)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+// +build windows
+
+package winterm
+
+import (
+	"bytes"
+	"fmt"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	. "github.com/Azure/go-ansiterm"
+)
+
+// keypadKeys maps keypad keys to the final character of the SS3 sequences
+// sent in application keypad mode.
+var keypadKeys = map[WORD]byte{
+	VK_MULTIPLY:  'j',
+	VK_ADD:       'k',
+	VK_SEPARATOR: 'l',
+	VK_SUBTRACT:  'm',
+	VK_DECIMAL:   'n',
+	VK_DIVIDE:    'o',
+}
+
+// keySequence returns the bytes a Unix terminal sends for a key event, encoded
+// according to the reader's modes, or nil if the event produces no input
+// (e.g. a key release or a lone modifier).
+func (ar *AnsiReader) keySequence(key *KEY_EVENT_RECORD) []byte {
+	if key.KeyDown == 0 {
+		// A character composed by typing its code on the numeric keypad
+		// with Alt held arrives as Alt is released
+		if key.VirtualKeyCode == VK_MENU && key.UnicodeChar != 0 {
+			return ar.encodeChar(key.UnicodeChar)
+		}
+		return nil
+	}
+
+	switch key.VirtualKeyCode {
+	case VK_PROCESSKEY:
+		// The IME is composing; the result arrives as separate characters
+		return nil
+	case 0, VK_PACKET:
+		// Characters not typed on a key, such as IME results, are sent
+		// as they are, whatever modifiers are held
+		if key.UnicodeChar == 0 {
+			return nil
+		}
+		return ar.encodeChar(key.UnicodeChar)
+	}
+
+	if key.UnicodeChar == 0x03 && ar.onControl != nil && ar.onControl(CTRL_C_EVENT) {
+		// Ctrl+C read in raw mode is handled as the event it raises
+		// when input is processed
+		return nil
+	}
+
+	shift := key.ControlKeyState&SHIFT_PRESSED != 0
+	ctrl := key.ControlKeyState&(LEFT_CTRL_PRESSED|RIGHT_CTRL_PRESSED) != 0
+	alt := key.ControlKeyState&(LEFT_ALT_PRESSED|RIGHT_ALT_PRESSED) != 0
+
+	// AltGr is reported as Right Alt with Left Ctrl; the characters it
+	// selects are sent as they are rather than as chords
+	altGr := RIGHT_ALT_PRESSED | LEFT_CTRL_PRESSED
+	if key.ControlKeyState&DWORD(altGr) == DWORD(altGr) && key.UnicodeChar != 0 {
+		ctrl, alt = false, false
+	}
+
+	vk := key.VirtualKeyCode
+	if VK_F13 <= vk && vk <= VK_F24 {
+		// As in xterm, F13-F24 are Shift+F1-F12
+		vk -= VK_F13 - VK_F1
+		shift = true
+	}
+
+	modifier := 1
+	if shift {
+		modifier++
+	}
+	if alt {
+		modifier += 2
+	}
+	if ctrl {
+		modifier += 4
+	}
+
+	if ar.modes.ApplicationKeypad() {
+		if final, ok := keypadFinal(vk, key.ControlKeyState); ok {
+			return []byte(KEY_ESC_O + string(final))
+		}
+	}
+
+	if seq, ok := ar.specialSequence(vk, modifier); ok {
+		return []byte(seq)
+	}
+
+	if seq, ok := ar.enhancedSequence(key, vk, modifier); ok {
+		return seq
+	}
+
+	chars := ar.characterSequence(key, vk, shift, ctrl)
+	if !alt || len(chars) == 0 {
+		return chars
+	}
+
+	if ar.eightBitMeta && len(chars) == 1 && chars[0] < utf8.RuneSelf {
+		// As xterm does in UTF-8 mode, the character with its high bit
+		// set is sent encoded as UTF-8
+		return encodeRune(rune(chars[0]) | 0x80)
+	}
+
+	return append([]byte{ANSI_ESCAPE_PRIMARY}, chars...)
+}
+
+// repeatKey returns the input for a key event, repeated as many times as the
+// key repeated while held, or once if repeats are coalesced.
+func (ar *AnsiReader) repeatKey(key *KEY_EVENT_RECORD) []byte {
+	seq := ar.keySequence(key)
+	if ar.coalesceRepeats || key.RepeatCount <= 1 || len(seq) == 0 {
+		return seq
+	}
+
+	return bytes.Repeat(seq, int(key.RepeatCount))
+}
+
+// isRepeat reports whether a key event repeats the held key, rather than
+// being a separate press.
+func isRepeat(held *KEY_EVENT_RECORD, key *KEY_EVENT_RECORD) bool {
+	return held != nil && key.KeyDown != 0 &&
+		key.VirtualKeyCode == held.VirtualKeyCode &&
+		key.UnicodeChar == held.UnicodeChar &&
+		key.ControlKeyState == held.ControlKeyState
+}
+
+// characterSequence returns the bytes sent for a key event that does not
+// select a special key, ignoring Alt.
+func (ar *AnsiReader) characterSequence(key *KEY_EVENT_RECORD, vk WORD, shift bool, ctrl bool) []byte {
+	switch {
+	case vk == VK_BACK && ctrl:
+		return []byte{0x08}
+	case vk == VK_BACK:
+		return []byte{0x7F}
+	case vk == VK_TAB && shift:
+		return []byte(KEY_ESC_CSI + "Z")
+	case vk == VK_SPACE && ctrl:
+		return []byte{0x00}
+	}
+
+	if key.UnicodeChar == 0 {
+		// Dead keys produce no character; the composed character arrives
+		// with the following key. The console leaves these control chords
+		// untranslated.
+		if ctrl {
+			switch vk {
+			case '2':
+				return []byte{0x00}
+			case '6':
+				return []byte{0x1E}
+			case VK_OEM_MINUS:
+				return []byte{0x1F}
+			}
+		}
+
+		return nil
+	}
+
+	return ar.encodeChar(key.UnicodeChar)
+}
+
+// encodeChar returns the UTF-8 encoding of a UTF-16 code unit. Characters
+// outside the Basic Multilingual Plane arrive as surrogate halves in
+// consecutive key events, and are sent once both have arrived.
+func (ar *AnsiReader) encodeChar(c WCHAR) []byte {
+	r := rune(c)
+	high := ar.surrogate
+	ar.surrogate = 0
+
+	switch {
+	case utf16.IsSurrogate(r) && r < 0xDC00:
+		ar.surrogate = r
+		if high != 0 {
+			return encodeRune(unicode.ReplacementChar)
+		}
+		return nil
+	case utf16.IsSurrogate(r):
+		// An unpaired low surrogate decodes to the replacement character
+		return encodeRune(utf16.DecodeRune(high, r))
+	case high != 0:
+		return append(encodeRune(unicode.ReplacementChar), encodeRune(r)...)
+	}
+
+	return encodeRune(r)
+}
+
+// encodeRune returns the UTF-8 encoding of r.
+func encodeRune(r rune) []byte {
+	buf := make([]byte, utf8.UTFMax)
+	return buf[:utf8.EncodeRune(buf, r)]
+}
+
+// keypadFinal returns the final character of the SS3 sequence sent for a
+// keypad key in application keypad mode. Digits and the decimal point arrive
+// as keypad keys only while Num Lock is on.
+func keypadFinal(vk WORD, controlKeyState DWORD) (byte, bool) {
+	switch {
+	case VK_NUMPAD0 <= vk && vk <= VK_NUMPAD9:
+		return byte('p' + vk - VK_NUMPAD0), true
+	case vk == VK_RETURN && controlKeyState&ENHANCED_KEY != 0:
+		return 'M', true
+	}
+
+	final, ok := keypadKeys[vk]
+	return final, ok
+}
+
+// win32KeySequence encodes a key event, press or release, in ConPTY's
+// win32-input-mode: CSI Vk ; Sc ; Uc ; Kd ; Cs ; Rc _.
+func win32KeySequence(key *KEY_EVENT_RECORD) []byte {
+	keyDown := 0
+	if key.KeyDown != 0 {
+		keyDown = 1
+	}
+
+	return []byte(fmt.Sprintf("%s%d;%d;%d;%d;%d;%d_", KEY_ESC_CSI,
+		key.VirtualKeyCode, key.VirtualScanCode, key.UnicodeChar, keyDown, key.ControlKeyState, key.RepeatCount))
+}
+
+// enhancedSequence encodes a key event in the kitty keyboard protocol or
+// xterm's modifyOtherKeys encoding, if the application enabled one and the
+// event is a chord that encoding distinguishes.
+func (ar *AnsiReader) enhancedSequence(key *KEY_EVENT_RECORD, vk WORD, modifier int) ([]byte, bool) {
+	kitty := ar.modes.KittyFlags()&1 != 0
+	level := ar.modes.ModifyOtherKeys()
+	if !kitty && level == 0 {
+		return nil, false
+	}
+
+	shift := (modifier-1)&1 != 0
+	ctrl := (modifier-1)&4 != 0
+	unshifted, shifted, ok := keyCodes(key, vk, shift)
+	if !ok {
+		return nil, false
+	}
+
+	// Shift alone selects text, which is sent as it is
+	text := unshifted >= 0x20 && unshifted != 0x7F
+	if modifier == 2 && text {
+		return nil, false
+	}
+
+	if kitty {
+		// Only Escape is sent differently without modifiers, so that it
+		// can be told apart from the start of a sequence
+		switch {
+		case modifier == 1 && unshifted == 0x1B:
+			return []byte(KEY_ESC_CSI + "27u"), true
+		case modifier == 1:
+			return nil, false
+		}
+		return []byte(fmt.Sprintf("%s%d;%du", KEY_ESC_CSI, unshifted, modifier)), true
+	}
+
+	// Level 1 leaves chords with well-known control codes alone
+	isLetter := 'a' <= unshifted && unshifted <= 'z'
+	if modifier == 1 || level == 1 && !(ctrl && (shift || !isLetter)) {
+		return nil, false
+	}
+
+	return []byte(fmt.Sprintf("%s27;%d;%d~", KEY_ESC_CSI, modifier, shifted)), true
+}
+
+// keyCodes returns the code points identifying the key of a key event, for
+// the enhanced encodings: the character on the key without Shift and with
+// it, as far as can be told, or its control code for keys such as Enter.
+func keyCodes(key *KEY_EVENT_RECORD, vk WORD, shift bool) (rune, rune, bool) {
+	switch {
+	case 'A' <= vk && vk <= 'Z':
+		lower := rune(vk) - 'A' + 'a'
+		if shift {
+			return lower, rune(vk), true
+		}
+		return lower, lower, true
+	case vk == VK_RETURN:
+		return '\r', '\r', true
+	case vk == VK_TAB:
+		return '\t', '\t', true
+	case vk == VK_BACK:
+		return 0x7F, 0x7F, true
+	case vk == VK_ESCAPE:
+		return 0x1B, 0x1B, true
+	case vk == VK_SPACE:
+		return ' ', ' ', true
+	}
+
+	// Control chords hide the character; otherwise it is on the event
+	r := rune(key.UnicodeChar)
+	if '0' <= vk && vk <= '9' {
+		if r < 0x20 || !shift {
+			r = rune(vk)
+		}
+		return rune(vk), r, true
+	}
+	if r < 0x20 || r == 0x7F || utf16.IsSurrogate(r) {
+		return 0, 0, false
+	}
+
+	return r, r, true
+}
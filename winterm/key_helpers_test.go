@@ -0,0 +1,129 @@
+// +build windows
+
+package winterm
+
+import (
+	"testing"
+)
+
+// keyDown returns the event of pressing the key vk, producing char, with the
+// given modifier state.
+func keyDown(vk WORD, char WCHAR, controlKeyState DWORD) KEY_EVENT_RECORD {
+	return KEY_EVENT_RECORD{KeyDown: 1, RepeatCount: 1, VirtualKeyCode: vk, UnicodeChar: char, ControlKeyState: controlKeyState}
+}
+
+func TestKeySequence(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      KEY_EVENT_RECORD
+		keypad   bool
+		meta     bool
+		expected string
+	}{
+		{"Letter", keyDown('A', 'a', 0), false, false, "a"},
+		{"Release", KEY_EVENT_RECORD{VirtualKeyCode: 'A', UnicodeChar: 'a'}, false, false, ""},
+		{"Modifier", keyDown(VK_MENU, 0, LEFT_ALT_PRESSED), false, false, ""},
+		{"AltNumpad", KEY_EVENT_RECORD{VirtualKeyCode: VK_MENU, UnicodeChar: 'é'}, false, false, "é"},
+		{"Packet", keyDown(VK_PACKET, 'é', LEFT_CTRL_PRESSED), false, false, "é"},
+		{"Ctrl", keyDown('A', 0x01, LEFT_CTRL_PRESSED), false, false, "\x01"},
+		{"Alt", keyDown('A', 'a', LEFT_ALT_PRESSED), false, false, "\x1ba"},
+		{"AltEightBitMeta", keyDown('A', 'a', LEFT_ALT_PRESSED), false, true, "á"},
+		{"AltGr", keyDown('Q', '@', RIGHT_ALT_PRESSED|LEFT_CTRL_PRESSED), false, false, "@"},
+		{"CtrlAltGr", keyDown('Q', '@', RIGHT_ALT_PRESSED|LEFT_CTRL_PRESSED|RIGHT_CTRL_PRESSED), false, false, "@"},
+		{"CtrlUp", keyDown(VK_UP, 0, LEFT_CTRL_PRESSED|ENHANCED_KEY), false, false, "\x1b[1;5A"},
+		{"F13", keyDown(VK_F13, 0, 0), false, false, "\x1b[1;2P"},
+		{"F17", keyDown(VK_F13+4, 0, 0), false, false, "\x1b[15;2~"},
+		{"Backspace", keyDown(VK_BACK, 0x08, 0), false, false, "\x7f"},
+		{"CtrlBackspace", keyDown(VK_BACK, 0x7F, LEFT_CTRL_PRESSED), false, false, "\x08"},
+		{"ShiftTab", keyDown(VK_TAB, '\t', SHIFT_PRESSED), false, false, "\x1b[Z"},
+		{"CtrlSpace", keyDown(VK_SPACE, ' ', LEFT_CTRL_PRESSED), false, false, "\x00"},
+		{"Ctrl2", keyDown('2', 0, LEFT_CTRL_PRESSED), false, false, "\x00"},
+		{"Ctrl6", keyDown('6', 0, LEFT_CTRL_PRESSED), false, false, "\x1e"},
+		{"CtrlMinus", keyDown(VK_OEM_MINUS, 0, LEFT_CTRL_PRESSED), false, false, "\x1f"},
+		{"DeadKey", keyDown(VK_OEM_MINUS, 0, 0), false, false, ""},
+		{"KeypadAdd", keyDown(VK_ADD, '+', 0), false, false, "+"},
+		{"ApplicationKeypadAdd", keyDown(VK_ADD, '+', 0), true, false, "\x1bOk"},
+		{"ApplicationKeypadDigit", keyDown(VK_NUMPAD0+5, '5', NUMLOCK_ON), true, false, "\x1bOu"},
+		{"ApplicationKeypadEnter", keyDown(VK_RETURN, '\r', ENHANCED_KEY), true, false, "\x1bOM"},
+		{"ApplicationKeypadReturn", keyDown(VK_RETURN, '\r', 0), true, false, "\r"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var opts []ReaderOption
+			if test.meta {
+				opts = append(opts, WithEightBitMeta())
+			}
+			ar := newAnsiReader(nil, opts)
+			ar.modes.SetApplicationKeypad(test.keypad)
+
+			if seq := ar.keySequence(&test.key); string(seq) != test.expected {
+				t.Errorf("sequence is %q, expected %q", seq, test.expected)
+			}
+		})
+	}
+}
+
+func TestKeySequenceSurrogates(t *testing.T) {
+	tests := []struct {
+		name     string
+		chars    []WCHAR
+		expected string
+	}{
+		{"Pair", []WCHAR{0xD83D, 0xDE00}, "😀"},
+		{"HighThenBMP", []WCHAR{0xD83D, 'a'}, "�a"},
+		{"HighThenHigh", []WCHAR{0xD83D, 0xD83D, 0xDE00}, "�😀"},
+		{"UnpairedLow", []WCHAR{0xDE00, 'a'}, "�a"},
+		{"HighPending", []WCHAR{'a', 0xD83D}, "a"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ar := newAnsiReader(nil, nil)
+
+			var seq []byte
+			for _, char := range test.chars {
+				key := keyDown(VK_PACKET, char, 0)
+				seq = append(seq, ar.keySequence(&key)...)
+			}
+			if string(seq) != test.expected {
+				t.Errorf("sequence is %q, expected %q", seq, test.expected)
+			}
+		})
+	}
+}
+
+func TestKeyRepeat(t *testing.T) {
+	repeated := keyDown('A', 'a', 0)
+	repeated.RepeatCount = 3
+	release := KEY_EVENT_RECORD{VirtualKeyCode: 'A', UnicodeChar: 'a'}
+
+	tests := []struct {
+		name      string
+		keys      []KEY_EVENT_RECORD
+		expected  string
+		coalesced string
+	}{
+		{"RepeatCount", []KEY_EVENT_RECORD{repeated}, "aaa", "a"},
+		{"Held", []KEY_EVENT_RECORD{keyDown('A', 'a', 0), keyDown('A', 'a', 0), keyDown('A', 'a', 0)}, "aaa", "a"},
+		{"Released", []KEY_EVENT_RECORD{keyDown('A', 'a', 0), release, keyDown('A', 'a', 0)}, "aa", "aa"},
+		{"ModifierChanged", []KEY_EVENT_RECORD{keyDown('A', 'a', 0), keyDown('A', 'A', SHIFT_PRESSED), keyDown('A', 'A', SHIFT_PRESSED)}, "aAA", "aA"},
+		{"OtherKey", []KEY_EVENT_RECORD{keyDown('A', 'a', 0), keyDown('B', 'b', 0), keyDown('A', 'a', 0)}, "aba", "aba"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			records := make([]INPUT_RECORD, len(test.keys))
+			for i, key := range test.keys {
+				records[i] = INPUT_RECORD{EventType: KEY_EVENT, KeyEvent: key}
+			}
+
+			if input := newAnsiReader(nil, nil).translateInput(records); string(input) != test.expected {
+				t.Errorf("input is %q, expected %q", input, test.expected)
+			}
+			if input := newAnsiReader(nil, []ReaderOption{WithCoalescedRepeats()}).translateInput(records); string(input) != test.coalesced {
+				t.Errorf("with repeats coalesced, input is %q, expected %q", input, test.coalesced)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+// +build windows
+
+package winterm
+
+import (
+	"strconv"
+	"strings"
+
+	. "github.com/Azure/go-ansiterm"
+)
+
+// Keymap maps the terminfo capability names of special keys to the sequences
+// the keys send. Keys held with modifiers are named by the extended
+// capabilities ncurses defines for xterm: "kLFT" is Shift+Left and "kLFT5"
+// Ctrl+Left, with the suffix the xterm modifier value (3 Alt, 4 Shift+Alt,
+// 5 Ctrl, 6 Shift+Ctrl, 7 Ctrl+Alt, 8 Shift+Ctrl+Alt). Function keys held
+// with modifiers are numbered as xterm numbers them: kf13-kf24 are
+// Shift+F1-F12, kf25-kf36 Ctrl, kf37-kf48 Shift+Ctrl, kf49-kf60 Alt, and
+// kf61-kf63 Shift+Alt+F1-F3.
+//
+// A key held with modifiers for which the keymap has no sequence sends the
+// sequence of the unmodified key. In application cursor key mode, cursor
+// keys mapped to CSI final are sent as SS3 final.
+type Keymap map[string]string
+
+// keyCapability names a cursor or editing key: the capability for the
+// unmodified key, and the prefix of the capabilities naming it with modifiers.
+type keyCapability struct {
+	name     string
+	modified string
+}
+
+var keyCapabilities = map[WORD]keyCapability{
+	VK_UP:     {"kcuu1", "kUP"},
+	VK_DOWN:   {"kcud1", "kDN"},
+	VK_RIGHT:  {"kcuf1", "kRIT"},
+	VK_LEFT:   {"kcub1", "kLFT"},
+	VK_HOME:   {"khome", "kHOM"},
+	VK_END:    {"kend", "kEND"},
+	VK_INSERT: {"kich1", "kIC"},
+	VK_DELETE: {"kdch1", "kDC"},
+	VK_PRIOR:  {"kpp", "kPRV"},
+	VK_NEXT:   {"knp", "kNXT"},
+}
+
+// functionKeyOffsets maps xterm modifier values to the offset added to the
+// number of a function key held with them.
+var functionKeyOffsets = map[int]int{
+	2: 12,
+	5: 24,
+	6: 36,
+	3: 48,
+	4: 60,
+}
+
+// capabilityNames returns the capability naming a special key held with
+// modifiers, which is empty if there is none, and the capability naming the
+// unmodified key. It returns false if vk is not a special key.
+func capabilityNames(vk WORD, modifier int) (string, string, bool) {
+	if VK_F1 <= vk && vk <= VK_F12 {
+		n := int(vk-VK_F1) + 1
+		base := "kf" + strconv.Itoa(n)
+		if modifier == 1 {
+			return base, base, true
+		}
+
+		offset, ok := functionKeyOffsets[modifier]
+		if !ok || n+offset > 63 {
+			return "", base, true
+		}
+		return "kf" + strconv.Itoa(n+offset), base, true
+	}
+
+	key, ok := keyCapabilities[vk]
+	switch {
+	case !ok:
+		return "", "", false
+	case modifier == 1:
+		return key.name, key.name, true
+	case modifier == 2:
+		return key.modified, key.name, true
+	}
+
+	return key.modified + strconv.Itoa(modifier), key.name, true
+}
+
+// specialSequence returns the sequence the reader's keymap gives a special
+// key held with modifiers, or false if vk is not a special key.
+func (ar *AnsiReader) specialSequence(vk WORD, modifier int) (string, bool) {
+	name, base, ok := capabilityNames(vk, modifier)
+	if !ok {
+		return "", false
+	}
+
+	seq, ok := ar.keymap[name]
+	if !ok {
+		seq, ok = ar.keymap[base]
+		modifier = 1
+	}
+	if !ok {
+		return "", false
+	}
+
+	_, cursor := cursorKeys[vk]
+	if cursor && modifier == 1 && ar.modes.ApplicationCursorKeys() &&
+		len(seq) == len(KEY_ESC_CSI)+1 && strings.HasPrefix(seq, KEY_ESC_CSI) {
+		seq = KEY_ESC_O + seq[len(KEY_ESC_CSI):]
+	}
+
+	return seq, true
+}
+
+// cursorKeys maps xterm keys sent as CSI final, or CSI 1 ; modifier final.
+var cursorKeys = map[WORD]byte{
+	VK_UP:    'A',
+	VK_DOWN:  'B',
+	VK_RIGHT: 'C',
+	VK_LEFT:  'D',
+	VK_END:   'F',
+	VK_HOME:  'H',
+}
+
+// ss3Keys maps xterm keys sent as SS3 final, or CSI 1 ; modifier final.
+var ss3Keys = map[WORD]byte{
+	VK_F1: 'P',
+	VK_F2: 'Q',
+	VK_F3: 'R',
+	VK_F4: 'S',
+}
+
+// tildeKeys maps xterm keys sent as CSI code ~, or CSI code ; modifier ~.
+var tildeKeys = map[WORD]int{
+	VK_INSERT: 2,
+	VK_DELETE: 3,
+	VK_PRIOR:  5,
+	VK_NEXT:   6,
+	VK_F5:     15,
+	VK_F6:     17,
+	VK_F7:     18,
+	VK_F8:     19,
+	VK_F9:     20,
+	VK_F10:    21,
+	VK_F11:    23,
+	VK_F12:    24,
+}
+
+// modifierParams holds the xterm modifier parameters, indexed by the
+// modifier value less one.
+var modifierParams = []string{
+	"",
+	KEY_CONTROL_PARAM_2,
+	KEY_CONTROL_PARAM_3,
+	KEY_CONTROL_PARAM_4,
+	KEY_CONTROL_PARAM_5,
+	KEY_CONTROL_PARAM_6,
+	KEY_CONTROL_PARAM_7,
+	KEY_CONTROL_PARAM_8,
+}
+
+// XtermKeymap returns the special keys as xterm sends them, which is the
+// reader's default.
+func XtermKeymap() Keymap {
+	keymap := Keymap{}
+	for vk := range keyCapabilities {
+		addXtermKey(keymap, vk)
+	}
+	for vk := WORD(VK_F1); vk <= VK_F12; vk++ {
+		addXtermKey(keymap, vk)
+	}
+
+	return keymap
+}
+
+// addXtermKey adds a key, with each combination of modifiers that has a
+// capability name, to an xterm keymap.
+func addXtermKey(keymap Keymap, vk WORD) {
+	for modifier := 1; modifier <= 8; modifier++ {
+		name, _, _ := capabilityNames(vk, modifier)
+		if name == "" {
+			continue
+		}
+
+		param := modifierParams[modifier-1]
+		if final, ok := cursorKeys[vk]; ok {
+			keymap[name] = modifiedSequence(KEY_ESC_CSI, final, modifier)
+		} else if final, ok := ss3Keys[vk]; ok {
+			keymap[name] = modifiedSequence(KEY_ESC_O, final, modifier)
+		} else {
+			keymap[name] = KEY_ESC_CSI + strconv.Itoa(tildeKeys[vk]) + param + "~"
+		}
+	}
+}
+
+// modifiedSequence returns prefix followed by final, or if any modifier keys
+// are held, CSI 1 ; modifier final.
+func modifiedSequence(prefix string, final byte, modifier int) string {
+	if modifier == 1 {
+		return prefix + string(final)
+	}
+
+	return KEY_ESC_CSI + "1" + modifierParams[modifier-1] + string(final)
+}
+
+// LinuxKeymap returns the special keys as the Linux console sends them. It
+// sends no modifiers other than Shift with F1-F8.
+func LinuxKeymap() Keymap {
+	return Keymap{
+		"kcuu1": "\x1b[A",
+		"kcud1": "\x1b[B",
+		"kcuf1": "\x1b[C",
+		"kcub1": "\x1b[D",
+		"khome": "\x1b[1~",
+		"kich1": "\x1b[2~",
+		"kdch1": "\x1b[3~",
+		"kend":  "\x1b[4~",
+		"kpp":   "\x1b[5~",
+		"knp":   "\x1b[6~",
+		"kf1":   "\x1b[[A",
+		"kf2":   "\x1b[[B",
+		"kf3":   "\x1b[[C",
+		"kf4":   "\x1b[[D",
+		"kf5":   "\x1b[[E",
+		"kf6":   "\x1b[17~",
+		"kf7":   "\x1b[18~",
+		"kf8":   "\x1b[19~",
+		"kf9":   "\x1b[20~",
+		"kf10":  "\x1b[21~",
+		"kf11":  "\x1b[23~",
+		"kf12":  "\x1b[24~",
+		"kf13":  "\x1b[25~",
+		"kf14":  "\x1b[26~",
+		"kf15":  "\x1b[28~",
+		"kf16":  "\x1b[29~",
+		"kf17":  "\x1b[31~",
+		"kf18":  "\x1b[32~",
+		"kf19":  "\x1b[33~",
+		"kf20":  "\x1b[34~",
+	}
+}
+
+// RxvtKeymap returns the special keys as rxvt sends them, with Shift and Ctrl
+// selecting the final character of the editing and function keys.
+func RxvtKeymap() Keymap {
+	keymap := Keymap{
+		"kcuu1": "\x1b[A",
+		"kcud1": "\x1b[B",
+		"kcuf1": "\x1b[C",
+		"kcub1": "\x1b[D",
+		"kUP":   "\x1b[a",
+		"kDN":   "\x1b[b",
+		"kRIT":  "\x1b[c",
+		"kLFT":  "\x1b[d",
+		"kUP5":  "\x1bOa",
+		"kDN5":  "\x1bOb",
+		"kRIT5": "\x1bOc",
+		"kLFT5": "\x1bOd",
+	}
+
+	editing := map[string]int{"kHOM": 7, "kEND": 8, "kIC": 2, "kDC": 3, "kPRV": 5, "kNXT": 6}
+	for _, key := range keyCapabilities {
+		if code, ok := editing[key.modified]; ok {
+			keymap[key.name] = KEY_ESC_CSI + strconv.Itoa(code) + "~"
+			keymap[key.modified] = KEY_ESC_CSI + strconv.Itoa(code) + "$"
+			keymap[key.modified+"5"] = KEY_ESC_CSI + strconv.Itoa(code) + "^"
+		}
+	}
+
+	// Shift+F1-F10 send F11-F20; Shift+F11 and F12 change the final character
+	codes := []int{11, 12, 13, 14, 15, 17, 18, 19, 20, 21, 23, 24, 25, 26, 28, 29, 31, 32, 33, 34}
+	for i := 0; i < 12; i++ {
+		keymap["kf"+strconv.Itoa(i+1)] = KEY_ESC_CSI + strconv.Itoa(codes[i]) + "~"
+		keymap["kf"+strconv.Itoa(i+25)] = KEY_ESC_CSI + strconv.Itoa(codes[i]) + "^"
+		if i < 10 {
+			keymap["kf"+strconv.Itoa(i+13)] = KEY_ESC_CSI + strconv.Itoa(codes[i+10]) + "~"
+			keymap["kf"+strconv.Itoa(i+37)] = KEY_ESC_CSI + strconv.Itoa(codes[i+10]) + "^"
+		} else {
+			keymap["kf"+strconv.Itoa(i+13)] = KEY_ESC_CSI + strconv.Itoa(codes[i]) + "$"
+			keymap["kf"+strconv.Itoa(i+37)] = KEY_ESC_CSI + strconv.Itoa(codes[i]) + "@"
+		}
+	}
+
+	return keymap
+}
@@ -0,0 +1,134 @@
+// +build windows
+
+package winterm
+
+import (
+	"fmt"
+
+	. "github.com/Azure/go-ansiterm"
+)
+
+// mouseButtons holds the console button states in xterm button order: left,
+// middle, right.
+var mouseButtons = []DWORD{
+	FROM_LEFT_1ST_BUTTON_PRESSED,
+	FROM_LEFT_2ND_BUTTON_PRESSED,
+	RIGHTMOST_BUTTON_PRESSED,
+}
+
+// mouseSequence returns the reports a mouse event produces under the mouse
+// tracking mode set by the application, or nil if it produces none.
+func (ar *AnsiReader) mouseSequence(mouse *MOUSE_EVENT_RECORD) []byte {
+	// The console reports which buttons are held rather than which changed
+	held := ar.buttons
+	ar.buttons = mouse.ButtonState & (FROM_LEFT_1ST_BUTTON_PRESSED | FROM_LEFT_2ND_BUTTON_PRESSED | RIGHTMOST_BUTTON_PRESSED)
+
+	tracking := ar.modes.MouseTracking()
+	if tracking == MouseOff {
+		return nil
+	}
+
+	x, y, ok := ar.mousePosition(mouse.MousePosition)
+	if !ok {
+		return nil
+	}
+
+	modifiers := 0
+	if mouse.ControlKeyState&SHIFT_PRESSED != 0 {
+		modifiers |= 4
+	}
+	if mouse.ControlKeyState&(LEFT_ALT_PRESSED|RIGHT_ALT_PRESSED) != 0 {
+		modifiers |= 8
+	}
+	if mouse.ControlKeyState&(LEFT_CTRL_PRESSED|RIGHT_CTRL_PRESSED) != 0 {
+		modifiers |= 16
+	}
+
+	// The high word of the button state holds the wheel delta
+	delta := int16(mouse.ButtonState >> 16)
+
+	switch {
+	case mouse.EventFlags&MOUSE_WHEELED != 0:
+		button := 64
+		if delta < 0 {
+			button = 65
+		}
+		return ar.mouseReport(button|modifiers, x, y, true)
+
+	case mouse.EventFlags&MOUSE_HWHEELED != 0:
+		button := 66
+		if delta > 0 {
+			button = 67
+		}
+		return ar.mouseReport(button|modifiers, x, y, true)
+
+	case mouse.EventFlags&MOUSE_MOVED != 0:
+		if tracking == MouseMotion || tracking == MouseDrags && ar.buttons != 0 {
+			button := 3
+			for i, state := range mouseButtons {
+				if ar.buttons&state != 0 {
+					button = i
+					break
+				}
+			}
+			return ar.mouseReport(button|32|modifiers, x, y, true)
+		}
+		return nil
+	}
+
+	var reports []byte
+	for i, state := range mouseButtons {
+		switch {
+		case ar.buttons&state != 0 && held&state == 0:
+			reports = append(reports, ar.mouseReport(i|modifiers, x, y, true)...)
+		case ar.buttons&state == 0 && held&state != 0:
+			reports = append(reports, ar.mouseReport(i|modifiers, x, y, false)...)
+		}
+	}
+
+	return reports
+}
+
+// mousePosition converts a console mouse position to the 1-based column and
+// row reported to the application, relative to the window when the screen
+// buffer is known (see WithScreenBuffer).
+func (ar *AnsiReader) mousePosition(position COORD) (int, int, bool) {
+	x, y := int(position.X), int(position.Y)
+	if ar.screen != 0 {
+		info, err := GetConsoleScreenBufferInfo(uintptr(ar.screen))
+		if err == nil {
+			x -= int(info.Window.Left)
+			y -= int(info.Window.Top)
+		}
+	}
+
+	if x < 0 || y < 0 {
+		return 0, 0, false
+	}
+
+	return x + 1, y + 1, true
+}
+
+// mouseReport encodes a mouse report in the encoding selected by the
+// application, returning nil if the position cannot be encoded.
+func (ar *AnsiReader) mouseReport(button int, x int, y int, press bool) []byte {
+	if ar.modes.SGRMouse() {
+		final := 'M'
+		if !press {
+			final = 'm'
+		}
+		return []byte(fmt.Sprintf("%s<%d;%d;%d%c", KEY_ESC_CSI, button, x, y, final))
+	}
+
+	// The original encoding cannot identify the button released
+	if !press {
+		button |= 3
+	}
+
+	// Each value is offset by 32 and sent as a single byte
+	if x > 255-32 || y > 255-32 {
+		return nil
+	}
+
+	return []byte{ANSI_ESCAPE_PRIMARY, ANSI_ESCAPE_SECONDARY, 'M', byte(32 + button), byte(32 + x), byte(32 + y)}
+}
@@ -0,0 +1,199 @@
+package winterm
+
+import (
+	"io"
+	"io/ioutil"
+
+	. "github.com/Azure/go-ansiterm"
+)
+
+// BellStyle selects how the handler responds to BEL. Styles may be combined.
+type BellStyle int
+
+const (
+	// BellAudible writes BEL to the console, which sounds the system beep.
+	BellAudible BellStyle = 1 << iota
+
+	// BellFlashWindow flashes the console window's caption and taskbar button.
+	BellFlashWindow
+
+	// BellFlashScreen briefly inverts the colors of the visible window.
+	BellFlashScreen
+)
+
+// SGRStyle identifies a text style the console cannot render natively.
+type SGRStyle int
+
+const (
+	StyleFaint SGRStyle = iota
+	StyleItalic
+	StyleBlink
+	StyleStrikethrough
+)
+
+// StyleFallback selects how an SGRStyle is approximated with console attributes.
+type StyleFallback int
+
+const (
+	// FallbackNone ignores the style.
+	FallbackNone StyleFallback = iota
+
+	// FallbackDim clears the foreground intensity.
+	FallbackDim
+
+	// FallbackBright sets the foreground intensity.
+	FallbackBright
+
+	// FallbackUnderline underlines the text.
+	FallbackUnderline
+
+	// FallbackBrightBackground sets the background intensity.
+	FallbackBrightBackground
+)
+
+// defaultStyleFallbacks returns the fallbacks used unless overridden by WithStyleFallback.
+func defaultStyleFallbacks() map[SGRStyle]StyleFallback {
+	return map[SGRStyle]StyleFallback{
+		StyleFaint:         FallbackDim,
+		StyleItalic:        FallbackNone,
+		StyleBlink:         FallbackBrightBackground,
+		StyleStrikethrough: FallbackNone,
+	}
+}
+
+// HandlerOption configures optional behavior of a WindowsAnsiEventHandler.
+type HandlerOption func(*WindowsAnsiEventHandler)
+
+// WithRuneWidth overrides the function used to compute how many console cells
+// a rune occupies, for embedders whose fonts disagree with the East Asian
+// width tables. A width of zero marks a rune that combines with its predecessor.
+func WithRuneWidth(width func(rune) int) HandlerOption {
+	return func(h *WindowsAnsiEventHandler) {
+		h.runeWidth = width
+	}
+}
+
+// WithResizeCallback registers a function invoked with the new window size
+// whenever the handler notices the console window has been resized, so the
+// embedder can forward the change (e.g. as SIGWINCH) to the remote process.
+func WithResizeCallback(onResize func(cols int, rows int)) HandlerOption {
+	return func(h *WindowsAnsiEventHandler) {
+		h.onResize = onResize
+	}
+}
+
+// WithPreserveScrollback makes ED 3 (erase saved lines) leave the console's
+// backing buffer untouched, so user scrollback is never discarded.
+func WithPreserveScrollback() HandlerOption {
+	return func(h *WindowsAnsiEventHandler) {
+		h.preserveScrollback = true
+	}
+}
+
+// WithBell selects how BEL is rendered; the default is BellAudible. Passing
+// zero silences BEL entirely.
+func WithBell(style BellStyle) HandlerOption {
+	return func(h *WindowsAnsiEventHandler) {
+		h.bell = style
+	}
+}
+
+// WithStyleFallback selects how style is approximated, overriding the default
+// (faint dims the foreground, blink brightens the background, and italic and
+// strikethrough are ignored).
+func WithStyleFallback(style SGRStyle, fallback StyleFallback) HandlerOption {
+	return func(h *WindowsAnsiEventHandler) {
+		h.styleFallbacks[style] = fallback
+	}
+}
+
+// WithWindowErase confines ED and EL to the visible window. By default they
+// erase whole rows of the backing buffer, and ED 0 and 1 extend through the
+// rows below and above the window.
+func WithWindowErase() HandlerOption {
+	return func(h *WindowsAnsiEventHandler) {
+		h.windowErase = true
+	}
+}
+
+// WithViewportPinning keeps the console window where the user scrolled it
+// while reading history, instead of letting output and cursor movement
+// scroll it back to the cursor. Output continues at the bottom of the buffer
+// and the window follows it again once the user scrolls back down.
+func WithViewportPinning() HandlerOption {
+	return func(h *WindowsAnsiEventHandler) {
+		h.pinViewport = true
+	}
+}
+
+// WithAllowResize lets applications resize the console window and backing
+// buffer with XTWINOPS (CSI 8 ; rows ; columns t). By default such requests
+// are ignored.
+func WithAllowResize() HandlerOption {
+	return func(h *WindowsAnsiEventHandler) {
+		h.allowResize = true
+	}
+}
+
+// WithDeferredUpdates renders all output to a hidden screen buffer that is
+// only displayed, as a whole, when Sync is called. This removes flicker from
+// full-screen redraws at the cost of explicit presentation; without it,
+// output is deferred only within a synchronized update (private mode 2026).
+func WithDeferredUpdates() HandlerOption {
+	return func(h *WindowsAnsiEventHandler) {
+		h.deferred = true
+	}
+}
+
+// WithBufferSwap presents frames (see WithDeferredUpdates) by making the
+// hidden buffer the active console screen buffer, rather than copying it into
+// the window. The swap is atomic, but while a frame is being rendered other
+// output to the console's handle may land in the buffer that is not shown.
+func WithBufferSwap() HandlerOption {
+	return func(h *WindowsAnsiEventHandler) {
+		h.bufferSwap = true
+	}
+}
+
+// WithOutputCodepage sets the code page text is encoded in when written to
+// the writer passed to NewWinEventHandlerForWriter. It defaults to the
+// console's output code page (GetConsoleOutputCP), so text renders correctly
+// on consoles using OEM code pages; characters the code page cannot represent
+// are replaced. CP_UTF8 writes UTF-8 unchanged.
+func WithOutputCodepage(codepage uint32) HandlerOption {
+	return func(h *WindowsAnsiEventHandler) {
+		h.codepage = codepage
+	}
+}
+
+// WithResponseWriter sends replies to queries (DA, DSR, DECRQSS, XTGETTCAP
+// and the XTWINOPS reports) to w, typically the input of the process
+// producing the output, instead of printing them to the console.
+func WithResponseWriter(w io.Writer) HandlerOption {
+	return func(h *WindowsAnsiEventHandler) {
+		h.responses = w
+	}
+}
+
+// WithoutResponses discards replies to queries, for consumers that only render
+// output. See also the parser's WithoutQueries option.
+func WithoutResponses() HandlerOption {
+	return WithResponseWriter(ioutil.Discard)
+}
+
+// WithLogger directs the handler's diagnostics to logger, which may be a
+// *logrus.Logger or an adapter from the logrusadapter package. By default
+// they are discarded.
+func WithLogger(logger Logger) HandlerOption {
+	return func(h *WindowsAnsiEventHandler) {
+		h.logger = logger
+	}
+}
+
+// WithLogOutput writes the handler's diagnostics to w, as ansiterm.NewLogger
+// formats them.
+func WithLogOutput(w io.Writer) HandlerOption {
+	return func(h *WindowsAnsiEventHandler) {
+		h.logger = NewLogger(w)
+	}
+}
@@ -0,0 +1,107 @@
+// +build windows
+
+package winterm
+
+import (
+	"strings"
+
+	. "github.com/Azure/go-ansiterm"
+)
+
+const (
+	pasteStart = KEY_ESC_CSI + "200~"
+	pasteEnd   = KEY_ESC_CSI + "201~"
+
+	// defaultPasteThreshold is the number of characters read at once above
+	// which input is taken to be pasted; see WithPasteThreshold.
+	defaultPasteThreshold = 8
+)
+
+// isPaste reports whether a batch of input records looks like pasted text.
+// The console delivers a paste as a burst of key presses, far faster than
+// they can be typed, so they arrive together.
+func (ar *AnsiReader) isPaste(records []INPUT_RECORD) bool {
+	chars := 0
+	for i := range records {
+		key := &records[i].KeyEvent
+		if records[i].EventType == KEY_EVENT && key.KeyDown != 0 && key.UnicodeChar != 0 {
+			chars++
+		}
+	}
+
+	return chars >= ar.pasteThreshold
+}
+
+// handlePaste filters the input translated from records if it was pasted
+// (see WithPasteFilter), enclosing it in the bracketed paste markers if the
+// application asked for them.
+func (ar *AnsiReader) handlePaste(records []INPUT_RECORD, input []byte) []byte {
+	if !ar.pasting && !ar.isPaste(records) {
+		return input
+	}
+
+	input = ar.pasteFilter.apply(input)
+
+	if !ar.pasting {
+		ar.pasting = true
+		ar.bracketing = ar.modes.BracketedPaste()
+		if ar.bracketing {
+			input = append([]byte(pasteStart), input...)
+		}
+	}
+
+	// A paste larger than the record buffer spans several reads; it ends
+	// when no more input is waiting
+	count, err := ar.source.pending()
+	if err == nil {
+		ar.waiting = count
+	}
+	if err != nil || count == 0 {
+		if ar.bracketing {
+			input = append(input, pasteEnd...)
+		}
+		ar.pasting = false
+	}
+
+	return input
+}
+
+// apply filters the control characters other than tab, carriage return, and
+// line feed from pasted input, so the paste cannot end itself early or issue
+// commands.
+func (filter PasteFilter) apply(input []byte) []byte {
+	if filter == PasteUnfiltered {
+		return input
+	}
+
+	var filtered strings.Builder
+	for _, r := range string(input) {
+		switch {
+		case r == '\t' || r == '\r' || r == '\n':
+			filtered.WriteRune(r)
+		case r < 0x20 || 0x7F <= r && r <= 0x9F:
+			if filter == PasteEscapeControls {
+				filtered.WriteString(caretNotation(r))
+			}
+		default:
+			filtered.WriteRune(r)
+		}
+	}
+
+	return []byte(filtered.String())
+}
+
+// caretNotation returns a control character as cat -v shows it: ^@ to ^_ for
+// C0, ^? for DEL, and M-^@ to M-^_ for C1.
+func caretNotation(r rune) string {
+	prefix := ""
+	if r >= 0x80 {
+		prefix = "M-"
+		r -= 0x80
+	}
+
+	if r == 0x7F {
+		return prefix + "^?"
+	}
+	return prefix + "^" + string(r+'@')
+}
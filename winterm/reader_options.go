@@ -0,0 +1,148 @@
+// +build windows
+
+package winterm
+
+import (
+	"io"
+	"syscall"
+)
+
+// ReaderOption configures optional behavior of an AnsiReader.
+type ReaderOption func(*AnsiReader)
+
+// WithInputModes encodes input according to modes, typically those of the
+// handler rendering the console's output (see WindowsAnsiEventHandler.InputModes).
+func WithInputModes(modes *InputModes) ReaderOption {
+	return func(ar *AnsiReader) {
+		ar.modes = modes
+	}
+}
+
+// WithEightBitMeta sends Alt+character as the character with its high bit set,
+// rather than prefixed with ESC. Only ASCII characters are affected.
+func WithEightBitMeta() ReaderOption {
+	return func(ar *AnsiReader) {
+		ar.eightBitMeta = true
+	}
+}
+
+// WithScreenBuffer reports mouse positions relative to the window of the
+// screen buffer identified by handle. Otherwise positions are reported
+// relative to the top left of the buffer, which is correct only if the buffer
+// has no scrollback.
+func WithScreenBuffer(handle syscall.Handle) ReaderOption {
+	return func(ar *AnsiReader) {
+		ar.screen = handle
+	}
+}
+
+// WithPasteThreshold sets the number of characters arriving together above
+// which input is taken to be pasted, and so filtered (see WithPasteFilter) and
+// bracketed when the application enables bracketed paste mode. The default
+// is 8.
+func WithPasteThreshold(chars int) ReaderOption {
+	return func(ar *AnsiReader) {
+		ar.pasteThreshold = chars
+	}
+}
+
+// PasteFilter selects how control characters other than tab, carriage return,
+// and line feed are treated in pasted input, which might otherwise run
+// commands or end a bracketed paste early.
+type PasteFilter int
+
+const (
+	// PasteUnfiltered delivers control characters as they are.
+	PasteUnfiltered PasteFilter = iota
+
+	// PasteStripControls removes C0 and C1 control characters and DEL.
+	PasteStripControls
+
+	// PasteEscapeControls replaces them with their caret notation, as
+	// cat -v shows them (e.g. ^[ for ESC).
+	PasteEscapeControls
+)
+
+// WithPasteFilter filters control characters from pasted input, whether or
+// not the application enabled bracketed paste mode.
+func WithPasteFilter(filter PasteFilter) ReaderOption {
+	return func(ar *AnsiReader) {
+		ar.pasteFilter = filter
+	}
+}
+
+// WithPasteSanitizing removes control characters other than tab, carriage
+// return, and line feed from pastes; it is WithPasteFilter(PasteStripControls).
+func WithPasteSanitizing() ReaderOption {
+	return WithPasteFilter(PasteStripControls)
+}
+
+// WithWin32InputMode allows key events to be sent in ConPTY's win32-input-mode
+// encoding, which preserves releases, virtual keys, scan codes and modifier
+// state, once the application enables it with CSI ? 9001 h.
+func WithWin32InputMode() ReaderOption {
+	return func(ar *AnsiReader) {
+		ar.win32Input = true
+	}
+}
+
+// WithWindowSizeCallback registers a function the reader invokes with the new
+// window size when the console reports a resize, so the host can forward it
+// to a remote pseudo-terminal as SIGWINCH would be on Unix. The console only
+// reports resizes of the screen buffer; pass the buffer with WithScreenBuffer
+// so the size reported is that of its window.
+func WithWindowSizeCallback(onResize func(cols int, rows int)) ReaderOption {
+	return func(ar *AnsiReader) {
+		ar.onResize = onResize
+	}
+}
+
+// WithResizeReports also reports resizes in the input as CSI 8 ; rows ; cols t,
+// the form of xterm's window size report.
+func WithResizeReports() ReaderOption {
+	return func(ar *AnsiReader) {
+		ar.reportResize = true
+	}
+}
+
+// WithKeymap replaces the sequences sent for special keys, e.g. with
+// LinuxKeymap or RxvtKeymap to match what the remote side expects.
+func WithKeymap(keymap Keymap) ReaderOption {
+	return func(ar *AnsiReader) {
+		ar.keymap = keymap
+	}
+}
+
+// WithInputTrace records the input records the reader reads, and the bytes it
+// translates them to, to w; see NewReplayReader and VerifyInputTrace. Errors
+// writing the trace are ignored.
+func WithInputTrace(w io.Writer) ReaderOption {
+	return func(ar *AnsiReader) {
+		ar.trace = w
+	}
+}
+
+// WithControlHandler passes console control events to handle rather than
+// leaving them to the default handling, which terminates the process, so a
+// host can forward them to the processes it runs, e.g. as signals to a
+// container. Ctrl+C read from the input in raw mode is also passed to handle
+// as CTRL_C_EVENT instead of being sent as 0x03. handle reports whether it
+// handled the event; if not, Ctrl+C is sent in-band and other events are
+// passed on. handle is called on a separate thread for events the console
+// raises. The system terminates the process once handle returns from
+// CTRL_CLOSE_EVENT, CTRL_LOGOFF_EVENT or CTRL_SHUTDOWN_EVENT, so it should
+// finish any forwarding before returning. The handler is removed by Close.
+func WithControlHandler(handle func(event ControlEvent) bool) ReaderOption {
+	return func(ar *AnsiReader) {
+		ar.onControl = handle
+	}
+}
+
+// WithCoalescedRepeats sends a held key once for each batch of input read,
+// however often it repeated, rather than once per repeat, so auto-repeat does
+// not queue up input on a slow link.
+func WithCoalescedRepeats() ReaderOption {
+	return func(ar *AnsiReader) {
+		ar.coalesceRepeats = true
+	}
+}
@@ -1,90 +1,186 @@
-// +build windows
-
 package winterm
 
-func (h *WindowsAnsiEventHandler) scrollPageUp() error {
-	return h.scrollPage(1)
+// scrollUp scrolls the lines from firstLine (relative to the window top) to the
+// bottom of the scroll region up by param lines.
+func (h *WindowsAnsiEventHandler) scrollUp(param int, firstLine int) error {
+	return h.scroll(param, firstLine)
 }
 
-func (h *WindowsAnsiEventHandler) scrollPageDown() error {
-	return h.scrollPage(-1)
+// scrollDown scrolls the lines from firstLine (relative to the window top) to
+// the bottom of the scroll region down by param lines.
+func (h *WindowsAnsiEventHandler) scrollDown(param int, firstLine int) error {
+	return h.scroll(-param, firstLine)
 }
 
-func (h *WindowsAnsiEventHandler) scrollPage(param int) error {
-	info, err := GetConsoleScreenBufferInfo(h.fd)
+func (h *WindowsAnsiEventHandler) scroll(param int, firstLine int) error {
+
+	info, err := h.getConsoleScreenBufferInfo()
 	if err != nil {
 		return err
 	}
 
-	tmpScrollTop := h.sr.top
-	tmpScrollBottom := h.sr.bottom
+	h.logger.Infof("scroll: firstLine: %d, scrollRegion: %+v", firstLine, h.sr)
+	h.logger.Infof("scroll: windowTop: %d, windowBottom: %d", info.Window.Top, info.Window.Bottom)
 
-	// Set scroll region to whole window
-	h.sr.top = 0
-	h.sr.bottom = int(info.Size.Y - 1)
+	rect := info.Window
 
-	err = h.scroll(param)
+	// Lines being scrolled, between the left and right margins, in Windows
+	// backing buffer coordinates
+	top := rect.Top + SHORT(firstLine)
+	bottom := rect.Top + SHORT(h.sr.bottom)
+	left := rect.Left + SHORT(h.sr.left)
+	right := rect.Left + SHORT(h.sr.right)
 
-	h.sr.top = tmpScrollTop
-	h.sr.bottom = tmpScrollBottom
+	// Area from backing buffer to be moved
+	scrollRect := SMALL_RECT{
+		Top:    top,
+		Bottom: bottom,
+		Left:   left,
+		Right:  right,
+	}
 
-	return err
-}
+	// Clipping region should be the original scroll region, so that the
+	// lines vacated within it are filled and no others are touched
+	clipRegion := scrollRect
 
-func (h *WindowsAnsiEventHandler) scrollUp(param int) error {
-	return h.scroll(param)
-}
+	// Origin to which area should be moved
+	destOrigin := COORD{
+		X: left,
+		Y: top - SHORT(param),
+	}
+
+	char := CHAR_INFO{
+		UnicodeChar: ' ',
+		Attributes:  info.Attributes,
+	}
+
+	if err := h.console.ScrollConsoleScreenBuffer(h.fd, scrollRect, clipRegion, destOrigin, char); err != nil {
+		return err
+	}
 
-func (h *WindowsAnsiEventHandler) scrollDown(param int) error {
-	return h.scroll(-param)
+	return nil
 }
 
-func (h *WindowsAnsiEventHandler) scroll(param int) error {
+// insertDeleteLines inserts (scrolls down) or deletes (scrolls up) param lines
+// between the cursor row and the bottom of the scroll region, and moves the
+// cursor to the left margin. It has no effect when the cursor is outside the
+// scroll region.
+func (h *WindowsAnsiEventHandler) insertDeleteLines(param int, insert bool) error {
+	info, err := h.getConsoleScreenBufferInfo()
+	if err != nil {
+		return err
+	}
+
+	row := int(info.CursorPosition.Y - info.Window.Top)
+	col := int(info.CursorPosition.X - info.Window.Left)
+	if row < h.sr.top || h.sr.bottom < row || col < h.sr.left || h.sr.right < col {
+		return nil
+	}
+
+	if insert {
+		err = h.scrollDown(param, row)
+	} else {
+		err = h.scrollUp(param, row)
+	}
 
-	info, err := GetConsoleScreenBufferInfo(h.fd)
 	if err != nil {
 		return err
 	}
 
-	logger.Infof("scroll: scrollTop: %d, scrollBottom: %d", h.sr.top, h.sr.bottom)
-	logger.Infof("scroll: windowTop: %d, windowBottom: %d", info.Window.Top, info.Window.Bottom)
+	return h.setCursorPosition(COORD{info.Window.Left + SHORT(h.sr.left), info.CursorPosition.Y}, info.Size)
+}
 
-	rect := info.Window
+// insertCharacters shifts the cells from the cursor to the right margin (or the
+// right edge of the window when the cursor is past the margin) right by param
+// columns, discarding those shifted past it, and blanks the vacated cells.
+func (h *WindowsAnsiEventHandler) insertCharacters(param int) error {
+	info, err := h.getConsoleScreenBufferInfo()
+	if err != nil {
+		return err
+	}
 
-	// Current scroll region in Windows backing buffer coordinates
-	top := rect.Top + SHORT(h.sr.top)
-	bottom := rect.Top + SHORT(h.sr.bottom)
+	rect := info.Window
+	position := info.CursorPosition
 
-	// Area from backing buffer to be copied
-	scrollRect := SMALL_RECT{
-		Top:    top + SHORT(param),
-		Bottom: bottom + SHORT(param),
-		Left:   rect.Left,
-		Right:  rect.Right,
+	right := rect.Left + SHORT(h.sr.right)
+	if position.X > right {
+		right = rect.Right
 	}
 
-	// Clipping region should be the original scroll region
-	clipRegion := SMALL_RECT{
-		Top:    top,
-		Bottom: bottom,
-		Left:   rect.Left,
-		Right:  rect.Right,
+	// Area of the current line from the cursor to the right margin
+	scrollRect := SMALL_RECT{
+		Top:    position.Y,
+		Bottom: position.Y,
+		Left:   position.X,
+		Right:  right,
 	}
 
-	// Origin to which area should be copied
 	destOrigin := COORD{
-		X: rect.Left,
-		Y: top,
+		X: position.X + SHORT(param),
+		Y: position.Y,
 	}
 
 	char := CHAR_INFO{
 		UnicodeChar: ' ',
-		Attributes:  0,
+		Attributes:  info.Attributes,
 	}
 
-	if err := ScrollConsoleScreenBuffer(h.fd, scrollRect, clipRegion, destOrigin, char); err != nil {
+	return h.console.ScrollConsoleScreenBuffer(h.fd, scrollRect, scrollRect, destOrigin, char)
+}
+
+// resizeConsole resizes the window to rows by cols, growing the backing
+// buffer as needed and keeping its height (the scrollback). A zero dimension
+// is left unchanged, and the size is limited to the largest window possible.
+func (h *WindowsAnsiEventHandler) resizeConsole(info *CONSOLE_SCREEN_BUFFER_INFO, rows int, cols int) error {
+	current := windowSize(info.Window)
+	size := current
+	if rows > 0 {
+		size.Y = SHORT(rows)
+	}
+	if cols > 0 {
+		size.X = SHORT(cols)
+	}
+
+	largest, err := h.console.GetLargestConsoleWindowSize(h.fd)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	size.X = ensureInRange(size.X, 1, largest.X)
+	size.Y = ensureInRange(size.Y, 1, largest.Y)
+	if size == current {
+		return nil
+	}
+
+	h.logger.Infof("resizeConsole: %v --> %v", current, size)
+	defer h.Invalidate()
+
+	// The window must always fit in the buffer, so first shrink the window
+	// to the smaller of the two sizes, then size the buffer, then grow the
+	// window
+	top := info.Window.Top
+	interim := SMALL_RECT{Left: 0, Top: top, Right: minShort(current.X, size.X) - 1, Bottom: top + minShort(current.Y, size.Y) - 1}
+	if err := h.console.SetConsoleWindowInfo(h.fd, true, interim); err != nil {
+		return err
+	}
+
+	buffer := COORD{X: size.X, Y: info.Size.Y}
+	if buffer.Y < size.Y {
+		buffer.Y = size.Y
+	}
+	if err := h.console.SetConsoleScreenBufferSize(h.fd, buffer); err != nil {
+		return err
+	}
+
+	if top+size.Y > buffer.Y {
+		top = buffer.Y - size.Y
+	}
+	return h.console.SetConsoleWindowInfo(h.fd, true, SMALL_RECT{Left: 0, Top: top, Right: size.X - 1, Bottom: top + size.Y - 1})
+}
+
+func minShort(a SHORT, b SHORT) SHORT {
+	if a < b {
+		return a
+	}
+	return b
 }
@@ -0,0 +1,27 @@
+// +build windows
+
+package winterm
+
+import (
+	"sync/atomic"
+
+	. "github.com/Azure/go-ansiterm"
+)
+
+var callStats atomic.Value // Of *Stats
+
+// CountConsoleCalls counts the calls the package's functions make to the
+// console and other Windows APIs, for every handler and reader in the
+// process, in stats, by function name. If stats is nil, calls are no longer
+// counted.
+func CountConsoleCalls(stats *Stats) {
+	callStats.Store(stats)
+}
+
+// countCall counts a call to function in the Stats given to
+// CountConsoleCalls, if any.
+func countCall(function string) {
+	if stats, _ := callStats.Load().(*Stats); stats != nil {
+		stats.AddCall(function)
+	}
+}
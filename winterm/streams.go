@@ -0,0 +1,98 @@
+// +build windows
+
+package winterm
+
+import (
+	"io"
+	"os"
+	"syscall"
+
+	. "github.com/Azure/go-ansiterm"
+)
+
+// The standard handles, which select the file NewAnsiReader and NewAnsiWriter
+// use on every platform.
+const (
+	STD_INPUT_HANDLE  = syscall.STD_INPUT_HANDLE
+	STD_OUTPUT_HANDLE = syscall.STD_OUTPUT_HANDLE
+	STD_ERROR_HANDLE  = syscall.STD_ERROR_HANDLE
+)
+
+// NewAnsiReaderFromFile creates a reader for the console input buffer behind
+// file, typically os.Stdin. If file is not a console, the returned error is a
+// *NotConsoleError.
+func NewAnsiReaderFromFile(file *os.File, opts ...ReaderOption) (*AnsiReader, error) {
+	return NewAnsiReaderFromHandle(syscall.Handle(file.Fd()), opts...)
+}
+
+// NewAnsiReader returns a reader translating the console input of the standard
+// handle nFile (STD_INPUT_HANDLE), matching the constructor of
+// Docker's pkg/term/windows. If the handle is not a console, e.g. because
+// input is redirected, the file is read untranslated. Close closes the file.
+func NewAnsiReader(nFile int) io.ReadCloser {
+	file, fd := GetStdFile(nFile)
+
+	reader, err := NewAnsiReaderFromHandle(syscall.Handle(fd))
+	if err != nil {
+		return file
+	}
+
+	return &stdReader{AnsiReader: reader, file: file, fd: fd}
+}
+
+// stdReader is an AnsiReader that closes the standard file it reads.
+type stdReader struct {
+	*AnsiReader
+	file *os.File
+	fd   uintptr
+}
+
+// Fd returns the console handle the reader translates the input of.
+func (r *stdReader) Fd() uintptr {
+	return r.fd
+}
+
+func (r *stdReader) Close() error {
+	r.AnsiReader.Close()
+	return r.file.Close()
+}
+
+// NewAnsiWriter returns a writer rendering ANSI output to the console screen
+// buffer of the standard handle nFile (STD_OUTPUT_HANDLE or
+// STD_ERROR_HANDLE), matching the constructor of Docker's
+// pkg/term/windows. If the handle is not a console, e.g. because output is
+// redirected, output is written to the file unchanged.
+func NewAnsiWriter(nFile int) io.Writer {
+	file, fd := GetStdFile(nFile)
+
+	handler, err := CreateWinEventHandler(fd, file)
+	if err != nil {
+		return file
+	}
+
+	return &stdWriter{
+		handler: handler,
+		parser:  CreateParser("Ground", handler, WithUTF8()),
+		fd:      fd,
+	}
+}
+
+// stdWriter parses output written to it and renders it to the console.
+type stdWriter struct {
+	handler *WindowsAnsiEventHandler
+	parser  *AnsiParser
+	fd      uintptr
+}
+
+// Fd returns the console handle the writer renders to.
+func (w *stdWriter) Fd() uintptr {
+	return w.fd
+}
+
+func (w *stdWriter) Write(p []byte) (int, error) {
+	locker := w.handler.Locker()
+	locker.Lock()
+	defer locker.Unlock()
+
+	return w.parser.Parse(p)
+}
@@ -0,0 +1,53 @@
+// +build !windows
+
+package winterm
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// The standard handles, with the values Windows gives them, which select the
+// file NewAnsiReader and NewAnsiWriter use on every platform.
+const (
+	STD_INPUT_HANDLE  = -10
+	STD_OUTPUT_HANDLE = -11
+	STD_ERROR_HANDLE  = -12
+)
+
+// NewAnsiReader returns the standard input file, selected by nFile
+// (STD_INPUT_HANDLE). Outside Windows terminals produce ANSI input
+// themselves, so it is read untranslated; this lets code written against the
+// Windows reader build unchanged on other platforms.
+func NewAnsiReader(nFile int) io.ReadCloser {
+	file, _ := GetStdFile(nFile)
+	return file
+}
+
+// NewAnsiWriter returns the standard output or error file, selected by nFile
+// (STD_OUTPUT_HANDLE or STD_ERROR_HANDLE). Outside Windows terminals
+// interpret ANSI output themselves, so output is written to it unchanged;
+// a PassthroughHandler can be used to filter it through the parser instead.
+func NewAnsiWriter(nFile int) io.Writer {
+	file, _ := GetStdFile(nFile)
+	return file
+}
+
+// GetStdFile returns the standard file selected by nFile and its file
+// descriptor.
+func GetStdFile(nFile int) (*os.File, uintptr) {
+	var file *os.File
+	switch nFile {
+	case STD_INPUT_HANDLE:
+		file = os.Stdin
+	case STD_OUTPUT_HANDLE:
+		file = os.Stdout
+	case STD_ERROR_HANDLE:
+		file = os.Stderr
+	default:
+		panic(fmt.Errorf("Invalid standard handle identifier: %v", nFile))
+	}
+
+	return file, file.Fd()
+}
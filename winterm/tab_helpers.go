@@ -0,0 +1,81 @@
+package winterm
+
+// defaultTabWidth is the distance between the initial tab stops.
+const defaultTabWidth = 8
+
+// resetTabStops sets a tab stop every eight columns across the window.
+func (h *WindowsAnsiEventHandler) resetTabStops() {
+	h.tabStops = make([]bool, h.windowSize.X)
+	for x := defaultTabWidth; x < len(h.tabStops); x += defaultTabWidth {
+		h.tabStops[x] = true
+	}
+}
+
+// resizeTabStops adapts the tab stops to a new window width. Columns added
+// to the window get the default tab stops.
+func (h *WindowsAnsiEventHandler) resizeTabStops(width int) {
+	if width <= len(h.tabStops) {
+		h.tabStops = h.tabStops[:width]
+		return
+	}
+
+	for x := len(h.tabStops); x < width; x++ {
+		h.tabStops = append(h.tabStops, x > 0 && x%defaultTabWidth == 0)
+	}
+}
+
+// isTabStop reports whether there is a tab stop at the window-relative column x.
+func (h *WindowsAnsiEventHandler) isTabStop(x int) bool {
+	return x < len(h.tabStops) && h.tabStops[x]
+}
+
+// setTabStop sets or clears the tab stop at the window-relative column x.
+func (h *WindowsAnsiEventHandler) setTabStop(x int, set bool) {
+	if x < len(h.tabStops) {
+		h.tabStops[x] = set
+	}
+}
+
+// horizontalBounds returns the columns, in buffer coordinates, within which
+// tabulation moves the cursor from column x: the left and right margins when
+// x lies between them, otherwise the whole window.
+func (h *WindowsAnsiEventHandler) horizontalBounds(window SMALL_RECT, x SHORT) (SHORT, SHORT) {
+	left, right := window.Left+SHORT(h.sr.left), window.Left+SHORT(h.sr.right)
+	if h.lrMargins && left <= x && x <= right {
+		return left, right
+	}
+
+	return window.Left, window.Right
+}
+
+// moveCursorTab moves the cursor forward (n > 0) or backward (n < 0) by |n|
+// tab stops, stopping at the bounds if there are too few.
+func (h *WindowsAnsiEventHandler) moveCursorTab(n int) error {
+	info, err := h.getConsoleScreenBufferInfo()
+	if err != nil {
+		return err
+	}
+
+	left, right := h.horizontalBounds(info.Window, info.CursorPosition.X)
+
+	// Tab stops are relative to the window
+	x := int(info.CursorPosition.X - info.Window.Left)
+	first, last := int(left-info.Window.Left), int(right-info.Window.Left)
+
+	for ; n > 0 && x < last; n-- {
+		x++
+		for x < last && !h.isTabStop(x) {
+			x++
+		}
+	}
+
+	for ; n < 0 && x > first; n++ {
+		x--
+		for x > first && !h.isTabStop(x) {
+			x--
+		}
+	}
+
+	position := COORD{X: info.Window.Left + SHORT(x), Y: info.CursorPosition.Y}
+	return h.setCursorPosition(position, info.Size)
+}
@@ -0,0 +1,269 @@
+// +build windows
+
+package winterm
+
+import (
+	"sync"
+	"syscall"
+
+	. "github.com/Azure/go-ansiterm"
+	"github.com/Azure/go-ansiterm/asciicast"
+)
+
+// responsePollInterval is how often, in milliseconds, a Read waiting for
+// console input checks for replies to queries written meanwhile.
+const responsePollInterval = 50
+
+// Terminal presents a console as a single terminal stream, for code that
+// expects one io.ReadWriter, such as an ssh session or container attach.
+// Writes are parsed as ANSI output and rendered by a WindowsAnsiEventHandler;
+// reads return console input translated by an AnsiReader. The two share
+// their input modes, so cursor key, keypad, mouse, and bracketed paste modes
+// set by the output apply to the input, and replies to queries in the output
+// are returned by Read ahead of the input, as from a terminal.
+//
+// Read and Write may be called from separate goroutines.
+type Terminal struct {
+	handler *WindowsAnsiEventHandler
+	parser  *AnsiParser
+	reader  *AnsiReader
+	in      syscall.Handle
+	state   *ConsoleState // Modes to restore on Close, or nil
+
+	mutex     sync.Mutex
+	responses []byte // Replies to queries not yet read
+
+	handlerOpts []HandlerOption
+	readerOpts  []ReaderOption
+	raw         bool
+	recorder    *asciicast.Writer
+}
+
+// TerminalOption configures optional behavior of a Terminal.
+type TerminalOption func(*Terminal)
+
+// WithHandlerOptions configures the terminal's output handler.
+func WithHandlerOptions(opts ...HandlerOption) TerminalOption {
+	return func(t *Terminal) {
+		t.handlerOpts = append(t.handlerOpts, opts...)
+	}
+}
+
+// WithReaderOptions configures the terminal's input reader.
+func WithReaderOptions(opts ...ReaderOption) TerminalOption {
+	return func(t *Terminal) {
+		t.readerOpts = append(t.readerOpts, opts...)
+	}
+}
+
+// WithRawMode switches the console to raw mode with MakeRaw when the terminal
+// is created, restoring the previous modes on Close.
+func WithRawMode() TerminalOption {
+	return func(t *Terminal) {
+		t.raw = true
+	}
+}
+
+// WithRecorder records the session to an asciinema cast: the output written
+// to the terminal, the input read from it, and resizes.
+func WithRecorder(recorder *asciicast.Writer) TerminalOption {
+	return func(t *Terminal) {
+		t.recorder = recorder
+	}
+}
+
+// NewTerminal creates a terminal reading from the console input buffer in and
+// writing to the screen buffer out. If either handle is not a console, the
+// returned error is a *NotConsoleError.
+func NewTerminal(in syscall.Handle, out syscall.Handle, opts ...TerminalOption) (*Terminal, error) {
+	t := &Terminal{in: in}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	if t.raw {
+		state, err := MakeRaw(in, out)
+		if err != nil {
+			return nil, err
+		}
+		t.state = state
+	}
+
+	// Replies go to the input unless the caller's options redirect them
+	handlerOpts := append([]HandlerOption{WithResponseWriter(terminalResponses{t})}, t.handlerOpts...)
+	handler, err := NewWinEventHandler(out, handlerOpts...)
+	if err != nil {
+		t.restore()
+		return nil, err
+	}
+
+	readerOpts := append([]ReaderOption{WithInputModes(handler.InputModes()), WithScreenBuffer(out)}, t.readerOpts...)
+	reader, err := NewAnsiReaderFromHandle(in, readerOpts...)
+	if err != nil {
+		handler.Close()
+		t.restore()
+		return nil, err
+	}
+
+	t.handler = handler
+	t.reader = reader
+	t.parser = CreateParser("Ground", handler, WithUTF8())
+	return t, nil
+}
+
+// Handler returns the handler rendering the terminal's output.
+func (t *Terminal) Handler() *WindowsAnsiEventHandler {
+	return t.handler
+}
+
+// Reader returns the reader translating the terminal's input.
+func (t *Terminal) Reader() *AnsiReader {
+	return t.reader
+}
+
+// Write renders ANSI output to the console.
+func (t *Terminal) Write(p []byte) (int, error) {
+	locker := t.handler.Locker()
+	locker.Lock()
+	defer locker.Unlock()
+
+	n, err := t.parser.Parse(p)
+	if t.recorder != nil && n > 0 {
+		t.recorder.Write(p[:n])
+	}
+	return n, err
+}
+
+// Read blocks until input or a reply to a query is available, then returns as
+// much of it as fits in p. A reply written while Read waits for input is
+// returned within responsePollInterval.
+func (t *Terminal) Read(p []byte) (int, error) {
+	n, err := t.read(p)
+	if t.recorder != nil && n > 0 {
+		t.recorder.Input(p[:n])
+	}
+	return n, err
+}
+
+// read reads input or replies to queries into p.
+func (t *Terminal) read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	for {
+		if n := t.readResponses(p); n != 0 {
+			return n, nil
+		}
+
+		if len(t.reader.pending) != 0 {
+			return t.reader.Read(p)
+		}
+
+		ready, err := WaitForSingleObject(uintptr(t.in), responsePollInterval)
+		if err != nil {
+			return 0, err
+		}
+		if ready {
+			if err := t.reader.fill(); err != nil {
+				return 0, err
+			}
+		}
+	}
+}
+
+// readResponses copies pending replies to queries into p.
+func (t *Terminal) readResponses(p []byte) int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	n := copy(p, t.responses)
+	t.responses = t.responses[n:]
+	return n
+}
+
+// terminalResponses queues the handler's replies to queries for Read.
+type terminalResponses struct {
+	t *Terminal
+}
+
+func (r terminalResponses) Write(p []byte) (int, error) {
+	r.t.mutex.Lock()
+	defer r.t.mutex.Unlock()
+
+	r.t.responses = append(r.t.responses, p...)
+	return len(p), nil
+}
+
+// Size returns the size of the console window in character cells.
+func (t *Terminal) Size() (cols int, rows int, err error) {
+	locker := t.handler.Locker()
+	locker.Lock()
+	defer locker.Unlock()
+
+	info, err := t.handler.getConsoleScreenBufferInfo()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	size := windowSize(info.Window)
+	return int(size.X), int(size.Y), nil
+}
+
+// Resize resizes the console window to cols by rows, within the largest size
+// the display allows, as a remote client's window change would.
+func (t *Terminal) Resize(cols int, rows int) error {
+	locker := t.handler.Locker()
+	locker.Lock()
+	defer locker.Unlock()
+
+	if err := t.handler.Flush(); err != nil {
+		return err
+	}
+
+	info, err := t.handler.getConsoleScreenBufferInfo()
+	if err != nil {
+		return err
+	}
+
+	if err := t.handler.resizeConsole(info, rows, cols); err != nil {
+		return err
+	}
+
+	if t.recorder != nil {
+		// Record the size the display allowed
+		info, err := t.handler.getConsoleScreenBufferInfo()
+		if err != nil {
+			return err
+		}
+		size := windowSize(info.Window)
+		t.recorder.Resize(int(size.X), int(size.Y))
+	}
+	return nil
+}
+
+// Close closes the handler, restoring the console state it captured, and the
+// reader, and restores the console modes if the terminal switched to raw mode.
+func (t *Terminal) Close() error {
+	locker := t.handler.Locker()
+	locker.Lock()
+	err := t.handler.Close()
+	locker.Unlock()
+
+	if readerErr := t.reader.Close(); err == nil {
+		err = readerErr
+	}
+
+	if restoreErr := t.restore(); err == nil {
+		err = restoreErr
+	}
+	return err
+}
+
+// restore restores the console modes captured by WithRawMode, if any.
+func (t *Terminal) restore() error {
+	if t.state == nil {
+		return nil
+	}
+	return t.state.Restore()
+}
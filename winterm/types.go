@@ -0,0 +1,234 @@
+package winterm
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/Azure/go-ansiterm/winterm/consoleapi"
+)
+
+// Windows Console constants
+const (
+	// Console modes
+	// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms686033(v=vs.85).aspx.
+	ENABLE_PROCESSED_INPUT = 0x0001
+	ENABLE_LINE_INPUT      = 0x0002
+	ENABLE_ECHO_INPUT      = 0x0004
+	ENABLE_WINDOW_INPUT    = 0x0008
+	ENABLE_MOUSE_INPUT     = 0x0010
+	ENABLE_INSERT_MODE     = 0x0020
+	ENABLE_QUICK_EDIT_MODE = 0x0040
+	ENABLE_EXTENDED_FLAGS  = 0x0080
+
+	ENABLE_PROCESSED_OUTPUT   = 0x0001
+	ENABLE_WRAP_AT_EOL_OUTPUT = 0x0002
+
+	// Character attributes
+	// Note:
+	// -- The attributes are combined to produce various colors (e.g., Blue + Green will create Cyan).
+	//    Clearing all foreground or background colors results in black; setting all creates white.
+	// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms682088(v=vs.85).aspx#_win32_character_attributes.
+	FOREGROUND_BLUE      WORD = 0x0001
+	FOREGROUND_GREEN     WORD = 0x0002
+	FOREGROUND_RED       WORD = 0x0004
+	FOREGROUND_INTENSITY WORD = 0x0008
+	FOREGROUND_MASK      WORD = 0x000F
+
+	BACKGROUND_BLUE      WORD = 0x0010
+	BACKGROUND_GREEN     WORD = 0x0020
+	BACKGROUND_RED       WORD = 0x0040
+	BACKGROUND_INTENSITY WORD = 0x0080
+	BACKGROUND_MASK      WORD = 0x00F0
+
+	COMMON_LVB_MASK          WORD = 0xFF00
+	COMMON_LVB_REVERSE_VIDEO WORD = 0x4000
+	COMMON_LVB_UNDERSCORE    WORD = 0x8000
+
+	// Input event types
+	// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms683499(v=vs.85).aspx.
+	KEY_EVENT                = 0x0001
+	MOUSE_EVENT              = 0x0002
+	WINDOW_BUFFER_SIZE_EVENT = 0x0004
+	MENU_EVENT               = 0x0008
+	FOCUS_EVENT              = 0x0010
+
+	// Console control events
+	// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms683242(v=vs.85).aspx.
+	CTRL_C_EVENT        = 0
+	CTRL_BREAK_EVENT    = 1
+	CTRL_CLOSE_EVENT    = 2
+	CTRL_LOGOFF_EVENT   = 5
+	CTRL_SHUTDOWN_EVENT = 6
+
+	// Mouse button states and event flags
+	// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms684239(v=vs.85).aspx.
+	FROM_LEFT_1ST_BUTTON_PRESSED = 0x0001
+	RIGHTMOST_BUTTON_PRESSED     = 0x0002
+	FROM_LEFT_2ND_BUTTON_PRESSED = 0x0004
+	MOUSE_MOVED                  = 0x0001
+	DOUBLE_CLICK                 = 0x0002
+	MOUSE_WHEELED                = 0x0004
+	MOUSE_HWHEELED               = 0x0008
+
+	// WaitForSingleObject return codes
+	WAIT_ABANDONED = 0x00000080
+	WAIT_FAILED    = 0xFFFFFFFF
+	WAIT_SIGNALED  = 0x0000000
+	WAIT_TIMEOUT   = 0x00000102
+
+	// CreateConsoleScreenBuffer access, sharing, and flags
+	// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms682122(v=vs.85).aspx.
+	GENERIC_READ            = 0x80000000
+	GENERIC_WRITE           = 0x40000000
+	FILE_SHARE_READ         = 0x00000001
+	FILE_SHARE_WRITE        = 0x00000002
+	CONSOLE_TEXTMODE_BUFFER = 0x00000001
+
+	// Code pages
+	// See https://msdn.microsoft.com/en-us/library/windows/desktop/dd317756(v=vs.85).aspx.
+	CP_UTF8 = 65001
+
+	// FlashWindowEx flags
+	// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms679348(v=vs.85).aspx.
+	FLASHW_STOP      = 0x00000000
+	FLASHW_CAPTION   = 0x00000001
+	FLASHW_TRAY      = 0x00000002
+	FLASHW_ALL       = FLASHW_CAPTION | FLASHW_TRAY
+	FLASHW_TIMER     = 0x00000004
+	FLASHW_TIMERNOFG = 0x0000000C
+
+	// CreateProcess flag and attribute attaching a process to a pseudo console
+	// See https://docs.microsoft.com/en-us/windows/console/creating-a-pseudoconsole-session.
+	EXTENDED_STARTUPINFO_PRESENT        = 0x00080000
+	PROC_THREAD_ATTRIBUTE_PSEUDOCONSOLE = 0x00020016
+
+	// WaitForSingleObject wait duration
+	WAIT_INFINITE       = 0xFFFFFFFF
+	WAIT_ONE_SECOND     = 1000
+	WAIT_HALF_SECOND    = 500
+	WAIT_QUARTER_SECOND = 250
+)
+
+// Windows API Console types
+// -- See https://msdn.microsoft.com/en-us/library/windows/desktop/aa383751(v=vs.85).aspx for core types (e.g., SHORT)
+// -- See https://msdn.microsoft.com/en-us/library/windows/desktop/ms682101(v=vs.85).aspx for Console specific types (e.g., COORD)
+// -- See https://msdn.microsoft.com/en-us/library/aa296569(v=vs.60).aspx for comments on alignment
+// The core types are aliases, so that COORD and SMALL_RECT convert to and
+// from windows.Coord and windows.SmallRect.
+type (
+	SHORT = int16
+	BOOL  = int32
+	WORD  = uint16
+	WCHAR = uint16
+	DWORD = uint32
+
+	CHAR_INFO           = consoleapi.CharInfo
+	CONSOLE_CURSOR_INFO = consoleapi.ConsoleCursorInfo
+
+	CONSOLE_FONT_INFO struct {
+		Font     DWORD
+		FontSize COORD
+	}
+
+	CONSOLE_SCREEN_BUFFER_INFO struct {
+		Size              COORD
+		CursorPosition    COORD
+		Attributes        WORD
+		Window            SMALL_RECT
+		MaximumWindowSize COORD
+	}
+
+	COORD struct {
+		X SHORT
+		Y SHORT
+	}
+
+	SMALL_RECT struct {
+		Left   SHORT
+		Top    SHORT
+		Right  SHORT
+		Bottom SHORT
+	}
+
+	// INPUT_RECORD is a C/C++ union of which KEY_EVENT_RECORD is one case, it is also the largest
+	// See https://msdn.microsoft.com/en-us/library/windows/desktop/ms683499(v=vs.85).aspx.
+	INPUT_RECORD struct {
+		EventType WORD
+		KeyEvent  KEY_EVENT_RECORD
+	}
+
+	KEY_EVENT_RECORD struct {
+		KeyDown         BOOL
+		RepeatCount     WORD
+		VirtualKeyCode  WORD
+		VirtualScanCode WORD
+		UnicodeChar     WCHAR
+		ControlKeyState DWORD
+	}
+
+	// MOUSE_EVENT_RECORD is a case of the INPUT_RECORD union; see INPUT_RECORD.MouseEvent.
+	MOUSE_EVENT_RECORD struct {
+		MousePosition   COORD
+		ButtonState     DWORD
+		ControlKeyState DWORD
+		EventFlags      DWORD
+	}
+
+	// FOCUS_EVENT_RECORD is a case of the INPUT_RECORD union; see INPUT_RECORD.FocusEvent.
+	FOCUS_EVENT_RECORD struct {
+		SetFocus BOOL
+	}
+
+	// WINDOW_BUFFER_SIZE is a case of the INPUT_RECORD union; see INPUT_RECORD.WindowBufferSizeEvent.
+	WINDOW_BUFFER_SIZE struct {
+		Size COORD
+	}
+
+	FLASHWINFO struct {
+		Size    uint32
+		Hwnd    uintptr
+		Flags   DWORD
+		Count   uint32
+		Timeout DWORD
+	}
+)
+
+// boolToBOOL converts a Go bool into a Windows BOOL.
+func boolToBOOL(f bool) BOOL {
+	if f {
+		return BOOL(1)
+	} else {
+		return BOOL(0)
+	}
+}
+
+// MouseEvent returns the record's event as a mouse event. It is meaningful
+// only if EventType is MOUSE_EVENT.
+func (record *INPUT_RECORD) MouseEvent() *MOUSE_EVENT_RECORD {
+	return (*MOUSE_EVENT_RECORD)(unsafe.Pointer(&record.KeyEvent))
+}
+
+// FocusEvent returns the record's event as a focus event. It is meaningful
+// only if EventType is FOCUS_EVENT.
+func (record *INPUT_RECORD) FocusEvent() *FOCUS_EVENT_RECORD {
+	return (*FOCUS_EVENT_RECORD)(unsafe.Pointer(&record.KeyEvent))
+}
+
+// WindowBufferSizeEvent returns the record's event as a screen buffer resize.
+// It is meaningful only if EventType is WINDOW_BUFFER_SIZE_EVENT.
+func (record *INPUT_RECORD) WindowBufferSizeEvent() *WINDOW_BUFFER_SIZE {
+	return (*WINDOW_BUFFER_SIZE)(unsafe.Pointer(&record.KeyEvent))
+}
+
+// String helpers
+func (info CONSOLE_SCREEN_BUFFER_INFO) String() string {
+	return fmt.Sprintf("Size(%v) Cursor(%v) Window(%v) Max(%v)", info.Size, info.CursorPosition, info.Window, info.MaximumWindowSize)
+}
+
+func (coord COORD) String() string {
+	return fmt.Sprintf("%v,%v", coord.X, coord.Y)
+}
+
+func (rect SMALL_RECT) String() string {
+	return fmt.Sprintf("(%v,%v),(%v,%v)", rect.Left, rect.Top, rect.Right, rect.Bottom)
+}
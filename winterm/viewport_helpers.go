@@ -0,0 +1,49 @@
+package winterm
+
+// With WithViewportPinning, the handler distinguishes the screen, the window
+// rectangle it addresses, from the viewport, the rectangle the console
+// displays. They differ while the user has scrolled back to read history.
+
+// trackViewport updates the screen and viewport from freshly queried console
+// state and reports the screen in info.Window. The viewport is considered
+// scrolled away when it has moved off the cursor without being resized.
+func (h *WindowsAnsiEventHandler) trackViewport(info *CONSOLE_SCREEN_BUFFER_INFO) {
+	window := info.Window
+	cursor := info.CursorPosition
+
+	scrolledAway := h.screen != SMALL_RECT{} &&
+		window != h.screen &&
+		windowSize(window) == windowSize(h.screen) &&
+		(cursor.Y < window.Top || cursor.Y > window.Bottom)
+
+	if !scrolledAway {
+		h.screen = window
+		h.viewportPinned = false
+		return
+	}
+
+	// Output may have advanced the cursor below the screen
+	if cursor.Y > h.screen.Bottom {
+		shift := cursor.Y - h.screen.Bottom
+		h.screen.Top += shift
+		h.screen.Bottom += shift
+	}
+
+	if !h.viewportPinned {
+		h.logger.Infof("trackViewport: pinned %v, screen %v", window, h.screen)
+	}
+
+	h.viewport = window
+	h.viewportPinned = true
+	info.Window = h.screen
+}
+
+// restoreViewport returns the console window to the pinned viewport after an
+// operation that makes the console scroll the window to the cursor.
+func (h *WindowsAnsiEventHandler) restoreViewport() error {
+	if !h.viewportPinned {
+		return nil
+	}
+
+	return h.console.SetConsoleWindowInfo(h.fd, true, h.viewport)
+}
@@ -0,0 +1,144 @@
+// +build windows
+
+package winterm
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+// CreateWinEventHandler creates a handler that renders events to the console
+// identified by fd. Printed bytes are decoded as UTF-8 and written as UTF-16
+// through WriteConsoleW, so the parser driving the handler should be created
+// with the WithUTF8 option. If fd is not a console, the returned error is a
+// *NotConsoleError.
+//
+// file is unused and retained for compatibility; see NewWinEventHandler.
+func CreateWinEventHandler(fd uintptr, file *os.File, opts ...HandlerOption) (*WindowsAnsiEventHandler, error) {
+	return NewWinEventHandler(syscall.Handle(fd), opts...)
+}
+
+// NewWinEventHandler creates a handler that renders events to the console
+// screen buffer identified by handle, which need not be backed by an os.File
+// (e.g. a buffer from CreateConsoleScreenBuffer). Text is written through
+// WriteConsoleW as described for CreateWinEventHandler.
+func NewWinEventHandler(handle syscall.Handle, opts ...HandlerOption) (*WindowsAnsiEventHandler, error) {
+	return NewWinEventHandlerForWriter(handle, nil, opts...)
+}
+
+// NewWinEventHandlerForWriter creates a handler that performs console
+// operations (cursor movement, erasing, scrolling, attributes) on handle but
+// writes text and control characters to w, encoded in the console's output
+// code page (see WithOutputCodepage). A nil w writes through WriteConsoleW,
+// as NewWinEventHandler does.
+func NewWinEventHandlerForWriter(handle syscall.Handle, w io.Writer, opts ...HandlerOption) (*WindowsAnsiEventHandler, error) {
+	return NewConsoleEventHandler(win32Console{}, uintptr(handle), w, opts...)
+}
+
+// win32Console is the Console of the process, calling the Windows API.
+type win32Console struct{}
+
+func (win32Console) CreateConsoleScreenBuffer() (uintptr, error) {
+	return CreateConsoleScreenBuffer()
+}
+
+func (win32Console) CloseHandle(handle uintptr) error {
+	return syscall.CloseHandle(syscall.Handle(handle))
+}
+
+func (win32Console) SetConsoleActiveScreenBuffer(handle uintptr) error {
+	return SetConsoleActiveScreenBuffer(handle)
+}
+
+func (win32Console) GetConsoleScreenBufferInfo(handle uintptr) (*CONSOLE_SCREEN_BUFFER_INFO, error) {
+	return GetConsoleScreenBufferInfo(handle)
+}
+
+func (win32Console) SetConsoleScreenBufferSize(handle uintptr, coord COORD) error {
+	return SetConsoleScreenBufferSize(handle, coord)
+}
+
+func (win32Console) SetConsoleWindowInfo(handle uintptr, isAbsolute bool, rect SMALL_RECT) error {
+	return SetConsoleWindowInfo(handle, isAbsolute, rect)
+}
+
+func (win32Console) GetLargestConsoleWindowSize(handle uintptr) (COORD, error) {
+	return GetLargestConsoleWindowSize(handle)
+}
+
+func (win32Console) GetCurrentConsoleFont(handle uintptr) (*CONSOLE_FONT_INFO, error) {
+	return GetCurrentConsoleFont(handle)
+}
+
+func (win32Console) GetConsoleMode(handle uintptr) (uint32, error) {
+	return GetConsoleMode(handle)
+}
+
+func (win32Console) SetConsoleMode(handle uintptr, mode uint32) error {
+	return SetConsoleMode(handle, mode)
+}
+
+func (win32Console) GetConsoleCursorInfo(handle uintptr, cursorInfo *CONSOLE_CURSOR_INFO) error {
+	return GetConsoleCursorInfo(handle, cursorInfo)
+}
+
+func (win32Console) SetConsoleCursorInfo(handle uintptr, cursorInfo *CONSOLE_CURSOR_INFO) error {
+	return SetConsoleCursorInfo(handle, cursorInfo)
+}
+
+func (win32Console) SetConsoleCursorPosition(handle uintptr, coord COORD) error {
+	return SetConsoleCursorPosition(handle, coord)
+}
+
+func (win32Console) SetConsoleTextAttribute(handle uintptr, attribute WORD) error {
+	return SetConsoleTextAttribute(handle, attribute)
+}
+
+func (win32Console) WriteConsole(handle uintptr, buffer []uint16) error {
+	return WriteConsole(handle, buffer)
+}
+
+func (win32Console) FillConsoleOutputCharacter(handle uintptr, char WCHAR, count uint32, coord COORD) error {
+	return FillConsoleOutputCharacter(handle, char, count, coord)
+}
+
+func (win32Console) FillConsoleOutputAttribute(handle uintptr, attribute WORD, count uint32, coord COORD) error {
+	return FillConsoleOutputAttribute(handle, attribute, count, coord)
+}
+
+func (win32Console) ScrollConsoleScreenBuffer(handle uintptr, scrollRect SMALL_RECT, clipRect SMALL_RECT, destOrigin COORD, char CHAR_INFO) error {
+	return ScrollConsoleScreenBuffer(handle, scrollRect, clipRect, destOrigin, char)
+}
+
+func (win32Console) ReadConsoleOutput(handle uintptr, buffer []CHAR_INFO, bufferSize COORD, bufferCoord COORD, readRegion *SMALL_RECT) error {
+	return ReadConsoleOutput(handle, buffer, bufferSize, bufferCoord, readRegion)
+}
+
+func (win32Console) WriteConsoleOutput(handle uintptr, buffer []CHAR_INFO, bufferSize COORD, bufferCoord COORD, writeRegion *SMALL_RECT) error {
+	return WriteConsoleOutput(handle, buffer, bufferSize, bufferCoord, writeRegion)
+}
+
+func (win32Console) GetConsoleOutputCP() (uint32, error) {
+	return GetConsoleOutputCP()
+}
+
+func (win32Console) WideCharToMultiByte(codepage uint32, text []uint16) ([]byte, error) {
+	return WideCharToMultiByte(codepage, text)
+}
+
+func (win32Console) GetConsoleTitle() (string, error) {
+	return GetConsoleTitle()
+}
+
+func (win32Console) SetConsoleTitle(title string) error {
+	return SetConsoleTitle(title)
+}
+
+func (win32Console) GetConsoleWindow() uintptr {
+	return GetConsoleWindow()
+}
+
+func (win32Console) FlashWindowEx(info *FLASHWINFO) {
+	FlashWindowEx(info)
+}
@@ -1,149 +1,644 @@
-// +build windows
-
 package winterm
 
 import (
-	"io/ioutil"
-	"os"
+	"fmt"
+	"io"
 	"strconv"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
 
 	. "github.com/Azure/go-ansiterm"
-	"github.com/Sirupsen/logrus"
 )
 
-var logger *logrus.Logger
-
 type WindowsAnsiEventHandler struct {
-	fd        uintptr
-	file      *os.File
-	infoReset *CONSOLE_SCREEN_BUFFER_INFO
-	sr        scrollRegion
+	*emulationState
+
+	console     Console
+	deferred    bool
+	bufferSwap  bool
+	closed      bool
+	writer      io.Writer
+	codepage    uint32
+	infoReset   *CONSOLE_SCREEN_BUFFER_INFO
+	cursorInfo  CONSOLE_CURSOR_INFO
+	modeReset   uint32
+	titleReset  string
+	utf8Buffer  []byte
+	cluster     []rune
+	text        []rune
+	lastCluster []rune // The most recently printed character, for REP
+	runeWidth   func(rune) int
+	onResize    func(cols int, rows int)
+	bell        BellStyle
+	logger      Logger
+
+	styleFallbacks map[SGRStyle]StyleFallback
+
+	preserveScrollback bool
+	windowErase        bool
+	pinViewport        bool
+	allowResize        bool
+
+	// responses receives replies to queries; nil prints them to the console
+	responses io.Writer
+}
+
+// emulationState is the terminal state layered over the console by the
+// handler. Handlers writing to the same console share it so their margins,
+// modes, and character sets agree, and so they render to the same frame.
+type emulationState struct {
+	mutex       sync.Mutex
+	fd          uintptr
+	front       uintptr // The displayed buffer while rendering a frame to fd, otherwise 0
+	primary     uintptr // The console's own buffer while rendering a frame, otherwise 0
+	handlers    int     // The handlers sharing the state that are not closed
+	sr          scrollRegion
+	charsets    [4]byte
+	gl          int
+	insertMode  bool
+	originMode  bool
+	noAutowrap  bool
+	lrMargins   bool
+	inverse     bool
+	hideCursor  bool
+	sgrStack    []pushedAttributes
+	tabStops    []bool // Indexed by window-relative column
+	savedCursor savedCursor
+	windowSize  COORD
+	inputModes  *InputModes
+
+	// screen and viewport are tracked for WithViewportPinning
+	screen         SMALL_RECT
+	viewport       SMALL_RECT
+	viewportPinned bool
+
+	// info caches the console state, accounting for the handler's own
+	// changes. It is nil when the console must be re-queried.
+	info *CONSOLE_SCREEN_BUFFER_INFO
+}
+
+// NotConsoleError is returned by the handler constructors when the handle does
+// not refer to a console, e.g. because output is redirected to a file or pipe
+// or the process is detached from its console.
+type NotConsoleError struct {
+	Err error
+}
+
+func (e *NotConsoleError) Error() string {
+	return "winterm: handle is not a console: " + e.Err.Error()
 }
 
-func CreateWinEventHandler(fd uintptr, file *os.File) *WindowsAnsiEventHandler {
-	logFile := ioutil.Discard
+// IsNotConsole reports whether err indicates the handle is not a console.
+func IsNotConsole(err error) bool {
+	_, ok := err.(*NotConsoleError)
+	return ok
+}
+
+// NewConsoleEventHandler creates a handler that renders events through
+// console to its screen buffer identified by handle, writing text to w as
+// described for NewWinEventHandlerForWriter, or with console.WriteConsole if
+// w is nil. If handle is not a console, the returned error is a
+// *NotConsoleError.
+func NewConsoleEventHandler(console Console, handle uintptr, w io.Writer, opts ...HandlerOption) (*WindowsAnsiEventHandler, error) {
+	fd := handle
+
+	// A console mode exists only for console handles, so this also
+	// distinguishes redirected output
+	modeReset, err := console.GetConsoleMode(fd)
+	if err != nil {
+		return nil, &NotConsoleError{Err: err}
+	}
 
-	if isDebugEnv := os.Getenv(LogEnv); isDebugEnv == "1" {
-		logFile, _ = os.Create("winEventHandler.log")
+	infoReset, err := console.GetConsoleScreenBufferInfo(fd)
+	if err != nil {
+		return nil, err
 	}
 
-	logger = &logrus.Logger{
-		Out:       logFile,
-		Formatter: new(logrus.TextFormatter),
-		Level:     logrus.DebugLevel,
+	var cursorInfo CONSOLE_CURSOR_INFO
+	if err := console.GetConsoleCursorInfo(fd, &cursorInfo); err != nil {
+		return nil, err
 	}
 
-	infoReset, err := GetConsoleScreenBufferInfo(fd)
+	// An empty title is reported as a failure; treat it as no title
+	titleReset, _ := console.GetConsoleTitle()
+
+	// Text written as bytes is interpreted in the console's output code page
+	codepage, err := console.GetConsoleOutputCP()
 	if err != nil {
-		return nil
+		codepage = CP_UTF8
+	}
+
+	size := windowSize(infoReset.Window)
+
+	h := &WindowsAnsiEventHandler{
+		emulationState: &emulationState{fd: fd, handlers: 1, windowSize: size, inputModes: &InputModes{}},
+		console:        console,
+		writer:         w,
+		codepage:       codepage,
+		infoReset:      infoReset,
+		cursorInfo:     cursorInfo,
+		modeReset:      modeReset,
+		titleReset:     titleReset,
+		runeWidth:      RuneWidth,
+		bell:           BellAudible,
+		logger:         DiscardLogger,
+
+		styleFallbacks: defaultStyleFallbacks(),
+	}
+
+	h.resetState()
+	h.resetTabStops()
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	if h.deferred {
+		if err := h.beginFrame(); err != nil {
+			return nil, err
+		}
+	}
+
+	return h, nil
+}
+
+// NewSharedHandler creates a handler for a second stream to the same console,
+// such as stderr alongside stdout. Text is written to w as described for
+// NewWinEventHandlerForWriter, or through WriteConsoleW if w is nil. The new
+// handler shares h's margins, modes, and character sets, along with its
+// options; only partially written characters are tracked per stream. Any
+// frame being rendered is shared too, and is presented, and the console
+// restored, once all the handlers sharing it are closed.
+//
+// Handlers sharing state must not process events concurrently. Callers
+// driving them from separate goroutines should hold Locker across each call to
+// the parser's Parse.
+func (h *WindowsAnsiEventHandler) NewSharedHandler(w io.Writer) *WindowsAnsiEventHandler {
+	shared := *h
+	shared.closed = false
+	shared.handlers++
+	shared.writer = w
+	shared.utf8Buffer = nil
+	shared.cluster = nil
+	shared.text = nil
+	shared.lastCluster = nil
+	return &shared
+}
+
+// Locker returns the lock shared by handlers created with NewSharedHandler.
+func (h *WindowsAnsiEventHandler) Locker() sync.Locker {
+	return &h.mutex
+}
+
+// Modes reports the terminal modes tracked by the handler.
+type Modes struct {
+	Insert             bool // IRM
+	Origin             bool // DECOM
+	Autowrap           bool // DECAWM
+	LeftRightMargins   bool // DECLRMM
+	ReverseVideo       bool // SGR 7
+	CursorVisible      bool // DECTCEM
+	SynchronizedUpdate bool // Private mode 2026, or WithDeferredUpdates
+}
+
+// CursorPosition returns the cursor position relative to the top left of the
+// window, counting from 0. The console is only queried if the handler's cached
+// state has been invalidated. Text not yet flushed is not accounted for.
+func (h *WindowsAnsiEventHandler) CursorPosition() (COORD, error) {
+	info, err := h.getConsoleScreenBufferInfo()
+	if err != nil {
+		return COORD{}, err
+	}
+
+	return COORD{X: info.CursorPosition.X - info.Window.Left, Y: info.CursorPosition.Y - info.Window.Top}, nil
+}
+
+// Attributes returns the console attributes applied to subsequent text. The
+// console is only queried if the handler's cached state has been invalidated.
+func (h *WindowsAnsiEventHandler) Attributes() (WORD, error) {
+	info, err := h.getConsoleScreenBufferInfo()
+	if err != nil {
+		return 0, err
+	}
+
+	return info.Attributes, nil
+}
+
+// ScrollRegion returns the margins set by DECSTBM and DECSLRM, relative to
+// the top left of the window and counting from 0.
+func (h *WindowsAnsiEventHandler) ScrollRegion() (top int, bottom int, left int, right int) {
+	return h.sr.top, h.sr.bottom, h.sr.left, h.sr.right
+}
+
+// Modes returns the current terminal modes.
+func (h *WindowsAnsiEventHandler) Modes() Modes {
+	return Modes{
+		Insert:             h.insertMode,
+		Origin:             h.originMode,
+		Autowrap:           !h.noAutowrap,
+		LeftRightMargins:   h.lrMargins,
+		ReverseVideo:       h.inverse,
+		CursorVisible:      !h.hideCursor,
+		SynchronizedUpdate: h.front != 0,
 	}
+}
 
-	sr := scrollRegion{int(infoReset.Window.Top), int(infoReset.Window.Bottom)}
+// InputModes returns the input modes set by the application, for sharing with
+// an AnsiReader reading the same console (see WithInputModes).
+func (h *WindowsAnsiEventHandler) InputModes() *InputModes {
+	return h.inputModes
+}
 
-	return &WindowsAnsiEventHandler{
-		fd:        fd,
-		file:      file,
-		infoReset: infoReset,
-		sr:        sr,
+// resetState returns the emulation state tracked by the handler (margins,
+// character sets, and modes) to its initial values.
+func (h *WindowsAnsiEventHandler) resetState() {
+	h.sr = scrollRegion{0, int(h.windowSize.Y) - 1, 0, int(h.windowSize.X) - 1}
+	h.charsets = [4]byte{ANSI_CHARSET_ASCII, ANSI_CHARSET_ASCII, ANSI_CHARSET_ASCII, ANSI_CHARSET_ASCII}
+	h.gl = 0
+	h.insertMode = false
+	h.originMode = false
+	h.noAutowrap = h.modeReset&ENABLE_WRAP_AT_EOL_OUTPUT == 0
+	h.lrMargins = false
+	h.inverse = false
+	h.hideCursor = h.cursorInfo.Visible == 0
+	h.inputModes.reset()
+	h.savedCursor = savedCursor{
+		attributes: h.infoReset.Attributes,
+		charsets:   h.charsets,
 	}
 }
 
+// savedCursor holds the state saved by DECSC and restored by DECRC.
+type savedCursor struct {
+	position   COORD // Relative to the top left of the window
+	attributes WORD
+	inverse    bool
+	originMode bool
+	charsets   [4]byte
+	gl         int
+}
+
+// scrollRegion holds the margins, relative to the top left of the window.
 type scrollRegion struct {
 	top    int
 	bottom int
+	left   int
+	right  int
+}
+
+// getConsoleScreenBufferInfo retrieves the console state, first reacting to
+// any change in window size since the previous call.
+func (h *WindowsAnsiEventHandler) getConsoleScreenBufferInfo() (*CONSOLE_SCREEN_BUFFER_INFO, error) {
+	if h.info == nil {
+		info, err := h.console.GetConsoleScreenBufferInfo(h.fd)
+		if err != nil {
+			return nil, err
+		}
+
+		if h.pinViewport {
+			h.trackViewport(info)
+		}
+
+		if size := windowSize(info.Window); size != h.windowSize {
+			h.resize(h.windowSize, size)
+		}
+
+		h.info = info
+	}
+
+	// Return a copy so callers cannot corrupt the cache
+	info := *h.info
+	return &info, nil
+}
+
+// Invalidate discards the cached console state, so it is re-queried before
+// it is next used. The handler tracks its own changes to the cursor and
+// attributes and invalidates the cache itself after writing text, which may
+// scroll the window. Call Invalidate when something else may have changed the
+// console, e.g. after a window resize event or output from another process.
+func (h *WindowsAnsiEventHandler) Invalidate() {
+	h.info = nil
+}
+
+// setTextAttribute sets the attributes for subsequent text.
+func (h *WindowsAnsiEventHandler) setTextAttribute(attributes WORD) error {
+	if err := h.console.SetConsoleTextAttribute(h.fd, attributes); err != nil {
+		h.Invalidate()
+		return err
+	}
+
+	if h.info != nil {
+		h.info.Attributes = attributes
+	}
+
+	return nil
+}
+
+// resize recomputes the state that depends on the window size. A scroll
+// region covering the whole window keeps covering it; a narrower one is
+// clamped into the new window.
+func (h *WindowsAnsiEventHandler) resize(oldSize COORD, newSize COORD) {
+	h.logger.Infof("resize: %v --> %v", oldSize, newSize)
+
+	lastRow := int(newSize.Y) - 1
+	if h.sr.top == 0 && h.sr.bottom == int(oldSize.Y)-1 || h.sr.bottom > lastRow {
+		h.sr.bottom = lastRow
+	}
+
+	if h.sr.top >= h.sr.bottom {
+		h.sr.top, h.sr.bottom = 0, lastRow
+	}
+
+	lastCol := int(newSize.X) - 1
+	if h.sr.left == 0 && h.sr.right == int(oldSize.X)-1 || h.sr.right > lastCol {
+		h.sr.right = lastCol
+	}
+
+	if h.sr.left >= h.sr.right {
+		h.sr.left, h.sr.right = 0, lastCol
+	}
+
+	h.windowSize = newSize
+	h.resizeTabStops(int(newSize.X))
+
+	if h.onResize != nil {
+		h.onResize(int(newSize.X), int(newSize.Y))
+	}
+}
+
+func windowSize(window SMALL_RECT) COORD {
+	return COORD{X: window.Right - window.Left + 1, Y: window.Bottom - window.Top + 1}
 }
 
 func (h *WindowsAnsiEventHandler) Print(b byte) error {
-	logger.Infof("Print: [%v]", string(b))
+	h.logger.Infof("Print: [%v]", string(b))
 
-	bytes := []byte{b}
+	// Collect bytes until they form a complete (or invalid) UTF-8 sequence;
+	// invalid sequences are written as U+FFFD and the remaining bytes retried.
+	h.utf8Buffer = append(h.utf8Buffer, b)
+	for len(h.utf8Buffer) > 0 && utf8.FullRune(h.utf8Buffer) {
+		r, size := utf8.DecodeRune(h.utf8Buffer)
+		h.utf8Buffer = h.utf8Buffer[size:]
 
-	_, err := h.file.Write(bytes)
-	if err != nil {
+		if err := h.writeRune(TranslateCharset(h.charsets[h.gl], r)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeRune adds r to the grapheme cluster being assembled, writing out the
+// previous cluster once r is known to begin a new one.
+func (h *WindowsAnsiEventHandler) writeRune(r rune) error {
+	if ExtendsCluster(h.cluster, r, h.runeWidth) {
+		h.cluster = append(h.cluster, r)
+		return nil
+	}
+
+	if err := h.flushCluster(); err != nil {
 		return err
 	}
 
+	h.cluster = append(h.cluster, r)
 	return nil
 }
 
+// textBatchSize bounds how many runes of text are buffered before they are
+// written to the console.
+const textBatchSize = 4096
+
+// flushCluster moves the pending grapheme cluster to the text batch as a unit.
+func (h *WindowsAnsiEventHandler) flushCluster() error {
+	if len(h.cluster) == 0 {
+		return nil
+	}
+
+	// Detach the cluster first, wrapping below re-enters Flush via Execute
+	cluster := h.cluster
+	h.cluster = nil
+	h.lastCluster = cluster
+
+	// Wrapping and inserting depend on the cursor position, so the batch
+	// must be written first
+	width := h.runeWidth(cluster[0])
+	if width == 2 && !h.noAutowrap || h.insertMode {
+		if err := h.flushText(); err != nil {
+			return err
+		}
+
+		if width == 2 {
+			if err := h.wrapForWideRune(); err != nil {
+				return err
+			}
+		}
+
+		if h.insertMode {
+			if err := h.insertCharacters(width); err != nil {
+				return err
+			}
+		}
+	}
+
+	h.text = append(h.text, cluster...)
+	if len(h.text) >= textBatchSize {
+		return h.flushText()
+	}
+
+	return nil
+}
+
+// flushText writes the batched text with a single call.
+func (h *WindowsAnsiEventHandler) flushText() error {
+	if len(h.text) == 0 {
+		return nil
+	}
+
+	text := h.text
+	h.text = h.text[:0]
+	return h.writeRunes(text)
+}
+
+// writeRunes writes runes at the cursor, to the writer if there is one and
+// otherwise directly to the console.
+func (h *WindowsAnsiEventHandler) writeRunes(runes []rune) error {
+	// The console moves the cursor and may scroll
+	h.Invalidate()
+
+	// Frames are rendered to the hidden buffer, not the writer
+	if h.writer != nil && h.front == 0 {
+		if h.codepage == CP_UTF8 {
+			if _, err := io.WriteString(h.writer, string(runes)); err != nil {
+				return err
+			}
+
+			return h.restoreViewport()
+		}
+
+		b, err := h.console.WideCharToMultiByte(h.codepage, utf16.Encode(runes))
+		if err != nil {
+			return err
+		}
+
+		if _, err := h.writer.Write(b); err != nil {
+			return err
+		}
+
+		return h.restoreViewport()
+	}
+
+	if err := h.console.WriteConsole(h.fd, utf16.Encode(runes)); err != nil {
+		return err
+	}
+
+	return h.restoreViewport()
+}
+
+// wrapForWideRune moves the cursor to the start of the next line when only
+// a single cell remains, since a double-width character cannot straddle the
+// right edge of the window.
+func (h *WindowsAnsiEventHandler) wrapForWideRune() error {
+	info, err := h.getConsoleScreenBufferInfo()
+	if err != nil {
+		return err
+	}
+
+	if h.noAutowrap || info.CursorPosition.X < info.Window.Right {
+		return nil
+	}
+
+	if err := h.Execute(ANSI_CARRIAGE_RETURN); err != nil {
+		return err
+	}
+
+	return h.Execute(ANSI_LINE_FEED)
+}
+
 func (h *WindowsAnsiEventHandler) Execute(b byte) error {
-	logger.Infof("Execute %#x", b)
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("Execute %#x", b)
+
+	switch b {
+	case ANSI_SHIFT_OUT:
+		h.gl = 1
+		return nil
+	case ANSI_SHIFT_IN:
+		h.gl = 0
+		return nil
+	case ANSI_BEL:
+		return h.ringBell()
+	case ANSI_TAB:
+		return h.moveCursorTab(1)
+	}
 
-	info, err := GetConsoleScreenBufferInfo(h.fd)
+	info, err := h.getConsoleScreenBufferInfo()
 	if err != nil {
 		return err
 	}
 
-	if int(info.CursorPosition.Y) == h.sr.bottom {
+	if info.CursorPosition.Y == info.Window.Top+SHORT(h.sr.bottom) {
 		if ANSI_LINE_FEED == b {
 			// Scroll up one row if we attempt to line feed at the bottom
 			// of the scroll region
-			if err := h.scrollUp(1); err != nil {
+			if err := h.scrollUp(1, h.sr.top); err != nil {
 				return err
 			}
 
-			// Clear line
-			// if err := h.CUD(1); err != nil {
-			// 	return err
-			// }
-			if err := h.EL(0); err != nil {
-				return err
-			}
+			// The cursor stays on the bottom row, so of the new line the
+			// console would start only the carriage return remains
+			b = ANSI_CARRIAGE_RETURN
 		}
 	}
 
 	if ANSI_BEL <= b && b <= ANSI_CARRIAGE_RETURN {
-		return h.Print(b)
+		return h.writeRunes([]rune{rune(b)})
 	}
 
 	return nil
 }
 
 func (h *WindowsAnsiEventHandler) CUU(param int) error {
-	logger.Infof("CUU: [%v]", []string{strconv.Itoa(param)})
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("CUU: [%v]", []string{strconv.Itoa(param)})
 	return h.moveCursorVertical(-param)
 }
 
 func (h *WindowsAnsiEventHandler) CUD(param int) error {
-	logger.Infof("CUD: [%v]", []string{strconv.Itoa(param)})
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("CUD: [%v]", []string{strconv.Itoa(param)})
 	return h.moveCursorVertical(param)
 }
 
 func (h *WindowsAnsiEventHandler) CUF(param int) error {
-	logger.Infof("CUF: [%v]", []string{strconv.Itoa(param)})
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("CUF: [%v]", []string{strconv.Itoa(param)})
 	return h.moveCursorHorizontal(param)
 }
 
 func (h *WindowsAnsiEventHandler) CUB(param int) error {
-	logger.Infof("CUB: [%v]", []string{strconv.Itoa(param)})
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("CUB: [%v]", []string{strconv.Itoa(param)})
 	return h.moveCursorHorizontal(-param)
 }
 
 func (h *WindowsAnsiEventHandler) CNL(param int) error {
-	logger.Infof("CNL: [%v]", []string{strconv.Itoa(param)})
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("CNL: [%v]", []string{strconv.Itoa(param)})
 	return h.moveCursorLine(param)
 }
 
 func (h *WindowsAnsiEventHandler) CPL(param int) error {
-	logger.Infof("CPL: [%v]", []string{strconv.Itoa(param)})
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("CPL: [%v]", []string{strconv.Itoa(param)})
 	return h.moveCursorLine(-param)
 }
 
 func (h *WindowsAnsiEventHandler) CHA(param int) error {
-	logger.Infof("CHA: [%v]", []string{strconv.Itoa(param)})
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("CHA: [%v]", []string{strconv.Itoa(param)})
 	return h.moveCursorColumn(param)
 }
 
 func (h *WindowsAnsiEventHandler) CUP(row int, col int) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
 	rowStr, colStr := strconv.Itoa(row), strconv.Itoa(col)
-	logger.Infof("CUP: [%v]", []string{rowStr, colStr})
-	info, err := GetConsoleScreenBufferInfo(h.fd)
+	h.logger.Infof("CUP: [%v]", []string{rowStr, colStr})
+	info, err := h.getConsoleScreenBufferInfo()
 	if err != nil {
 		return err
 	}
 
 	rect := info.Window
-	rowS := AddInRange(SHORT(row-1), rect.Top, rect.Top, rect.Bottom)
+	top, bottom := h.verticalBounds(rect)
+	rowS := AddInRange(SHORT(row-1), top, top, bottom)
 	colS := AddInRange(SHORT(col-1), rect.Left, rect.Left, rect.Right)
 	position := COORD{colS, rowS}
 
@@ -151,226 +646,1213 @@ func (h *WindowsAnsiEventHandler) CUP(row int, col int) error {
 }
 
 func (h *WindowsAnsiEventHandler) HVP(row int, col int) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
 	rowS, colS := strconv.Itoa(row), strconv.Itoa(row)
-	logger.Infof("HVP: [%v]", []string{rowS, colS})
+	h.logger.Infof("HVP: [%v]", []string{rowS, colS})
 	return h.CUP(row, col)
 }
 
-func (h *WindowsAnsiEventHandler) DECTCEM(visible bool) error {
-	logger.Infof("DECTCEM: [%v]", []string{strconv.FormatBool(visible)})
+func (h *WindowsAnsiEventHandler) VPA(param int) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
 
-	return nil
+	h.logger.Infof("VPA: [%v]", []string{strconv.Itoa(param)})
+	return h.moveCursorRow(param)
 }
 
-func (h *WindowsAnsiEventHandler) ED(param int) error {
-	logger.Infof("ED: [%v]", []string{strconv.Itoa(param)})
+func (h *WindowsAnsiEventHandler) CHT(param int) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
 
-	// [J  -- Erases from the cursor to the end of the screen, including the cursor position.
-	// [1J -- Erases from the beginning of the screen to the cursor, including the cursor position.
-	// [2J -- Erases the complete display. The cursor does not move.
-	// [3J -- Erases the complete display and backing buffer, cursor moves to (0,0)
-	// Notes:
-	// -- ANSI.SYS always moved the cursor to (0,0) for both [2J and [3J
-	// -- Clearing the entire buffer, versus just the Window, works best for Windows Consoles
+	h.logger.Infof("CHT: [%v]", []string{strconv.Itoa(param)})
+	return h.moveCursorTab(param)
+}
 
-	info, err := GetConsoleScreenBufferInfo(h.fd)
-	if err != nil {
+func (h *WindowsAnsiEventHandler) CBT(param int) error {
+	if err := h.Flush(); err != nil {
 		return err
 	}
 
-	var start COORD
-	var end COORD
-
-	switch param {
-	case 0:
-		start = info.CursorPosition
-		end = COORD{info.Size.X - 1, info.Size.Y - 1}
+	h.logger.Infof("CBT: [%v]", []string{strconv.Itoa(param)})
+	return h.moveCursorTab(-param)
+}
 
-	case 1:
-		start = COORD{0, 0}
-		end = info.CursorPosition
+func (h *WindowsAnsiEventHandler) HTS() error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
 
-	case 2:
-		start = COORD{0, 0}
-		end = COORD{info.Size.X - 1, info.Size.Y - 1}
+	h.logger.Infof("HTS: []")
 
-	case 3:
-		start = COORD{0, 0}
-		end = COORD{info.Size.X - 1, info.Size.Y - 1}
+	info, err := h.getConsoleScreenBufferInfo()
+	if err != nil {
+		return err
 	}
 
-	err = h.clearRange(info.Attributes, start, end)
-	if err != nil {
+	h.setTabStop(int(info.CursorPosition.X-info.Window.Left), true)
+	return nil
+}
+
+func (h *WindowsAnsiEventHandler) TBC(param int) error {
+	if err := h.Flush(); err != nil {
 		return err
 	}
 
-	if param == 2 || param == 3 {
-		err = h.setCursorPosition(COORD{0, 0}, info.Size)
+	h.logger.Infof("TBC: [%v]", []string{strconv.Itoa(param)})
+
+	// [g  -- Clears the tab stop at the cursor column.
+	// [3g -- Clears all tab stops.
+	switch param {
+	case 0:
+		info, err := h.getConsoleScreenBufferInfo()
 		if err != nil {
 			return err
 		}
+
+		h.setTabStop(int(info.CursorPosition.X-info.Window.Left), false)
+
+	case 3:
+		for x := range h.tabStops {
+			h.tabStops[x] = false
+		}
 	}
 
 	return nil
 }
 
-func (h *WindowsAnsiEventHandler) EL(param int) error {
-	logger.Infof("EL: [%v]", strconv.Itoa(param))
-
-	// [K  -- Erases from the cursor to the end of the line, including the cursor position.
-	// [1K -- Erases from the beginning of the line to the cursor, including the cursor position.
-	// [2K -- Erases the complete line.
-
-	info, err := GetConsoleScreenBufferInfo(h.fd)
-	if err != nil {
+func (h *WindowsAnsiEventHandler) DECTCEM(visible bool) error {
+	if err := h.Flush(); err != nil {
 		return err
 	}
 
-	var start COORD
-	var end COORD
-
-	switch param {
-	case 0:
-		start = info.CursorPosition
-		end = COORD{info.Size.X, info.CursorPosition.Y}
-
-	case 1:
-		start = COORD{0, info.CursorPosition.Y}
-		end = info.CursorPosition
+	h.logger.Infof("DECTCEM: [%v]", []string{strconv.FormatBool(visible)})
 
-	case 2:
-		start = COORD{0, info.CursorPosition.Y}
-		end = COORD{info.Size.X, info.CursorPosition.Y}
+	var info CONSOLE_CURSOR_INFO
+	if err := h.console.GetConsoleCursorInfo(h.fd, &info); err != nil {
+		return err
 	}
 
-	err = h.clearRange(info.Attributes, start, end)
-	if err != nil {
+	info.Visible = boolToBOOL(visible)
+	if err := h.console.SetConsoleCursorInfo(h.fd, &info); err != nil {
 		return err
 	}
 
+	h.hideCursor = !visible
 	return nil
 }
 
-func (h *WindowsAnsiEventHandler) IL(param int) error {
-	logger.Infof("IL: [%v]", strconv.Itoa(param))
-	if err := h.scrollDown(param); err != nil {
+func (h *WindowsAnsiEventHandler) SynchronizedUpdate(enable bool) error {
+	if err := h.Flush(); err != nil {
 		return err
 	}
 
-	return h.EL(2)
-}
+	h.logger.Infof("SynchronizedUpdate: [%v]", enable)
 
-func (h *WindowsAnsiEventHandler) DL(param int) error {
-	logger.Infof("DL: [%v]", strconv.Itoa(param))
-	return h.scrollUp(param)
+	if enable {
+		return h.beginFrame()
+	}
+
+	// In deferred mode the frame stays open for the next update
+	if h.deferred {
+		return h.Sync()
+	}
+
+	return h.endFrame()
 }
 
-func (h *WindowsAnsiEventHandler) SGR(params []int) error {
-	strings := []string{}
-	for _, v := range params {
-		logger.Infof("SGR: [%v]", strings)
-		strings = append(strings, strconv.Itoa(v))
+func (h *WindowsAnsiEventHandler) DECSCUSR(style int) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("DECSCUSR: [%v]", []string{strconv.Itoa(style)})
+
+	// The console cursor is always a full-width block whose height is a
+	// percentage of the cell, so the styles map onto heights:
+	// -- 0 restores the original cursor, 1/2 select a block, 3/4 an underline,
+	//    and 5/6 a bar, approximated by a half-height block
+	// -- Windows does not expose per-cursor blinking, so blink variants match steady ones
+	var info CONSOLE_CURSOR_INFO
+	if err := h.console.GetConsoleCursorInfo(h.fd, &info); err != nil {
+		return err
+	}
+
+	switch style {
+	case 0:
+		info.Size = h.cursorInfo.Size
+	case 1, 2:
+		info.Size = 100
+	case 3, 4:
+		info.Size = 15
+	case 5, 6:
+		info.Size = 50
+	default:
+		return nil
+	}
+
+	return h.console.SetConsoleCursorInfo(h.fd, &info)
+}
+
+func (h *WindowsAnsiEventHandler) IRM(insert bool) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("IRM: [%v]", []string{strconv.FormatBool(insert)})
+
+	h.insertMode = insert
+	return nil
+}
+
+func (h *WindowsAnsiEventHandler) DECCKM(enable bool) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("DECCKM: [%v]", []string{strconv.FormatBool(enable)})
+	h.inputModes.SetApplicationCursorKeys(enable)
+	return nil
+}
+
+func (h *WindowsAnsiEventHandler) DECKPAM(enable bool) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("DECKPAM: [%v]", []string{strconv.FormatBool(enable)})
+	h.inputModes.SetApplicationKeypad(enable)
+	return nil
+}
+
+func (h *WindowsAnsiEventHandler) MouseMode(mode int, enable bool) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("MouseMode: [%v]", []string{strconv.Itoa(mode), strconv.FormatBool(enable)})
+
+	switch {
+	case mode == 1006:
+		h.inputModes.SetSGRMouse(enable)
+	case enable:
+		h.inputModes.SetMouseTracking(MouseTracking(mode))
+	default:
+		// As in xterm, resetting any tracking mode stops tracking
+		h.inputModes.SetMouseTracking(MouseOff)
+	}
+
+	return nil
+}
+
+func (h *WindowsAnsiEventHandler) BracketedPaste(enable bool) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("BracketedPaste: [%v]", []string{strconv.FormatBool(enable)})
+	h.inputModes.SetBracketedPaste(enable)
+	return nil
+}
+
+func (h *WindowsAnsiEventHandler) Win32InputMode(enable bool) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("Win32InputMode: [%v]", []string{strconv.FormatBool(enable)})
+	h.inputModes.SetWin32Input(enable)
+	return nil
+}
+
+func (h *WindowsAnsiEventHandler) FocusReporting(enable bool) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("FocusReporting: [%v]", []string{strconv.FormatBool(enable)})
+	h.inputModes.SetFocusReporting(enable)
+	return nil
+}
+
+func (h *WindowsAnsiEventHandler) AlternateScreen(mode int, enable bool) error {
+	if err := h.Flush(); err != nil {
+		return err
 	}
 
-	logger.Infof("SGR: [%v]", strings)
+	// The console has a single screen buffer per handler; full-screen
+	// programs draw over the main one, as on consoles without VT support
+	h.logger.Infof("AlternateScreen: [%v]", []string{strconv.Itoa(mode), strconv.FormatBool(enable)})
+	return nil
+}
+
+func (h *WindowsAnsiEventHandler) XTMODKEYS(params []int) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("XTMODKEYS: [%v]", params)
+
+	if params[0] == 4 {
+		h.inputModes.SetModifyOtherKeys(params[1])
+	}
+
+	return nil
+}
+
+func (h *WindowsAnsiEventHandler) KittyKeyboard(op byte, params []int) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("KittyKeyboard: [%c %v]", op, params)
+
+	switch op {
+	case '>':
+		h.inputModes.pushKittyFlags(params[0])
+	case '<':
+		n := params[0]
+		if n == 0 {
+			n = 1
+		}
+		h.inputModes.popKittyFlags(n)
+	case '=':
+		mode := params[1]
+		if mode == 0 {
+			mode = 1
+		}
+		h.inputModes.updateKittyFlags(params[0], mode)
+	case '?':
+		return h.respond(fmt.Sprintf("\x1b[?%du", h.inputModes.KittyFlags()))
+	}
+
+	return nil
+}
+
+func (h *WindowsAnsiEventHandler) DECOM(enable bool) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("DECOM: [%v]", []string{strconv.FormatBool(enable)})
+
+	// Changing origin mode homes the cursor to the new origin
+	h.originMode = enable
+	return h.CUP(1, 1)
+}
+
+func (h *WindowsAnsiEventHandler) DECAWM(enable bool) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("DECAWM: [%v]", []string{strconv.FormatBool(enable)})
 
-	info, err := GetConsoleScreenBufferInfo(h.fd)
+	// With ENABLE_WRAP_AT_EOL_OUTPUT cleared, the console keeps overwriting
+	// the last cell of the line rather than wrapping
+	mode, err := h.console.GetConsoleMode(h.fd)
 	if err != nil {
 		return err
 	}
 
-	attributes := info.Attributes
-	if len(params) <= 0 {
-		attributes = h.infoReset.Attributes
+	if enable {
+		mode |= ENABLE_WRAP_AT_EOL_OUTPUT
 	} else {
-		for _, attr := range params {
+		mode &^= ENABLE_WRAP_AT_EOL_OUTPUT
+	}
 
-			if attr == ANSI_SGR_RESET {
-				attributes = h.infoReset.Attributes
-				continue
-			}
+	if err := h.console.SetConsoleMode(h.fd, mode); err != nil {
+		return err
+	}
 
-			attributes = collectAnsiIntoWindowsAttributes(attributes, h.infoReset.Attributes, SHORT(attr))
+	h.noAutowrap = !enable
+	return nil
+}
+
+func (h *WindowsAnsiEventHandler) ED(param int) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("ED: [%v]", []string{strconv.Itoa(param)})
+
+	// [J  -- Erases from the cursor to the end of the screen, including the cursor position.
+	// [1J -- Erases from the beginning of the screen to the cursor, including the cursor position.
+	// [2J -- Erases the visible window, leaving the scrollback above it intact.
+	// [3J -- Erases the scrollback (the backing buffer outside the window), keeping the window contents.
+	// Notes:
+	// -- ANSI.SYS always moved the cursor to (0,0) for both [2J and [3J; [2J homes it to the window origin
+	// -- WithPreserveScrollback turns [3J into a no-op
+
+	info, err := h.getConsoleScreenBufferInfo()
+	if err != nil {
+		return err
+	}
+
+	bounds := h.eraseBounds(info)
+
+	var start COORD
+	var end COORD
+
+	switch param {
+	case 0:
+		start = info.CursorPosition
+		end = COORD{bounds.Right, bounds.Bottom}
+
+	case 1:
+		start = COORD{bounds.Left, bounds.Top}
+		end = info.CursorPosition
+
+	case 2:
+		start = COORD{bounds.Left, info.Window.Top}
+		end = COORD{bounds.Right, info.Window.Bottom}
+
+	case 3:
+		if h.preserveScrollback {
+			return nil
 		}
+
+		return h.clearScrollback(info)
 	}
 
-	err = SetConsoleTextAttribute(h.fd, attributes)
+	err = h.clearRange(info.Attributes, start, end, bounds)
 	if err != nil {
 		return err
 	}
 
+	if param == 2 {
+		err = h.setCursorPosition(COORD{info.Window.Left, info.Window.Top}, info.Size)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (h *WindowsAnsiEventHandler) SU(param int) error {
-	logger.Infof("SU: [%v]", []string{strconv.Itoa(param)})
-	return h.scrollPageUp()
+func (h *WindowsAnsiEventHandler) EL(param int) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("EL: [%v]", strconv.Itoa(param))
+
+	// [K  -- Erases from the cursor to the end of the line, including the cursor position.
+	// [1K -- Erases from the beginning of the line to the cursor, including the cursor position.
+	// [2K -- Erases the complete line.
+
+	info, err := h.getConsoleScreenBufferInfo()
+	if err != nil {
+		return err
+	}
+
+	bounds := h.eraseBounds(info)
+
+	var start COORD
+	var end COORD
+
+	switch param {
+	case 0:
+		start = info.CursorPosition
+		end = COORD{bounds.Right, info.CursorPosition.Y}
+
+	case 1:
+		start = COORD{bounds.Left, info.CursorPosition.Y}
+		end = info.CursorPosition
+
+	case 2:
+		start = COORD{bounds.Left, info.CursorPosition.Y}
+		end = COORD{bounds.Right, info.CursorPosition.Y}
+	}
+
+	err = h.clearRange(info.Attributes, start, end, bounds)
+	if err != nil {
+		return err
+	}
+
+	return nil
 }
 
-func (h *WindowsAnsiEventHandler) SD(param int) error {
-	logger.Infof("SD: [%v]", []string{strconv.Itoa(param)})
-	return h.scrollPageDown()
+func (h *WindowsAnsiEventHandler) IL(param int) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("IL: [%v]", strconv.Itoa(param))
+	return h.insertDeleteLines(param, true)
 }
 
-func (h *WindowsAnsiEventHandler) DA(params []string) error {
-	logger.Infof("DA: [%v]", params)
+func (h *WindowsAnsiEventHandler) DL(param int) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
 
-	// See the site below for details of the device attributes command
-	// http://vt100.net/docs/vt220-rm/chapter4.html
+	h.logger.Infof("DL: [%v]", strconv.Itoa(param))
+	return h.insertDeleteLines(param, false)
+}
 
-	// First character of first parameter string is '>'
-	if params[0][0] == '>' {
-		// Secondary device attribute request:
-		// Respond with:
-		// "I am a VT220 version 1.0, no options.
-		//                    CSI     >     1     ;     1     0     ;     0     c    CR    LF
-		bytes := []byte{CSI_ENTRY, 0x3E, 0x31, 0x3B, 0x31, 0x30, 0x3B, 0x30, 0x63, 0x0D, 0x0A}
+func (h *WindowsAnsiEventHandler) SGR(params []int) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
 
-		for _, b := range bytes {
-			h.Print(b)
-		}
+	strings := []string{}
+	for _, v := range params {
+		h.logger.Infof("SGR: [%v]", strings)
+		strings = append(strings, strconv.Itoa(v))
+	}
+
+	h.logger.Infof("SGR: [%v]", strings)
+
+	info, err := h.getConsoleScreenBufferInfo()
+	if err != nil {
+		return err
+	}
+
+	// Colors are tracked as if not inverted, so changes while reverse video
+	// is active update the intended plane
+	attributes := info.Attributes
+	if h.inverse {
+		attributes = invertAttributes(attributes)
+	}
+
+	if len(params) <= 0 {
+		attributes = h.infoReset.Attributes
+		h.inverse = false
 	} else {
-		// Primary device attribute request:
-		// Respond with:
-		// "I am a service class 2 terminal (62) with 132 columns (1),
-		// printer port (2), selective erase (6), DRCS (7), UDK (8),
-		// and I support 7-bit national replacement character sets (9)."
-		//                    CSI     ?     6     2     ;     1     ;     2     ;     6     ;     7     ;     8     ;     9     c    CR    LF
-		bytes := []byte{CSI_ENTRY, 0x3F, 0x36, 0x32, 0x3B, 0x31, 0x3B, 0x32, 0x3B, 0x36, 0x3B, 0x37, 0x3B, 0x38, 0x3B, 0x39, 0x63, 0x0D, 0x0A}
+		for i := 0; i < len(params); i++ {
+			attr := params[i]
+
+			switch attr {
+			case ANSI_SGR_RESET:
+				attributes = h.infoReset.Attributes
+				h.inverse = false
+				continue
+			case ANSI_SGR_REVERSE:
+				h.inverse = true
+				continue
+			case ANSI_SGR_REVERSE_OFF:
+				h.inverse = false
+				continue
+			case 38, 48:
+				// 256 color and 24-bit colors are downsampled to the
+				// console's 16
+				color, n := ExtendedColor(params[i+1:])
+				if n == 0 {
+					i = len(params)
+					continue
+				}
+				i += n
+				attributes = colorIntoWindowsAttributes(attributes, color, attr == 48)
+				continue
+			}
+
+			attributes = collectAnsiIntoWindowsAttributes(attributes, h.infoReset.Attributes, SHORT(attr))
 
-		for _, b := range bytes {
-			h.Print(b)
+			if style, on, ok := sgrStyle(SHORT(attr)); ok {
+				attributes = applyStyleFallback(attributes, h.styleFallbacks[style], on)
+			}
 		}
 	}
 
+	if h.inverse {
+		attributes = invertAttributes(attributes)
+	}
+
+	err = h.setTextAttribute(attributes)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
-func (h *WindowsAnsiEventHandler) DECSTBM(top int, bottom int) error {
-	logger.Infof("DECSTBM: [%d, %d]", top, bottom)
+func (h *WindowsAnsiEventHandler) REP(param int) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
 
-	// Windows is 0 indexed, Linux is 1 indexed
-	h.sr.top = top - 1
-	h.sr.bottom = bottom - 1
+	h.logger.Infof("REP: [%v]", []string{strconv.Itoa(param)})
 
-	return nil
-}
+	if len(h.lastCluster) == 0 {
+		return nil
+	}
 
-func (h *WindowsAnsiEventHandler) RI() error {
-	logger.Info("RI: []")
+	if param < 1 {
+		param = 1
+	}
 
-	info, err := GetConsoleScreenBufferInfo(h.fd)
+	// Repeats beyond the cells left before the bottom margin only scroll
+	// the same character through it, so a huge count from the peer cannot
+	// keep the handler writing
+	info, err := h.getConsoleScreenBufferInfo()
 	if err != nil {
 		return err
 	}
 
-	if info.Window.Top == info.CursorPosition.Y {
-		if err := h.scrollPageDown(); err != nil {
+	_, bottom := h.verticalMoveBounds(info.Window, info.CursorPosition.Y)
+	if cells := int(bottom-info.CursorPosition.Y)*int(info.Size.X) + int(info.Size.X-info.CursorPosition.X); param > cells {
+		param = cells
+	}
+
+	// Clusters, wide characters, and insertion need the print path
+	r := h.lastCluster[0]
+	if len(h.lastCluster) > 1 || h.runeWidth(r) != 1 || r > 0xFFFF || h.insertMode {
+		cluster := h.lastCluster
+		for i := 0; i < param; i++ {
+			for _, r := range cluster {
+				if err := h.writeRune(r); err != nil {
+					return err
+				}
+			}
+		}
+		return h.Flush()
+	}
+
+	for param > 0 {
+		info, err := h.getConsoleScreenBufferInfo()
+		if err != nil {
 			return err
 		}
 
-		return h.EL(2)
-	} else {
-		return h.CUU(1)
-	}
+		// Without autowrap, the excess overwrites the last column
+		remaining := int(info.Size.X - info.CursorPosition.X)
+		if h.noAutowrap && param > remaining {
+			param = remaining
+		}
+
+		// Fill the run within the current row, but leave writing the last
+		// column to the console so that it wraps and scrolls as usual
+		n := param
+		if n >= remaining {
+			n = remaining - 1
+		}
+
+		if n > 0 {
+			if err := h.console.FillConsoleOutputCharacter(h.fd, WCHAR(r), uint32(n), info.CursorPosition); err != nil {
+				return err
+			}
+			if err := h.console.FillConsoleOutputAttribute(h.fd, info.Attributes, uint32(n), info.CursorPosition); err != nil {
+				return err
+			}
+
+			position := COORD{X: info.CursorPosition.X + SHORT(n), Y: info.CursorPosition.Y}
+			if err := h.setCursorPosition(position, info.Size); err != nil {
+				return err
+			}
+
+			param -= n
+		}
+
+		if param > 0 {
+			if err := h.writeRunes([]rune{r}); err != nil {
+				return err
+			}
+			param--
+		}
+	}
+
+	return nil
+}
+
+func (h *WindowsAnsiEventHandler) SU(param int) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("SU: [%v]", []string{strconv.Itoa(param)})
+	return h.scrollUp(param, h.sr.top)
+}
+
+func (h *WindowsAnsiEventHandler) SD(param int) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("SD: [%v]", []string{strconv.Itoa(param)})
+	return h.scrollDown(param, h.sr.top)
+}
+
+func (h *WindowsAnsiEventHandler) DA(params []string) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("DA: [%v]", params)
+
+	// See the site below for details of the device attributes command
+	// http://vt100.net/docs/vt220-rm/chapter4.html
+
+	// First character of first parameter string is '>'
+	if params[0][0] == '>' {
+		// Secondary device attribute request:
+		// Respond with:
+		// "I am a VT220 version 1.0, no options.
+		//                    CSI     >     1     ;     1     0     ;     0     c    CR    LF
+		bytes := []byte{CSI_ENTRY, 0x3E, 0x31, 0x3B, 0x31, 0x30, 0x3B, 0x30, 0x63, 0x0D, 0x0A}
+		return h.respond(string(bytes))
+	}
+
+	// Primary device attribute request:
+	// Respond with:
+	// "I am a service class 2 terminal (62) with 132 columns (1),
+	// printer port (2), selective erase (6), DRCS (7), UDK (8),
+	// and I support 7-bit national replacement character sets (9)."
+	//                    CSI     ?     6     2     ;     1     ;     2     ;     6     ;     7     ;     8     ;     9     c    CR    LF
+	bytes := []byte{CSI_ENTRY, 0x3F, 0x36, 0x32, 0x3B, 0x31, 0x3B, 0x32, 0x3B, 0x36, 0x3B, 0x37, 0x3B, 0x38, 0x3B, 0x39, 0x63, 0x0D, 0x0A}
+	return h.respond(string(bytes))
+}
+
+func (h *WindowsAnsiEventHandler) DSR(param int) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("DSR: [%v]", []string{strconv.Itoa(param)})
+
+	switch param {
+	case 5:
+		// Operating status: always OK
+		return h.respond("\x1b[0n")
+	case 6:
+		// Cursor position report, relative to the margins in origin mode
+		info, err := h.getConsoleScreenBufferInfo()
+		if err != nil {
+			return err
+		}
+
+		top, _ := h.verticalBounds(info.Window)
+		return h.respond(fmt.Sprintf("\x1b[%d;%dR", info.CursorPosition.Y-top+1, info.CursorPosition.X-info.Window.Left+1))
+	}
+
+	return nil
+}
+
+func (h *WindowsAnsiEventHandler) DECSTBM(top int, bottom int) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("DECSTBM: [%d, %d]", top, bottom)
+
+	if _, err := h.getConsoleScreenBufferInfo(); err != nil {
+		return err
+	}
+
+	// Omitted (zero) margins default to the edges of the window
+	height := int(h.windowSize.Y)
+	if top < 1 {
+		top = 1
+	}
+
+	if bottom < 1 || bottom > height {
+		bottom = height
+	}
+
+	// The region must span at least two lines, otherwise the request is ignored
+	if top >= bottom {
+		return nil
+	}
+
+	// Windows is 0 indexed, Linux is 1 indexed
+	h.sr.top = top - 1
+	h.sr.bottom = bottom - 1
+
+	// Setting the margins homes the cursor (to the region origin in origin mode)
+	return h.CUP(1, 1)
+}
+
+func (h *WindowsAnsiEventHandler) DECLRMM(enable bool) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("DECLRMM: [%v]", []string{strconv.FormatBool(enable)})
+
+	// Leaving left/right margin mode resets the margins to the window edges
+	h.lrMargins = enable
+	if !enable {
+		h.sr.left, h.sr.right = 0, int(h.windowSize.X)-1
+	}
+
+	return nil
+}
+
+func (h *WindowsAnsiEventHandler) DECSLRM(left int, right int) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("DECSLRM: [%d, %d]", left, right)
+
+	if !h.lrMargins {
+		return nil
+	}
+
+	if _, err := h.getConsoleScreenBufferInfo(); err != nil {
+		return err
+	}
+
+	// Omitted (zero) margins default to the edges of the window
+	width := int(h.windowSize.X)
+	if left < 1 {
+		left = 1
+	}
+
+	if right < 1 || right > width {
+		right = width
+	}
+
+	// The region must span at least two columns, otherwise the request is ignored
+	if left >= right {
+		return nil
+	}
+
+	h.sr.left = left - 1
+	h.sr.right = right - 1
+
+	return h.CUP(1, 1)
+}
+
+func (h *WindowsAnsiEventHandler) DECRQSS(setting string) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("DECRQSS: [%v]", setting)
+
+	// See http://vt100.net/docs/vt510-rm/DECRQSS
+	// Respond with DCS 1 $ r <setting> ST for supported settings, and
+	// DCS 0 $ r ST for anything else
+	var response string
+	switch setting {
+	case "r":
+		response = fmt.Sprintf("\x1bP1$r%d;%dr\x1b\\", h.sr.top+1, h.sr.bottom+1)
+	case "s":
+		response = fmt.Sprintf("\x1bP1$r%d;%ds\x1b\\", h.sr.left+1, h.sr.right+1)
+	default:
+		response = "\x1bP0$r\x1b\\"
+	}
+
+	return h.respond(response)
+}
+
+// termcap is the capabilities reported by XTGETTCAP. 256 color and 24-bit
+// colors are downsampled to the console's 16, so only those are reported,
+// for applications to choose colors the console shows as intended.
+var termcap = map[string]string{
+	"Co":     "16",
+	"colors": "16",
+	"TN":     "xterm",
+	"name":   "xterm",
+}
+
+func (h *WindowsAnsiEventHandler) XTGETTCAP(names []string) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("XTGETTCAP: %v", names)
+
+	// See http://invisible-island.net/xterm/ctlseqs/ctlseqs.html#h3-Device-Control-functions
+	// Respond with DCS 1 + r name = value ST for each supported capability,
+	// and DCS 0 + r name ST for anything else
+	for _, name := range names {
+		if err := h.respond(TermcapReply(termcap, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *WindowsAnsiEventHandler) XTWINOPS(params []int) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("XTWINOPS: %v", params)
+
+	// See http://invisible-island.net/xterm/ctlseqs/ctlseqs.html#h2-Functions-using-CSI-_-ordered-by-the-final-character_s_
+	// [8;r;ct -- Resizes the window to r rows and c columns (0 keeps the current size), with WithAllowResize.
+	// [14t    -- Reports the window size in pixels as CSI 4 ; height ; width t.
+	// [18t    -- Reports the window size in characters as CSI 8 ; rows ; columns t.
+	// [19t    -- Reports the largest possible window size in characters as CSI 9 ; rows ; columns t.
+	// Other operations are ignored.
+	info, err := h.getConsoleScreenBufferInfo()
+	if err != nil {
+		return err
+	}
+
+	size := windowSize(info.Window)
+
+	switch params[0] {
+	case 8:
+		if !h.allowResize {
+			return nil
+		}
+		return h.resizeConsole(info, params[1], params[2])
+
+	case 14:
+		font, err := h.console.GetCurrentConsoleFont(h.fd)
+		if err != nil {
+			return err
+		}
+		return h.respond(fmt.Sprintf("\x1b[4;%d;%dt", int(size.Y)*int(font.FontSize.Y), int(size.X)*int(font.FontSize.X)))
+
+	case 18:
+		return h.respond(fmt.Sprintf("\x1b[8;%d;%dt", size.Y, size.X))
+
+	case 19:
+		largest, err := h.console.GetLargestConsoleWindowSize(h.fd)
+		if err != nil {
+			return err
+		}
+		return h.respond(fmt.Sprintf("\x1b[9;%d;%dt", largest.Y, largest.X))
+	}
+
+	return nil
+}
+
+// respond sends a response to a request from the application, to the writer
+// set by WithResponseWriter or else back through the console.
+func (h *WindowsAnsiEventHandler) respond(response string) error {
+	if h.responses != nil {
+		_, err := io.WriteString(h.responses, response)
+		return err
+	}
+
+	for _, b := range []byte(response) {
+		if err := h.Print(b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (h *WindowsAnsiEventHandler) RI() error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("RI: []")
+
+	info, err := h.getConsoleScreenBufferInfo()
+	if err != nil {
+		return err
+	}
+
+	// Scroll down one row if we attempt to reverse index at the top of the
+	// scroll region
+	if info.CursorPosition.Y == info.Window.Top+SHORT(h.sr.top) {
+		return h.scrollDown(1, h.sr.top)
+	}
+
+	return h.CUU(1)
+}
+
+func (h *WindowsAnsiEventHandler) RIS() error {
+	h.logger.Infof("RIS: []")
+
+	// Discard, rather than flush, any incomplete character
+	h.utf8Buffer = nil
+	h.cluster = nil
+	h.lastCluster = nil
+	if err := h.flushText(); err != nil {
+		return err
+	}
+
+	if err := h.restoreConsole(); err != nil {
+		return err
+	}
+
+	if _, err := h.getConsoleScreenBufferInfo(); err != nil {
+		return err
+	}
+
+	h.resetState()
+	h.resetTabStops()
+	h.sgrStack = nil
+	return h.ED(2)
+}
+
+func (h *WindowsAnsiEventHandler) DECSTR() error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("DECSTR: []")
+
+	// See http://vt100.net/docs/vt220-rm/table4-10.html
+	// Unlike RIS, the display is left intact. Autowrap returns to the
+	// console's initial setting rather than the VT220's "off".
+	mode, err := h.console.GetConsoleMode(h.fd)
+	if err != nil {
+		return err
+	}
+
+	mode = (mode &^ ENABLE_WRAP_AT_EOL_OUTPUT) | (h.modeReset & ENABLE_WRAP_AT_EOL_OUTPUT)
+	if err := h.console.SetConsoleMode(h.fd, mode); err != nil {
+		return err
+	}
+
+	var cursorInfo CONSOLE_CURSOR_INFO
+	if err := h.console.GetConsoleCursorInfo(h.fd, &cursorInfo); err != nil {
+		return err
+	}
+
+	cursorInfo.Visible = boolToBOOL(true)
+	if err := h.console.SetConsoleCursorInfo(h.fd, &cursorInfo); err != nil {
+		return err
+	}
+
+	if err := h.setTextAttribute(h.infoReset.Attributes); err != nil {
+		return err
+	}
+
+	if _, err := h.getConsoleScreenBufferInfo(); err != nil {
+		return err
+	}
+
+	h.resetState()
+	h.hideCursor = false
+	return nil
+}
+
+func (h *WindowsAnsiEventHandler) XTPUSHSGR(params []int) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("XTPUSHSGR: %v", params)
+
+	info, err := h.getConsoleScreenBufferInfo()
+	if err != nil {
+		return err
+	}
+
+	if len(h.sgrStack) < maxSGRStack {
+		attributes := info.Attributes
+		if h.inverse {
+			attributes = invertAttributes(attributes)
+		}
+		h.sgrStack = append(h.sgrStack, pushAttributes(attributes, h.inverse, params))
+	}
+	return nil
+}
+
+func (h *WindowsAnsiEventHandler) XTPOPSGR() error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("XTPOPSGR: []")
+
+	if len(h.sgrStack) == 0 {
+		return nil
+	}
+
+	info, err := h.getConsoleScreenBufferInfo()
+	if err != nil {
+		return err
+	}
+
+	// As in SGR, colors are restored as if not inverted
+	attributes := info.Attributes
+	if h.inverse {
+		attributes = invertAttributes(attributes)
+	}
+
+	attributes, h.inverse = h.sgrStack[len(h.sgrStack)-1].pop(attributes, h.inverse)
+	h.sgrStack = h.sgrStack[:len(h.sgrStack)-1]
+
+	if h.inverse {
+		attributes = invertAttributes(attributes)
+	}
+	return h.setTextAttribute(attributes)
+}
+
+func (h *WindowsAnsiEventHandler) DECSC() error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("DECSC: []")
+
+	info, err := h.getConsoleScreenBufferInfo()
+	if err != nil {
+		return err
+	}
+
+	h.savedCursor = savedCursor{
+		position: COORD{
+			X: info.CursorPosition.X - info.Window.Left,
+			Y: info.CursorPosition.Y - info.Window.Top,
+		},
+		attributes: info.Attributes,
+		inverse:    h.inverse,
+		originMode: h.originMode,
+		charsets:   h.charsets,
+		gl:         h.gl,
+	}
+
+	return nil
+}
+
+func (h *WindowsAnsiEventHandler) DECRC() error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("DECRC: []")
+
+	info, err := h.getConsoleScreenBufferInfo()
+	if err != nil {
+		return err
+	}
+
+	saved := h.savedCursor
+	h.originMode = saved.originMode
+	h.inverse = saved.inverse
+	h.charsets = saved.charsets
+	h.gl = saved.gl
+
+	if err := h.setTextAttribute(saved.attributes); err != nil {
+		return err
+	}
+
+	position := COORD{
+		X: AddInRange(saved.position.X, info.Window.Left, info.Window.Left, info.Window.Right),
+		Y: AddInRange(saved.position.Y, info.Window.Top, info.Window.Top, info.Window.Bottom),
+	}
+
+	return h.setCursorPosition(position, info.Size)
+}
+
+func (h *WindowsAnsiEventHandler) DECALN() error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("DECALN: []")
+
+	info, err := h.getConsoleScreenBufferInfo()
+	if err != nil {
+		return err
+	}
+
+	// Fill row by row since the window may be narrower than the buffer
+	width := uint32(info.Window.Right - info.Window.Left + 1)
+	for y := info.Window.Top; y <= info.Window.Bottom; y++ {
+		start := COORD{X: info.Window.Left, Y: y}
+		if err := h.console.FillConsoleOutputCharacter(h.fd, 'E', width, start); err != nil {
+			return err
+		}
+		if err := h.console.FillConsoleOutputAttribute(h.fd, info.Attributes, width, start); err != nil {
+			return err
+		}
+	}
+
+	// DECALN also resets the margins and homes the cursor
+	h.sr = scrollRegion{0, int(h.windowSize.Y) - 1, 0, int(h.windowSize.X) - 1}
+	h.originMode = false
+	return h.setCursorPosition(COORD{X: info.Window.Left, Y: info.Window.Top}, info.Size)
+}
+
+func (h *WindowsAnsiEventHandler) SCS(set int, charset byte) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("SCS: [%d, %c]", set, charset)
+
+	if set < 0 || len(h.charsets) <= set {
+		return nil
+	}
+
+	h.charsets[set] = charset
+	return nil
+}
+
+func (h *WindowsAnsiEventHandler) OSC(command int, text string) error {
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	h.logger.Infof("OSC: [%d, %q]", command, text)
+
+	// OSC 0 sets the icon name and window title, OSC 2 just the title; the
+	// console has no icon name, so both set its title
+	switch command {
+	case 0, 2:
+		return h.console.SetConsoleTitle(text)
+	}
+
+	return nil
+}
+
+func (h *WindowsAnsiEventHandler) Flush() error {
+	if err := h.flushCluster(); err != nil {
+		return err
+	}
+
+	return h.flushText()
+}
+
+// Close writes any pending output, presents any frame being rendered, and
+// restores the console modes, cursor visibility and shape, text attributes,
+// and title captured when the handler was created. Handlers created with
+// NewSharedHandler only write their pending output until the last of them is
+// closed. Further calls do nothing.
+func (h *WindowsAnsiEventHandler) Close() error {
+	if h.closed {
+		return nil
+	}
+
+	if err := h.Flush(); err != nil {
+		return err
+	}
+
+	if h.handlers > 1 {
+		h.handlers--
+		h.closed = true
+		return nil
+	}
+
+	if err := h.endFrame(); err != nil {
+		return err
+	}
+
+	h.handlers--
+	h.closed = true
+	return h.restoreConsole()
+}
+
+// restoreConsole returns the console state the handler may have changed to
+// what was captured when the handler was created.
+func (h *WindowsAnsiEventHandler) restoreConsole() error {
+	if err := h.console.SetConsoleMode(h.fd, h.modeReset); err != nil {
+		return err
+	}
+
+	cursorInfo := h.cursorInfo
+	if err := h.console.SetConsoleCursorInfo(h.fd, &cursorInfo); err != nil {
+		return err
+	}
+
+	if err := h.setTextAttribute(h.infoReset.Attributes); err != nil {
+		return err
+	}
+
+	if h.titleReset != "" {
+		if err := h.console.SetConsoleTitle(h.titleReset); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,421 @@
+package winterm_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/Azure/go-ansiterm"
+	"github.com/Azure/go-ansiterm/winterm"
+	"github.com/Azure/go-ansiterm/winterm/wintermtest"
+)
+
+// testTerminal renders output with a handler on an in-memory console.
+type testTerminal struct {
+	t       *testing.T
+	console *wintermtest.Console
+	handler *winterm.WindowsAnsiEventHandler
+	parser  *ansiterm.AnsiParser
+}
+
+func newTestTerminal(t *testing.T, console *wintermtest.Console, opts ...winterm.HandlerOption) *testTerminal {
+	t.Helper()
+
+	handler, err := winterm.NewConsoleEventHandler(console, console.Handle(), nil, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testTerminal{
+		t:       t,
+		console: console,
+		handler: handler,
+		parser:  ansiterm.CreateParser("Ground", handler, ansiterm.WithUTF8()),
+	}
+}
+
+func (tt *testTerminal) write(output string) {
+	tt.t.Helper()
+
+	if _, err := tt.parser.Parse([]byte(output)); err != nil {
+		tt.t.Fatalf("parsing %q: %v", output, err)
+	}
+}
+
+// expect checks the window's contents and the cursor position within it.
+func (tt *testTerminal) expect(lines []string, x int, y int) {
+	tt.t.Helper()
+
+	if screen := tt.console.Screen(tt.console.Handle()); !reflect.DeepEqual(screen, lines) {
+		tt.t.Errorf("screen is %q, expected %q", screen, lines)
+	}
+
+	info, err := tt.console.GetConsoleScreenBufferInfo(tt.console.Handle())
+	if err != nil {
+		tt.t.Fatal(err)
+	}
+
+	cursor := winterm.COORD{X: info.CursorPosition.X - info.Window.Left, Y: info.CursorPosition.Y - info.Window.Top}
+	if expected := (winterm.COORD{X: winterm.SHORT(x), Y: winterm.SHORT(y)}); cursor != expected {
+		tt.t.Errorf("cursor is at %+v, expected %+v", cursor, expected)
+	}
+}
+
+func TestWinEventHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		lines  []string // Of a 10 by 4 window
+		x, y   int
+	}{
+		{"Print", "hello\r\nworld", []string{"hello", "world", "", ""}, 5, 1},
+		{"Wrap", "0123456789ab", []string{"0123456789", "ab", "", ""}, 2, 1},
+		{"ScrollAtBottom", "a\r\nb\r\nc\r\nd\r\ne", []string{"b", "c", "d", "e"}, 1, 3},
+		{"CUP", "\x1b[2;3Hx\x1b[Hy", []string{"y", "  x", "", ""}, 1, 0},
+		{"CUPClamped", "\x1b[99;99H", []string{"", "", "", ""}, 9, 3},
+		{"CursorMovement", "\x1b[3;5H\x1b[Aa\x1b[2Db\x1b[Bc\x1b[3Cd", []string{"", "   ba", "    c   d", ""}, 9, 2},
+		{"ED", "ab\r\ncd\r\nef\x1b[2;2H\x1b[J", []string{"ab", "c", "", ""}, 1, 1},
+		{"EDAbove", "ab\r\ncd\r\nef\x1b[2;2H\x1b[1J", []string{"", "", "ef", ""}, 1, 1},
+		{"EL", "abcdef\x1b[4G\x1b[K", []string{"abc", "", "", ""}, 3, 0},
+		{"ELStart", "abcdef\x1b[4G\x1b[1K", []string{"    ef", "", "", ""}, 3, 0},
+		{"IL", "a\r\nb\r\nc\r\nd\x1b[2H\x1b[L", []string{"a", "", "b", "c"}, 0, 1},
+		{"DL", "a\r\nb\r\nc\r\nd\x1b[2H\x1b[2M", []string{"a", "d", "", ""}, 0, 1},
+		{"DECSTBM", "top\x1b[2;3r\x1b[3H1\r\n2\r\n3", []string{"top", "2", "3", ""}, 1, 2},
+		{"DECSTBMReverseIndex", "a\r\nb\r\nc\r\nd\x1b[2;3r\x1b[2H\x1bM", []string{"a", "", "b", "d"}, 0, 1},
+		{"DECSTBMOrigin", "\x1b[2;3r\x1b[?6h\x1b[Hx\x1b[9Hy", []string{"", "x", "y", ""}, 1, 2},
+		{"CUDAboveRegion", "\x1b[2;3r\x1b[1;1H\x1b[99B", []string{"", "", "", ""}, 0, 2},
+		{"CUDInRegion", "\x1b[2;3r\x1b[2;1H\x1b[99B", []string{"", "", "", ""}, 0, 2},
+		{"CUDBelowRegion", "\x1b[2;3r\x1b[4;1H\x1b[99B", []string{"", "", "", ""}, 0, 3},
+		{"CUUAboveRegion", "\x1b[2;3r\x1b[1;1H\x1b[99A", []string{"", "", "", ""}, 0, 0},
+		{"CUUInRegion", "\x1b[2;3r\x1b[3;1H\x1b[99A", []string{"", "", "", ""}, 0, 1},
+		{"CUUBelowRegion", "\x1b[2;3r\x1b[4;1H\x1b[99A", []string{"", "", "", ""}, 0, 1},
+		{"CNLAboveRegion", "\x1b[2;3r\x1b[1;5H\x1b[99E", []string{"", "", "", ""}, 0, 2},
+		{"CPLBelowRegion", "\x1b[2;3r\x1b[4;5H\x1b[99F", []string{"", "", "", ""}, 0, 1},
+		{"DECSLRM", "abcd\r\nefgh\r\nijkl\x1b[?69h\x1b[2;3s\x1b[2;2H\x1b[L", []string{"abcd", "e  h", "ifgl", " jk"}, 1, 1},
+		{"DECAWM", "\x1b[?7l0123456789ab", []string{"012345678b", "", "", ""}, 9, 0},
+		{"IRM", "abc\x1b[1G\x1b[4hxy", []string{"xyabc", "", "", ""}, 2, 0},
+		{"Tab", "a\tb", []string{"a       b", "", "", ""}, 9, 0},
+		{"DECSCDECRC", "ab\x1b7\x1b[3;3Hx\x1b8c", []string{"abc", "", "  x", ""}, 3, 0},
+		{"REP", "ab\x1b[3b", []string{"abbbb", "", "", ""}, 5, 0},
+		{"REPClamped", "\x1b[4;5Hab\x1b[999999999b", []string{"", "", "    abbbbb", ""}, 0, 3},
+		{"WideWrap", "012345678世", []string{"012345678", "世", "", ""}, 2, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tt := newTestTerminal(t, wintermtest.NewConsole(10, 4))
+			tt.write(test.output)
+			tt.expect(test.lines, test.x, test.y)
+		})
+	}
+}
+
+func TestWinEventHandlerAttributes(t *testing.T) {
+	tt := newTestTerminal(t, wintermtest.NewConsole(10, 4))
+	tt.write("\x1b[31;1;44ma\x1b[0mb")
+
+	expected := winterm.FOREGROUND_RED | winterm.FOREGROUND_INTENSITY | winterm.BACKGROUND_BLUE
+	if cell := tt.console.Cell(tt.console.Handle(), 0, 0); cell.Attributes != expected {
+		t.Errorf("attributes of a are %#x, expected %#x", cell.Attributes, expected)
+	}
+
+	initial := winterm.FOREGROUND_RED | winterm.FOREGROUND_GREEN | winterm.FOREGROUND_BLUE
+	if cell := tt.console.Cell(tt.console.Handle(), 1, 0); cell.Attributes != initial {
+		t.Errorf("attributes of b are %#x, expected %#x", cell.Attributes, initial)
+	}
+}
+
+func TestWinEventHandlerSGRStack(t *testing.T) {
+	tt := newTestTerminal(t, wintermtest.NewConsole(10, 4))
+
+	// All attributes are pushed by default, reverse video included; only
+	// the background when selected
+	tt.write("\x1b[31;44m\x1b[#{\x1b[0;32;7ma\x1b[#}b\x1b[11#{\x1b[33;42mc\x1b[#}d")
+
+	expected := []winterm.WORD{
+		winterm.FOREGROUND_RED | winterm.BACKGROUND_BLUE,
+		winterm.FOREGROUND_RED | winterm.FOREGROUND_GREEN | winterm.BACKGROUND_GREEN,
+		winterm.FOREGROUND_RED | winterm.FOREGROUND_GREEN | winterm.BACKGROUND_BLUE,
+	}
+	for i, attributes := range expected {
+		if cell := tt.console.Cell(tt.console.Handle(), i+1, 0); cell.Attributes != attributes {
+			t.Errorf("attributes of %c are %#x, expected %#x", "bcd"[i], cell.Attributes, attributes)
+		}
+	}
+}
+
+func TestWinEventHandlerScrollback(t *testing.T) {
+	for _, windowErase := range []bool{false, true} {
+		// A window of 4 rows onto a buffer of 10, with a line of
+		// scrollback above it
+		console := wintermtest.NewConsole(10, 10)
+		if err := console.WriteConsole(console.Handle(), utf16.Encode([]rune("history"))); err != nil {
+			t.Fatal(err)
+		}
+		if err := console.SetConsoleWindowInfo(console.Handle(), true, winterm.SMALL_RECT{Top: 3, Right: 9, Bottom: 6}); err != nil {
+			t.Fatal(err)
+		}
+
+		var opts []winterm.HandlerOption
+		if windowErase {
+			opts = append(opts, winterm.WithWindowErase())
+		}
+		tt := newTestTerminal(t, console, opts...)
+
+		// The cursor is addressed relative to the window
+		tt.write("\x1b[Ha\r\nb\r\nc\r\nd\r\ne")
+		tt.expect([]string{"b", "c", "d", "e"}, 1, 3)
+
+		// Erasing above the cursor erases the scrollback too, unless
+		// confined to the window
+		tt.write("\x1b[1J")
+		expected := ""
+		if windowErase {
+			expected = "history"
+		}
+		if line := console.Line(console.Handle(), 0); line != expected {
+			t.Errorf("with window erase %v, the scrollback is %q after ED, expected %q", windowErase, line, expected)
+		}
+	}
+}
+
+func TestWinEventHandlerResize(t *testing.T) {
+	tt := newTestTerminal(t, wintermtest.NewConsole(10, 4), winterm.WithAllowResize())
+	tt.write("\x1b[8;6;20t\x1b[6;20Hx")
+
+	info, err := tt.console.GetConsoleScreenBufferInfo(tt.console.Handle())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := (winterm.SMALL_RECT{Right: 19, Bottom: 5}); info.Window != expected {
+		t.Errorf("window is %+v, expected %+v", info.Window, expected)
+	}
+
+	// The margins are reset to the new size
+	if _, bottom, _, right := tt.handler.ScrollRegion(); bottom != 5 || right != 19 {
+		t.Errorf("scroll region extends to row %d, column %d, expected 5, 19", bottom, right)
+	}
+}
+
+func TestWinEventHandlerResponses(t *testing.T) {
+	var responses bytes.Buffer
+	tt := newTestTerminal(t, wintermtest.NewConsole(10, 4), winterm.WithResponseWriter(&responses))
+	tt.write("\x1b[2;3H\x1b[6n\x1b[18t\x1bP+q436F;524742\x1b\\")
+
+	if expected := "\x1b[2;3R\x1b[8;4;10t\x1bP1+r436F=3136\x1b\\\x1bP0+r524742\x1b\\"; responses.String() != expected {
+		t.Errorf("responses are %q, expected %q", responses.String(), expected)
+	}
+}
+
+func TestWinEventHandlerTitle(t *testing.T) {
+	console := wintermtest.NewConsole(10, 4)
+	console.Title = "shell"
+	tt := newTestTerminal(t, console)
+
+	tt.write("\x1b]0;vim\x07")
+	if console.Title != "vim" {
+		t.Errorf("title is %q, expected \"vim\"", console.Title)
+	}
+
+	// RIS restores the title the console had
+	tt.write("\x1bc")
+	if console.Title != "shell" {
+		t.Errorf("title is %q after RIS, expected \"shell\"", console.Title)
+	}
+}
+
+func TestWinEventHandlerDECTCEM(t *testing.T) {
+	tt := newTestTerminal(t, wintermtest.NewConsole(10, 4))
+
+	tests := []struct {
+		output  string
+		visible bool
+	}{
+		{"\x1b[?25l", false},
+		{"\x1b[?25h", true},
+		{"\x1b[?25l\x1b[!p", true}, // DECSTR
+		{"\x1b[?25l\x1bc", true},   // RIS
+	}
+
+	for _, test := range tests {
+		tt.write(test.output)
+
+		var cursorInfo winterm.CONSOLE_CURSOR_INFO
+		if err := tt.console.GetConsoleCursorInfo(tt.console.Handle(), &cursorInfo); err != nil {
+			t.Fatal(err)
+		}
+		if visible := cursorInfo.Visible != 0; visible != test.visible {
+			t.Errorf("after %q, the cursor is visible %v, expected %v", test.output, visible, test.visible)
+		}
+		if visible := tt.handler.Modes().CursorVisible; visible != test.visible {
+			t.Errorf("after %q, the mode reports the cursor visible %v, expected %v", test.output, visible, test.visible)
+		}
+	}
+}
+
+func TestWinEventHandlerSynchronizedUpdate(t *testing.T) {
+	for _, bufferSwap := range []bool{false, true} {
+		var opts []winterm.HandlerOption
+		if bufferSwap {
+			opts = append(opts, winterm.WithBufferSwap())
+		}
+		tt := newTestTerminal(t, wintermtest.NewConsole(10, 4), opts...)
+		tt.write("old")
+
+		// The update is rendered to a hidden buffer, displayed when it ends
+		tt.write("\x1b[?2026h\x1b[2J\x1b[Hnew")
+		if n := tt.console.ScreenBuffers(); n != 2 {
+			t.Errorf("with buffer swap %v, %d screen buffers open during the update, expected 2", bufferSwap, n)
+		}
+		if lines := tt.console.Screen(tt.console.ActiveScreenBuffer()); lines[0] != "old" {
+			t.Errorf("with buffer swap %v, %q is displayed during the update, expected \"old\"", bufferSwap, lines[0])
+		}
+
+		tt.write("\x1b[?2026l")
+		if tt.console.ActiveScreenBuffer() != tt.console.Handle() {
+			t.Errorf("with buffer swap %v, the original buffer is not active after the update", bufferSwap)
+		}
+		if n := tt.console.ScreenBuffers(); n != 1 {
+			t.Errorf("with buffer swap %v, %d screen buffers open after the update, expected 1", bufferSwap, n)
+		}
+		tt.expect([]string{"new", "", "", ""}, 3, 0)
+	}
+}
+
+func TestNewConsoleEventHandlerNotConsole(t *testing.T) {
+	console := wintermtest.NewConsole(10, 4)
+	_, err := winterm.NewConsoleEventHandler(console, console.Handle()+1, nil)
+	if !winterm.IsNotConsole(err) {
+		t.Errorf("error is %v, expected a *NotConsoleError", err)
+	}
+}
+
+func TestNewSharedHandler(t *testing.T) {
+	tt := newTestTerminal(t, wintermtest.NewConsole(10, 4), winterm.WithDeferredUpdates())
+	shared := tt.handler.NewSharedHandler(nil)
+	sharedParser := ansiterm.CreateParser("Ground", shared, ansiterm.WithUTF8())
+
+	// Both streams render to the same frame
+	tt.write("out\r\n")
+	if _, err := sharedParser.Parse([]byte("err")); err != nil {
+		t.Fatal(err)
+	}
+	if n := tt.console.ScreenBuffers(); n != 2 {
+		t.Errorf("%d screen buffers open while rendering, expected 2", n)
+	}
+
+	// The frame is presented, and its buffer closed, with the last handler
+	if err := shared.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := tt.console.ScreenBuffers(); n != 2 {
+		t.Errorf("%d screen buffers open with one handler closed, expected 2", n)
+	}
+	if err := tt.handler.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := tt.console.ScreenBuffers(); n != 1 {
+		t.Errorf("%d screen buffers open with both handlers closed, expected 1", n)
+	}
+	if tt.console.ActiveScreenBuffer() != tt.console.Handle() {
+		t.Error("the original buffer is not active with both handlers closed")
+	}
+	tt.expect([]string{"out", "err", "", ""}, 3, 1)
+}
+
+func TestWinEventHandlerInvalidate(t *testing.T) {
+	tt := newTestTerminal(t, wintermtest.NewConsole(10, 4))
+	tt.write("\x1b[4;5H")
+
+	// The window shrinks behind the handler's back; until told, it works
+	// from its cached state
+	if err := tt.console.SetConsoleWindowInfo(tt.console.Handle(), true, winterm.SMALL_RECT{Right: 9, Bottom: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if position, err := tt.handler.CursorPosition(); err != nil || position != (winterm.COORD{X: 4, Y: 3}) {
+		t.Errorf("cursor position is %+v, %v before Invalidate, expected the cached {X:4 Y:3}", position, err)
+	}
+
+	// Once invalidated, the console is queried and the margins follow the
+	// new size
+	tt.handler.Invalidate()
+	tt.write("\x1b[99;99H")
+	if _, bottom, _, _ := tt.handler.ScrollRegion(); bottom != 2 {
+		t.Errorf("scroll region extends to row %d after Invalidate, expected 2", bottom)
+	}
+	tt.expect([]string{"", "", ""}, 9, 2)
+}
+
+func TestWinEventHandlerClose(t *testing.T) {
+	console := wintermtest.NewConsole(10, 4)
+	console.Title = "shell"
+	tt := newTestTerminal(t, console)
+
+	initial, err := console.GetConsoleScreenBufferInfo(console.Handle())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mode, err := console.GetConsoleMode(console.Handle())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt.write("\x1b]0;vim\x07\x1b[?7l\x1b[?25l\x1b[31ma")
+	if err := tt.handler.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if actual, err := console.GetConsoleMode(console.Handle()); err != nil || actual != mode {
+		t.Errorf("mode is %#x, %v after Close, expected %#x", actual, err, mode)
+	}
+
+	var cursorInfo winterm.CONSOLE_CURSOR_INFO
+	if err := console.GetConsoleCursorInfo(console.Handle(), &cursorInfo); err != nil || cursorInfo.Visible == 0 {
+		t.Errorf("cursor is %+v, %v after Close, expected it visible", cursorInfo, err)
+	}
+
+	if info, err := console.GetConsoleScreenBufferInfo(console.Handle()); err != nil || info.Attributes != initial.Attributes {
+		t.Errorf("attributes are %+v, %v after Close, expected %#x", info, err, initial.Attributes)
+	}
+
+	if console.Title != "shell" {
+		t.Errorf("title is %q after Close, expected \"shell\"", console.Title)
+	}
+
+	// Closing again does nothing
+	if err := tt.handler.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWinEventHandlerViewportPinning(t *testing.T) {
+	// A window of 4 rows onto a buffer of 10, with history above it
+	console := wintermtest.NewConsole(10, 10)
+	if err := console.SetConsoleWindowInfo(console.Handle(), true, winterm.SMALL_RECT{Top: 3, Right: 9, Bottom: 6}); err != nil {
+		t.Fatal(err)
+	}
+	tt := newTestTerminal(t, console, winterm.WithViewportPinning())
+	tt.write("\x1b[Ha\r\nb")
+
+	// The user scrolls back to the top of the buffer
+	pinned := winterm.SMALL_RECT{Right: 9, Bottom: 3}
+	if err := console.SetConsoleWindowInfo(console.Handle(), true, pinned); err != nil {
+		t.Fatal(err)
+	}
+	tt.handler.Invalidate()
+
+	// Output continues below, leaving the window where it is
+	tt.write("\r\nf\r\ng")
+	info, err := console.GetConsoleScreenBufferInfo(console.Handle())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Window != pinned {
+		t.Errorf("window is %+v after output, expected %+v", info.Window, pinned)
+	}
+	if lines := []string{console.Line(console.Handle(), 5), console.Line(console.Handle(), 6)}; !reflect.DeepEqual(lines, []string{"f", "g"}) {
+		t.Errorf("rows 5 and 6 are %q, expected [\"f\" \"g\"]", lines)
+	}
+}
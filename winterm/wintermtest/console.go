@@ -0,0 +1,709 @@
+// Package wintermtest provides an in-memory Windows console, so that winterm
+// handlers can be tested without a console, on any platform:
+//
+//	console := wintermtest.NewConsole(80, 24)
+//	handler, err := winterm.NewConsoleEventHandler(console, console.Handle(), nil)
+//	...
+//	parser := ansiterm.CreateParser("Ground", handler, ansiterm.WithUTF8())
+//	parser.Parse(output)
+//	lines := console.Screen(console.Handle())
+//
+// The console models what the handler relies on: screen buffers with a
+// window onto them, the cursor and attributes, and the output processing and
+// wrapping of WriteConsole as the console host performs them without virtual
+// terminal processing. It does not model input.
+package wintermtest
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/Azure/go-ansiterm"
+	"github.com/Azure/go-ansiterm/winterm"
+)
+
+// Errors returned by the console for the failures the Windows API reports as
+// ERROR_INVALID_HANDLE and ERROR_INVALID_PARAMETER.
+var (
+	ErrInvalidHandle    = errors.New("wintermtest: the handle is invalid")
+	ErrInvalidParameter = errors.New("wintermtest: the parameter is incorrect")
+)
+
+// The attributes marking the halves of a double-width character.
+const (
+	commonLVBLeadingByte  winterm.WORD = 0x0100
+	commonLVBTrailingByte winterm.WORD = 0x0200
+)
+
+// defaultAttributes is the gray on black of a new console.
+const defaultAttributes = winterm.FOREGROUND_RED | winterm.FOREGROUND_GREEN | winterm.FOREGROUND_BLUE
+
+// Console is an in-memory console implementing winterm.Console. It is not
+// safe for concurrent use.
+type Console struct {
+	buffers map[uintptr]*screenBuffer
+	next    uintptr
+	handle  uintptr
+	active  uintptr
+
+	CodePage uint32                    // The output code page, initially CP_UTF8
+	Title    string                    // The title, as set by SetConsoleTitle
+	Largest  winterm.COORD             // The largest window possible, as reported by GetLargestConsoleWindowSize
+	Font     winterm.CONSOLE_FONT_INFO // The font, initially of 8 by 16 pixel cells
+	Window   uintptr                   // The console window, zero for a console without one
+	Flashes  []winterm.FLASHWINFO      // The calls to FlashWindowEx
+	Bells    int                       // The BEL characters written with processed output
+}
+
+// screenBuffer is a screen buffer, its cells stored row by row.
+type screenBuffer struct {
+	size       winterm.COORD
+	cells      []winterm.CHAR_INFO
+	cursor     winterm.COORD
+	attributes winterm.WORD
+	window     winterm.SMALL_RECT
+	mode       uint32
+	cursorInfo winterm.CONSOLE_CURSOR_INFO
+}
+
+// NewConsole creates a console with a single screen buffer of cols by rows
+// cells, all of them in the window, which is active and returned by Handle.
+// Its largest window is that of a 1920 by 1080 pixel display, or the buffer
+// if that is larger.
+func NewConsole(cols int, rows int) *Console {
+	c := &Console{
+		buffers:  make(map[uintptr]*screenBuffer),
+		next:     1,
+		CodePage: winterm.CP_UTF8,
+		Largest:  winterm.COORD{X: 240, Y: 67},
+		Font:     winterm.CONSOLE_FONT_INFO{FontSize: winterm.COORD{X: 8, Y: 16}},
+	}
+
+	size := winterm.COORD{X: winterm.SHORT(cols), Y: winterm.SHORT(rows)}
+	if size.X > c.Largest.X {
+		c.Largest.X = size.X
+	}
+	if size.Y > c.Largest.Y {
+		c.Largest.Y = size.Y
+	}
+
+	c.handle = c.newScreenBuffer(size)
+	c.active = c.handle
+	return c
+}
+
+// newScreenBuffer adds a blank screen buffer of the given size, with the
+// window at its top left and as large as possible.
+func (c *Console) newScreenBuffer(size winterm.COORD) uintptr {
+	b := &screenBuffer{
+		size:       size,
+		cells:      make([]winterm.CHAR_INFO, int(size.X)*int(size.Y)),
+		attributes: defaultAttributes,
+		window:     winterm.SMALL_RECT{Right: minShort(size.X, c.Largest.X) - 1, Bottom: minShort(size.Y, c.Largest.Y) - 1},
+		mode:       winterm.ENABLE_PROCESSED_OUTPUT | winterm.ENABLE_WRAP_AT_EOL_OUTPUT,
+		cursorInfo: winterm.CONSOLE_CURSOR_INFO{Size: 25, Visible: 1},
+	}
+	b.blank(b.cells)
+
+	handle := c.next
+	c.next++
+	c.buffers[handle] = b
+	return handle
+}
+
+// Handle returns the screen buffer the console was created with, as
+// GetStdHandle(STD_OUTPUT_HANDLE) would.
+func (c *Console) Handle() uintptr {
+	return c.handle
+}
+
+// ActiveScreenBuffer returns the screen buffer being displayed.
+func (c *Console) ActiveScreenBuffer() uintptr {
+	return c.active
+}
+
+// ScreenBuffers returns the number of screen buffers open.
+func (c *Console) ScreenBuffers() int {
+	return len(c.buffers)
+}
+
+// Screen returns the text of each row of the window onto the screen buffer
+// identified by handle, without trailing blanks.
+func (c *Console) Screen(handle uintptr) []string {
+	b := c.buffers[handle]
+	if b == nil {
+		return nil
+	}
+
+	var lines []string
+	for y := b.window.Top; y <= b.window.Bottom; y++ {
+		lines = append(lines, b.text(y, b.window.Left, b.window.Right))
+	}
+	return lines
+}
+
+// Line returns the text of row y of the screen buffer identified by handle,
+// counting from the top of the buffer, without trailing blanks.
+func (c *Console) Line(handle uintptr, y int) string {
+	b := c.buffers[handle]
+	if b == nil || y < 0 || y >= int(b.size.Y) {
+		return ""
+	}
+
+	return b.text(winterm.SHORT(y), 0, b.size.X-1)
+}
+
+// Cell returns the cell at column x of row y of the screen buffer identified
+// by handle, counting from its top left.
+func (c *Console) Cell(handle uintptr, x int, y int) winterm.CHAR_INFO {
+	b := c.buffers[handle]
+	if b == nil || !b.contains(winterm.SHORT(x), winterm.SHORT(y)) {
+		return winterm.CHAR_INFO{}
+	}
+
+	return *b.cell(winterm.SHORT(x), winterm.SHORT(y))
+}
+
+var _ winterm.Console = (*Console)(nil)
+
+func (c *Console) lookup(handle uintptr) (*screenBuffer, error) {
+	b := c.buffers[handle]
+	if b == nil {
+		return nil, ErrInvalidHandle
+	}
+
+	return b, nil
+}
+
+// CreateConsoleScreenBuffer creates a blank screen buffer the size of the
+// active screen buffer's window.
+func (c *Console) CreateConsoleScreenBuffer() (uintptr, error) {
+	size := winterm.COORD{X: 80, Y: 25}
+	if b := c.buffers[c.active]; b != nil {
+		size = windowSize(b.window)
+	}
+
+	return c.newScreenBuffer(size), nil
+}
+
+func (c *Console) CloseHandle(handle uintptr) error {
+	if _, err := c.lookup(handle); err != nil {
+		return err
+	}
+
+	delete(c.buffers, handle)
+	return nil
+}
+
+func (c *Console) SetConsoleActiveScreenBuffer(handle uintptr) error {
+	if _, err := c.lookup(handle); err != nil {
+		return err
+	}
+
+	c.active = handle
+	return nil
+}
+
+func (c *Console) GetConsoleScreenBufferInfo(handle uintptr) (*winterm.CONSOLE_SCREEN_BUFFER_INFO, error) {
+	b, err := c.lookup(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	return &winterm.CONSOLE_SCREEN_BUFFER_INFO{
+		Size:              b.size,
+		CursorPosition:    b.cursor,
+		Attributes:        b.attributes,
+		Window:            b.window,
+		MaximumWindowSize: winterm.COORD{X: minShort(b.size.X, c.Largest.X), Y: minShort(b.size.Y, c.Largest.Y)},
+	}, nil
+}
+
+// SetConsoleScreenBufferSize resizes the screen buffer, keeping the contents
+// at its top left. The window must fit within the new size.
+func (c *Console) SetConsoleScreenBufferSize(handle uintptr, size winterm.COORD) error {
+	b, err := c.lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	if b.window.Right >= size.X || b.window.Bottom >= size.Y {
+		return ErrInvalidParameter
+	}
+
+	cells := make([]winterm.CHAR_INFO, int(size.X)*int(size.Y))
+	b.blank(cells)
+	for y := winterm.SHORT(0); y < minShort(size.Y, b.size.Y); y++ {
+		copy(cells[int(y)*int(size.X):int(y+1)*int(size.X)], b.cells[int(y)*int(b.size.X):int(y+1)*int(b.size.X)])
+	}
+
+	b.size = size
+	b.cells = cells
+	b.cursor.X = minShort(b.cursor.X, size.X-1)
+	b.cursor.Y = minShort(b.cursor.Y, size.Y-1)
+	return nil
+}
+
+// SetConsoleWindowInfo moves and resizes the window, which must fit within
+// the screen buffer and the largest window possible.
+func (c *Console) SetConsoleWindowInfo(handle uintptr, isAbsolute bool, rect winterm.SMALL_RECT) error {
+	b, err := c.lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	if !isAbsolute {
+		rect.Left += b.window.Left
+		rect.Top += b.window.Top
+		rect.Right += b.window.Right
+		rect.Bottom += b.window.Bottom
+	}
+
+	size := windowSize(rect)
+	if rect.Left < 0 || rect.Top < 0 || rect.Right >= b.size.X || rect.Bottom >= b.size.Y ||
+		size.X < 1 || size.Y < 1 || size.X > c.Largest.X || size.Y > c.Largest.Y {
+		return ErrInvalidParameter
+	}
+
+	b.window = rect
+	return nil
+}
+
+func (c *Console) GetLargestConsoleWindowSize(handle uintptr) (winterm.COORD, error) {
+	if _, err := c.lookup(handle); err != nil {
+		return winterm.COORD{}, err
+	}
+
+	return c.Largest, nil
+}
+
+func (c *Console) GetCurrentConsoleFont(handle uintptr) (*winterm.CONSOLE_FONT_INFO, error) {
+	if _, err := c.lookup(handle); err != nil {
+		return nil, err
+	}
+
+	font := c.Font
+	return &font, nil
+}
+
+func (c *Console) GetConsoleMode(handle uintptr) (uint32, error) {
+	b, err := c.lookup(handle)
+	if err != nil {
+		return 0, err
+	}
+
+	return b.mode, nil
+}
+
+func (c *Console) SetConsoleMode(handle uintptr, mode uint32) error {
+	b, err := c.lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	b.mode = mode
+	return nil
+}
+
+func (c *Console) GetConsoleCursorInfo(handle uintptr, cursorInfo *winterm.CONSOLE_CURSOR_INFO) error {
+	b, err := c.lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	*cursorInfo = b.cursorInfo
+	return nil
+}
+
+func (c *Console) SetConsoleCursorInfo(handle uintptr, cursorInfo *winterm.CONSOLE_CURSOR_INFO) error {
+	b, err := c.lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	if cursorInfo.Size < 1 || cursorInfo.Size > 100 {
+		return ErrInvalidParameter
+	}
+
+	b.cursorInfo = *cursorInfo
+	return nil
+}
+
+// SetConsoleCursorPosition moves the cursor, scrolling the window to it if
+// it is outside.
+func (c *Console) SetConsoleCursorPosition(handle uintptr, coord winterm.COORD) error {
+	b, err := c.lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	if !b.contains(coord.X, coord.Y) {
+		return ErrInvalidParameter
+	}
+
+	b.cursor = coord
+	b.showCursor()
+	return nil
+}
+
+func (c *Console) SetConsoleTextAttribute(handle uintptr, attribute winterm.WORD) error {
+	b, err := c.lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	b.attributes = attribute
+	return nil
+}
+
+// WriteConsole writes text at the cursor in the current attributes. With
+// ENABLE_PROCESSED_OUTPUT, BEL, BS, TAB, CR, and LF are interpreted, LF
+// starting a new line; with ENABLE_WRAP_AT_EOL_OUTPUT, the cursor moves to the
+// next line after the last column is written, and otherwise stays on it. The
+// buffer scrolls up when a new line is started on its last row, and the window
+// scrolls to keep the cursor in view.
+//
+// A cell holds a single UTF-16 code unit, so characters outside the Basic
+// Multilingual Plane are written as U+FFFD, and those with no width are
+// dropped. Double-width characters take two cells, marked as the leading and
+// trailing halves.
+func (c *Console) WriteConsole(handle uintptr, buffer []uint16) error {
+	b, err := c.lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	processed := b.mode&winterm.ENABLE_PROCESSED_OUTPUT != 0
+	wrap := b.mode&winterm.ENABLE_WRAP_AT_EOL_OUTPUT != 0
+
+	for _, r := range utf16.Decode(buffer) {
+		if processed {
+			switch r {
+			case '\a':
+				c.Bells++
+				continue
+			case '\b':
+				if b.cursor.X > 0 {
+					b.cursor.X--
+				}
+				continue
+			case '\t':
+				b.cursor.X = minShort((b.cursor.X/8+1)*8, b.size.X-1)
+				continue
+			case '\r':
+				b.cursor.X = 0
+				continue
+			case '\n':
+				b.newLine()
+				continue
+			}
+		}
+
+		width := winterm.SHORT(ansiterm.RuneWidth(r))
+		if width < 1 {
+			continue
+		}
+		if width > b.size.X {
+			width = 1
+		}
+		if r > 0xFFFF {
+			r = utf8.RuneError
+		}
+
+		if b.cursor.X+width > b.size.X {
+			if !wrap {
+				b.cursor.X = b.size.X - width
+			} else {
+				b.newLine()
+			}
+		}
+
+		cell := b.cell(b.cursor.X, b.cursor.Y)
+		*cell = winterm.CHAR_INFO{UnicodeChar: winterm.WCHAR(r), Attributes: b.attributes}
+		if width == 2 {
+			cell.Attributes |= commonLVBLeadingByte
+			*b.cell(b.cursor.X+1, b.cursor.Y) = winterm.CHAR_INFO{UnicodeChar: winterm.WCHAR(r), Attributes: b.attributes | commonLVBTrailingByte}
+		}
+
+		b.cursor.X += width
+		if b.cursor.X == b.size.X {
+			if wrap {
+				b.newLine()
+			} else {
+				b.cursor.X = b.size.X - 1
+			}
+		}
+	}
+
+	b.showCursor()
+	return nil
+}
+
+// FillConsoleOutputCharacter writes char to count cells from coord onward,
+// continuing onto the following rows, up to the end of the buffer.
+func (c *Console) FillConsoleOutputCharacter(handle uintptr, char winterm.WCHAR, count uint32, coord winterm.COORD) error {
+	return c.fill(handle, count, coord, func(cell *winterm.CHAR_INFO) {
+		cell.UnicodeChar = char
+	})
+}
+
+// FillConsoleOutputAttribute applies attribute to count cells from coord
+// onward, continuing onto the following rows, up to the end of the buffer.
+func (c *Console) FillConsoleOutputAttribute(handle uintptr, attribute winterm.WORD, count uint32, coord winterm.COORD) error {
+	return c.fill(handle, count, coord, func(cell *winterm.CHAR_INFO) {
+		cell.Attributes = attribute
+	})
+}
+
+func (c *Console) fill(handle uintptr, count uint32, coord winterm.COORD, set func(cell *winterm.CHAR_INFO)) error {
+	b, err := c.lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	if !b.contains(coord.X, coord.Y) {
+		return ErrInvalidParameter
+	}
+
+	start := int(coord.Y)*int(b.size.X) + int(coord.X)
+	for i := start; i < len(b.cells) && uint32(i-start) < count; i++ {
+		set(&b.cells[i])
+	}
+	return nil
+}
+
+// ScrollConsoleScreenBuffer moves the cells of scrollRect, limited to the
+// buffer, so that its top left is at destOrigin. Only cells within clipRect
+// change: those of scrollRect that are not overwritten are filled with char.
+func (c *Console) ScrollConsoleScreenBuffer(handle uintptr, scrollRect winterm.SMALL_RECT, clipRect winterm.SMALL_RECT, destOrigin winterm.COORD, char winterm.CHAR_INFO) error {
+	b, err := c.lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	bounds := winterm.SMALL_RECT{Right: b.size.X - 1, Bottom: b.size.Y - 1}
+	source, ok := intersect(scrollRect, bounds)
+	if !ok {
+		return ErrInvalidParameter
+	}
+
+	clip, ok := intersect(clipRect, bounds)
+	if !ok {
+		return nil
+	}
+
+	cells := make([]winterm.CHAR_INFO, len(b.cells))
+	copy(cells, b.cells)
+
+	dx := destOrigin.X - scrollRect.Left
+	dy := destOrigin.Y - scrollRect.Top
+	for y := clip.Top; y <= clip.Bottom; y++ {
+		for x := clip.Left; x <= clip.Right; x++ {
+			switch {
+			case inside(source, x-dx, y-dy):
+				*b.cell(x, y) = cells[int(y-dy)*int(b.size.X)+int(x-dx)]
+			case inside(source, x, y):
+				*b.cell(x, y) = char
+			}
+		}
+	}
+	return nil
+}
+
+// ReadConsoleOutput copies the cells of readRegion, limited to the screen
+// buffer and to buffer, into buffer from bufferCoord onward. readRegion is
+// updated to the region read.
+func (c *Console) ReadConsoleOutput(handle uintptr, buffer []winterm.CHAR_INFO, bufferSize winterm.COORD, bufferCoord winterm.COORD, readRegion *winterm.SMALL_RECT) error {
+	return c.copyRegion(handle, buffer, bufferSize, bufferCoord, readRegion, func(cell *winterm.CHAR_INFO, i int) {
+		buffer[i] = *cell
+	})
+}
+
+// WriteConsoleOutput copies cells from buffer, starting at bufferCoord, to
+// writeRegion, limited to the screen buffer and to buffer. writeRegion is
+// updated to the region written.
+func (c *Console) WriteConsoleOutput(handle uintptr, buffer []winterm.CHAR_INFO, bufferSize winterm.COORD, bufferCoord winterm.COORD, writeRegion *winterm.SMALL_RECT) error {
+	return c.copyRegion(handle, buffer, bufferSize, bufferCoord, writeRegion, func(cell *winterm.CHAR_INFO, i int) {
+		*cell = buffer[i]
+	})
+}
+
+// copyRegion calls copyCell for each cell of region, limited to the screen
+// buffer and to the bufferSize cells of buffer from bufferCoord onward, with
+// the index of the corresponding cell of buffer.
+func (c *Console) copyRegion(handle uintptr, buffer []winterm.CHAR_INFO, bufferSize winterm.COORD, bufferCoord winterm.COORD, region *winterm.SMALL_RECT, copyCell func(cell *winterm.CHAR_INFO, i int)) error {
+	b, err := c.lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	if int(bufferSize.X)*int(bufferSize.Y) > len(buffer) || !inside(winterm.SMALL_RECT{Right: bufferSize.X - 1, Bottom: bufferSize.Y - 1}, bufferCoord.X, bufferCoord.Y) {
+		return ErrInvalidParameter
+	}
+
+	// The part of region that the rest of buffer covers
+	available := winterm.SMALL_RECT{
+		Left:   region.Left,
+		Top:    region.Top,
+		Right:  region.Left + bufferSize.X - bufferCoord.X - 1,
+		Bottom: region.Top + bufferSize.Y - bufferCoord.Y - 1,
+	}
+
+	clipped, ok := intersect(*region, winterm.SMALL_RECT{Right: b.size.X - 1, Bottom: b.size.Y - 1})
+	if ok {
+		clipped, ok = intersect(clipped, available)
+	}
+	if !ok {
+		return ErrInvalidParameter
+	}
+
+	for y := clipped.Top; y <= clipped.Bottom; y++ {
+		for x := clipped.Left; x <= clipped.Right; x++ {
+			i := int(bufferCoord.Y+y-region.Top)*int(bufferSize.X) + int(bufferCoord.X+x-region.Left)
+			copyCell(b.cell(x, y), i)
+		}
+	}
+
+	*region = clipped
+	return nil
+}
+
+// GetConsoleOutputCP returns CodePage.
+func (c *Console) GetConsoleOutputCP() (uint32, error) {
+	return c.CodePage, nil
+}
+
+// WideCharToMultiByte encodes text as UTF-8 for CP_UTF8, and as ASCII for
+// every other code page, with '?' for the characters ASCII lacks.
+func (c *Console) WideCharToMultiByte(codepage uint32, text []uint16) ([]byte, error) {
+	runes := utf16.Decode(text)
+	if codepage == winterm.CP_UTF8 {
+		return []byte(string(runes)), nil
+	}
+
+	b := make([]byte, len(runes))
+	for i, r := range runes {
+		if r < utf8.RuneSelf {
+			b[i] = byte(r)
+		} else {
+			b[i] = '?'
+		}
+	}
+	return b, nil
+}
+
+func (c *Console) GetConsoleTitle() (string, error) {
+	return c.Title, nil
+}
+
+func (c *Console) SetConsoleTitle(title string) error {
+	c.Title = title
+	return nil
+}
+
+func (c *Console) GetConsoleWindow() uintptr {
+	return c.Window
+}
+
+// FlashWindowEx records the call in Flashes.
+func (c *Console) FlashWindowEx(info *winterm.FLASHWINFO) {
+	c.Flashes = append(c.Flashes, *info)
+}
+
+func (b *screenBuffer) contains(x winterm.SHORT, y winterm.SHORT) bool {
+	return 0 <= x && x < b.size.X && 0 <= y && y < b.size.Y
+}
+
+func (b *screenBuffer) cell(x winterm.SHORT, y winterm.SHORT) *winterm.CHAR_INFO {
+	return &b.cells[int(y)*int(b.size.X)+int(x)]
+}
+
+// blank fills cells with spaces in the current attributes.
+func (b *screenBuffer) blank(cells []winterm.CHAR_INFO) {
+	for i := range cells {
+		cells[i] = winterm.CHAR_INFO{UnicodeChar: ' ', Attributes: b.attributes}
+	}
+}
+
+// newLine moves the cursor to the start of the next row, first scrolling the
+// buffer up if the cursor is on its last row.
+func (b *screenBuffer) newLine() {
+	b.cursor.X = 0
+	if b.cursor.Y < b.size.Y-1 {
+		b.cursor.Y++
+		return
+	}
+
+	copy(b.cells, b.cells[b.size.X:])
+	b.blank(b.cells[len(b.cells)-int(b.size.X):])
+}
+
+// showCursor scrolls the window the least needed to bring the cursor into
+// view.
+func (b *screenBuffer) showCursor() {
+	size := windowSize(b.window)
+	if b.cursor.X < b.window.Left {
+		b.window.Left = b.cursor.X
+	} else if b.cursor.X > b.window.Right {
+		b.window.Left = b.cursor.X - size.X + 1
+	}
+	if b.cursor.Y < b.window.Top {
+		b.window.Top = b.cursor.Y
+	} else if b.cursor.Y > b.window.Bottom {
+		b.window.Top = b.cursor.Y - size.Y + 1
+	}
+
+	b.window.Right = b.window.Left + size.X - 1
+	b.window.Bottom = b.window.Top + size.Y - 1
+}
+
+// text returns the characters of row y from left to right, without trailing
+// blanks or the trailing halves of double-width characters.
+func (b *screenBuffer) text(y winterm.SHORT, left winterm.SHORT, right winterm.SHORT) string {
+	var sb strings.Builder
+	for x := left; x <= right; x++ {
+		cell := b.cell(x, y)
+		if cell.Attributes&commonLVBTrailingByte != 0 {
+			continue
+		}
+		sb.WriteRune(rune(cell.UnicodeChar))
+	}
+
+	return strings.TrimRight(sb.String(), " ")
+}
+
+func windowSize(rect winterm.SMALL_RECT) winterm.COORD {
+	return winterm.COORD{X: rect.Right - rect.Left + 1, Y: rect.Bottom - rect.Top + 1}
+}
+
+// intersect returns the intersection of a and b, and whether it is not
+// empty.
+func intersect(a winterm.SMALL_RECT, b winterm.SMALL_RECT) (winterm.SMALL_RECT, bool) {
+	r := winterm.SMALL_RECT{
+		Left:   maxShort(a.Left, b.Left),
+		Top:    maxShort(a.Top, b.Top),
+		Right:  minShort(a.Right, b.Right),
+		Bottom: minShort(a.Bottom, b.Bottom),
+	}
+	return r, r.Left <= r.Right && r.Top <= r.Bottom
+}
+
+func inside(rect winterm.SMALL_RECT, x winterm.SHORT, y winterm.SHORT) bool {
+	return rect.Left <= x && x <= rect.Right && rect.Top <= y && y <= rect.Bottom
+}
+
+func minShort(a winterm.SHORT, b winterm.SHORT) winterm.SHORT {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxShort(a winterm.SHORT, b winterm.SHORT) winterm.SHORT {
+	if a > b {
+		return a
+	}
+	return b
+}